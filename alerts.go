@@ -0,0 +1,256 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// Alert condition, checked every time stats are dumped
+type alertRule interface {
+	// Name of the alert, as shown in messages (e.g. "High-traffic")
+	name() string
+	// Whether the alert is firing, along with a description of the condition
+	evaluate(s *stats) (bool, string)
+}
+
+// Alert condition blaming individual clients
+type abuseRule interface {
+	alertRule
+	// IPs of the clients triggering the alert
+	offenders(s *stats) []string
+}
+
+// Alert condition comparing a single value against a threshold
+type measuredRule interface {
+	alertRule
+	// Value compared against the threshold, and the threshold itself
+	measure(s *stats) (float64, float64)
+}
+
+// Alert condition with a second, higher threshold telling critical
+// conditions apart from mere warnings
+type tieredRule interface {
+	alertRule
+	// Severity of the firing alert, 0 if no critical threshold is set
+	severity(s *stats) severity
+}
+
+// Alert condition relying on the time it is checked at, rather than only
+// on stats
+type clockedRule interface {
+	alertRule
+	// Replace the wall clock, e.g. with log time when analyzing
+	setClock(now func() time.Time)
+}
+
+// Urgency of a firing alert
+type severity int
+
+const (
+	severityWarning severity = iota + 1
+	severityCritical
+)
+
+func (l severity) String() string {
+	switch l {
+	case severityWarning:
+		return "warning"
+	case severityCritical:
+		return "critical"
+	}
+	return ""
+}
+
+// Command-line flag to set the critical threshold of high-traffic alerts
+var qpsCritical = flag.Float64("qps-critical", 0, "Average QPS above which high-traffic alerts are critical rather than warnings (0 makes every alert critical)")
+
+// Alert firing when the average QPS in the window exceeds -qps
+type highTrafficRule struct {
+	critical float64
+}
+
+func (highTrafficRule) name() string {
+	return "High-traffic"
+}
+
+func (highTrafficRule) evaluate(s *stats) (bool, string) {
+	qps, _ := s.getQueryRate()
+	return s.alerting, fmt.Sprintf("at %f queries per second on average", qps)
+}
+
+func (highTrafficRule) measure(s *stats) (float64, float64) {
+	qps, err := s.getQueryRate()
+	if err != nil {
+		qps = 0
+	}
+	return qps, *qpsThreshold
+}
+
+func (r highTrafficRule) severity(s *stats) severity {
+	if r.critical == 0 {
+		return 0
+	}
+	if qps, err := s.getQueryRate(); err == nil && qps > r.critical {
+		return severityCritical
+	}
+	return severityWarning
+}
+
+// Build the alert rules enabled through command-line flags
+func configuredAlertRules() []alertRule {
+	rules := []alertRule{highTrafficRule{critical: *qpsCritical}}
+	if *geoQPS > 0 {
+		rules = append(rules, &geoTrafficRule{threshold: *geoQPS, critical: *geoQPSCritical})
+	}
+	if *scanErrors > 0 {
+		rules = append(rules, &scanningRule{errors: *scanErrors, paths: *scanPaths})
+	}
+	if *referrerSpamRequests > 0 {
+		rules = append(rules, &referrerSpamRule{requests: *referrerSpamRequests, share: *referrerSpamShare})
+	}
+	if *authFailureQPS > 0 && len(authSections) > 0 {
+		rules = append(rules, &bruteForceRule{sections: authSections, threshold: *authFailureQPS})
+	}
+	if *ipQPS > 0 || *ipRequests > 0 {
+		rules = append(rules, &clientTrafficRule{qps: *ipQPS, requests: *ipRequests})
+	}
+	if *userQPS > 0 || *userRequests > 0 {
+		rules = append(rules, &userTrafficRule{qps: *userQPS, requests: *userRequests})
+	}
+	if *vhostQPS > 0 {
+		rules = append(rules, &vhostTrafficRule{threshold: *vhostQPS, critical: *vhostQPSCritical})
+	}
+	if *lowTrafficPeriod > 0 {
+		rules = append(rules, &lowTrafficRule{threshold: *minQPS, period: *lowTrafficPeriod, now: time.Now})
+	}
+	if *clientErrorPercent > 0 {
+		rules = append(rules, &clientErrorRule{threshold: *clientErrorPercent, period: *clientErrorPeriod, now: time.Now})
+	}
+	if *sloObjective > 0 {
+		rules = append(rules, &sloBurnRule{objective: *sloObjective})
+	}
+	if *apdexTarget > 0 {
+		rules = append(rules, &apdexRule{target: *apdexTarget, t: *apdexT})
+	}
+	for _, threshold := range sectionQPS {
+		rules = append(rules, &sectionTrafficRule{threshold})
+	}
+	if *newErrors > 0 {
+		rules = append(rules, &newErrorRule{errors: *newErrors, baseline: *newErrorBaseline})
+	}
+	for _, threshold := range sectionErrorPercent {
+		rules = append(rules, &sectionErrorRule{threshold})
+	}
+	for _, expression := range alertExprs {
+		rules = append(rules, &expressionRule{expression})
+	}
+	if *acceptAggregates {
+		rules = append(rules, &fleetTrafficRule{threshold: *qpsThreshold, critical: *qpsCritical})
+	}
+	var labels []string
+	for label := range sourceQPS {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		rules = append(rules, &sourceTrafficRule{label: label, threshold: sourceQPS[label]})
+	}
+	return rules
+}
+
+// Keeps track of which alerts are firing
+type alertTracker struct {
+	rules      []alertRule
+	firing     map[string]bool
+	severities map[string]severity // Severity of firing tiered alerts
+	bans       *banList            // Receives the offenders of abuse rules, if enabled
+	out        io.Writer           // Where alerts being triggered or abandoned are displayed
+
+	subscribers []alertSubscriber
+}
+
+// Alert being triggered, abandoned, escalated or de-escalated
+type alertChange struct {
+	Name     string
+	Firing   bool
+	Severity severity // 0 for alerts without a critical threshold, as urgent as critical ones
+	Detail   string
+
+	// Value compared against the threshold, for rules measuring one
+	Measured  bool
+	Value     float64
+	Threshold float64
+
+	// Busiest sections and client IPs when an alert is triggered
+	TopSections []apiCount
+	TopIPs      []apiCount
+}
+
+// Description of a firing alert, along with its severity if tiered
+func (c alertChange) state() string {
+	if c.Severity != 0 {
+		return fmt.Sprintf("firing (%s) %s", c.Severity, c.Detail)
+	}
+	return "firing " + c.Detail
+}
+
+// Function called whenever an alert changes
+type alertSubscriber func(change alertChange)
+
+func newAlertTracker(rules []alertRule) *alertTracker {
+	return &alertTracker{rules: rules, firing: make(map[string]bool), severities: make(map[string]severity), out: os.Stdout}
+}
+
+// Evaluate every rule, displaying alerts being triggered, abandoned or
+// changing severity
+func (a *alertTracker) check(s *stats) {
+	for _, rule := range a.rules {
+		firing, detail := rule.evaluate(s)
+		var level severity
+		if tiered, ok := rule.(tieredRule); ok && firing {
+			level = tiered.severity(s)
+		}
+
+		name := rule.name()
+		color := ansiBold + ansiRed
+		if level == severityWarning {
+			color = ansiBold + ansiYellow
+		}
+		switch {
+		case a.firing[name] && !firing:
+			fmt.Fprintln(a.out, colorize(ansiBold+ansiGreen, name+" alerting not firing anymore"))
+		case !a.firing[name] && firing && level != 0:
+			fmt.Fprintln(a.out, colorize(color, fmt.Sprintf("%s alerting is firing (%s) %s", name, level, detail)))
+		case !a.firing[name] && firing:
+			fmt.Fprintln(a.out, colorize(color, name+" alerting is firing "+detail))
+		case firing && level > a.severities[name]:
+			fmt.Fprintln(a.out, colorize(color, fmt.Sprintf("%s alerting escalated to %s %s", name, level, detail)))
+		case firing && level < a.severities[name]:
+			fmt.Fprintln(a.out, colorize(color, fmt.Sprintf("%s alerting down to %s %s", name, level, detail)))
+		}
+		if a.firing[name] != firing || a.severities[name] != level {
+			change := alertChange{Name: name, Firing: firing, Severity: level, Detail: detail}
+			if measured, ok := rule.(measuredRule); ok {
+				change.Measured = true
+				change.Value, change.Threshold = measured.measure(s)
+			}
+			if firing {
+				change.TopSections = apiCounts(s.scaled(s.sectionCounts), *topN)
+				change.TopIPs = apiCounts(s.scaled(s.ipCounts), *topN)
+			}
+			for _, subscriber := range a.subscribers {
+				subscriber(change)
+			}
+		}
+		a.firing[name] = firing
+		a.severities[name] = level
+
+		if abuse, ok := rule.(abuseRule); ok && a.bans != nil {
+			a.bans.update(rule.name(), abuse.offenders(s))
+		}
+	}
+}
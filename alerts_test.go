@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Rule whose outcome is set by the test
+type fakeTieredRule struct {
+	firing bool
+	level  severity
+}
+
+func (r *fakeTieredRule) name() string { return "High-traffic" }
+
+func (r *fakeTieredRule) evaluate(s *stats) (bool, string) {
+	return r.firing, "at 30.000000 queries per second on average"
+}
+
+func (r *fakeTieredRule) severity(s *stats) severity { return r.level }
+
+func (r *fakeTieredRule) measure(s *stats) (float64, float64) { return 30, 10 }
+
+func TestAlertSeverities(t *testing.T) {
+	rule := &fakeTieredRule{}
+	var out bytes.Buffer
+	alerts := newAlertTracker([]alertRule{rule})
+	alerts.out = &out
+	var changes []alertChange
+	alerts.subscribers = append(alerts.subscribers, func(change alertChange) {
+		changes = append(changes, change)
+	})
+
+	steps := []struct {
+		firing  bool
+		level   severity
+		message string
+	}{
+		{true, severityWarning, "High-traffic alerting is firing (warning) at 30.000000"},
+		{true, severityWarning, ""},
+		{true, severityCritical, "High-traffic alerting escalated to critical at 30.000000"},
+		{true, severityWarning, "High-traffic alerting down to warning at 30.000000"},
+		{false, 0, "High-traffic alerting not firing anymore"},
+		{true, 0, "High-traffic alerting is firing at 30.000000"},
+	}
+	for _, step := range steps {
+		out.Reset()
+		rule.firing, rule.level = step.firing, step.level
+		alerts.check(newStats())
+		if step.message == "" && out.Len() > 0 || !strings.Contains(out.String(), step.message) {
+			t.Errorf("%+v != %+v", out.String(), step.message)
+		}
+	}
+
+	var severities []severity
+	for _, change := range changes {
+		severities = append(severities, change.Severity)
+	}
+	expected := []severity{severityWarning, severityCritical, severityWarning, 0, 0}
+	if !reflect.DeepEqual(severities, expected) {
+		t.Errorf("%+v != %+v", severities, expected)
+	}
+	if !changes[0].Measured || changes[0].Value != 30 || changes[0].Threshold != 10 {
+		t.Errorf("Unexpected measure: %+v", changes[0])
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// Command-line flags to analyze log files after the fact
+var analyzeMode = flag.Bool("analyze", false, "Read log files to the end instead of following them, then write a report")
+var analyzeStep = flag.Duration("analyze-step", 10*time.Second, "Log time between alert checks when analyzing, as the interval between dumps when following")
+var reportFormat = flag.String("report", "text", "Format of the analysis report: text, html or markdown")
+var reportFile = flag.String("report-file", "", "File to write the analysis report to, instead of standard output")
+
+// Alert changing during an analysis
+type alertEvent struct {
+	Time time.Time
+	alertChange
+}
+
+// Outcome of analyzing log files
+type analysis struct {
+	Start     time.Time // Log time of the first record
+	End       time.Time // Log time of the last record
+	Total     *interval
+	Intervals []*interval
+	Alerts    []alertEvent
+	stats     *stats
+}
+
+// Function writing an analysis report
+type reportWriter func(w io.Writer, a *analysis) error
+
+// Report writer for the given format
+func newReportWriter(format string) (reportWriter, error) {
+	switch format {
+	case "text":
+		return writeTextReport, nil
+	case "html":
+		return writeHTMLReport, nil
+	case "markdown":
+		return writeMarkdownReport, nil
+	}
+	return nil, fmt.Errorf("Unknown report format: %s", format)
+}
+
+// Analyze the files given on the command line and write a report. Stats
+// are not dumped periodically and alerts are displayed on standard error,
+// which keeps standard output for the report. Interrupting the analysis
+// still reports on what was read so far
+func (m *monitor) runAnalysis() error {
+	writeReport, err := newReportWriter(*reportFormat)
+	if err != nil {
+		return err
+	}
+	out := os.Stdout
+	if *reportFile != "" {
+		if out, err = os.Create(*reportFile); err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	m.alerts.out = os.Stderr
+	a, err := m.analyze(analyzedFiles(), *analyzeStep, stop)
+	m.sinksMutex.Lock()
+	closeSinks(m.sinks)
+	m.sinksMutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeReport(out, a)
+}
+
+// Files read by analyze and replay: those given as arguments, else the
+// labeled sources, plus the access log file unless only sources are given
+func analyzedFiles() []labeledFile {
+	var files []labeledFile
+	for _, path := range fileArgs {
+		files = append(files, labeledFile{path: path})
+	}
+	if len(files) > 0 {
+		return files
+	}
+	files = append(files, sourceFiles...)
+	if len(files) == 0 || isFlagSet("filename") {
+		files = append(files, labeledFile{path: *fileName})
+	}
+	return files
+}
+
+// Read log files one after the other, accounting for records as when
+// following them. Time is taken from log timestamps: every step, alerts
+// are checked and an interval is closed. Reading stops early, with
+// whatever was read so far, upon a signal
+func (m *monitor) analyze(files []labeledFile, step time.Duration, stop <-chan os.Signal) (*analysis, error) {
+	a := &analysis{stats: m.stats}
+
+	// Alerts are attributed to, and rules relying on a clock follow, the
+	// log time they were checked at
+	var now time.Time
+	for _, rule := range m.alerts.rules {
+		if rule, ok := rule.(clockedRule); ok {
+			rule.setClock(func() time.Time { return now })
+		}
+	}
+	m.alerts.subscribers = append(m.alerts.subscribers, func(change alertChange) {
+		a.Alerts = append(a.Alerts, alertEvent{Time: now, alertChange: change})
+	})
+	closeInterval := func(t time.Time) {
+		now = t
+		m.mutex.Lock()
+		m.alerts.check(m.stats)
+		closed := m.stats.history.rotate(t)
+		m.stats.resetLatencies()
+		m.stats.resetPathTraffic()
+		m.stats.endSessionInterval()
+		m.mutex.Unlock()
+		m.sinksMutex.Lock()
+		m.sinkErrors = writeSinks(m.sinks, closed)
+		m.sinksMutex.Unlock()
+	}
+
+	var next time.Time
+	err := m.readFiles(files, stop, func(parsedLog *logRecord) {
+		if next.IsZero() {
+			a.Start = parsedLog.Timestamp
+			start := parsedLog.Timestamp.Truncate(step)
+			m.stats.history = newHistory(time.Duration(math.MaxInt64), start)
+			next = start.Add(step)
+		}
+		for !parsedLog.Timestamp.Before(next) {
+			closeInterval(next)
+			next = next.Add(step)
+		}
+		if parsedLog.Timestamp.After(a.End) {
+			a.End = parsedLog.Timestamp
+		}
+		m.account(parsedLog)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !next.IsZero() {
+		closeInterval(a.End)
+		a.Intervals = m.stats.history.intervals
+	}
+	a.Total = newInterval(a.Start)
+	a.Total.End = a.End
+	for _, interval := range a.Intervals {
+		a.Total.merge(interval)
+	}
+	return a, nil
+}
+
+// Hand over the records in every file, stopping early upon a signal
+func (m *monitor) readFiles(files []labeledFile, stop <-chan os.Signal, handle func(*logRecord)) error {
+	for _, file := range files {
+		f, err := os.Open(file.path)
+		if err != nil {
+			return err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			select {
+			case <-stop:
+				f.Close()
+				return nil
+			default:
+			}
+			parsedLog, err := m.record(inputLine{text: scanner.Text(), source: file.label})
+			if err != nil {
+				f.Close()
+				return err
+			}
+			if parsedLog != nil {
+				handle(parsedLog)
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write a summary of the analysis, followed by stats as in periodic dumps
+func writeTextReport(w io.Writer, a *analysis) error {
+	weight := a.stats.weight()
+	requests := int(math.Round(float64(a.Total.Requests) * weight))
+	fmt.Fprintf(w, "Analyzed %d requests from %s to %s\n", requests, displayTime(a.Start).Format(time.RFC3339), displayTime(a.End).Format(time.RFC3339))
+	if seconds := a.End.Sub(a.Start).Seconds(); seconds > 0 {
+		fmt.Fprintf(w, "Average QPS: %.2f\n", float64(requests)/seconds)
+	}
+	fmt.Fprintf(w, "Bytes sent: %d\n", int(math.Round(float64(a.Total.Bytes)*weight)))
+
+	var classes []string
+	for class := range a.Total.ResponseCodes {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		count := a.Total.ResponseCodes[class]
+		fmt.Fprintf(w, "%s: %.2f%%\n", class, float64(count)*100/float64(a.Total.Requests))
+	}
+
+	if len(a.Alerts) > 0 {
+		fmt.Fprintf(w, "Alerts:\n")
+		for _, event := range a.Alerts {
+			state := "resolved"
+			if event.Firing {
+				state = event.state()
+			}
+			fmt.Fprintf(w, "%s %s %s\n", displayTime(event.Time).Format(time.RFC3339), event.Name, state)
+		}
+	}
+	if len(a.Intervals) > 0 {
+		newHeatmap(a.Intervals).writeText(w, weight)
+	}
+	fmt.Fprint(w, "---\n")
+	a.stats.writeStats(w)
+	return nil
+}
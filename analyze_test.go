@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Write an access log with a one-minute burst of 20 QPS followed by three
+// minutes at 1 QPS
+func writeAnalyzedLog(t *testing.T) string {
+	var lines []string
+	start := time.Date(2018, 5, 9, 16, 0, 0, 0, time.UTC)
+	for second := 0; second < 240; second++ {
+		n := 1
+		if second < 60 {
+			n = 20
+		}
+		ts := start.Add(time.Duration(second) * time.Second).Format("02/Jan/2006:15:04:05 -0700")
+		for i := 0; i < n; i++ {
+			status := 200
+			if i == 1 {
+				status = 503
+			}
+			lines = append(lines, fmt.Sprintf(`127.0.0.%d - - [%s] "GET /api/user HTTP/1.0" %d 100`, i, ts, status))
+		}
+	}
+	path := filepath.Join(t.TempDir(), "access.log")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Analyze a log with the high-traffic rule only
+func analyzeLog(t *testing.T, path string) *analysis {
+	m := &monitor{
+		stats:  newStats(),
+		mutex:  &sync.Mutex{},
+		alerts: newAlertTracker([]alertRule{highTrafficRule{}}),
+		parser: w3cParser{},
+	}
+	m.alerts.out = &bytes.Buffer{}
+	a, err := m.analyze([]labeledFile{{path: path}}, 10*time.Second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestAnalyze(t *testing.T) {
+	a := analyzeLog(t, writeAnalyzedLog(t))
+
+	if a.Total.Requests != 60*20+180 {
+		t.Errorf("%+v != %+v", 60*20+180, a.Total.Requests)
+	}
+	if len(a.Intervals) != 24 {
+		t.Errorf("%+v != %+v", 24, len(a.Intervals))
+	}
+	expected := time.Date(2018, 5, 9, 16, 3, 59, 0, time.UTC)
+	if !a.End.Equal(expected) {
+		t.Errorf("%+v != %+v", expected, a.End)
+	}
+
+	// Fires 10s into the burst, resolves once the window is quiet enough
+	if len(a.Alerts) != 2 || !a.Alerts[0].Firing || a.Alerts[1].Firing {
+		t.Fatalf("Expected the high-traffic alert to fire and resolve: %+v", a.Alerts)
+	}
+	fired := time.Date(2018, 5, 9, 16, 0, 10, 0, time.UTC)
+	if !a.Alerts[0].Time.Equal(fired) {
+		t.Errorf("%+v != %+v", fired, a.Alerts[0].Time)
+	}
+}
+
+func TestTextReport(t *testing.T) {
+	a := analyzeLog(t, writeAnalyzedLog(t))
+
+	var buf bytes.Buffer
+	if err := writeTextReport(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	report := buf.String()
+	for _, expected := range []string{
+		"Analyzed 1380 requests from 2018-05-09T16:00:00Z to 2018-05-09T16:03:59Z\n",
+		"5XX: 4.35%\n",
+		"2018-05-09T16:00:10Z High-traffic firing",
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("%q not in %q", expected, report)
+		}
+	}
+}
+
+func TestHTMLReport(t *testing.T) {
+	a := analyzeLog(t, writeAnalyzedLog(t))
+
+	var buf bytes.Buffer
+	if err := writeHTMLReport(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	report := buf.String()
+	for _, expected := range []string{
+		"<svg ",
+		"<polyline ",
+		`fill="#ffcdd2"`,
+		"<td>/api</td>",
+		"High-traffic",
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("%q not in report", expected)
+		}
+	}
+}
+
+func TestMarkdownReport(t *testing.T) {
+	a := analyzeLog(t, writeAnalyzedLog(t))
+
+	var buf bytes.Buffer
+	if err := writeMarkdownReport(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	report := buf.String()
+	for _, expected := range []string{
+		"- **Requests:** 1380 (5.77 QPS on average)\n",
+		"- **Responses:** 2XX 95.65%, 5XX 4.35%\n",
+		"| `/api` | 1380 | 100.0% |\n",
+		"| High-traffic | 2018-05-09T16:00:10Z | 2018-05-09T16:",
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("%q not in %q", expected, report)
+		}
+	}
+}
+
+func TestAlertWindows(t *testing.T) {
+	start := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	events := []alertEvent{
+		{Time: start, alertChange: alertChange{Name: "High-traffic", Firing: true, Severity: severityWarning}},
+		{Time: start.Add(time.Minute), alertChange: alertChange{Name: "Scanning", Firing: true}},
+		{Time: start.Add(90 * time.Second), alertChange: alertChange{Name: "High-traffic", Firing: true, Severity: severityCritical}},
+		{Time: start.Add(2 * time.Minute), alertChange: alertChange{Name: "High-traffic", Firing: false}},
+	}
+	windows := alertWindows(events)
+	if len(windows) != 2 {
+		t.Fatalf("%+v != %+v", 2, len(windows))
+	}
+	if windows[0].name != "High-traffic" || windows[0].end.Sub(windows[0].start) != 2*time.Minute || windows[0].severity != severityCritical {
+		t.Errorf("Unexpected window: %+v", windows[0])
+	}
+	if windows[1].name != "Scanning" || !windows[1].end.IsZero() {
+		t.Errorf("Unexpected window: %+v", windows[1])
+	}
+}
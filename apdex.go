@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Command-line flags to score latencies and alert on user satisfaction
+var apdexT = flag.Duration("apdex-t", 500*time.Millisecond, "Apdex threshold T: requests served within T satisfy users, within 4T are tolerated")
+var apdexTarget = flag.Float64("apdex-target", 0, "Apdex score of an interval below which to alert, e.g. 0.85 (0 disables)")
+
+// Apdex score of latencies, between 0 (every user frustrated) and 1 (every
+// user satisfied)
+func apdex(latencies []time.Duration, t time.Duration) float64 {
+	if len(latencies) == 0 {
+		return 1
+	}
+	satisfied, tolerating := 0, 0
+	for _, latency := range latencies {
+		switch {
+		case latency <= t:
+			satisfied++
+		case latency <= 4*t:
+			tolerating++
+		}
+	}
+	return (float64(satisfied) + float64(tolerating)/2) / float64(len(latencies))
+}
+
+// Apdex score of every latency in the current interval, and whether any
+func (s *stats) overallApdex(t time.Duration) (float64, bool) {
+	var latencies []time.Duration
+	for _, samples := range s.sectionLatencies {
+		latencies = append(latencies, samples...)
+	}
+	return apdex(latencies, t), len(latencies) > 0
+}
+
+// Section along with its Apdex score
+type sectionApdex struct {
+	section string
+	score   float64
+}
+
+// The N sections with the lowest Apdex score in the current interval
+func (s *stats) lowestApdexSections(n int, t time.Duration) []sectionApdex {
+	var lowest []sectionApdex
+	for section, latencies := range s.sectionLatencies {
+		lowest = append(lowest, sectionApdex{section: section, score: apdex(latencies, t)})
+	}
+	sort.Slice(lowest, func(i, j int) bool {
+		if lowest[i].score == lowest[j].score {
+			return lowest[i].section < lowest[j].section
+		}
+		return lowest[i].score < lowest[j].score
+	})
+	if len(lowest) > n {
+		lowest = lowest[:n]
+	}
+	return lowest
+}
+
+// Dumps the overall Apdex score, and the N sections scoring lowest, in the
+// current interval
+func (s *stats) dumpApdex(w *tabwriter.Writer, n int, t time.Duration) {
+	score, _ := s.overallApdex(t)
+	fmt.Fprintf(w, "Apdex (T=%s): %.2f\n", t, score)
+	fmt.Fprintf(w, "Top %d sections by lowest Apdex:\n", n)
+	for _, v := range s.lowestApdexSections(n, t) {
+		fmt.Fprintf(w, "%.2f\t %s\n", v.score, v.section)
+	}
+}
+
+// Alert firing when the Apdex score of an interval drops below a target
+type apdexRule struct {
+	target float64
+	t      time.Duration
+	score  float64 // As of the last check
+}
+
+func (r *apdexRule) name() string {
+	return "Apdex"
+}
+
+func (r *apdexRule) evaluate(s *stats) (bool, string) {
+	score, ok := s.overallApdex(r.t)
+	r.score = score
+	if !ok {
+		// No latencies, nothing to score
+		return false, ""
+	}
+	return score < r.target, fmt.Sprintf("at %.2f with T=%s", score, r.t)
+}
+
+func (r *apdexRule) measure(s *stats) (float64, float64) {
+	return r.score, r.target
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestApdex(t *testing.T) {
+	latencies := []time.Duration{
+		100 * time.Millisecond, 500 * time.Millisecond, // Satisfied
+		time.Second, 2 * time.Second, // Tolerating
+		3 * time.Second, // Frustrated
+	}
+	if score := apdex(latencies, 500*time.Millisecond); score != 0.6 {
+		t.Errorf("%+v != %+v", score, 0.6)
+	}
+	if score := apdex(nil, time.Second); score != 1 {
+		t.Errorf("%+v != %+v", score, 1)
+	}
+}
+
+func TestApdexRule(t *testing.T) {
+	s := newStats()
+	rule := &apdexRule{target: 0.8, t: 100 * time.Millisecond}
+	if firing, _ := rule.evaluate(s); firing {
+		t.Errorf("Expected no alert without latencies")
+	}
+
+	for i := 0; i < 10; i++ {
+		s.updateStats(&logRecord{Section: "/static", StatusCode: 200, Latency: 10 * time.Millisecond})
+	}
+	for i := 0; i < 5; i++ {
+		s.updateStats(&logRecord{Section: "/api", StatusCode: 200, Latency: time.Second})
+	}
+	if firing, detail := rule.evaluate(s); !firing || detail != "at 0.67 with T=100ms" {
+		t.Errorf("Expected an alert, got %v %s", firing, detail)
+	}
+	if value, threshold := rule.measure(s); value >= threshold {
+		t.Errorf("%+v >= %+v", value, threshold)
+	}
+
+	expected := []sectionApdex{{"/api", 0}, {"/static", 1}}
+	if lowest := s.lowestApdexSections(5, rule.t); !reflect.DeepEqual(lowest, expected) {
+		t.Errorf("%+v != %+v", lowest, expected)
+	}
+
+	s.resetLatencies()
+	if firing, _ := rule.evaluate(s); firing {
+		t.Errorf("Expected the alert to stop once the interval is over")
+	}
+}
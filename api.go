@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Counter for a key, as returned by the REST API
+type apiCount struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+func apiCounts(counters map[string]int, n int) []apiCount {
+	result := []apiCount{}
+	for _, v := range topCounts(counters, n) {
+		result = append(result, apiCount{Key: v.key, Count: v.count})
+	}
+	return result
+}
+
+// Current stats, as returned by the REST API
+type apiStats struct {
+	Time          time.Time      `json:"time"`
+	ResponseCodes map[string]int `json:"response_codes"`
+	TopSections   []apiCount     `json:"top_sections"`
+	TopIPs        []apiCount     `json:"top_ips"`
+	QPS           float64        `json:"qps"`
+	QPSAverages   []float64      `json:"qps_averages"` // Over the last 1, 5 and 15 minutes
+	Alerting      []string       `json:"alerting"`
+}
+
+// Alert state, as returned by the REST API
+type apiAlert struct {
+	Name   string `json:"name"`
+	Firing bool   `json:"firing"`
+}
+
+// Serve the REST query API on the HTTP listener. Handlers hold the mutex
+// while reading stats
+func registerAPI(s *stats, mutex *sync.Mutex, alerts *alertTracker) {
+	httpMux.HandleFunc("/api/stats/current", func(w http.ResponseWriter, r *http.Request) {
+		n, ok := apiTopN(w, r)
+		if !ok {
+			return
+		}
+		mutex.Lock()
+		current := apiStats{
+			Time:          time.Now(),
+			ResponseCodes: s.scaled(s.httpResponseCodes),
+			TopSections:   apiCounts(s.scaled(s.sectionCounts), n),
+			TopIPs:        apiCounts(s.scaled(s.ipCounts), n),
+			Alerting:      alerts.firingNames(),
+		}
+		for _, qps := range s.qpsAverages() {
+			current.QPSAverages = append(current.QPSAverages, finite(qps))
+		}
+		if qps, err := s.getQueryRate(); err == nil {
+			current.QPS = finite(qps)
+		}
+		mutex.Unlock()
+		writeJSON(w, current)
+	})
+
+	httpMux.HandleFunc("/api/stats/history", func(w http.ResponseWriter, r *http.Request) {
+		window, ok := apiWindow(w, r)
+		if !ok {
+			return
+		}
+		mutex.Lock()
+		intervals := []*interval{}
+		if s.history != nil {
+			for _, i := range s.history.since(time.Now().Add(-window)) {
+				intervals = append(intervals, i.scaled(s.weight()))
+			}
+		}
+		mutex.Unlock()
+		writeJSON(w, intervals)
+	})
+
+	httpMux.HandleFunc("/api/sections/top", func(w http.ResponseWriter, r *http.Request) {
+		n, ok := apiTopN(w, r)
+		if !ok {
+			return
+		}
+		window, ok := apiWindow(w, r)
+		if !ok {
+			return
+		}
+		mutex.Lock()
+		sections := s.sectionCounts
+		if window > 0 && s.history != nil {
+			now := time.Now()
+			sections = s.history.total(now.Add(-window), now).Sections
+		}
+		top := apiCounts(s.scaled(sections), n)
+		mutex.Unlock()
+		writeJSON(w, top)
+	})
+
+	if *sqliteDatabase != "" {
+		httpMux.HandleFunc("/api/rollups", func(w http.ResponseWriter, r *http.Request) {
+			period := r.URL.Query().Get("period")
+			if period == "" {
+				period = "day"
+			}
+			if err := checkRollupPeriod(period); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			window, ok := apiWindow(w, r)
+			if !ok {
+				return
+			}
+			var since time.Time
+			if window > 0 {
+				since = time.Now().Add(-window)
+			}
+			rollups, err := queryRollups(*sqliteDatabase, period, since)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, rollups)
+		})
+	}
+
+	httpMux.HandleFunc("/api/alerts", func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		result := []apiAlert{}
+		for _, rule := range alerts.rules {
+			result = append(result, apiAlert{Name: rule.name(), Firing: alerts.firing[rule.name()]})
+		}
+		mutex.Unlock()
+		writeJSON(w, result)
+	})
+}
+
+// Number of top entries requested with ?n=, defaulting to -top
+func apiTopN(w http.ResponseWriter, r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("n")
+	if value == "" {
+		return *topN, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		http.Error(w, "Invalid n: "+value, http.StatusBadRequest)
+		return 0, false
+	}
+	return n, true
+}
+
+// Time window requested with ?window= (e.g. 5m), zero meaning all time
+func apiWindow(w http.ResponseWriter, r *http.Request) (time.Duration, bool) {
+	value := r.URL.Query().Get("window")
+	if value == "" {
+		return 0, true
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil || window <= 0 {
+		http.Error(w, "Invalid window: "+value, http.StatusBadRequest)
+		return 0, false
+	}
+	return window, true
+}
+
+// Rate as reported by the API, which cannot encode infinite rates, e.g. of
+// windows spanning no time
+func finite(v float64) float64 {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0
+	}
+	return v
+}
+
+// Reply with a value as JSON, or with an error if it cannot be encoded
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(data, '\n'))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAPI(t *testing.T) {
+	s := newStats()
+	s.history = newHistory(time.Hour, time.Now().Add(-time.Minute))
+	ts := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	s.updateStats(&logRecord{IP: "10.0.0.1", Timestamp: ts, StatusCode: 200, Section: "/old"})
+	s.history.rotate(time.Now().Add(-30 * time.Minute))
+	for i := 0; i < 3; i++ {
+		s.updateStats(&logRecord{IP: "10.0.0.1", Timestamp: ts.Add(time.Duration(i) * time.Second), StatusCode: 200, Section: "/api"})
+	}
+
+	alerts := newAlertTracker([]alertRule{highTrafficRule{}})
+	alerts.firing["High-traffic"] = true
+	registerAPI(s, &sync.Mutex{}, alerts)
+
+	get := func(url string, v interface{}) int {
+		w := httptest.NewRecorder()
+		httpMux.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code
+	}
+
+	var current apiStats
+	get("/api/stats/current", &current)
+	if current.ResponseCodes["2XX"] != 4 || current.QPS != 2 || len(current.Alerting) != 1 {
+		t.Errorf("Unexpected current stats %+v", current)
+	}
+
+	var top []apiCount
+	get("/api/sections/top?n=1", &top)
+	if len(top) != 1 || top[0] != (apiCount{Key: "/api", Count: 3}) {
+		t.Errorf("Unexpected top sections %+v", top)
+	}
+	get("/api/sections/top?window=5m", &top)
+	if len(top) != 1 || top[0].Key != "/api" {
+		t.Errorf("Unexpected top sections in window %+v", top)
+	}
+	if code := get("/api/sections/top?window=soon", &top); code != http.StatusBadRequest {
+		t.Errorf("Unexpected status code %d", code)
+	}
+
+	var intervals []*interval
+	get("/api/stats/history?window=1h", &intervals)
+	if len(intervals) != 1 || intervals[0].Sections["/old"] != 1 {
+		t.Errorf("Unexpected history %+v", intervals)
+	}
+
+	var alertStates []apiAlert
+	get("/api/alerts", &alertStates)
+	if len(alertStates) != 1 || alertStates[0] != (apiAlert{Name: "High-traffic", Firing: true}) {
+		t.Errorf("Unexpected alerts %+v", alertStates)
+	}
+
+	// A window spanning no time has an infinite rate, which JSON cannot hold
+	s.logsInWindow = s.logsInWindow[:1]
+	current = apiStats{}
+	if code := get("/api/stats/current", &current); code != http.StatusOK || current.Time.IsZero() || current.QPS != 0 {
+		t.Errorf("Unexpected current stats %d %+v", code, current)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeJSON(w, math.Inf(1))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("%+v != %+v", w.Code, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+)
+
+// Common attack signature found in request URIs
+type attackSignature struct {
+	name    string
+	pattern *regexp.Regexp
+	decoded bool // Whether the pattern applies to the URL-decoded URI
+}
+
+// Signatures checked against every request, in order
+var attackSignatures = []attackSignature{
+	{"suspicious-encoding", regexp.MustCompile(`(?i)%00|%25[0-9a-f]{2}|%c0%ae|%c1%9c|%u[0-9a-f]{4}`), false},
+	{"path-traversal", regexp.MustCompile(`\.\./|\.\.\\`), true},
+	{"file-inclusion", regexp.MustCompile(`(?i)/etc/(passwd|shadow)|win\.ini|(php|file|expect|data)://`), true},
+	{"sql-injection", regexp.MustCompile(`(?i)union(\s|/\*.*?\*/)+(all(\s|/\*.*?\*/)+)?select|'\s*or\s+'?\d+'?\s*=\s*'?\d+|sleep\(\s*\d+\s*\)|benchmark\(|information_schema`), true},
+	{"xss", regexp.MustCompile(`(?i)<script|javascript:|on(error|load)\s*=`), true},
+	{"command-injection", regexp.MustCompile(`(?i)[;|&]\s*(cat|wget|curl|nc|id|whoami|uname)\b|\$\(|` + "`"), true},
+}
+
+// Name of the first attack signature found in a request URI, empty if none
+func detectAttack(uri string) string {
+	decoded, err := url.QueryUnescape(uri)
+	if err != nil {
+		decoded = uri
+	}
+	for _, signature := range attackSignatures {
+		target := uri
+		if signature.decoded {
+			target = decoded
+		}
+		if signature.pattern.MatchString(target) {
+			return signature.name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectAttack(t *testing.T) {
+	x := map[string]string{
+		"/api/user?id=42":                         "",
+		"/search?q=rock+%26+roll":                 "",
+		"/static/../../etc/passwd":                "path-traversal",
+		"/download?file=..%2F..%2Fconfig.php":     "path-traversal",
+		"/index.php?page=php://filter/resource=x": "file-inclusion",
+		"/items?id=1+UNION+ALL+SELECT+password":   "sql-injection",
+		"/items?id=1%27%20or%201=1":               "sql-injection",
+		"/comment?text=%3Cscript%3Ealert(1)":      "xss",
+		"/ping?host=127.0.0.1;cat+/etc/hosts":     "command-injection",
+		"/file%00.jpg":                            "suspicious-encoding",
+		"/%252e%252e/secret":                      "suspicious-encoding",
+	}
+	for uri, expected := range x {
+		if actual := detectAttack(uri); actual != expected {
+			t.Errorf("%s: %q != %q", uri, expected, actual)
+		}
+	}
+}
+
+func TestUpdateStatsAttacks(t *testing.T) {
+	s := newStats()
+	ts := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	s.updateStats(&logRecord{IP: "10.0.0.1", Timestamp: ts, StatusCode: 404, Section: "/", Attack: "path-traversal"})
+	s.updateStats(&logRecord{IP: "10.0.0.1", Timestamp: ts, StatusCode: 404, Section: "/", Attack: "xss"})
+	s.updateStats(&logRecord{IP: "10.0.0.2", Timestamp: ts, StatusCode: 200, Section: "/"})
+
+	if s.attackerCounts["10.0.0.1"] != 2 {
+		t.Errorf("%d != %d", 2, s.attackerCounts["10.0.0.1"])
+	}
+	if len(s.attackCounts) != 2 || len(s.attackerCounts) != 1 {
+		t.Errorf("Unexpected attack counters: %v %v", s.attackCounts, s.attackerCounts)
+	}
+}
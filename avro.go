@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// Avro types of each kind of field
+var avroTypes = map[fieldKind]interface{}{
+	stringField: "string",
+	intField:    "long",
+	floatField:  "double",
+	boolField:   "boolean",
+	timeField:   map[string]string{"type": "long", "logicalType": "timestamp-millis"},
+}
+
+// Avro schema of exported records
+func avroSchema() string {
+	type avroField struct {
+		Name string      `json:"name"`
+		Type interface{} `json:"type"`
+	}
+	schema := struct {
+		Type      string      `json:"type"`
+		Name      string      `json:"name"`
+		Namespace string      `json:"namespace"`
+		Fields    []avroField `json:"fields"`
+	}{Type: "record", Name: "Record", Namespace: "http_monitor"}
+	for _, field := range recordFields {
+		schema.Fields = append(schema.Fields, avroField{field.name, avroTypes[field.kind]})
+	}
+	data, _ := json.Marshal(schema)
+	return string(data)
+}
+
+// Record encoded as an Avro datum of the schema of exported records
+func avroRecord(r *logRecord) []byte {
+	var data []byte
+	var b [binary.MaxVarintLen64]byte
+	for _, field := range recordFields {
+		switch v := field.value(r).(type) {
+		case string:
+			data = append(data, b[:binary.PutVarint(b[:], int64(len(v)))]...)
+			data = append(data, v...)
+		case int64:
+			data = append(data, b[:binary.PutVarint(b[:], v)]...)
+		case float64:
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+			data = append(data, b[:8]...)
+		case bool:
+			if v {
+				data = append(data, 1)
+			} else {
+				data = append(data, 0)
+			}
+		case time.Time:
+			data = append(data, b[:binary.PutVarint(b[:], v.UnixMilli())]...)
+		}
+	}
+	return data
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAvroSchema(t *testing.T) {
+	var schema struct {
+		Name   string
+		Fields []struct {
+			Name string
+			Type interface{}
+		}
+	}
+	if err := json.Unmarshal([]byte(avroSchema()), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.Name != "Record" || len(schema.Fields) != len(recordFields) {
+		t.Fatalf("Unexpected schema %+v", schema)
+	}
+	if schema.Fields[0].Name != "time" || schema.Fields[0].Type.(map[string]interface{})["logicalType"] != "timestamp-millis" {
+		t.Errorf("Unexpected time field %+v", schema.Fields[0])
+	}
+	if schema.Fields[7].Name != "status" || schema.Fields[7].Type != "long" {
+		t.Errorf("Unexpected status field %+v", schema.Fields[7])
+	}
+}
+
+func TestAvroRecord(t *testing.T) {
+	r := &logRecord{
+		Timestamp:  time.Unix(1, 0),
+		IP:         "::1",
+		StatusCode: 200,
+		Latency:    time.Millisecond,
+		Bot:        true,
+		Received:   time.Unix(0, 0),
+	}
+	expected := []byte{
+		0xd0, 0x0f, // 1000ms
+		0x06, ':', ':', '1',
+		0x00, 0x00, 0x00, 0x00, 0x00, // Empty user, method, section, resource and protocol
+		0x90, 0x03, // 200
+		0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f, // 1.0
+		0x00, 0x00,
+		0x01,
+		0x00,
+		0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00,
+	}
+	if data := avroRecord(r); !bytes.Equal(data, expected) {
+		t.Errorf("% x != % x", data, expected)
+	}
+}
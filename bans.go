@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Command-line flag to publish abusive clients, e.g. to fail2ban
+var banFile = flag.String("ban-file", "", "File (or unix:///path socket) receiving the IPs of abusive clients, one \"<time> <ip> <reason>\" line each")
+
+// Publishes clients blamed by abuse rules as lines such as
+// "2019-01-01T10:00:00Z 10.0.0.1 Scanning", which fail2ban can match with
+// a "^\S+ <HOST> " failregex. Each client is published once for as long
+// as it keeps offending
+type banList struct {
+	target string
+	banned map[string]map[string]bool // Currently banned IPs, for each reason
+
+	now func() time.Time
+}
+
+func newBanList(target string) *banList {
+	return &banList{target: target, banned: make(map[string]map[string]bool), now: time.Now}
+}
+
+// Publish the offenders of a rule not already banned for it
+func (b *banList) update(reason string, offenders []string) {
+	current := make(map[string]bool)
+	var lines []string
+	for _, ip := range offenders {
+		current[ip] = true
+		if !b.banned[reason][ip] {
+			lines = append(lines, fmt.Sprintf("%s %s %s\n", b.now().UTC().Format(time.RFC3339), ip, reason))
+		}
+	}
+	b.banned[reason] = current
+
+	if len(lines) > 0 {
+		if err := b.write(strings.Join(lines, "")); err != nil {
+			log.Printf("Cannot write to ban file %s: %s", b.target, err)
+		}
+	}
+}
+
+func (b *banList) write(lines string) error {
+	var w io.WriteCloser
+	var err error
+	if strings.HasPrefix(b.target, "unix://") {
+		w, err = net.Dial("unix", strings.TrimPrefix(b.target, "unix://"))
+	} else {
+		w, err = os.OpenFile(b.target, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, lines); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
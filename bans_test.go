@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBanList(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "bans.log")
+	b := newBanList(fileName)
+	b.now = func() time.Time { return time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC) }
+
+	// Clients are banned once while they keep offending, and again when
+	// they come back after having stopped
+	b.update("Scanning", []string{"10.0.0.1"})
+	b.update("Scanning", []string{"10.0.0.1", "10.0.0.2"})
+	b.update("Brute-force", []string{"10.0.0.1"})
+	b.update("Scanning", nil)
+	b.update("Scanning", []string{"10.0.0.2"})
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "2019-01-01T10:00:00Z 10.0.0.1 Scanning\n" +
+		"2019-01-01T10:00:00Z 10.0.0.2 Scanning\n" +
+		"2019-01-01T10:00:00Z 10.0.0.1 Brute-force\n" +
+		"2019-01-01T10:00:00Z 10.0.0.2 Scanning\n"
+	if string(data) != expected {
+		t.Errorf("%q != %q", expected, string(data))
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Number of lines between alert checks when benchmarking, standing in for
+// the periodic checks of a dump
+const benchCheckLines = 10000
+
+// Outcome of a benchmark
+type benchResult struct {
+	lines     int
+	errors    int // Lines that could not be parsed
+	elapsed   time.Duration
+	mallocs   uint64
+	bytes     uint64
+	userCPU   time.Duration
+	systemCPU time.Duration
+}
+
+// Run the bench subcommand, measuring how fast lines go through parsing,
+// enrichment and stats so that changes can be compared
+func runBench(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	file := flags.String("file", "", "Access log file to read lines from, in the format given by -format, instead of generating them")
+	count := flags.Int("lines", 100000, "Number of lines generated when no file is given")
+	flags.Parse(args)
+
+	var lines []string
+	var err error
+	if *file != "" {
+		lines, err = readBenchLines(*file)
+	} else {
+		lines, err = generateBenchLines(*logFormat, *count)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := checkSampleRate(*sampleRate); err != nil {
+		return err
+	}
+	if err := checkSectionDepth(*sectionDepth); err != nil {
+		return err
+	}
+	parser, err := configuredLogParser()
+	if err != nil {
+		return err
+	}
+	s := newStats()
+	s.sampleRate = *sampleRate
+	s.history = newHistory(*historyRetention, time.Now())
+	m := &monitor{stats: s, mutex: &sync.Mutex{}, parser: parser}
+	m.alerts = newAlertTracker(configuredAlertRules())
+	m.alerts.out = io.Discard
+
+	result := m.bench(lines)
+	result.write(os.Stdout)
+	return nil
+}
+
+// Lines of an access log file, read up front so that reading is not
+// measured
+func readBenchLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// Synthetic lines in the given format, as written by the generate
+// subcommand at 100 QPS
+func generateBenchLines(format string, count int) ([]string, error) {
+	if format != "w3c" && format != "combined" && format != "iis" {
+		return nil, fmt.Errorf("Cannot generate lines in %s format, give a -file to read them from", format)
+	}
+	g := &logGenerator{
+		format:   format,
+		statuses: weightedChoices{{"200", 90}, {"304", 4}, {"404", 4}, {"500", 2}},
+		sections: weightedChoices{{"/", 30}, {"/api", 40}, {"/static", 20}, {"/admin", 5}, {"/login", 5}},
+		ips:      1000,
+		rand:     rand.New(rand.NewSource(1)),
+	}
+	t := time.Now()
+	lines := strings.Split(strings.TrimSpace(g.header(t)), "\n")
+	if lines[0] == "" {
+		lines = nil
+	}
+	for i := 0; i < count; i++ {
+		lines = append(lines, g.line(t.Add(time.Duration(i)*10*time.Millisecond)))
+	}
+	return lines, nil
+}
+
+// Parse, enrich and account for every line, measuring time, allocations
+// and CPU
+func (m *monitor) bench(lines []string) benchResult {
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	userBefore, systemBefore := cpuTimes()
+	start := time.Now()
+
+	result := benchResult{lines: len(lines)}
+	for i, line := range lines {
+		if err := m.process(inputLine{text: line}); err != nil {
+			result.errors++
+		}
+		if (i+1)%benchCheckLines == 0 {
+			m.alerts.check(m.stats)
+		}
+	}
+
+	result.elapsed = time.Since(start)
+	userAfter, systemAfter := cpuTimes()
+	runtime.ReadMemStats(&after)
+	result.mallocs = after.Mallocs - before.Mallocs
+	result.bytes = after.TotalAlloc - before.TotalAlloc
+	result.userCPU, result.systemCPU = userAfter-userBefore, systemAfter-systemBefore
+	return result
+}
+
+// Write a summary of the benchmark
+func (r benchResult) write(w io.Writer) {
+	fmt.Fprintf(w, "Processed %d lines in %s", r.lines, r.elapsed.Round(time.Millisecond))
+	if seconds := r.elapsed.Seconds(); seconds > 0 {
+		fmt.Fprintf(w, ": %.0f lines/sec", float64(r.lines)/seconds)
+	}
+	fmt.Fprintln(w)
+	if r.errors > 0 {
+		fmt.Fprintf(w, "Lines that could not be parsed: %d\n", r.errors)
+	}
+	if r.lines > 0 {
+		fmt.Fprintf(w, "Allocations: %.1f per line, %.0f bytes per line\n", float64(r.mallocs)/float64(r.lines), float64(r.bytes)/float64(r.lines))
+	}
+	fmt.Fprintf(w, "CPU time: %s user, %s system", r.userCPU.Round(time.Millisecond), r.systemCPU.Round(time.Millisecond))
+	if r.elapsed > 0 {
+		fmt.Fprintf(w, " (%.0f%% of wall time)", float64(r.userCPU+r.systemCPU)*100/float64(r.elapsed))
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBench(t *testing.T) {
+	lines, err := generateBenchLines("combined", 25000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines = append(lines, "garbage")
+	m := &monitor{stats: newStats(), mutex: &sync.Mutex{}, parser: combinedParser{}}
+	m.alerts = newAlertTracker(nil)
+
+	result := m.bench(lines)
+	if result.lines != 25001 || result.errors != 1 || result.mallocs == 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if requests := m.stats.httpResponseCodes["2XX"] + m.stats.httpResponseCodes["3XX"] + m.stats.httpResponseCodes["4XX"] + m.stats.httpResponseCodes["5XX"]; requests != 25000 {
+		t.Errorf("%+v != %+v", requests, 25000)
+	}
+
+	var out strings.Builder
+	result.write(&out)
+	if !strings.Contains(out.String(), "Processed 25001 lines") || !strings.Contains(out.String(), "Lines that could not be parsed: 1") {
+		t.Errorf("Unexpected summary: %s", out.String())
+	}
+
+	if _, err := generateBenchLines("json", 10); err == nil {
+		t.Errorf("Expected an error for a format that cannot be generated")
+	}
+}
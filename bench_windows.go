@@ -0,0 +1,23 @@
+package main
+
+import (
+	"syscall"
+	"time"
+)
+
+// CPU time spent by the process so far, in user and system mode
+func cpuTimes() (time.Duration, time.Duration) {
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return 0, 0
+	}
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(process, &creation, &exit, &kernel, &user); err != nil {
+		return 0, 0
+	}
+	// File times count 100-nanosecond intervals
+	ticks := func(t syscall.Filetime) time.Duration {
+		return time.Duration(int64(t.HighDateTime)<<32|int64(t.LowDateTime)) * 100
+	}
+	return ticks(user), ticks(kernel)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"regexp"
+	"strings"
+)
+
+// Command-line flags to classify bot and crawler traffic
+var botList = flag.String("bot-list", "", "File with additional user-agent substrings identifying bots, one per line")
+var alertExcludeBots = flag.Bool("alert-exclude-bots", false, "Ignore bot and crawler traffic in alerting")
+
+// User agents of well-known crawlers, monitoring services and HTTP
+// libraries. Most crawlers identify themselves as a bot, crawler or spider
+var knownBotRegExp = regexp.MustCompile(`(?i)bot\b|bot/|crawl|spider|slurp|archiver|` +
+	`facebookexternalhit|mediapartners-google|bingpreview|pingdom|uptimerobot|` +
+	`curl/|wget/|python-requests|python-urllib|go-http-client|java/|okhttp|` +
+	`libwww-perl|httpclient|headlesschrome|phantomjs`)
+
+// Lowercased user-agent substrings read from -bot-list
+var extraBotPatterns []string
+
+// Read additional bot user-agent substrings from a file
+func loadBotList(fileName string) ([]string, error) {
+	patterns, err := readPatterns(fileName)
+	for i := range patterns {
+		patterns[i] = strings.ToLower(patterns[i])
+	}
+	return patterns, err
+}
+
+// Whether a user agent belongs to a bot or crawler. Requests lacking a
+// user agent ("-") are assumed to come from scripts
+func isBot(userAgent string) bool {
+	if userAgent == "-" || knownBotRegExp.MatchString(userAgent) {
+		return true
+	}
+	lower := strings.ToLower(userAgent)
+	for _, pattern := range extraBotPatterns {
+		if strings.Contains(lower, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// Client type reported for a record, empty if the log format does not
+// carry user agents
+func clientType(log *logRecord) string {
+	switch {
+	case log.UserAgent == "":
+		return ""
+	case log.Bot:
+		return "bot"
+	default:
+		return "human"
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIsBot(t *testing.T) {
+	x := map[string]bool{
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)":            true,
+		"Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)":             true,
+		"Mozilla/5.0 (compatible; Yahoo! Slurp; http://help.yahoo.com/help/us/ysearch/slurp)": true,
+		"curl/7.64.1":            true,
+		"python-requests/2.22.0": true,
+		"Go-http-client/1.1":     true,
+		"-":                      true,
+		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 Chrome/79.0.3945.88 Safari/537.36": false,
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 13_3 like Mac OS X) Mobile/15E148":                 false,
+	}
+	for ua, expected := range x {
+		if actual := isBot(ua); actual != expected {
+			t.Errorf("%s: %v != %v", ua, expected, actual)
+		}
+	}
+}
+
+func TestLoadBotList(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "bots.txt")
+	if err := os.WriteFile(fileName, []byte("# Internal tools\nAcmeMonitor\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	patterns, err := loadBotList(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extraBotPatterns = patterns
+	defer func() { extraBotPatterns = nil }()
+
+	if !isBot("acmemonitor/1.0") {
+		t.Errorf("Expected user agent from bot list to be a bot")
+	}
+}
+
+// Test bot traffic is counted separately and optionally not alerted on
+func TestUpdateStatsBots(t *testing.T) {
+	*alertExcludeBots = true
+	defer func() { *alertExcludeBots = false }()
+
+	s := newStats()
+	ts := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	s.updateStats(&logRecord{Timestamp: ts, StatusCode: 200, Section: "/", UserAgent: "curl/7.64.1", Bot: true})
+	s.updateStats(&logRecord{Timestamp: ts, StatusCode: 200, Section: "/", UserAgent: "Mozilla/5.0"})
+	s.updateStats(&logRecord{Timestamp: ts, StatusCode: 200, Section: "/"})
+
+	expected := map[string]int{"bot": 1, "human": 1}
+	for clientType, count := range expected {
+		if s.clientTypeCounts[clientType] != count {
+			t.Errorf("%s: %d != %d", clientType, count, s.clientTypeCounts[clientType])
+		}
+	}
+	if len(s.logsInWindow) != 2 {
+		t.Errorf("%d != %d", 2, len(s.logsInWindow))
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Command-line flags to detect brute-force attempts against auth endpoints
+var authSections = stringSet{}
+var authFailureQPS = flag.Float64("auth-failure-qps", 0, "Alert on clients exceeding this rate of 401/403 responses from -auth-sections (0 disables)")
+
+func init() {
+	flag.Var(authSections, "auth-sections", "Comma-separated sections serving authentication, e.g. /login,/wp-login.php")
+}
+
+// Alert firing when single clients keep failing to authenticate against
+// any of the auth endpoints
+type bruteForceRule struct {
+	sections  stringSet
+	threshold float64
+}
+
+func (r *bruteForceRule) name() string {
+	return "Brute-force"
+}
+
+func (r *bruteForceRule) evaluate(s *stats) (bool, string) {
+	offenders := r.offenders(s)
+	return len(offenders) > 0, fmt.Sprintf("from %s", strings.Join(offenders, ", "))
+}
+
+// IPs of the clients failing to authenticate too often, sorted
+func (r *bruteForceRule) offenders(s *stats) []string {
+	failures := make(map[string]int)
+	for _, record := range s.logsInWindow {
+		if r.sections[record.Section] && (record.StatusCode == 401 || record.StatusCode == 403) {
+			failures[record.IP]++
+		}
+	}
+
+	var offenders []string
+	for ip, count := range failures {
+		if s.windowRate(count) > r.threshold {
+			offenders = append(offenders, ip)
+		}
+	}
+	sort.Strings(offenders)
+	return offenders
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBruteForceRule(t *testing.T) {
+	s := newStats()
+	ts := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+
+	// Over 10 seconds, one client fails to log in 20 times, another one
+	// fails twice and a third one gets many 403s elsewhere
+	for i := 0; i <= 20; i++ {
+		at := ts.Add(time.Duration(i) * 500 * time.Millisecond)
+		s.updateAlerting(&logRecord{IP: "10.0.0.1", Timestamp: at, StatusCode: 401, Section: "/login"})
+		s.updateAlerting(&logRecord{IP: "10.0.0.3", Timestamp: at, StatusCode: 403, Section: "/admin"})
+		if i%10 == 0 {
+			s.updateAlerting(&logRecord{IP: "10.0.0.2", Timestamp: at, StatusCode: 401, Section: "/wp-login.php"})
+		}
+	}
+
+	r := &bruteForceRule{sections: stringSet{"/login": true, "/wp-login.php": true}, threshold: 1}
+	firing, detail := r.evaluate(s)
+	if !firing {
+		t.Errorf("Expected brute-force alert to fire")
+	}
+	if expected := "from 10.0.0.1"; detail != expected {
+		t.Errorf("%q != %q", expected, detail)
+	}
+
+	r.threshold = 5
+	if firing, _ := r.evaluate(s); firing {
+		t.Errorf("Expected brute-force alert not to fire")
+	}
+}
+
+// Test a single failure does not make for an infinite rate
+func TestBruteForceRuleSingleFailure(t *testing.T) {
+	s := newStats()
+	s.updateAlerting(&logRecord{IP: "10.0.0.9", Timestamp: time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC), StatusCode: 401, Section: "/login"})
+
+	r := &bruteForceRule{sections: stringSet{"/login": true}, threshold: 1}
+	if offenders := r.offenders(s); len(offenders) != 0 {
+		t.Errorf("Unexpected offenders %v", offenders)
+	}
+}
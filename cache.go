@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Command-line flag to name the field holding the cache status
+var cacheStatusField = flag.String("cache-status-field", "", "JSON key, LTSV label or IIS field holding the cache status, e.g. upstream_cache_status or sc(X-Cache)")
+
+// Substrings of cache statuses, as written by nginx ($upstream_cache_status),
+// Varnish, Squid and CDNs (X-Cache), along with the result they denote.
+// Checked in order, so that e.g. TCP_REFRESH_MISS is a miss
+var cacheStatusResults = []struct {
+	substring string
+	result    string
+}{
+	{"BYPASS", "bypass"},
+	{"DYNAMIC", "bypass"},
+	{"PASS", "bypass"},
+	{"MISS", "miss"},
+	{"EXPIRED", "miss"},
+	{"HIT", "hit"},
+	{"STALE", "hit"},
+	{"UPDATING", "hit"},
+	{"REVALIDATED", "hit"},
+}
+
+// Classify a cache status as a hit, miss or bypass, or other if unknown
+func cacheResult(status string) string {
+	status = strings.ToUpper(status)
+	for _, v := range cacheStatusResults {
+		if strings.Contains(status, v.substring) {
+			return v.result
+		}
+	}
+	return "other"
+}
+
+// Share of hits, misses and bypasses among counters of cache results
+func cacheRatios(counters map[string]int) string {
+	total := 0
+	for _, count := range counters {
+		total += count
+	}
+	var ratios []string
+	for _, result := range []string{"hit", "miss", "bypass", "other"} {
+		if count, ok := counters[result]; ok && total > 0 {
+			ratios = append(ratios, fmt.Sprintf("%s %.1f%%", result, float64(count)*100/float64(total)))
+		}
+	}
+	return strings.Join(ratios, ", ")
+}
+
+// Dumps cache result ratios overall and for the N sections with the most
+// cached requests
+func (s *stats) dumpCacheResults(w *tabwriter.Writer, n int) {
+	fmt.Fprintf(w, "Cache results: %s\n", cacheRatios(s.cacheCounts))
+
+	totals := make(map[string]int)
+	for section, counters := range s.cacheSections {
+		for _, count := range counters {
+			totals[section] += count
+		}
+	}
+	var sections []string
+	for _, v := range topCounts(totals, n) {
+		sections = append(sections, v.key)
+	}
+	sort.Strings(sections)
+	fmt.Fprintf(w, "Cache results per section:\n")
+	for _, section := range sections {
+		fmt.Fprintf(w, "%s\t %s\n", section, cacheRatios(s.cacheSections[section]))
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCacheResult(t *testing.T) {
+	tests := map[string]string{
+		"HIT":                  "hit",
+		"Hit from cloudfront":  "hit",
+		"TCP_MEM_HIT":          "hit",
+		"STALE":                "hit",
+		"MISS":                 "miss",
+		"TCP_REFRESH_MISS":     "miss",
+		"Miss from cloudfront": "miss",
+		"EXPIRED":              "miss",
+		"BYPASS":               "bypass",
+		"DYNAMIC":              "bypass",
+		"-":                    "other",
+	}
+	for status, expected := range tests {
+		if result := cacheResult(status); result != expected {
+			t.Errorf("%s: %+v != %+v", status, expected, result)
+		}
+	}
+}
+
+func TestCacheRatios(t *testing.T) {
+	s := newStats()
+	statuses := []string{"HIT", "HIT", "HIT", "MISS"}
+	for _, status := range statuses {
+		s.updateStats(&logRecord{Section: "/static", StatusCode: 200, CacheStatus: status})
+	}
+	s.updateStats(&logRecord{Section: "/api", StatusCode: 200, CacheStatus: "BYPASS"})
+	s.updateStats(&logRecord{Section: "/api", StatusCode: 200})
+
+	expected := "hit 60.0%, miss 20.0%, bypass 20.0%"
+	if ratios := cacheRatios(s.cacheCounts); ratios != expected {
+		t.Errorf("%+v != %+v", expected, ratios)
+	}
+	expected = "hit 75.0%, miss 25.0%"
+	if ratios := cacheRatios(s.cacheSections["/static"]); ratios != expected {
+		t.Errorf("%+v != %+v", expected, ratios)
+	}
+}
+
+func TestCacheStatusField(t *testing.T) {
+	defer func(field string) { *cacheStatusField = field }(*cacheStatusField)
+	*cacheStatusField = "upstream_cache_status"
+
+	r, err := jsonParser{}.parse(`{"remote_addr":"127.0.0.1","time_local":"09/May/2018:16:00:41 +0000","request":"GET /static/app.js HTTP/1.1","status":200,"upstream_cache_status":"HIT"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.CacheStatus != "HIT" {
+		t.Errorf("%+v != %+v", "HIT", r.CacheStatus)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Command-line flags to resume tailing files where a previous run left off
+var checkpointFile = flag.String("checkpoint", "", "File recording the position of tailed files, to resume from it after a restart")
+var checkpointInterval = flag.Duration("checkpoint-interval", 10*time.Second, "How often the position of tailed files is recorded")
+
+// Position of a tailed file
+type checkpoint struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+// Positions of every tailed file, periodically persisted as JSON
+type checkpointStore struct {
+	path string
+
+	mutex       sync.Mutex
+	checkpoints map[string]checkpoint
+}
+
+// Load previously recorded positions, if any
+func openCheckpointStore(path string) (*checkpointStore, error) {
+	store := &checkpointStore{path: path, checkpoints: make(map[string]checkpoint)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.checkpoints); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (c *checkpointStore) get(fileName string) (checkpoint, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	position, ok := c.checkpoints[fileName]
+	return position, ok
+}
+
+func (c *checkpointStore) set(fileName string, position checkpoint) {
+	c.mutex.Lock()
+	c.checkpoints[fileName] = position
+	c.mutex.Unlock()
+}
+
+// Persist positions, replacing the file atomically
+func (c *checkpointStore) save() error {
+	c.mutex.Lock()
+	data, err := json.Marshal(c.checkpoints)
+	c.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(c.path+".tmp", c.path)
+}
+
+// Persist positions forever
+func (c *checkpointStore) run(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := c.save(); err != nil {
+			log.Printf("Cannot save checkpoint %s: %s", c.path, err)
+		}
+	}
+}
+
+// Offset to resume tailing a file from. Files replaced since the position
+// was recorded (e.g. rotated) or shrunk (e.g. truncated) are read from the
+// beginning, so lines are never skipped
+func resumeOffset(info os.FileInfo, position checkpoint) int64 {
+	if fileInode(info) != position.Inode || info.Size() < position.Offset {
+		return 0
+	}
+	return position.Offset
+}
+
+// Global store of tailed files' positions, if enabled
+var checkpoints *checkpointStore
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "access.log")
+	if err := os.WriteFile(logFile, []byte("first\nsecond\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := openCheckpointStore(filepath.Join(dir, "checkpoint.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Read both lines, then restart after a third one was appended
+	lines := make(chan inputLine)
+	go (&fileInput{path: logFile, checkpoints: store}).run(lines)
+	for _, expected := range []string{"first", "second"} {
+		if line := readLine(t, lines); line != expected {
+			t.Errorf("%q != %q", expected, line)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := store.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("third\n")
+	f.Close()
+
+	restarted, err := openCheckpointStore(filepath.Join(dir, "checkpoint.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines = make(chan inputLine)
+	go (&fileInput{path: logFile, checkpoints: restarted}).run(lines)
+	if line := readLine(t, lines); line != "third" {
+		t.Errorf("%q != %q", "third", line)
+	}
+}
+
+func readLine(t *testing.T, lines <-chan inputLine) string {
+	select {
+	case line := <-lines:
+		return line.text
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for log line")
+	}
+	return ""
+}
+
+func TestResumeOffset(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "access.log")
+	if err := os.WriteFile(fileName, []byte("0123456789\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inode := fileInode(info)
+	x := map[checkpoint]int64{
+		{Inode: inode, Offset: 5}:     5,
+		{Inode: inode, Offset: 50}:    0,
+		{Inode: inode + 1, Offset: 5}: 0,
+	}
+	for position, expected := range x {
+		if offset := resumeOffset(info, position); offset != expected {
+			t.Errorf("%+v: %d != %d", position, expected, offset)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+// Command-line flags to alert on a sustained rate of client errors
+var clientErrorPercent = flag.Float64("client-error-percent", 0, "Alert when 4XX responses exceed this percentage of the requests in the alerting window for -client-error-period (0 disables)")
+var clientErrorPeriod = flag.Duration("client-error-period", 5*time.Minute, "How long the 4XX percentage must stay above -client-error-percent before alerting")
+
+// Alert firing when the share of 4XX responses in the window stays above
+// a threshold for a period, e.g. because of broken links or clients, or
+// an authentication outage
+type clientErrorRule struct {
+	threshold float64
+	period    time.Duration
+	now       func() time.Time
+	since     time.Time // When the share went above the threshold, zero if below
+	percent   float64   // As of the last check
+}
+
+func (r *clientErrorRule) name() string {
+	return "Client-errors"
+}
+
+func (r *clientErrorRule) setClock(now func() time.Time) {
+	r.now = now
+}
+
+func (r *clientErrorRule) evaluate(s *stats) (bool, string) {
+	errors := 0
+	for _, record := range s.logsInWindow {
+		if record.StatusCode >= 400 && record.StatusCode < 500 {
+			errors++
+		}
+	}
+	percent := 0.0
+	if len(s.logsInWindow) > 0 {
+		percent = float64(errors) * 100 / float64(len(s.logsInWindow))
+	}
+
+	r.percent = percent
+	now := r.now()
+	if percent <= r.threshold {
+		r.since = time.Time{}
+		return false, ""
+	}
+	if r.since.IsZero() {
+		r.since = now
+	}
+	return now.Sub(r.since) >= r.period, fmt.Sprintf("at %.2f%% of requests for %s", percent, now.Sub(r.since))
+}
+
+func (r *clientErrorRule) measure(s *stats) (float64, float64) {
+	return r.percent, r.threshold
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientErrorRule(t *testing.T) {
+	now := time.Date(2018, 5, 9, 16, 0, 0, 0, time.UTC)
+	rule := &clientErrorRule{threshold: 20, period: time.Minute, now: func() time.Time { return now }}
+
+	// Status codes in the window, and whether the alert fires after
+	// checking 30 seconds later
+	steps := []struct {
+		codes  []int
+		firing bool
+	}{
+		{[]int{200, 200, 404, 200, 200}, false},
+		{[]int{200, 404, 404, 200}, false},
+		{[]int{200, 404, 401, 200}, false},
+		{[]int{200, 404, 403, 200}, true},
+		{[]int{200, 200, 200, 200, 200, 200, 404}, false},
+		{[]int{404, 404}, false},
+	}
+	for i, step := range steps {
+		s := newStats()
+		for _, code := range step.codes {
+			s.logsInWindow = append(s.logsInWindow, &logRecord{StatusCode: code})
+		}
+		if firing, _ := rule.evaluate(s); firing != step.firing {
+			t.Errorf("Check %d: %+v != %+v", i, firing, step.firing)
+		}
+		now = now.Add(30 * time.Second)
+	}
+}
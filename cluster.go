@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Command-line flags to run as an agent shipping aggregates to a central
+// http_monitor, or as the aggregator receiving them
+var aggregatorURL = flag.String("aggregator", "", "URL of the aggregator to ship aggregates to, e.g. http://monitor:8080/aggregate")
+var aggregateInterval = flag.Duration("aggregate-interval", 10*time.Second, "How often aggregates are shipped to the aggregator")
+var aggregateTopK = flag.Int("aggregate-top", 100, "Number of top sections and client IPs included in each aggregate")
+var agentName = flag.String("agent-name", "", "Name identifying this agent to the aggregator (defaults to the hostname)")
+var acceptAggregates = flag.Bool("accept-aggregates", false, "Accept aggregates from agents on POST /aggregate and alert on fleet-wide traffic (requires -listen-http)")
+var clusterToken = flag.String("cluster-token", "", "Bearer token authenticating agents to the aggregator")
+
+// Counters accumulated by an agent between two shipments
+type aggregate struct {
+	Agent    string         `json:"agent"`
+	Buckets  map[int64]int  `json:"buckets"`  // Requests subject to alerting, per second (Unix time)
+	Statuses map[string]int `json:"statuses"` // Requests per response class
+	Sections map[string]int `json:"sections"` // Requests per section, only for the top ones
+	IPs      map[string]int `json:"ips"`      // Requests per client IP, only for the top ones
+}
+
+func newAggregate(agent string) *aggregate {
+	return &aggregate{
+		Agent:    agent,
+		Buckets:  make(map[int64]int),
+		Statuses: make(map[string]int),
+		Sections: make(map[string]int),
+		IPs:      make(map[string]int),
+	}
+}
+
+// Keep only the top K counters
+func trimCounts(counters map[string]int, k int) map[string]int {
+	result := make(map[string]int)
+	for _, v := range topCounts(counters, k) {
+		result[v.key] = v.count
+	}
+	return result
+}
+
+// Agent side: accumulates records and periodically ships them
+type aggregateShipper struct {
+	url   string
+	token string
+	topK  int
+
+	mutex   sync.Mutex
+	current *aggregate
+	weight  float64 // Requests each record stands for, when sampling
+
+	client *http.Client
+}
+
+func newAggregateShipper(url, token, agent string, topK int) *aggregateShipper {
+	if agent == "" {
+		agent, _ = os.Hostname()
+	}
+	return &aggregateShipper{
+		url:     url,
+		token:   token,
+		topK:    topK,
+		current: newAggregate(agent),
+		weight:  1,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Account for a record in the next aggregate, standing for the given
+// number of requests
+func (a *aggregateShipper) add(log *logRecord, weight float64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.weight = weight
+	a.current.Statuses[statusClass(log.StatusCode)]++
+	a.current.Sections[log.Section]++
+	a.current.IPs[log.IP]++
+	if !excludedFromAlerting(log) {
+		a.current.Buckets[log.Timestamp.Unix()]++
+	}
+}
+
+// Ship the records accumulated since the last shipment, scaled up when
+// sampling. Aggregates failing to ship are dropped, so a slow aggregator
+// does not grow agents' memory
+func (a *aggregateShipper) ship() error {
+	a.mutex.Lock()
+	shipped, weight := a.current, a.weight
+	a.current = newAggregate(shipped.Agent)
+	a.mutex.Unlock()
+
+	shipped.Statuses = scaleCounts(shipped.Statuses, weight)
+	shipped.Sections = trimCounts(scaleCounts(shipped.Sections, weight), a.topK)
+	shipped.IPs = trimCounts(scaleCounts(shipped.IPs, weight), a.topK)
+	for second, count := range shipped.Buckets {
+		shipped.Buckets[second] = int(math.Round(float64(count) * weight))
+	}
+	body, err := json.Marshal(shipped)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("Aggregator replied with %s", resp.Status)
+	}
+	return nil
+}
+
+// Ship aggregates forever
+func (a *aggregateShipper) run(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := a.ship(); err != nil {
+			log.Printf("Cannot ship aggregate to %s: %s", a.url, err)
+		}
+	}
+}
+
+// Aggregator side: handler merging the aggregates of every agent into stats
+func aggregateHandler(s *stats, mutex *sync.Mutex, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		a := newAggregate("")
+		if err := json.NewDecoder(r.Body).Decode(a); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mutex.Lock()
+		s.mergeAggregate(a)
+		mutex.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// Add the counters of an agent's aggregate
+func (s *stats) mergeAggregate(a *aggregate) {
+	for status, count := range a.Statuses {
+		s.httpResponseCodes[status] += count
+		s.agentCounts[a.Agent] += count
+	}
+	for section, count := range a.Sections {
+		s.sectionCounts[section] += count
+	}
+	for ip, count := range a.IPs {
+		s.ipCounts[ip] += count
+	}
+
+	var latest int64
+	now := time.Now()
+	for second, count := range a.Buckets {
+		s.fleetBuckets[second] += count
+		s.fleetReceived[second] = now
+		if second > latest {
+			latest = second
+		}
+	}
+	// Forget about buckets falling out of the alerting window
+	for second := range s.fleetBuckets {
+		if second <= latest-120 {
+			delete(s.fleetBuckets, second)
+			delete(s.fleetReceived, second)
+		}
+	}
+}
+
+// Forget about buckets shipped more than 2 minutes before now, so the fleet
+// stops alerting once every agent stopped shipping
+func (s *stats) expireFleet(now time.Time) {
+	for second, received := range s.fleetReceived {
+		if now.Sub(received) > alertingWindow {
+			delete(s.fleetBuckets, second)
+			delete(s.fleetReceived, second)
+		}
+	}
+}
+
+// Alert firing when the average QPS of the whole fleet exceeds -qps
+type fleetTrafficRule struct {
+	threshold float64
+	critical  float64
+}
+
+func (r *fleetTrafficRule) name() string {
+	return "Fleet-traffic"
+}
+
+func (r *fleetTrafficRule) evaluate(s *stats) (bool, string) {
+	s.expireFleet(time.Now())
+	qps, ok := fleetQPS(s)
+	if !ok {
+		return false, ""
+	}
+	return qps > r.threshold, fmt.Sprintf("at %f queries per second on average", qps)
+}
+
+func (r *fleetTrafficRule) measure(s *stats) (float64, float64) {
+	qps, _ := fleetQPS(s)
+	return qps, r.threshold
+}
+
+// Average QPS of the fleet in the window, if agents shipped any request
+func fleetQPS(s *stats) (float64, bool) {
+	var first, last int64
+	var total int
+	for second, count := range s.fleetBuckets {
+		if total == 0 || second < first {
+			first = second
+		}
+		if total == 0 || second > last {
+			last = second
+		}
+		total += count
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return float64(total) / float64(last-first+1), true
+}
+
+func (r *fleetTrafficRule) severity(s *stats) severity {
+	if r.critical == 0 {
+		return 0
+	}
+	if critical, _ := (&fleetTrafficRule{threshold: r.critical}).evaluate(s); critical {
+		return severityCritical
+	}
+	return severityWarning
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAggregateShipping(t *testing.T) {
+	s := newStats()
+	server := httptest.NewServer(aggregateHandler(s, &sync.Mutex{}, "secret"))
+	defer server.Close()
+
+	ts := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	for _, agent := range []string{"web01", "web02"} {
+		shipper := newAggregateShipper(server.URL, "secret", agent, 1)
+		// Each agent gets 2 requests per second for 10 seconds
+		for i := 0; i < 20; i++ {
+			at := ts.Add(time.Duration(i) * 500 * time.Millisecond)
+			section := "/api"
+			if i == 0 {
+				section = "/rare"
+			}
+			shipper.add(&logRecord{IP: "10.0.0.1", Timestamp: at, StatusCode: 200, Section: section}, 1)
+		}
+		if err := shipper.ship(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if s.httpResponseCodes["2XX"] != 40 || s.agentCounts["web01"] != 20 || s.agentCounts["web02"] != 20 {
+		t.Errorf("Unexpected counters %v %v", s.httpResponseCodes, s.agentCounts)
+	}
+	// Only the top section was shipped
+	if s.sectionCounts["/api"] != 38 || s.sectionCounts["/rare"] != 0 {
+		t.Errorf("Unexpected section counters %v", s.sectionCounts)
+	}
+
+	// The fleet gets 4 requests per second, while each agent only 2
+	if firing, detail := (&fleetTrafficRule{threshold: 3}).evaluate(s); !firing {
+		t.Errorf("Expected fleet traffic alert to fire")
+	} else if expected := "at 4.000000 queries per second on average"; detail != expected {
+		t.Errorf("%q != %q", expected, detail)
+	}
+
+	// Sampled agents ship estimates of their actual traffic
+	shipper := newAggregateShipper(server.URL, "secret", "web04", 1)
+	shipper.add(&logRecord{IP: "10.0.0.1", Timestamp: ts, StatusCode: 200, Section: "/api"}, 10)
+	if err := shipper.ship(); err != nil {
+		t.Fatal(err)
+	}
+	if s.agentCounts["web04"] != 10 || s.sectionCounts["/api"] != 48 {
+		t.Errorf("Unexpected sampled counters %v %v", s.agentCounts, s.sectionCounts)
+	}
+
+	shipper = newAggregateShipper(server.URL, "wrong", "web03", 1)
+	if err := shipper.ship(); err == nil {
+		t.Errorf("Expected unauthorized agent to fail shipping")
+	}
+}
+
+// Test fleet buckets expire once agents stop shipping
+func TestFleetTrafficExpiry(t *testing.T) {
+	s := newStats()
+	s.mergeAggregate(&aggregate{Agent: "web01", Buckets: map[int64]int{1000: 10, 1001: 10}})
+
+	rule := &fleetTrafficRule{threshold: 3}
+	if firing, _ := rule.evaluate(s); !firing {
+		t.Errorf("Expected fleet traffic alert to fire")
+	}
+
+	// Every agent stopped shipping more than 2 minutes ago
+	for second := range s.fleetReceived {
+		s.fleetReceived[second] = time.Now().Add(-3 * time.Minute)
+	}
+	if firing, _ := rule.evaluate(s); firing {
+		t.Errorf("Expected fleet traffic alert to stop firing")
+	}
+	if len(s.fleetBuckets) != 0 || len(s.fleetReceived) != 0 {
+		t.Errorf("Buckets not forgotten: %v %v", s.fleetBuckets, s.fleetReceived)
+	}
+}
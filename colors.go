@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+)
+
+// Command-line flag to disable colors
+var noColor = flag.Bool("no-color", false, "Never color console output, which is otherwise colored when writing to a terminal")
+
+// Whether console output is colored
+var colorOutput bool
+
+// ANSI escape sequences. Colors are all the same length, so that colored
+// cells stay aligned by tabwriter
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiDefault = "\x1b[39m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+)
+
+// Whether output to a file can be colored: only terminals can, unless
+// disabled with -no-color or the NO_COLOR environment variable
+func colorSupported(f *os.File) bool {
+	if *noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Wrap text in a color, when console output is colored
+func colorize(color string, text string) string {
+	if !colorOutput {
+		return text
+	}
+	return color + text + ansiReset
+}
+
+// Red for server errors, yellow for client errors and the default color
+// otherwise, for a status code or class
+func statusColor(status string) string {
+	switch {
+	case strings.HasPrefix(status, "5"):
+		return ansiRed
+	case strings.HasPrefix(status, "4"):
+		return ansiYellow
+	}
+	return ansiDefault
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestColorize(t *testing.T) {
+	tests := map[string]string{
+		"503": "\x1b[31m503\x1b[0m",
+		"4XX": "\x1b[33m4XX\x1b[0m",
+		"200": "\x1b[39m200\x1b[0m",
+	}
+
+	defer func() { colorOutput = false }()
+	colorOutput = true
+	for status, expected := range tests {
+		if colored := colorize(statusColor(status), status); colored != expected {
+			t.Errorf("%q != %q", expected, colored)
+		}
+	}
+
+	colorOutput = false
+	if plain := colorize(ansiRed, "503"); plain != "503" {
+		t.Errorf("%q != %q", "503", plain)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Subcommands, given as the first argument that is not a flag
+var commands = []struct {
+	name    string
+	summary string
+}{
+	{"tail", "Follow access logs, dumping stats and alerting (default)"},
+	{"analyze", "Read log files to the end, then write a report"},
+	{"replay", "Feed log files through stats and alerting at the pace they were written"},
+	{"serve", "Serve the APIs, network inputs and aggregates, without following the access log file"},
+	{"generate", "Write synthetic access log lines"},
+	{"bench", "Measure how fast lines are parsed and accounted for"},
+	{"rollups", "Write daily or weekly aggregates of the history recorded with -sqlite"},
+}
+
+// Subcommand being run
+var command = "tail"
+
+// Log files given as arguments to analyze and replay
+var fileArgs []string
+
+// Command-line flag of the replay subcommand
+var replaySpeed float64
+
+// Command-line usage, listing subcommands before global flags
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] [command [flags] [files]]\n\nCommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(out, "\nFlags, which may also follow the command:\n")
+	flag.PrintDefaults()
+}
+
+// Flag of a subcommand standing for a global one, so that giving it after
+// the subcommand is the same as giving it before
+type globalFlag struct {
+	*flag.Flag
+}
+
+func (f globalFlag) String() string {
+	if f.Flag == nil {
+		return ""
+	}
+	return f.Value.String()
+}
+
+func (f globalFlag) Set(value string) error {
+	return flag.Set(f.Name, value)
+}
+
+func (f globalFlag) IsBoolFlag() bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// Parse the arguments of the tail, analyze, replay and serve subcommands:
+// their own flags, global ones, then log files for analyze and replay
+func parseMonitorCommand(name string, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	switch name {
+	case "analyze":
+		fs.DurationVar(analyzeStep, "step", *analyzeStep, "Log time between alert checks")
+	case "replay":
+		fs.Float64Var(&replaySpeed, "speed", 1, "Factor by which replaying is faster than the logs were written, e.g. 10 (0 for no waiting)")
+	}
+	flag.VisitAll(func(f *flag.Flag) {
+		fs.Var(globalFlag{f}, f.Name, f.Usage)
+	})
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags]", os.Args[0], name)
+		if name == "analyze" || name == "replay" {
+			fmt.Fprintf(fs.Output(), " [files]")
+		}
+		fmt.Fprintf(fs.Output(), "\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	command = name
+	switch name {
+	case "analyze", "replay":
+		fileArgs = fs.Args()
+		*analyzeMode = *analyzeMode || name == "analyze"
+		if replaySpeed < 0 {
+			return fmt.Errorf("Replay speed must not be negative: %g", replaySpeed)
+		}
+		return nil
+	case "serve":
+		if *listenHTTP == "" && *listenGRPC == "" {
+			return fmt.Errorf("Serving requires -listen-http or -listen-grpc")
+		}
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("Unexpected arguments to %s: %v", name, fs.Args())
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseMonitorCommand(t *testing.T) {
+	defer func(step time.Duration, top int, analyze bool) {
+		*analyzeStep, *topN, *analyzeMode = step, top, analyze
+		command, fileArgs = "tail", nil
+	}(*analyzeStep, *topN, *analyzeMode)
+
+	if err := parseMonitorCommand("analyze", []string{"-step", "30s", "-top", "3", "a.log", "b.log"}); err != nil {
+		t.Fatal(err)
+	}
+	if *analyzeStep != 30*time.Second || *topN != 3 || !*analyzeMode || command != "analyze" {
+		t.Errorf("%+v != %+v", []interface{}{*analyzeStep, *topN, *analyzeMode, command}, []interface{}{30 * time.Second, 3, true, "analyze"})
+	}
+	if !isFlagSet("top") {
+		t.Errorf("Expected -top to be set as a global flag")
+	}
+	expected := []labeledFile{{path: "a.log"}, {path: "b.log"}}
+	if files := analyzedFiles(); !reflect.DeepEqual(files, expected) {
+		t.Errorf("%+v != %+v", files, expected)
+	}
+
+	if err := parseMonitorCommand("tail", []string{"a.log"}); err == nil {
+		t.Errorf("Expected an error for arguments to tail")
+	}
+	if err := parseMonitorCommand("serve", nil); err == nil {
+		t.Errorf("Expected an error for serving without listeners")
+	}
+}
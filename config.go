@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Command-line flag to read further flags from a file
+var configFile = flag.String("config", "", "File with one flag=value per line, re-read on SIGHUP. Flags given on the command line take precedence")
+
+// Flag accumulating values, such as a repeatable one, which is cleared
+// before the configuration file sets it again
+type resettableValue interface {
+	reset()
+}
+
+// Flag name and value read from a configuration file
+type configEntry struct {
+	name  string
+	value string
+}
+
+// Read flag=value lines, skipping blank lines and comments. Leading dashes
+// are optional and a bare name sets a boolean flag
+func readConfig(r io.Reader, fs *flag.FlagSet) ([]configEntry, error) {
+	var entries []configEntry
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entry := configEntry{name: line, value: "true"}
+		if i := strings.IndexByte(line, '='); i >= 0 {
+			entry = configEntry{name: strings.TrimSpace(line[:i]), value: strings.TrimSpace(line[i+1:])}
+		}
+		entry.name = strings.TrimLeft(entry.name, "-")
+		if fs.Lookup(entry.name) == nil {
+			return nil, fmt.Errorf("Unknown flag on line %d: %s", n, entry.name)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// Empty value of the same type as a flag's, to check values on before
+// setting any, or nil for types which cannot be made
+func scratchValue(value flag.Value) flag.Value {
+	t := reflect.TypeOf(value)
+	switch t.Kind() {
+	case reflect.Ptr:
+		scratch, _ := reflect.New(t.Elem()).Interface().(flag.Value)
+		return scratch
+	case reflect.Map:
+		scratch, _ := reflect.MakeMap(t).Interface().(flag.Value)
+		return scratch
+	}
+	return nil
+}
+
+// Set flags from a configuration file, except those in skip, returning the
+// names of those set. Every value is checked before any flag is touched, so
+// a malformed file changes nothing. Flags set by the previous file but not
+// by this one are set back to their default
+func applyConfig(r io.Reader, fs *flag.FlagSet, skip map[string]bool, previous map[string]bool) (map[string]bool, error) {
+	entries, err := readConfig(r, fs)
+	if err != nil {
+		return nil, err
+	}
+	scratches := make(map[string]flag.Value)
+	set := make(map[string]bool)
+	for _, entry := range entries {
+		if skip[entry.name] {
+			continue
+		}
+		set[entry.name] = true
+		scratch, ok := scratches[entry.name]
+		if !ok {
+			scratch = scratchValue(fs.Lookup(entry.name).Value)
+			scratches[entry.name] = scratch
+		}
+		if scratch == nil {
+			continue
+		}
+		if err := scratch.Set(entry.value); err != nil {
+			return nil, fmt.Errorf("Invalid value for %s: %s", entry.name, err)
+		}
+	}
+
+	for name := range previous {
+		if set[name] || skip[name] {
+			continue
+		}
+		f := fs.Lookup(name)
+		if value, ok := f.Value.(resettableValue); ok {
+			value.reset()
+			if f.DefValue == "" {
+				continue
+			}
+		}
+		if err := fs.Set(name, f.DefValue); err != nil {
+			return nil, fmt.Errorf("Cannot reset %s: %s", name, err)
+		}
+	}
+	cleared := make(map[string]bool)
+	for _, entry := range entries {
+		if skip[entry.name] {
+			continue
+		}
+		value := fs.Lookup(entry.name).Value
+		if value, ok := value.(resettableValue); ok && !cleared[entry.name] {
+			value.reset()
+			cleared[entry.name] = true
+		}
+		if err := fs.Set(entry.name, entry.value); err != nil {
+			return nil, fmt.Errorf("Invalid value for %s: %s", entry.name, err)
+		}
+	}
+	return set, nil
+}
+
+// Flags given on the command line, collected the first time the
+// configuration file is loaded, and those set by the file last time
+var commandLineFlags map[string]bool
+var configFileFlags map[string]bool
+
+// Set flags from -config
+func loadConfig() error {
+	if commandLineFlags == nil {
+		commandLineFlags = make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) {
+			commandLineFlags[f.Name] = true
+		})
+	}
+	f, err := os.Open(*configFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	set, err := applyConfig(f, flag.CommandLine, commandLineFlags, configFileFlags)
+	if err != nil {
+		return err
+	}
+	configFileFlags = set
+	return nil
+}
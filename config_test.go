@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestApplyConfig(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	threshold := fs.Float64("threshold", 10, "")
+	verbose := fs.Bool("verbose", false, "")
+	name := fs.String("name", "", "")
+	sections := stringSet{}
+	fs.Var(sections, "sections", "")
+	fs.Parse([]string{"-name=cli", "-sections=/old"})
+
+	config := `
+# Comments and blank lines are skipped
+-threshold = 25
+verbose
+name=config
+sections=/api
+sections=/login
+`
+	set, err := applyConfig(strings.NewReader(config), fs, map[string]bool{"name": true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *threshold != 25 || !*verbose {
+		t.Errorf("Flags not set: threshold=%v verbose=%v", *threshold, *verbose)
+	}
+	if *name != "cli" {
+		t.Errorf("Command-line flag overridden: %s", *name)
+	}
+	if sections.String() != "/api,/login" {
+		t.Errorf("%+v != %+v", "/api,/login", sections.String())
+	}
+
+	// Nothing is applied out of a malformed file
+	if _, err := applyConfig(strings.NewReader("threshold=50\nbogus=1\n"), fs, nil, set); err == nil {
+		t.Errorf("Expected an error for an unknown flag")
+	}
+	if _, err := applyConfig(strings.NewReader("threshold=50\nsections=/new\nverbose=maybe\n"), fs, nil, set); err == nil {
+		t.Errorf("Expected an error for an invalid value")
+	}
+	if *threshold != 25 || !*verbose || sections.String() != "/api,/login" {
+		t.Errorf("Flags changed by a malformed file: threshold=%v verbose=%v sections=%v", *threshold, *verbose, sections)
+	}
+
+	// Flags no longer set by the file are set back to their default
+	if _, err := applyConfig(strings.NewReader("verbose\n"), fs, nil, set); err != nil {
+		t.Fatal(err)
+	}
+	if *threshold != 10 || !*verbose || len(sections) != 0 {
+		t.Errorf("Flags not reset: threshold=%v verbose=%v sections=%v", *threshold, *verbose, sections)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"text/tabwriter"
+)
+
+// Command-line flag to choose whether dumps cover all history or the last
+// interval only
+var statsMode = flag.String("stats-mode", "cumulative", "What dumped counters cover: cumulative (everything seen so far) or interval (since the previous dump, with totals since start still shown)")
+
+// Check the stats mode is a known one
+func checkStatsMode(mode string) error {
+	if mode != "cumulative" && mode != "interval" {
+		return fmt.Errorf("Unknown stats mode: %s", mode)
+	}
+	return nil
+}
+
+// Fold per-interval counters into cumulative totals, then clear them so
+// that the next dump only reflects the next interval
+func (s *stats) resetCounters() {
+	if s.totalCodes == nil {
+		s.totalCodes = make(map[string]int)
+		s.totalSections = make(map[string]int)
+	}
+	for class, count := range s.httpResponseCodes {
+		s.totalCodes[class] += count
+		s.httpResponseCodes[class] = 0
+	}
+	for section, count := range s.sectionCounts {
+		s.totalSections[section] += count
+	}
+
+	s.sectionCounts = make(map[string]int)
+	s.podCounts = make(map[string]int)
+	s.countryCounts = make(map[string]int)
+	s.ipCounts = make(map[string]int)
+	s.userTraffic = make(map[string]*userTraffic)
+	s.groupCounts = make(map[string]int)
+	s.clientTypeCounts = make(map[string]int)
+	s.attackCounts = make(map[string]int)
+	s.attackerCounts = make(map[string]int)
+	s.spamReferrers = make(map[string]int)
+	s.vhostCounts = make(map[string]int)
+	s.vhostSections = make(map[string]map[string]int)
+	s.agentCounts = make(map[string]int)
+	s.cacheCounts = make(map[string]int)
+	s.cacheSections = make(map[string]map[string]int)
+	s.formatCounts = make(map[string]int)
+	s.protocolCounts = make(map[string]int)
+	s.tlsVersions = make(map[string]int)
+	s.tlsCiphers = make(map[string]int)
+	s.rejected = 0
+	s.transitions = make(map[string]int)
+	s.sizes = &sizeHistogram{}
+	for _, source := range s.sources {
+		source.resetCounters()
+	}
+}
+
+// Requests seen since start, whether or not counters were reset since
+func (s *stats) totalRequests() int {
+	requests := 0
+	for _, count := range s.httpResponseCodes {
+		requests += count
+	}
+	for _, count := range s.totalCodes {
+		requests += count
+	}
+	return requests
+}
+
+// Dump totals since start, once counters have been reset
+func (s *stats) dumpTotals(w *tabwriter.Writer, n int) {
+	if s.totalCodes == nil {
+		return
+	}
+	fmt.Fprintf(w, "Requests since start: %d\n", int(float64(s.totalRequests())*s.weight()+0.5))
+	sections := make(map[string]int)
+	for section, count := range s.totalSections {
+		sections[section] = count
+	}
+	for section, count := range s.sectionCounts {
+		sections[section] += count
+	}
+	dumpTopCounts(w, "sections since start", s.scaled(sections), n)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResetCounters(t *testing.T) {
+	s := newStats()
+	for _, r := range []*logRecord{
+		{IP: "10.0.0.1", Section: "/api", StatusCode: 200, Source: "web"},
+		{IP: "10.0.0.2", Section: "/api", StatusCode: 500},
+	} {
+		s.updateStats(r)
+	}
+
+	s.resetCounters()
+	s.updateStats(&logRecord{IP: "10.0.0.1", Section: "/static", StatusCode: 200})
+
+	if s.ipCounts["10.0.0.2"] != 0 || s.sectionCounts["/api"] != 0 || s.httpResponseCodes["2XX"] != 1 {
+		t.Errorf("Counters not reset: %+v %+v %+v", s.ipCounts, s.sectionCounts, s.httpResponseCodes)
+	}
+	if s.sources["web"].totalRequests() != 1 || len(s.sources["web"].sectionCounts) != 0 {
+		t.Errorf("Source counters not reset: %+v", s.sources["web"].sectionCounts)
+	}
+	if requests := s.totalRequests(); requests != 3 {
+		t.Errorf("%+v != %+v", requests, 3)
+	}
+	expected := map[string]int{"/api": 2}
+	if !reflect.DeepEqual(s.totalSections, expected) {
+		t.Errorf("%+v != %+v", s.totalSections, expected)
+	}
+}
+
+func TestCheckStatsMode(t *testing.T) {
+	for mode, valid := range map[string]bool{"cumulative": true, "interval": true, "hourly": false} {
+		if err := checkStatsMode(mode); (err == nil) != valid {
+			t.Errorf("%s: %v", mode, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Command-line flags to run in the background under classic init systems
+var daemon = flag.Bool("daemon", false, "Detach from the terminal and run in the background")
+var pidFile = flag.String("pidfile", "", "File to write the process ID to")
+var logFile = flag.String("log-file", "", "File to append diagnostics (and, when detached, reports) to")
+var signalName = flag.String("signal", "", "Send stop, reload or dump to the process in -pidfile and exit")
+
+// Environment variable telling a detached child apart from its parent
+const daemonEnv = "HTTP_MONITOR_DAEMON"
+
+// Whether this process is the detached child of a -daemon invocation
+func detached() bool {
+	return os.Getenv(daemonEnv) != ""
+}
+
+// Start a detached copy of this process, with its output sent to -log-file
+func daemonize() (int, error) {
+	out := os.DevNull
+	if *logFile != "" {
+		out = *logFile
+	}
+	f, err := os.OpenFile(out, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+	attr := &os.ProcAttr{
+		Env:   append(os.Environ(), daemonEnv+"=1"),
+		Files: []*os.File{nil, f, f},
+		Sys:   detachedProcAttr(),
+	}
+	process, err := os.StartProcess(executable, os.Args, attr)
+	if err != nil {
+		return 0, err
+	}
+	return process.Pid, process.Release()
+}
+
+// Process ID held by a pidfile
+func readPidFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("Invalid pidfile %s: %s", path, err)
+	}
+	return pid, nil
+}
+
+// Write the process ID to a pidfile, unless it names a running process
+func writePidFile(path string) error {
+	if pid, err := readPidFile(path); err == nil && pid != os.Getpid() && processAlive(pid) {
+		return fmt.Errorf("Already running with PID %d according to %s", pid, path)
+	}
+	return os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644)
+}
+
+// Send a named signal to the process in a pidfile
+func signalDaemon(path string, name string) error {
+	sig, ok := daemonSignals[name]
+	if !ok {
+		return fmt.Errorf("Unsupported signal: %s", name)
+	}
+	pid, err := readPidFile(path)
+	if err != nil {
+		return err
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Signal(sig)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestWritePidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "http_monitor.pid")
+
+	// Stale pidfiles are overwritten
+	if err := os.WriteFile(path, []byte("999999999\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writePidFile(path); err != nil {
+		t.Fatal(err)
+	}
+	pid, err := readPidFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid != os.Getpid() {
+		t.Errorf("%+v != %+v", os.Getpid(), pid)
+	}
+
+	// Pidfiles of running processes are not
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getppid())+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writePidFile(path); err == nil {
+		t.Errorf("Expected an error overwriting the pidfile of a running process")
+	}
+
+	if err := signalDaemon(path, "bogus"); err == nil {
+		t.Errorf("Expected an error sending an unsupported signal")
+	}
+}
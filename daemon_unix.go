@@ -0,0 +1,29 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// Signals sent by -signal
+var daemonSignals = map[string]os.Signal{
+	"stop":   syscall.SIGTERM,
+	"reload": syscall.SIGHUP,
+	"dump":   syscall.SIGUSR1,
+}
+
+// Run the detached child in a session of its own, away from the terminal
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// Whether a process exists, checked by sending it the null signal
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// Processes can only be killed on Windows
+var daemonSignals = map[string]os.Signal{
+	"stop": os.Kill,
+}
+
+// Run the detached child without a console
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{HideWindow: true}
+}
+
+// Whether a process exists
+func processAlive(pid int) bool {
+	_, err := os.FindProcess(pid)
+	return err == nil
+}
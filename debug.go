@@ -0,0 +1,82 @@
+package main
+
+import (
+	"expvar"
+	"flag"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+)
+
+// Command-line flag to enable the debug listener
+var debugAddr = flag.String("debug-addr", "", "Address for the debug listener serving expvar on /debug/vars and pprof on /debug/pprof/, e.g. localhost:6060")
+
+// Handlers served by the debug listener
+var debugMux = http.NewServeMux()
+
+func init() {
+	debugMux.Handle("/debug/vars", expvar.Handler())
+	debugMux.HandleFunc("/debug/pprof/", pprof.Index)
+	debugMux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	debugMux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	debugMux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	debugMux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
+
+// Start serving the debug listener in the background, if enabled
+func startDebugListener() {
+	if *debugAddr == "" {
+		return
+	}
+	go func() {
+		log.Panic(http.ListenAndServe(*debugAddr, debugMux))
+	}()
+}
+
+// Copy of a counters map, safe to marshal once the mutex is released
+func copyCounts(counters map[string]int) map[string]int {
+	result := make(map[string]int, len(counters))
+	for key, count := range counters {
+		result[key] = count
+	}
+	return result
+}
+
+// Publish stats and alert state as expvar variables, evaluated whenever
+// they are scraped
+func publishExpvars(s *stats, mutex *sync.Mutex, alerts *alertTracker) {
+	counters := func(get func() map[string]int) expvar.Func {
+		return func() interface{} {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return copyCounts(s.scaled(get()))
+		}
+	}
+	expvar.Publish("response_codes", counters(func() map[string]int { return s.httpResponseCodes }))
+	expvar.Publish("sections", counters(func() map[string]int { return s.sectionCounts }))
+	expvar.Publish("ips", counters(func() map[string]int { return s.ipCounts }))
+	expvar.Publish("pods", counters(func() map[string]int { return s.podCounts }))
+	expvar.Publish("countries", counters(func() map[string]int { return s.countryCounts }))
+	expvar.Publish("client_groups", counters(func() map[string]int { return s.groupCounts }))
+	expvar.Publish("client_types", counters(func() map[string]int { return s.clientTypeCounts }))
+	expvar.Publish("vhosts", counters(func() map[string]int { return s.vhostCounts }))
+
+	expvar.Publish("qps", expvar.Func(func() interface{} {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if qps, err := s.getQueryRate(); err == nil {
+			return qps
+		}
+		return 0
+	}))
+	expvar.Publish("alerts", expvar.Func(func() interface{} {
+		mutex.Lock()
+		defer mutex.Unlock()
+		state := make(map[string]bool)
+		for _, rule := range alerts.rules {
+			state[rule.name()] = alerts.firing[rule.name()]
+		}
+		return state
+	}))
+}
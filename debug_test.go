@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExpvars(t *testing.T) {
+	s := newStats()
+	ts := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	s.updateStats(&logRecord{IP: "10.0.0.1", Timestamp: ts, StatusCode: 200, Section: "/api"})
+	s.updateStats(&logRecord{IP: "10.0.0.2", Timestamp: ts.Add(time.Second), StatusCode: 500, Section: "/api"})
+
+	alerts := newAlertTracker([]alertRule{highTrafficRule{}})
+	publishExpvars(s, &sync.Mutex{}, alerts)
+
+	w := httptest.NewRecorder()
+	debugMux.ServeHTTP(w, httptest.NewRequest("GET", "/debug/vars", nil))
+	var vars struct {
+		ResponseCodes map[string]int  `json:"response_codes"`
+		Sections      map[string]int  `json:"sections"`
+		QPS           float64         `json:"qps"`
+		Alerts        map[string]bool `json:"alerts"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars.ResponseCodes["5XX"] != 1 || vars.Sections["/api"] != 2 || vars.QPS != 2 {
+		t.Errorf("Unexpected vars %+v", vars)
+	}
+	if firing, ok := vars.Alerts["High-traffic"]; !ok || firing {
+		t.Errorf("Unexpected alert state %v", vars.Alerts)
+	}
+}
+
+func TestPprof(t *testing.T) {
+	w := httptest.NewRecorder()
+	debugMux.ServeHTTP(w, httptest.NewRequest("GET", "/debug/pprof/heap?debug=1", nil))
+	if w.Code != 200 || !strings.Contains(w.Body.String(), "heap profile") {
+		t.Errorf("Unexpected heap profile response %d", w.Code)
+	}
+}
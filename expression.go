@@ -0,0 +1,258 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Alert condition combining metrics of the window, e.g.
+// "qps > 50 AND error_rate > 5%"
+type alertExpression struct {
+	text    string
+	metrics []string // Names of the metrics it refers to, sorted
+	eval    condition
+}
+
+// Alert expressions given on the command line
+type alertExpressions []*alertExpression
+
+func (l *alertExpressions) String() string {
+	var texts []string
+	for _, e := range *l {
+		texts = append(texts, e.text)
+	}
+	return strings.Join(texts, "; ")
+}
+
+func (l *alertExpressions) reset() {
+	*l = nil
+}
+
+func (l *alertExpressions) Set(value string) error {
+	e, err := parseAlertExpression(value)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, e)
+	return nil
+}
+
+// Command-line flag to alert on combinations of metrics
+var alertExprs alertExpressions
+
+func init() {
+	flag.Var(&alertExprs, "alert-expr", "Alert when an expression over the alerting window holds, e.g. \"qps > 50 AND error_rate > 5%\". Metrics: "+strings.Join(expressionMetrics, ", ")+" (repeatable)")
+}
+
+// Metrics alert expressions may refer to
+var expressionMetrics = []string{"requests", "qps", "error_rate", "client_error_rate", "bytes_per_sec", "unique_ips"}
+
+// Multipliers of the suffixes numbers may carry. Sizes are binary, and
+// percentages are written as such since rates are expressed in percent
+var numberSuffixes = map[string]float64{
+	"":   1,
+	"%":  1,
+	"K":  1000,
+	"M":  1000 * 1000,
+	"KB": 1 << 10,
+	"MB": 1 << 20,
+	"GB": 1 << 30,
+}
+
+// Tokens of alert expressions: parentheses, comparison operators, words
+// and numbers with an optional suffix
+var expressionTokenRegExp = regexp.MustCompile(`^\s*(\(|\)|>=|<=|==|!=|>|<|[A-Za-z_]+|[0-9.]+[A-Za-z%]*)`)
+
+// Compile an alert expression
+func parseAlertExpression(text string) (*alertExpression, error) {
+	p := &expressionParser{used: make(map[string]bool)}
+	rest := text
+	for strings.TrimSpace(rest) != "" {
+		m := expressionTokenRegExp.FindStringSubmatch(rest)
+		if m == nil {
+			return nil, fmt.Errorf("Unexpected input in alert expression: %s", strings.TrimSpace(rest))
+		}
+		p.tokens = append(p.tokens, m[1])
+		rest = rest[len(m[0]):]
+	}
+
+	eval, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("Unexpected %q in alert expression: %s", p.tokens[p.pos], text)
+	}
+	e := &alertExpression{text: text, eval: eval}
+	for metric := range p.used {
+		e.metrics = append(e.metrics, metric)
+	}
+	sort.Strings(e.metrics)
+	return e, nil
+}
+
+// Whether a condition holds given the values of metrics
+type condition func(metrics map[string]float64) bool
+
+// Recursive descent parser of alert expressions, where NOT binds tighter
+// than AND, which binds tighter than OR
+type expressionParser struct {
+	tokens []string
+	pos    int
+	used   map[string]bool // Metrics referred to
+}
+
+// Next token, or "" at the end of the expression
+func (p *expressionParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *expressionParser) or() (condition, error) {
+	left, err := p.and()
+	for err == nil && strings.EqualFold(p.peek(), "OR") {
+		p.pos++
+		var right condition
+		if right, err = p.and(); err == nil {
+			l := left
+			left = func(m map[string]float64) bool { return l(m) || right(m) }
+		}
+	}
+	return left, err
+}
+
+func (p *expressionParser) and() (condition, error) {
+	left, err := p.unary()
+	for err == nil && strings.EqualFold(p.peek(), "AND") {
+		p.pos++
+		var right condition
+		if right, err = p.unary(); err == nil {
+			l := left
+			left = func(m map[string]float64) bool { return l(m) && right(m) }
+		}
+	}
+	return left, err
+}
+
+func (p *expressionParser) unary() (condition, error) {
+	switch token := p.peek(); {
+	case strings.EqualFold(token, "NOT"):
+		p.pos++
+		c, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(m map[string]float64) bool { return !c(m) }, nil
+	case token == "(":
+		p.pos++
+		c, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("Missing closing parenthesis in alert expression")
+		}
+		p.pos++
+		return c, nil
+	}
+	return p.comparison()
+}
+
+func (p *expressionParser) comparison() (condition, error) {
+	if p.pos+3 > len(p.tokens) {
+		return nil, fmt.Errorf("Incomplete comparison in alert expression")
+	}
+	metric, op, literal := p.tokens[p.pos], p.tokens[p.pos+1], p.tokens[p.pos+2]
+	p.pos += 3
+
+	known := false
+	for _, name := range expressionMetrics {
+		known = known || name == metric
+	}
+	if !known {
+		return nil, fmt.Errorf("Unknown metric in alert expression: %s", metric)
+	}
+	p.used[metric] = true
+
+	i := strings.IndexFunc(literal, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(literal)
+	}
+	multiplier, ok := numberSuffixes[strings.ToUpper(literal[i:])]
+	if !ok {
+		return nil, fmt.Errorf("Unknown suffix in alert expression: %s", literal)
+	}
+	number, err := strconv.ParseFloat(literal[:i], 64)
+	if err != nil {
+		return nil, fmt.Errorf("Expected a number in alert expression: %s", literal)
+	}
+	value := number * multiplier
+
+	compare := map[string]func(a, b float64) bool{
+		">":  func(a, b float64) bool { return a > b },
+		">=": func(a, b float64) bool { return a >= b },
+		"<":  func(a, b float64) bool { return a < b },
+		"<=": func(a, b float64) bool { return a <= b },
+		"==": func(a, b float64) bool { return a == b },
+		"!=": func(a, b float64) bool { return a != b },
+	}[op]
+	if compare == nil {
+		return nil, fmt.Errorf("Expected a comparison operator in alert expression: %s", op)
+	}
+	return func(m map[string]float64) bool { return compare(m[metric], value) }, nil
+}
+
+// Metrics of the records in the alerting window
+func windowMetrics(s *stats) map[string]float64 {
+	var errors, clientErrors, bytes int
+	ips := make(map[string]bool)
+	for _, record := range s.logsInWindow {
+		switch {
+		case record.StatusCode >= 500:
+			errors++
+		case record.StatusCode >= 400:
+			clientErrors++
+		}
+		bytes += record.Size
+		ips[record.IP] = true
+	}
+
+	n := len(s.logsInWindow)
+	metrics := map[string]float64{
+		"requests":   float64(n) * s.weight(),
+		"unique_ips": float64(len(ips)),
+	}
+	if n > 0 {
+		metrics["error_rate"] = float64(errors) * 100 / float64(n)
+		metrics["client_error_rate"] = float64(clientErrors) * 100 / float64(n)
+	}
+	if n > 0 {
+		metrics["qps"] = s.windowRate(n)
+		metrics["bytes_per_sec"] = float64(bytes) * s.weight() / s.windowSpan()
+	}
+	return metrics
+}
+
+// Alert firing while an expression holds
+type expressionRule struct {
+	expression *alertExpression
+}
+
+func (r *expressionRule) name() string {
+	return fmt.Sprintf("Expression (%s)", r.expression.text)
+}
+
+func (r *expressionRule) evaluate(s *stats) (bool, string) {
+	metrics := windowMetrics(s)
+	var values []string
+	for _, metric := range r.expression.metrics {
+		values = append(values, fmt.Sprintf("%s=%.2f", metric, metrics[metric]))
+	}
+	return r.expression.eval(metrics), "with " + strings.Join(values, ", ")
+}
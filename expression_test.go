@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlertExpressions(t *testing.T) {
+	metrics := map[string]float64{
+		"qps":           60,
+		"error_rate":    3,
+		"bytes_per_sec": 200 * 1024 * 1024,
+		"unique_ips":    500,
+	}
+	expressions := map[string]bool{
+		"qps > 50":                                       true,
+		"qps > 50 AND error_rate > 5%":                   false,
+		"qps > 50 and error_rate <= 5%":                  true,
+		"bytes_per_sec > 100MB OR unique_ips > 1000":     true,
+		"bytes_per_sec > 1GB OR unique_ips > 1000":       false,
+		"NOT (qps < 10 OR error_rate > 1) OR qps == 60":  true,
+		"qps > 100 OR qps > 50 AND unique_ips != 500":    false,
+		"(qps > 100 OR qps > 50) AND unique_ips >= 0.5K": true,
+	}
+	for text, expected := range expressions {
+		e, err := parseAlertExpression(text)
+		if err != nil {
+			t.Errorf("%s: %s", text, err)
+			continue
+		}
+		if actual := e.eval(metrics); actual != expected {
+			t.Errorf("%s: %+v != %+v", text, actual, expected)
+		}
+	}
+
+	for _, text := range []string{"", "qps >", "qps 50", "latency > 1", "qps > 5XB", "(qps > 5", "qps > 5 qps", "qps > 5 AND", "qps ~ 5"} {
+		if _, err := parseAlertExpression(text); err == nil {
+			t.Errorf("Expected an error for %q", text)
+		}
+	}
+}
+
+func TestExpressionRule(t *testing.T) {
+	e, err := parseAlertExpression("requests >= 2 AND error_rate > 40%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newStats()
+	s.logsInWindow = []*logRecord{{IP: "10.0.0.1", StatusCode: 200}, {IP: "10.0.0.2", StatusCode: 503}}
+	firing, detail := (&expressionRule{e}).evaluate(s)
+	if !firing || detail != "with error_rate=50.00, requests=2.00" {
+		t.Errorf("Unexpected outcome: %+v %s", firing, detail)
+	}
+}
+
+// Test a single record does not make for infinite rates
+func TestWindowMetricsSingleRecord(t *testing.T) {
+	s := newStats()
+	s.updateAlerting(&logRecord{IP: "10.0.0.1", Timestamp: time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC), StatusCode: 200, Size: 1000})
+
+	metrics := windowMetrics(s)
+	if metrics["qps"] != 1 || metrics["bytes_per_sec"] != 1000 {
+		t.Errorf("Unexpected metrics %v", metrics)
+	}
+}
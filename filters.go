@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Regular expressions given on the command line. Values starting with @
+// name a file holding one regular expression per line
+type regexpList []*regexp.Regexp
+
+func (l *regexpList) String() string {
+	var patterns []string
+	for _, re := range *l {
+		patterns = append(patterns, re.String())
+	}
+	return strings.Join(patterns, " ")
+}
+
+func (l *regexpList) reset() {
+	*l = nil
+}
+
+func (l *regexpList) Set(value string) error {
+	patterns := []string{value}
+	if strings.HasPrefix(value, "@") {
+		var err error
+		if patterns, err = readPatterns(value[1:]); err != nil {
+			return err
+		}
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		*l = append(*l, re)
+	}
+	return nil
+}
+
+// Whether any of the regular expressions matches
+func (l regexpList) match(s string) bool {
+	for _, re := range l {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Read patterns from a file, one per line. Blank lines and lines starting
+// with # are ignored
+func readPatterns(fileName string) ([]string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// Comma-separated status codes (e.g. 499) and classes (e.g. 5XX) given on
+// the command line
+type statusSet map[string]bool
+
+func (s statusSet) String() string {
+	return stringSet(s).String()
+}
+
+func (s statusSet) reset() {
+	stringSet(s).reset()
+}
+
+func (s statusSet) Set(value string) error {
+	for _, status := range strings.Split(value, ",") {
+		status = strings.ToUpper(strings.TrimSpace(status))
+		if !statusRegExp.MatchString(status) {
+			return fmt.Errorf("Expected status code or class (e.g. 404 or 4XX): %s", status)
+		}
+		s[status] = true
+	}
+	return nil
+}
+
+// Status codes and classes accepted by statusSet
+var statusRegExp = regexp.MustCompile(`^[1-5]([0-9]{2}|XX)$`)
+
+// Whether a status code is in the set, either by itself or by its class
+func (s statusSet) match(code int) bool {
+	status := strconv.Itoa(code)
+	return s[status] || s[status[:1]+"XX"]
+}
+
+// User agents of well-known health probes from load balancers, container
+// orchestrators and uptime checkers
+var healthCheckRegExp = regexp.MustCompile(`(?i)kube-probe|ELB-HealthChecker|GoogleHC|` +
+	`Consul Health Check|HAProxy|Envoy/HC|Amazon-Route53-Health-Check-Service|nginx-health`)
+
+// Paths commonly serving health checks
+var healthCheckPathRegExp = regexp.MustCompile(`^/(health|healthz|healthcheck|ping|ready|readyz|live|livez|status)/?$`)
+
+// Whether a record looks like a health probe, either by its user agent or
+// by its path and the extra patterns given in -health-check-paths
+func isHealthCheck(log *logRecord) bool {
+	if healthCheckRegExp.MatchString(log.UserAgent) {
+		return true
+	}
+	path := log.Section + log.Resource
+	return healthCheckPathRegExp.MatchString(path) || healthCheckPaths.match(path)
+}
+
+// Command-line flags to drop requests before they reach stats and alerting
+var includePaths regexpList
+var excludePaths regexpList
+var includeStatus = statusSet{}
+var excludeStatus = statusSet{}
+var excludeHealthChecks = flag.Bool("exclude-health-checks", false, "Drop requests from health probes (e.g. kube-probe, ELB-HealthChecker)")
+var healthCheckPaths regexpList
+
+func init() {
+	flag.Var(&includePaths, "include", "Only process requests whose path matches this regular expression, or those in @file (repeatable)")
+	flag.Var(&excludePaths, "exclude", "Drop requests whose path matches this regular expression, or those in @file (repeatable)")
+	flag.Var(includeStatus, "status", "Only process requests with these comma-separated status codes or classes, e.g. 4XX,5XX")
+	flag.Var(excludeStatus, "exclude-status", "Drop requests with these comma-separated status codes or classes, e.g. 499")
+	flag.Var(&healthCheckPaths, "health-check-paths", "Additional regular expression, or those in @file, matching health check paths (repeatable)")
+}
+
+// Whether a record is dropped by the configured filters
+func filtered(log *logRecord) bool {
+	if len(includeStatus) > 0 && !includeStatus.match(log.StatusCode) {
+		return true
+	}
+	if excludeStatus.match(log.StatusCode) {
+		return true
+	}
+
+	if *excludeHealthChecks && isHealthCheck(log) {
+		return true
+	}
+
+	path := log.Section + log.Resource
+	if len(includePaths) > 0 && !includePaths.match(path) {
+		return true
+	}
+	return excludePaths.match(path)
+}
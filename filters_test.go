@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFiltered(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "exclude.txt")
+	if err := os.WriteFile(fileName, []byte("# Static assets\n\\.(css|js|png)$\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := excludePaths.Set("@" + fileName); err != nil {
+		t.Fatal(err)
+	}
+	if err := excludePaths.Set("^/api/internal"); err != nil {
+		t.Fatal(err)
+	}
+	if err := includePaths.Set("^/(api|static)"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { includePaths, excludePaths = nil, nil }()
+
+	x := map[string]bool{
+		"/api/user":          false,
+		"/api/internal/sync": true,
+		"/static/app.js":     true,
+		"/static/index.html": false,
+		"/report":            true,
+	}
+	for path, expected := range x {
+		section, resource := splitSection(path)
+		if actual := filtered(&logRecord{Section: section, Resource: resource}); actual != expected {
+			t.Errorf("%s: %v != %v", path, expected, actual)
+		}
+	}
+
+	if err := excludePaths.Set("("); err == nil {
+		t.Errorf("Expected error for invalid regular expression")
+	}
+}
+
+func TestFilteredStatus(t *testing.T) {
+	if err := includeStatus.Set("4xx, 5XX"); err != nil {
+		t.Fatal(err)
+	}
+	if err := excludeStatus.Set("499"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, set := range []statusSet{includeStatus, excludeStatus} {
+			for status := range set {
+				delete(set, status)
+			}
+		}
+	}()
+
+	x := map[int]bool{
+		200: true,
+		304: true,
+		404: false,
+		499: true,
+		503: false,
+	}
+	for code, expected := range x {
+		if actual := filtered(&logRecord{StatusCode: code, Section: "/"}); actual != expected {
+			t.Errorf("%d: %v != %v", code, expected, actual)
+		}
+	}
+
+	if err := excludeStatus.Set("600"); err == nil {
+		t.Errorf("Expected error for invalid status code")
+	}
+}
+
+func TestFilteredHealthChecks(t *testing.T) {
+	*excludeHealthChecks = true
+	if err := healthCheckPaths.Set("^/api/ping$"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		*excludeHealthChecks = false
+		healthCheckPaths = nil
+	}()
+
+	type testData struct {
+		path      string
+		userAgent string
+		expected  bool
+	}
+
+	x := []testData{
+		{"/", "kube-probe/1.17", true},
+		{"/", "ELB-HealthChecker/2.0", true},
+		{"/healthz", "-", true},
+		{"/api/ping", "curl/7.64.1", true},
+		{"/api/user", "Mozilla/5.0", false},
+		{"/health/report", "Mozilla/5.0", false},
+	}
+	for _, elem := range x {
+		section, resource := splitSection(elem.path)
+		r := &logRecord{Section: section, Resource: resource, UserAgent: elem.userAgent, StatusCode: 200}
+		if actual := filtered(r); actual != elem.expected {
+			t.Errorf("%+v: %v != %v", elem, elem.expected, actual)
+		}
+	}
+}
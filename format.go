@@ -0,0 +1,168 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Command-line flag to select the access log format
+var logFormat = flag.String("format", "w3c", "Access log format (w3c, combined, vhost_combined, iis, ltsv, json, custom)")
+
+// Command-line flag to reject lines not matching the log format
+var strictParsing = flag.Bool("strict", false, "Reject lines not matching -format exactly, instead of trying the w3c, combined, json and custom formats in turn")
+
+// Command-line flag to control how coarse sections are
+var sectionDepth = flag.Int("section-depth", 1, "Number of leading path components making up a section, e.g. 2 to group /api/v1/users as /api/v1")
+
+// Parser turning raw access log lines into log records
+type logParser interface {
+	// Parse a single line. Lines not carrying a request (e.g. directives)
+	// yield a nil record and no error
+	parse(line string) (*logRecord, error)
+}
+
+// Build a parser for the given access log format
+func newLogParser(format string) (logParser, error) {
+	switch format {
+	case "w3c":
+		return w3cParser{}, nil
+	case "combined":
+		return combinedParser{}, nil
+	case "vhost_combined":
+		return vhostCombinedParser{}, nil
+	case "iis":
+		return &iisParser{}, nil
+	case "ltsv":
+		return ltsvParser{}, nil
+	case "json":
+		return jsonParser{}, nil
+	case "custom":
+		return newRegexpParser(*customFormat)
+	}
+	return nil, fmt.Errorf("Unknown log format: %s", format)
+}
+
+// Build a parser rejecting lines that do not match the given format as a
+// whole, e.g. W3C lines followed by Combined fields
+func newStrictLogParser(format string) (logParser, error) {
+	switch format {
+	case "w3c":
+		return w3cParser{strict: true}, nil
+	case "combined":
+		return combinedParser{strict: true}, nil
+	case "vhost_combined":
+		return vhostCombinedParser{strict: true}, nil
+	}
+	return newLogParser(format)
+}
+
+// Build the parser for -format, strict or falling back to other formats
+func configuredLogParser() (logParser, error) {
+	if *strictParsing {
+		return newStrictLogParser(*logFormat)
+	}
+	return newLenientParser(*logFormat)
+}
+
+// Formats tried in turn for lines the configured one rejects
+var fallbackFormats = []string{"w3c", "combined", "json", "custom"}
+
+// Parser for messy logs mixing formats: lines are parsed with the first
+// format matching them, which is recorded
+type lenientParser struct {
+	formats []string
+	parsers []logParser
+}
+
+func newLenientParser(format string) (*lenientParser, error) {
+	primary, err := newLogParser(format)
+	if err != nil {
+		return nil, err
+	}
+	p := &lenientParser{formats: []string{format}, parsers: []logParser{primary}}
+	for _, fallback := range fallbackFormats {
+		if fallback == format || (fallback == "custom" && *customFormat == "") {
+			continue
+		}
+		parser, err := newStrictLogParser(fallback)
+		if err != nil {
+			return nil, err
+		}
+		p.formats = append(p.formats, fallback)
+		p.parsers = append(p.parsers, parser)
+	}
+	return p, nil
+}
+
+// Parse a line with the first format matching it, or fail as the
+// configured format does
+func (p *lenientParser) parse(line string) (*logRecord, error) {
+	var firstErr error
+	for i, parser := range p.parsers {
+		r, err := parser.parse(line)
+		if err == nil {
+			if r != nil {
+				r.Format = p.formats[i]
+			}
+			if i > 0 && verbosity() >= verbosityDebug {
+				log.Printf("Parsed line as %s instead of %s: %s", p.formats[i], p.formats[0], line)
+			}
+			return r, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// Parser for W3C-formatted access logs
+type w3cParser struct {
+	strict bool // Whether lines have to match as a whole
+}
+
+func (p w3cParser) parse(line string) (*logRecord, error) {
+	if p.strict {
+		matched := strictLogLineRegExp.FindStringSubmatch(line)
+		if matched == nil {
+			return nil, fmt.Errorf("Error parsing log line: %s", line)
+		}
+		return recordFromMatch(matched)
+	}
+	return parseLogLine(line)
+}
+
+// Request URI, or "-" when missing, e.g. as the client closed the
+// connection before sending a request line
+func requestURI(uri string) string {
+	if uri == "" {
+		return "-"
+	}
+	return uri
+}
+
+// Check that sections are made of at least one path component, as they
+// would otherwise all be empty
+func checkSectionDepth(depth int) error {
+	if depth < 1 {
+		return fmt.Errorf("Section depth must be at least 1: %d", depth)
+	}
+	return nil
+}
+
+// Split a request URI, once normalized, into its section (first
+// -section-depth path components) and the remaining resource
+func splitSection(uri string) (string, string) {
+	uri = normalizeURI(uri)
+	end := 0
+	for depth := 0; depth < *sectionDepth && end < len(uri); depth++ {
+		i := strings.IndexByte(uri[end+1:], '/')
+		if i < 0 {
+			return uri, ""
+		}
+		end += i + 1
+	}
+	return uri[:end], uri[end:]
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Regular expression for matching (and parsing) Combined-formatted access
+// logs, which append the referrer and user agent to W3C-formatted ones
+var combinedLineRegExp = regexp.MustCompile(logLineRegExp.String() +
+	// Referrer
+	` "` + quotedFieldRegExp + `"` +
+	// User agent
+	` "` + quotedFieldRegExp + `"`)
+
+// Regular expressions matching W3C and Combined lines as a whole
+var strictLogLineRegExp = regexp.MustCompile(`^` + logLineRegExp.String() + `$`)
+var strictCombinedLineRegExp = regexp.MustCompile(`^` + combinedLineRegExp.String() + `$`)
+var strictVHostCombinedLineRegExp = regexp.MustCompile(vhostCombinedLineRegExp.String() + `$`)
+
+// Contents of a quoted field, where quotes and backslashes are escaped
+// with a backslash (\" and \\), as Apache does, and other characters may be
+// escaped in hexadecimal (\x22), as nginx does
+const quotedFieldRegExp = `((?:[^"\\]|\\.)*)`
+
+// Regular expression for matching (and parsing) Apache's vhost_combined
+// access logs, which prepend the virtual host and port to Combined ones
+var vhostCombinedLineRegExp = regexp.MustCompile(`^([^ :]+)(?::\d+)? ` + combinedLineRegExp.String())
+
+// Parser for Combined-formatted access logs
+type combinedParser struct {
+	strict bool // Whether lines have to match as a whole
+}
+
+func (p combinedParser) parse(line string) (*logRecord, error) {
+	re := combinedLineRegExp
+	if p.strict {
+		re = strictCombinedLineRegExp
+	}
+	matched := re.FindStringSubmatch(line)
+	if matched == nil {
+		return nil, fmt.Errorf("Error parsing log line: %s", line)
+	}
+	return recordFromCombinedMatch(matched)
+}
+
+// Parser for vhost_combined-formatted access logs
+type vhostCombinedParser struct {
+	strict bool // Whether lines have to match as a whole
+}
+
+func (p vhostCombinedParser) parse(line string) (*logRecord, error) {
+	re := vhostCombinedLineRegExp
+	if p.strict {
+		re = strictVHostCombinedLineRegExp
+	}
+	matched := re.FindStringSubmatch(line)
+	if matched == nil {
+		return nil, fmt.Errorf("Error parsing log line: %s", line)
+	}
+	// Skip the virtual host so remaining groups line up with Combined ones
+	r, err := recordFromCombinedMatch(matched[1:])
+	if err != nil {
+		return nil, err
+	}
+	r.VHost = matched[1]
+	return r, nil
+}
+
+// Build a log record out of the groups matched by combinedLineRegExp
+func recordFromCombinedMatch(matched []string) (*logRecord, error) {
+	r, err := recordFromMatch(matched)
+	if err != nil {
+		return nil, err
+	}
+	r.Referrer = unquoteField(matched[12])
+	r.UserAgent = unquoteField(matched[13])
+	return r, nil
+}
+
+// Undo the escaping of a quoted field
+func unquoteField(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'x':
+			if i+2 < len(s) {
+				if c, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+					b.WriteByte(byte(c))
+					i += 2
+					continue
+				}
+			}
+			b.WriteString(`\x`)
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCombinedParser(t *testing.T) {
+	line := `83.149.9.216 - - [17/May/2015:10:05:03 +0000] "GET /presentations/kibana.png HTTP/1.1" 200 203023 "http://semicomplete.com/" "Mozilla/5.0 (Macintosh) Chrome/32.0.1700.77"`
+	expected := logRecord{
+		IP:         "83.149.9.216",
+		Identity:   "-",
+		User:       "-",
+		Timestamp:  time.Date(2015, 5, 17, 10, 05, 03, 0, time.UTC),
+		Action:     "GET",
+		Section:    "/presentations",
+		Resource:   "/kibana.png",
+		Protocol:   "HTTP/1.1",
+		StatusCode: 200,
+		Size:       203023,
+		Referrer:   "http://semicomplete.com/",
+		UserAgent:  "Mozilla/5.0 (Macintosh) Chrome/32.0.1700.77",
+	}
+
+	actual, err := combinedParser{}.parse(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *actual != expected {
+		t.Errorf("%+v != %+v", expected, *actual)
+	}
+
+	if _, err := (combinedParser{}).parse(`127.0.0.1 - jill [09/May/2018:16:00:41 +0000] "GET /api/user HTTP/1.0" 200 234`); err == nil {
+		t.Errorf("Expected error for line without referrer and user agent")
+	}
+}
+
+func TestVHostCombinedParser(t *testing.T) {
+	line := `www.example.com:443 10.0.0.1 - - [17/May/2015:10:05:03 +0000] "GET /index.html HTTP/1.1" 200 512 "-" "curl/7.64.1"`
+	actual, err := vhostCombinedParser{}.parse(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual.VHost != "www.example.com" || actual.IP != "10.0.0.1" || actual.UserAgent != "curl/7.64.1" || actual.Size != 512 {
+		t.Errorf("Unexpected record %+v", *actual)
+	}
+}
+
+func TestCombinedParserEscapedQuotes(t *testing.T) {
+	tests := map[string][]string{
+		// Apache
+		`10.0.0.1 - - [17/May/2015:10:05:03 +0000] "GET / HTTP/1.1" 200 512 "http://example.com/?q=\"quoted\"" "Mozilla/5.0 \"Evil\" C:\\Agent"`: {
+			`http://example.com/?q="quoted"`, `Mozilla/5.0 "Evil" C:\Agent`,
+		},
+		// nginx
+		`10.0.0.1 - - [17/May/2015:10:05:03 +0000] "GET / HTTP/1.1" 200 512 "-" "Mozilla/5.0 \x22Evil\x22"`: {
+			"-", `Mozilla/5.0 "Evil"`,
+		},
+	}
+	for line, expected := range tests {
+		actual, err := combinedParser{}.parse(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual.Referrer != expected[0] || actual.UserAgent != expected[1] {
+			t.Errorf("%+v != %+v", expected, []string{actual.Referrer, actual.UserAgent})
+		}
+	}
+}
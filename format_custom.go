@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+)
+
+// Command-line flag to describe the custom access log format
+var customFormat = flag.String("custom-format", "", `Regular expression matching lines of the custom log format, whose named groups are read as keys of the json format, e.g. (?P<ip>\S+) \[(?P<time>[^]]+)\] "(?P<request>[^"]*)" (?P<status>\d+)`)
+
+// Parser for access logs matched by a regular expression. Named groups are
+// read as the keys of JSON-formatted logs, e.g. ip, time, request or status
+type regexpParser struct {
+	re *regexp.Regexp
+}
+
+func newRegexpParser(expr string) (*regexpParser, error) {
+	if expr == "" {
+		return nil, fmt.Errorf("Missing -custom-format regular expression")
+	}
+	// Lines have to match as a whole
+	re, err := regexp.Compile(`^(?:` + expr + `)$`)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return &regexpParser{re: re}, nil
+		}
+	}
+	return nil, fmt.Errorf("No named groups in custom format: %s", expr)
+}
+
+func (p *regexpParser) parse(line string) (*logRecord, error) {
+	matched := p.re.FindStringSubmatch(line)
+	if matched == nil {
+		return nil, fmt.Errorf("Error parsing log line: %s", line)
+	}
+	fields := make(map[string]interface{})
+	for i, name := range p.re.SubexpNames() {
+		if name != "" && matched[i] != "" {
+			fields[name] = matched[i]
+		}
+	}
+	return recordFromFields(fields)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegexpParser(t *testing.T) {
+	p, err := newRegexpParser(`(?P<ip>\S+) \[(?P<time>[^]]+)\] "(?P<request>[^"]*)" (?P<status>\d+|-) (?P<request_time>[\d.]+)s`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := p.parse(`127.0.0.1 [2018-05-09T16:00:39Z] "GET /api/user HTTP/1.1" 503 0.250s`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := logRecord{
+		IP:         "127.0.0.1",
+		Identity:   "-",
+		User:       "-",
+		Timestamp:  time.Date(2018, 5, 9, 16, 0, 39, 0, time.UTC),
+		Action:     "GET",
+		Section:    "/api",
+		Resource:   "/user",
+		Protocol:   "HTTP/1.1",
+		StatusCode: 503,
+		Latency:    250 * time.Millisecond,
+	}
+	if *actual != expected {
+		t.Errorf("%+v != %+v", *actual, expected)
+	}
+
+	// Lines have to match as a whole
+	if _, err := p.parse(`127.0.0.1 [2018-05-09T16:00:39Z] "GET /api/user HTTP/1.1" 503 0.250s extra`); err == nil {
+		t.Errorf("Expected an error for a trailing field")
+	}
+}
+
+func TestRegexpParserErrors(t *testing.T) {
+	for _, expr := range []string{"", `(\S+) (\d+)`, `(?P<ip>\S+`} {
+		if _, err := newRegexpParser(expr); err == nil {
+			t.Errorf("Expected an error for %q", expr)
+		}
+	}
+}
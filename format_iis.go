@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamp format used by the date and time fields of IIS logs
+const iisTimeFormat = "2006-01-02 15:04:05"
+
+// Parser for IIS/W3C extended logs. Field layout is taken from the most
+// recent #Fields directive, which IIS writes again whenever it restarts
+type iisParser struct {
+	fields []string // Field names, in the order they appear on each line
+}
+
+func (p *iisParser) parse(line string) (*logRecord, error) {
+	if strings.HasPrefix(line, "#") {
+		if strings.HasPrefix(line, "#Fields:") {
+			p.fields = strings.Fields(strings.TrimPrefix(line, "#Fields:"))
+		}
+		return nil, nil
+	}
+	if p.fields == nil {
+		return nil, fmt.Errorf("No #Fields directive seen before log line: %s", line)
+	}
+
+	values := strings.Fields(line)
+	if len(values) != len(p.fields) {
+		return nil, fmt.Errorf("Expected %d fields but got %d: %s", len(p.fields), len(values), line)
+	}
+
+	r := &logRecord{Identity: "-", User: "-"}
+	var date, clock, uriStem, uriQuery string
+	for i, name := range p.fields {
+		value := values[i]
+		if name == *cacheStatusField && *cacheStatusField != "" {
+			r.CacheStatus = value
+		}
+		switch name {
+		case "date":
+			date = value
+		case "time":
+			clock = value
+		case "c-ip":
+			r.IP = value
+		case "cs-username":
+			r.User = value
+		case "cs-method":
+			r.Action = value
+		case "cs-uri-stem":
+			uriStem = value
+		case "cs-uri-query":
+			uriQuery = value
+		case "cs-version":
+			r.Protocol = value
+		case "sc-status":
+			if value == "-" {
+				// Aborted before a response was sent
+				continue
+			}
+			statusCode, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, err
+			}
+			r.StatusCode = statusCode
+		case "cs(User-Agent)":
+			// Spaces are encoded as plus signs
+			r.UserAgent = strings.Replace(value, "+", " ", -1)
+		case "cs-host", "cs(Host)":
+			r.VHost = value
+		case "cs(Referer)":
+			r.Referrer = value
+		case "sc-bytes":
+			if size, err := strconv.Atoi(value); err == nil {
+				r.Size = size
+			}
+		case "time-taken":
+			// Expressed in milliseconds
+			if ms, err := strconv.Atoi(value); err == nil {
+				r.Latency = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+
+	ts, err := time.ParseInLocation(iisTimeFormat, date+" "+clock, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	r.Timestamp = ts
+
+	if uriQuery != "" && uriQuery != "-" {
+		uriStem += "?" + uriQuery
+	}
+	r.Section, r.Resource = splitSection(uriStem)
+
+	return r, nil
+}
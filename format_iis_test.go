@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIISParser(t *testing.T) {
+
+	type testData struct {
+		logLine     string
+		expectedLog *logRecord
+	}
+
+	x := []testData{
+		{
+			`#Software: Microsoft Internet Information Services 10.0`,
+			nil,
+		},
+		{
+			`#Fields: date time s-ip cs-method cs-uri-stem cs-uri-query s-port cs-username c-ip sc-status sc-bytes`,
+			nil,
+		},
+		{
+			`2019-01-01 10:00:00 10.0.0.1 GET /api/user id=1 80 - 192.168.1.5 200 512`,
+			&logRecord{
+				IP:         "192.168.1.5",
+				Identity:   "-",
+				User:       "-",
+				Timestamp:  time.Date(2019, 1, 1, 10, 0, 0, 0, time.UTC),
+				Action:     "GET",
+				Section:    "/api",
+				Resource:   "/user?id=1",
+				StatusCode: 200,
+				Size:       512,
+			},
+		},
+		// IIS restarted with a different set of fields
+		{
+			`#Fields: date time c-ip cs-username cs-method cs-uri-stem cs-version sc-status`,
+			nil,
+		},
+		{
+			`2019-01-01 10:05:00 192.168.1.6 jill POST /report HTTP/1.1 404`,
+			&logRecord{
+				IP:         "192.168.1.6",
+				Identity:   "-",
+				User:       "jill",
+				Timestamp:  time.Date(2019, 1, 1, 10, 5, 0, 0, time.UTC),
+				Action:     "POST",
+				Section:    "/report",
+				Protocol:   "HTTP/1.1",
+				StatusCode: 404,
+			},
+		},
+	}
+
+	p := &iisParser{}
+	for _, elem := range x {
+		actualLog, err := p.parse(elem.logLine)
+		if err != nil {
+			t.Errorf("Error %s while parsing log line %s", err, elem.logLine)
+			continue
+		}
+		if elem.expectedLog == nil {
+			if actualLog != nil {
+				t.Errorf("Expected no record for directive %s", elem.logLine)
+			}
+			continue
+		}
+		if *actualLog != *elem.expectedLog {
+			t.Errorf("%+v != %+v", elem.expectedLog, actualLog)
+		}
+	}
+}
+
+// Test data lines are rejected until a #Fields directive is seen
+func TestIISParserNoFields(t *testing.T) {
+	p := &iisParser{}
+	if _, err := p.parse(`2019-01-01 10:00:00 192.168.1.5 GET /api 200`); err == nil {
+		t.Errorf("Expected error when no #Fields directive was seen")
+	}
+}
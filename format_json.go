@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parser for access logs written as one JSON object per line. Common key
+// names used by nginx, Apache and log shippers are recognized
+type jsonParser struct{}
+
+func (jsonParser) parse(line string) (*logRecord, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return nil, err
+	}
+	return recordFromFields(fields)
+}
+
+// Build a log record out of structured fields
+func recordFromFields(fields map[string]interface{}) (*logRecord, error) {
+	r := &logRecord{
+		IP:        jsonString(fields, "remote_addr", "client_ip", "ip", "host"),
+		Identity:  "-",
+		User:      "-",
+		Action:    jsonString(fields, "method", "request_method"),
+		Protocol:  jsonString(fields, "protocol", "server_protocol", "proto"),
+		Referrer:  jsonString(fields, "http_referer", "referer", "referrer"),
+		UserAgent: jsonString(fields, "http_user_agent", "user_agent", "ua"),
+		VHost:     jsonString(fields, "vhost", "server_name", "http_host"),
+	}
+	r.TLSVersion = canonicalTLSVersion(jsonString(fields, "ssl_protocol", "tls_version"))
+	r.TLSCipher = tlsField(jsonString(fields, "ssl_cipher", "tls_cipher"))
+	if *cacheStatusField != "" {
+		r.CacheStatus = jsonString(fields, *cacheStatusField)
+	}
+	if user := jsonString(fields, "remote_user", "user"); user != "" {
+		r.User = user
+	}
+
+	uri := jsonString(fields, "uri", "request_uri", "path")
+	if req := strings.Fields(jsonString(fields, "request")); len(req) == 3 {
+		// Full request line, e.g. "GET /api/user HTTP/1.1"
+		if r.Action == "" {
+			r.Action = req[0]
+		}
+		if uri == "" {
+			uri = req[1]
+		}
+		if r.Protocol == "" {
+			r.Protocol = req[2]
+		}
+	}
+	if uri == "" && jsonString(fields, "request") != "-" {
+		return nil, fmt.Errorf("Missing request URI in record: %v", fields)
+	}
+	uri = requestURI(uri)
+	r.Section, r.Resource = splitSection(uri)
+
+	ts, err := parseJSONTime(jsonString(fields, "time", "timestamp", "@timestamp", "time_local", "time_iso8601"))
+	if err != nil {
+		return nil, err
+	}
+	r.Timestamp = ts
+
+	// Requests aborted before a response was sent lack a status code
+	status, ok := jsonNumber(fields, "status", "status_code")
+	if !ok && jsonString(fields, "status", "status_code") != "-" {
+		return nil, fmt.Errorf("Missing status code in record: %v", fields)
+	}
+	r.StatusCode = int(status)
+
+	if size, ok := jsonNumber(fields, "body_bytes_sent", "bytes_sent", "bytes", "size"); ok {
+		r.Size = int(size)
+	}
+
+	// Request time is expressed in (fractional) seconds
+	if reqtime, ok := jsonNumber(fields, "request_time", "reqtime", "duration"); ok {
+		r.Latency = time.Duration(reqtime * float64(time.Second))
+	}
+
+	return r, nil
+}
+
+// Value of the first of the given keys holding a string
+func jsonString(fields map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if v, ok := fields[key].(string); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// Value of the first of the given keys holding a number, either as a JSON
+// number or as a numeric string
+func jsonNumber(fields map[string]interface{}, keys ...string) (float64, bool) {
+	for _, key := range keys {
+		switch v := fields[key].(type) {
+		case float64:
+			return v, true
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Parse either a W3C (optionally bracketed) or a RFC 3339 timestamp
+func parseJSONTime(s string) (time.Time, error) {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if ts, err := time.ParseInLocation(strftime, s, time.UTC); err == nil {
+		return ts, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
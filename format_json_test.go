@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJSONParser(t *testing.T) {
+
+	type testData struct {
+		logLine     string
+		expectedLog *logRecord
+	}
+
+	x := []testData{
+		{
+			`{"remote_addr":"127.0.0.1","remote_user":"jill","time_local":"09/May/2018:16:00:41 +0000","request":"GET /api/user HTTP/1.0","status":"200","body_bytes_sent":"234","request_time":"0.5"}`,
+			&logRecord{
+				IP:         "127.0.0.1",
+				Identity:   "-",
+				User:       "jill",
+				Timestamp:  time.Date(2018, 5, 9, 16, 00, 41, 0, time.UTC),
+				Action:     "GET",
+				Section:    "/api",
+				Resource:   "/user",
+				Protocol:   "HTTP/1.0",
+				StatusCode: 200,
+				Size:       234,
+				Latency:    500 * time.Millisecond,
+			},
+		},
+		{
+			`{"ip":"127.0.0.1","@timestamp":"2018-05-09T16:00:39Z","method":"POST","path":"/report","status":404,"bytes":12}`,
+			&logRecord{
+				IP:         "127.0.0.1",
+				Identity:   "-",
+				User:       "-",
+				Timestamp:  time.Date(2018, 5, 9, 16, 00, 39, 0, time.UTC),
+				Action:     "POST",
+				Section:    "/report",
+				StatusCode: 404,
+				Size:       12,
+			},
+		},
+		{
+			// Aborted before a request line was received
+			`{"remote_addr":"127.0.0.1","time_local":"09/May/2018:16:00:41 +0000","request":"-","status":"-","body_bytes_sent":"-"}`,
+			&logRecord{
+				IP:        "127.0.0.1",
+				Identity:  "-",
+				User:      "-",
+				Timestamp: time.Date(2018, 5, 9, 16, 00, 41, 0, time.UTC),
+				Section:   "-",
+			},
+		},
+	}
+
+	for _, elem := range x {
+		actualLog, err := jsonParser{}.parse(elem.logLine)
+		if err != nil {
+			t.Errorf("Error %s while parsing log line %s", err, elem.logLine)
+			continue
+		}
+		if !actualLog.Timestamp.Equal(elem.expectedLog.Timestamp) {
+			t.Errorf("%s != %s", elem.expectedLog.Timestamp, actualLog.Timestamp)
+		}
+		actualLog.Timestamp = elem.expectedLog.Timestamp
+		if *actualLog != *elem.expectedLog {
+			t.Errorf("%+v != %+v", elem.expectedLog, actualLog)
+		}
+	}
+}
+
+// Test records without a status code are rejected
+func TestJSONParserMissingStatus(t *testing.T) {
+	if _, err := (jsonParser{}).parse(`{"path":"/api","time":"2018-05-09T16:00:39Z"}`); err == nil {
+		t.Errorf("Expected error for record without status code")
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parser for Labeled Tab-Separated Values (http://ltsv.org) access logs,
+// as commonly emitted by nginx
+type ltsvParser struct{}
+
+func (ltsvParser) parse(line string) (*logRecord, error) {
+	labels := make(map[string]string)
+	for _, field := range strings.Split(line, "\t") {
+		if i := strings.IndexByte(field, ':'); i > 0 {
+			labels[field[:i]] = field[i+1:]
+		}
+	}
+
+	r := &logRecord{
+		IP:        labels["host"],
+		Identity:  "-",
+		User:      "-",
+		Action:    labels["method"],
+		Protocol:  labels["protocol"],
+		Referrer:  labels["referer"],
+		UserAgent: labels["ua"],
+		VHost:     labels["vhost"],
+	}
+	r.TLSVersion = canonicalTLSVersion(labels["ssl_protocol"])
+	r.TLSCipher = tlsField(labels["ssl_cipher"])
+	if *cacheStatusField != "" {
+		r.CacheStatus = labels[*cacheStatusField]
+	}
+	if v, ok := labels["ident"]; ok {
+		r.Identity = v
+	}
+	if v, ok := labels["user"]; ok {
+		r.User = v
+	}
+
+	uri := labels["uri"]
+	if req, ok := labels["req"]; ok {
+		// Full request line, e.g. "GET /api/user HTTP/1.1"
+		if parts := strings.Fields(req); len(parts) == 3 {
+			if r.Action == "" {
+				r.Action = parts[0]
+			}
+			if uri == "" {
+				uri = parts[1]
+			}
+			if r.Protocol == "" {
+				r.Protocol = parts[2]
+			}
+		}
+	}
+	if uri == "" && labels["req"] != "-" {
+		return nil, fmt.Errorf("Missing uri label in log line: %s", line)
+	}
+	uri = requestURI(uri)
+	r.Section, r.Resource = splitSection(uri)
+
+	ts, err := parseLTSVTime(labels["time"])
+	if err != nil {
+		return nil, err
+	}
+	r.Timestamp = ts
+
+	// Requests aborted before a response was sent lack a status code
+	if labels["status"] != "-" {
+		if r.StatusCode, err = strconv.Atoi(labels["status"]); err != nil {
+			return nil, err
+		}
+	}
+
+	if size, err := strconv.Atoi(labels["size"]); err == nil {
+		r.Size = size
+	}
+
+	// Request time is expressed in (fractional) seconds
+	if reqtime, err := strconv.ParseFloat(labels["reqtime"], 64); err == nil {
+		r.Latency = time.Duration(reqtime * float64(time.Second))
+	}
+
+	return r, nil
+}
+
+// Parse the time label, which is either a bracketed W3C timestamp or, as
+// with nginx's $time_iso8601, an RFC 3339 one
+func parseLTSVTime(s string) (time.Time, error) {
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		return time.ParseInLocation(strftime, s[1:len(s)-1], time.UTC)
+	}
+	return time.Parse(time.RFC3339, s)
+}
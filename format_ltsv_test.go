@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLTSVParser(t *testing.T) {
+
+	type testData struct {
+		logLine     string
+		expectedLog *logRecord
+	}
+
+	x := []testData{
+		{
+			"time:[09/May/2018:16:00:41 +0000]\thost:127.0.0.1\tuser:jill\tmethod:GET\turi:/api/user\tprotocol:HTTP/1.1\tstatus:200\tsize:234\treqtime:0.250",
+			&logRecord{
+				IP:         "127.0.0.1",
+				Identity:   "-",
+				User:       "jill",
+				Timestamp:  time.Date(2018, 5, 9, 16, 00, 41, 0, time.UTC),
+				Action:     "GET",
+				Section:    "/api",
+				Resource:   "/user",
+				Protocol:   "HTTP/1.1",
+				StatusCode: 200,
+				Size:       234,
+				Latency:    250 * time.Millisecond,
+			},
+		},
+		{
+			"time:2018-05-09T16:00:39Z\thost:127.0.0.1\treq:POST /report HTTP/1.0\tstatus:503\tsize:-",
+			&logRecord{
+				IP:         "127.0.0.1",
+				Identity:   "-",
+				User:       "-",
+				Timestamp:  time.Date(2018, 5, 9, 16, 00, 39, 0, time.UTC),
+				Action:     "POST",
+				Section:    "/report",
+				Protocol:   "HTTP/1.0",
+				StatusCode: 503,
+			},
+		},
+	}
+
+	for _, elem := range x {
+		actualLog, err := ltsvParser{}.parse(elem.logLine)
+		if err != nil {
+			t.Errorf("Error %s while parsing log line %s", err, elem.logLine)
+			continue
+		}
+		if !actualLog.Timestamp.Equal(elem.expectedLog.Timestamp) {
+			t.Errorf("%s != %s", elem.expectedLog.Timestamp, actualLog.Timestamp)
+		}
+		actualLog.Timestamp = elem.expectedLog.Timestamp
+		if *actualLog != *elem.expectedLog {
+			t.Errorf("%+v != %+v", elem.expectedLog, actualLog)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSplitSection(t *testing.T) {
+	tests := []struct {
+		depth    int
+		uri      string
+		section  string
+		resource string
+	}{
+		{1, "/api/v1/users", "/api", "/v1/users"},
+		{2, "/api/v1/users", "/api/v1", "/users"},
+		{3, "/api/v1/users", "/api/v1/users", ""},
+		{2, "/api", "/api", ""},
+		{2, "/", "/", ""},
+	}
+
+	defer func(depth int) { *sectionDepth = depth }(*sectionDepth)
+	for _, test := range tests {
+		*sectionDepth = test.depth
+		section, resource := splitSection(test.uri)
+		if section != test.section || resource != test.resource {
+			t.Errorf("%+v != %+v", test, []string{section, resource})
+		}
+	}
+}
+
+func TestLenientParser(t *testing.T) {
+	defer func(expr string) { *customFormat = expr }(*customFormat)
+	*customFormat = `(?P<ip>\S+) (?P<time>\S+) (?P<method>[A-Z]+) (?P<uri>\S+) (?P<status>\d+)`
+	p, err := newLenientParser("combined")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line   string
+		format string
+	}{
+		{`127.0.0.1 - - [09/May/2018:16:00:39 +0000] "GET /api/user HTTP/1.0" 200 123 "-" "curl/7.58.0"`, "combined"},
+		{`127.0.0.1 - - [09/May/2018:16:00:39 +0000] "GET /api/user HTTP/1.0" 200 123`, "w3c"},
+		{`{"remote_addr":"127.0.0.1","time":"2018-05-09T16:00:39Z","request":"GET /api/user HTTP/1.1","status":200}`, "json"},
+		{`127.0.0.1 2018-05-09T16:00:39Z GET /api/user 200`, "custom"},
+	}
+	for _, test := range tests {
+		r, err := p.parse(test.line)
+		if err != nil {
+			t.Errorf("Error %s while parsing log line %s", err, test.line)
+			continue
+		}
+		if r.Format != test.format || r.Section != "/api" {
+			t.Errorf("%+v != %+v", []string{r.Format, r.Section}, []string{test.format, "/api"})
+		}
+	}
+
+	if _, err := p.parse("garbage"); err == nil {
+		t.Errorf("Expected an error for a line matching no format")
+	}
+}
+
+func TestStrictParser(t *testing.T) {
+	p, err := newStrictLogParser("w3c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.parse(`127.0.0.1 - - [09/May/2018:16:00:39 +0000] "GET /api/user HTTP/1.0" 200 123`); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if _, err := p.parse(`127.0.0.1 - - [09/May/2018:16:00:39 +0000] "GET /api/user HTTP/1.0" 200 123 "-" "curl/7.58.0"`); err == nil {
+		t.Errorf("Expected an error for Combined fields")
+	}
+}
+
+func TestCheckSectionDepth(t *testing.T) {
+	if err := checkSectionDepth(2); err != nil {
+		t.Error(err)
+	}
+	for _, depth := range []int{0, -1} {
+		if err := checkSectionDepth(depth); err == nil {
+			t.Errorf("Accepted section depth %d", depth)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamp format of generated W3C and Combined log lines
+const generateTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// Time between writes of generated log lines
+const generateTick = 100 * time.Millisecond
+
+// User agents of generated Combined and IIS log lines
+var generateUserAgents = []string{
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_2) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15",
+	"curl/8.5.0",
+	"Googlebot/2.1 (+http://www.google.com/bot.html)",
+}
+
+// Value picked at random, proportionally to its weight
+type weightedChoice struct {
+	value  string
+	weight float64
+}
+
+// Weighted values given on the command line as comma-separated value=weight
+// pairs, e.g. 200=90,404=10
+type weightedChoices []weightedChoice
+
+func (w *weightedChoices) String() string {
+	var pairs []string
+	for _, choice := range *w {
+		pairs = append(pairs, fmt.Sprintf("%s=%g", choice.value, choice.weight))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (w *weightedChoices) Set(value string) error {
+	*w = nil
+	for _, pair := range strings.Split(value, ",") {
+		i := strings.LastIndexByte(pair, '=')
+		if i <= 0 {
+			return fmt.Errorf("Expected value=weight: %s", pair)
+		}
+		weight, err := strconv.ParseFloat(pair[i+1:], 64)
+		if err != nil || weight < 0 {
+			return fmt.Errorf("Invalid weight: %s", pair)
+		}
+		*w = append(*w, weightedChoice{value: pair[:i], weight: weight})
+	}
+	return nil
+}
+
+// Sum of the weights
+func (w weightedChoices) total() float64 {
+	total := 0.0
+	for _, choice := range w {
+		total += choice.weight
+	}
+	return total
+}
+
+// Pick a value at random, or "" if every weight is zero
+func (w weightedChoices) pick(r *rand.Rand) string {
+	x := r.Float64() * w.total()
+	for _, choice := range w {
+		if x < choice.weight {
+			return choice.value
+		}
+		x -= choice.weight
+	}
+	return ""
+}
+
+// Generator of synthetic access log lines
+type logGenerator struct {
+	format   string // w3c, combined or iis
+	statuses weightedChoices
+	sections weightedChoices
+	ips      int // Number of distinct client IPs
+	rand     *rand.Rand
+}
+
+// IIS directives describing the fields of generated lines
+func (g *logGenerator) header(t time.Time) string {
+	if g.format != "iis" {
+		return ""
+	}
+	return "#Software: http_monitor generate\n" +
+		"#Version: 1.0\n" +
+		"#Date: " + t.UTC().Format(iisTimeFormat) + "\n" +
+		"#Fields: date time c-ip cs-method cs-uri-stem cs-uri-query sc-status sc-bytes time-taken cs(User-Agent) cs(Referer)\n"
+}
+
+// Log line for a request made at the given time
+func (g *logGenerator) line(t time.Time) string {
+	n := g.rand.Intn(g.ips)
+	ip := fmt.Sprintf("10.%d.%d.%d", n>>16&0xff, n>>8&0xff, n&0xff)
+	method := "GET"
+	if g.rand.Intn(10) == 0 {
+		method = "POST"
+	}
+	path := g.sections.pick(g.rand)
+	if path != "/" {
+		path += fmt.Sprintf("/page%d", g.rand.Intn(20))
+	}
+	status := g.statuses.pick(g.rand)
+	size := 200 + g.rand.Intn(20000)
+	agent := generateUserAgents[g.rand.Intn(len(generateUserAgents))]
+
+	switch g.format {
+	case "combined":
+		return fmt.Sprintf(`%s - - [%s] "%s %s HTTP/1.1" %s %d "-" "%s"`, ip, t.Format(generateTimeFormat), method, path, status, size, agent)
+	case "iis":
+		return fmt.Sprintf("%s %s %s %s - %s %d %d %s -", t.UTC().Format(iisTimeFormat), ip, method, path, status, size, 1+g.rand.Intn(500), strings.Replace(agent, " ", "+", -1))
+	}
+	return fmt.Sprintf(`%s - - [%s] "%s %s HTTP/1.1" %s %d`, ip, t.Format(generateTimeFormat), method, path, status, size)
+}
+
+// Write log lines as requests are made at the given rate, until count lines
+// are written or the duration elapses (when not zero)
+func (g *logGenerator) run(w io.Writer, qps float64, count int, duration time.Duration) error {
+	out := bufio.NewWriter(w)
+	start := time.Now()
+	if _, err := io.WriteString(out, g.header(start)); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(generateTick)
+	defer ticker.Stop()
+	written := 0
+	for now := range ticker.C {
+		due := int(now.Sub(start).Seconds() * qps)
+		for ; written < due && (count == 0 || written < count); written++ {
+			if _, err := fmt.Fprintln(out, g.line(now)); err != nil {
+				return err
+			}
+		}
+		if err := out.Flush(); err != nil {
+			return err
+		}
+		if (count > 0 && written >= count) || (duration > 0 && now.Sub(start) >= duration) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Run the generate subcommand, writing synthetic access log lines e.g. to
+// demo alerting or test it end to end
+func runGenerate(args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	format := flags.String("format", "w3c", "Format of generated lines (w3c, combined, iis)")
+	qps := flags.Float64("qps", 10, "Requests per second")
+	count := flags.Int("count", 0, "Number of lines after which to stop (0 for no limit)")
+	duration := flags.Duration("duration", 0, "Time after which to stop, e.g. 5m (0 for no limit)")
+	output := flags.String("output", "", "File to append lines to, instead of standard output")
+	ips := flags.Int("ips", 50, "Number of distinct client IPs")
+	seed := flags.Int64("seed", 0, "Seed of the random generator (0 for a random one)")
+	statuses := weightedChoices{{"200", 90}, {"304", 4}, {"404", 4}, {"500", 2}}
+	sections := weightedChoices{{"/", 30}, {"/api", 40}, {"/static", 20}, {"/admin", 5}, {"/login", 5}}
+	flags.Var(&statuses, "statuses", "Distribution of status codes, as comma-separated code=weight pairs")
+	flags.Var(&sections, "sections", "Distribution of sections, as comma-separated section=weight pairs")
+	flags.Parse(args)
+
+	if *format != "w3c" && *format != "combined" && *format != "iis" {
+		return fmt.Errorf("Unknown log format: %s", *format)
+	}
+	if *qps <= 0 {
+		return fmt.Errorf("QPS must be positive: %g", *qps)
+	}
+	if *ips <= 0 {
+		return fmt.Errorf("Number of client IPs must be positive: %d", *ips)
+	}
+	for _, choice := range statuses {
+		if code, err := strconv.Atoi(choice.value); err != nil || code < 100 || code > 599 {
+			return fmt.Errorf("Invalid status code: %s", choice.value)
+		}
+	}
+	for _, choices := range []weightedChoices{statuses, sections} {
+		if choices.total() <= 0 {
+			return fmt.Errorf("Expected some positive weight: %s", choices.String())
+		}
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	g := &logGenerator{format: *format, statuses: statuses, sections: sections, ips: *ips, rand: rand.New(rand.NewSource(*seed))}
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.OpenFile(*output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return g.run(w, *qps, *count, *duration)
+}
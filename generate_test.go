@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGeneratedLines(t *testing.T) {
+	now := time.Date(2024, 3, 7, 12, 30, 0, 0, time.UTC)
+	for _, format := range []string{"w3c", "combined", "iis"} {
+		g := &logGenerator{
+			format:   format,
+			statuses: weightedChoices{{"200", 1}, {"503", 0}},
+			sections: weightedChoices{{"/", 1}, {"/api", 1}},
+			ips:      3,
+			rand:     rand.New(rand.NewSource(1)),
+		}
+		parser, err := newLogParser(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Directives set up the IIS parser
+		if header := strings.TrimSpace(g.header(now)); header != "" {
+			for _, line := range strings.Split(header, "\n") {
+				if _, err := parser.parse(line); err != nil {
+					t.Errorf("%s: %v", line, err)
+				}
+			}
+		}
+		for i := 0; i < 20; i++ {
+			line := g.line(now)
+			r, err := parser.parse(line)
+			if err != nil {
+				t.Fatalf("%s: %v", line, err)
+			}
+			if r.StatusCode != 200 || !r.Timestamp.Equal(now) {
+				t.Errorf("%s: %+v", line, r)
+			}
+			if r.Section != "/" && r.Section != "/api" {
+				t.Errorf("%s: unexpected section %s", line, r.Section)
+			}
+		}
+	}
+}
+
+func TestWeightedChoices(t *testing.T) {
+	var choices weightedChoices
+	if err := choices.Set("200=90,a=b=10"); err != nil {
+		t.Fatal(err)
+	}
+	expected := weightedChoices{{"200", 90}, {"a=b", 10}}
+	if !reflect.DeepEqual(choices, expected) {
+		t.Errorf("%+v != %+v", choices, expected)
+	}
+	for _, value := range []string{"200", "200=x", "=1", "200=-1"} {
+		if err := choices.Set(value); err == nil {
+			t.Errorf("Expected an error for %s", value)
+		}
+	}
+
+	choices = weightedChoices{{"rare", 1}, {"common", 9}}
+	r := rand.New(rand.NewSource(1))
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[choices.pick(r)]++
+	}
+	if share := float64(counts["common"]) / 10000; share < 0.85 || share > 0.95 {
+		t.Errorf("Unexpected share of common picks: %.2f", share)
+	}
+}
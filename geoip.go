@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// Command-line flags to enrich client IPs with their geographical location
+var geoIPDatabase = flag.String("geoip-db", "", "Path to a MaxMind GeoLite2/GeoIP2 City database used to locate client IPs")
+var geoQPS = flag.Float64("geo-qps", 0, "Average QPS threshold for traffic from any single country (0 disables the alert)")
+var geoQPSCritical = flag.Float64("geo-qps-critical", 0, "Average QPS above which geo-traffic alerts are critical rather than warnings (0 makes every alert critical)")
+
+// How often the database file is checked for updates
+const geoIPReloadInterval = 30 * time.Second
+
+// MaxMind database, reloaded whenever the file changes (e.g. after
+// geoipupdate runs)
+type geoIP struct {
+	path    string
+	mutex   sync.RWMutex
+	reader  *geoip2.Reader
+	modTime time.Time
+}
+
+func openGeoIP(path string) (*geoIP, error) {
+	g := &geoIP{path: path}
+	if err := g.reload(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// (Re)open the database file
+func (g *geoIP) reload() error {
+	info, err := os.Stat(g.path)
+	if err != nil {
+		return err
+	}
+	reader, err := geoip2.Open(g.path)
+	if err != nil {
+		return err
+	}
+
+	g.mutex.Lock()
+	old := g.reader
+	g.reader = reader
+	g.modTime = info.ModTime()
+	g.mutex.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// Periodically reload the database when its modification time changes
+func (g *geoIP) watch() {
+	for {
+		time.Sleep(geoIPReloadInterval)
+		info, err := os.Stat(g.path)
+		if err != nil {
+			log.Printf("Cannot stat GeoIP database: %s", err)
+			continue
+		}
+		g.mutex.RLock()
+		changed := !info.ModTime().Equal(g.modTime)
+		g.mutex.RUnlock()
+		if changed {
+			if err := g.reload(); err != nil {
+				log.Printf("Cannot reload GeoIP database: %s", err)
+			}
+		}
+	}
+}
+
+// Country ISO code and city name of an IP, empty when unknown
+func (g *geoIP) lookup(ip string) (string, string) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return "", ""
+	}
+
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	record, err := g.reader.City(addr)
+	if err != nil {
+		return "", ""
+	}
+	return record.Country.IsoCode, record.City.Names["en"]
+}
+
+// Alert firing when traffic from a single country exceeds a QPS threshold
+type geoTrafficRule struct {
+	threshold float64
+	critical  float64
+}
+
+func (r *geoTrafficRule) name() string {
+	return "Geo-traffic"
+}
+
+func (r *geoTrafficRule) evaluate(s *stats) (bool, string) {
+	counts := make(map[string]int)
+	for _, record := range s.logsInWindow {
+		if record.Country != "" {
+			counts[record.Country]++
+		}
+	}
+
+	// Look for the country with the highest query rate
+	var top string
+	var topQPS float64
+	for country, count := range counts {
+		qps := s.windowRate(count)
+		if qps > topQPS || (qps == topQPS && country < top) {
+			top, topQPS = country, qps
+		}
+	}
+	return topQPS > r.threshold, fmt.Sprintf("for %s at %f queries per second on average", top, topQPS)
+}
+
+func (r *geoTrafficRule) severity(s *stats) severity {
+	if r.critical == 0 {
+		return 0
+	}
+	if critical, _ := (&geoTrafficRule{threshold: r.critical}).evaluate(s); critical {
+		return severityCritical
+	}
+	return severityWarning
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Test the geo-traffic alert fires for the busiest country only
+func TestGeoTrafficRule(t *testing.T) {
+	s := &stats{}
+	r := &geoTrafficRule{threshold: 1.0}
+
+	s.updateAlerting(&logRecord{Timestamp: time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC), Country: "ES"})
+	for i := 0; i < 5; i++ {
+		s.updateAlerting(&logRecord{Timestamp: time.Date(2019, 01, 01, 10, 00, 10, 0, time.UTC), Country: "US"})
+	}
+	if firing, detail := r.evaluate(s); firing {
+		t.Errorf("Unexpected alerting triggered %s", detail)
+	}
+
+	for i := 0; i < 10; i++ {
+		s.updateAlerting(&logRecord{Timestamp: time.Date(2019, 01, 01, 10, 00, 10, 0, time.UTC), Country: "US"})
+	}
+	firing, detail := r.evaluate(s)
+	if !firing {
+		t.Errorf("Expected alerting to be triggered")
+	}
+	if detail != "for US at 1.500000 queries per second on average" {
+		t.Errorf("Unexpected alert detail %s", detail)
+	}
+}
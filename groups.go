@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Named set of client networks, e.g. office=10.1.0.0/16,192.168.0.0/24
+type cidrGroup struct {
+	name     string
+	networks []*net.IPNet
+}
+
+// Client groups, matched in the order they were given
+type cidrGroups []cidrGroup
+
+func (g *cidrGroups) String() string {
+	var groups []string
+	for _, group := range *g {
+		var networks []string
+		for _, network := range group.networks {
+			networks = append(networks, network.String())
+		}
+		groups = append(groups, group.name+"="+strings.Join(networks, ","))
+	}
+	return strings.Join(groups, " ")
+}
+
+func (g *cidrGroups) reset() {
+	*g = nil
+}
+
+func (g *cidrGroups) Set(value string) error {
+	i := strings.IndexByte(value, '=')
+	if i <= 0 {
+		return fmt.Errorf("Expected name=cidr[,cidr...]: %s", value)
+	}
+	group := cidrGroup{name: value[:i]}
+	for _, cidr := range strings.Split(value[i+1:], ",") {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return err
+		}
+		group.networks = append(group.networks, network)
+	}
+	*g = append(*g, group)
+	return nil
+}
+
+// Name of the first group containing the IP, empty if none does
+func (g cidrGroups) match(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+	for _, group := range g {
+		for _, network := range group.networks {
+			if network.Contains(addr) {
+				return group.name
+			}
+		}
+	}
+	return ""
+}
+
+// Comma-separated set of names given on the command line
+type stringSet map[string]bool
+
+func (s stringSet) String() string {
+	var names []string
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func (s stringSet) reset() {
+	for name := range s {
+		delete(s, name)
+	}
+}
+
+func (s stringSet) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			s[name] = true
+		}
+	}
+	return nil
+}
+
+// Command-line flags to group clients by network
+var clientGroups cidrGroups
+var alertExcludeGroups = stringSet{}
+
+func init() {
+	flag.Var(&clientGroups, "cidr-group", "Named group of client networks, e.g. office=10.1.0.0/16,192.168.0.0/24 (repeatable)")
+	flag.Var(alertExcludeGroups, "alert-exclude-groups", "Comma-separated client groups whose traffic is ignored by alerting")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCIDRGroups(t *testing.T) {
+	var g cidrGroups
+	for _, value := range []string{"office=10.1.0.0/16,192.168.0.0/24", "vpn=10.0.0.0/8"} {
+		if err := g.Set(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.Set("10.0.0.0/8"); err == nil {
+		t.Errorf("Expected error for group without name")
+	}
+
+	x := map[string]string{
+		"10.1.2.3":    "office",
+		"192.168.0.9": "office",
+		"10.2.0.1":    "vpn",
+		"8.8.8.8":     "",
+		"not-an-ip":   "",
+	}
+	for ip, expected := range x {
+		if group := g.match(ip); group != expected {
+			t.Errorf("%s: %q != %q", ip, expected, group)
+		}
+	}
+}
+
+// Test traffic from excluded groups is counted but never alerted on
+func TestUpdateStatsExcludedGroup(t *testing.T) {
+	alertExcludeGroups["known-bots"] = true
+	defer delete(alertExcludeGroups, "known-bots")
+
+	s := newStats()
+	ts := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	s.updateStats(&logRecord{Timestamp: ts, StatusCode: 200, Section: "/api", Group: "known-bots"})
+	s.updateStats(&logRecord{Timestamp: ts, StatusCode: 200, Section: "/api", Group: "office"})
+
+	if s.groupCounts["known-bots"] != 1 || s.groupCounts["office"] != 1 {
+		t.Errorf("Unexpected group counters %v", s.groupCounts)
+	}
+	if len(s.logsInWindow) != 1 {
+		t.Errorf("Expected only non-excluded traffic in the alerting window")
+	}
+}
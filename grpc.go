@@ -0,0 +1,179 @@
+package main
+
+import (
+	"flag"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/falfaro/http_monitor/monitorpb"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Command-line flag to serve the gRPC streaming API
+var listenGRPC = flag.String("listen-grpc", "", "Address for the gRPC streaming API, e.g. :9090")
+
+// Fans out published messages to every subscriber. Subscribers falling
+// behind miss messages rather than stalling the pipeline
+type broker struct {
+	mutex       sync.Mutex
+	subscribers map[chan interface{}]bool
+}
+
+func newBroker() *broker {
+	return &broker{subscribers: make(map[chan interface{}]bool)}
+}
+
+func (b *broker) subscribe() chan interface{} {
+	ch := make(chan interface{}, 1024)
+	b.mutex.Lock()
+	b.subscribers[ch] = true
+	b.mutex.Unlock()
+	return ch
+}
+
+func (b *broker) unsubscribe(ch chan interface{}) {
+	b.mutex.Lock()
+	delete(b.subscribers, ch)
+	b.mutex.Unlock()
+}
+
+func (b *broker) publish(msg interface{}) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// Implementation of the Monitor gRPC service
+type grpcServer struct {
+	monitorpb.UnimplementedMonitorServer
+
+	records   *broker
+	snapshots *broker
+	alerts    *broker
+
+	addr string // Address actually listened on
+}
+
+func newGRPCServer() *grpcServer {
+	return &grpcServer{records: newBroker(), snapshots: newBroker(), alerts: newBroker()}
+}
+
+// Start serving the gRPC API in the background
+func (g *grpcServer) start(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	g.addr = l.Addr().String()
+	server := grpc.NewServer()
+	monitorpb.RegisterMonitorServer(server, g)
+	go server.Serve(l)
+	return nil
+}
+
+// Relay the messages of a broker until the client goes away
+func relay(b *broker, stream grpc.ServerStream) error {
+	ch := b.subscribe()
+	defer b.unsubscribe(ch)
+	for {
+		select {
+		case msg := <-ch:
+			if err := stream.SendMsg(msg); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (g *grpcServer) StreamRecords(_ *monitorpb.StreamRequest, stream grpc.ServerStreamingServer[monitorpb.Record]) error {
+	return relay(g.records, stream)
+}
+
+func (g *grpcServer) StreamSnapshots(_ *monitorpb.StreamRequest, stream grpc.ServerStreamingServer[monitorpb.Snapshot]) error {
+	return relay(g.snapshots, stream)
+}
+
+func (g *grpcServer) StreamAlerts(_ *monitorpb.StreamRequest, stream grpc.ServerStreamingServer[monitorpb.AlertEvent]) error {
+	return relay(g.alerts, stream)
+}
+
+// Publish an alert transition
+func (g *grpcServer) alertChanged(change alertChange) {
+	g.alerts.publish(&monitorpb.AlertEvent{
+		Time:   timestamppb.Now(),
+		Name:   change.Name,
+		Firing: change.Firing,
+		Detail: change.Detail,
+	})
+}
+
+// Protobuf version of a log record
+func recordMessage(log *logRecord) *monitorpb.Record {
+	return &monitorpb.Record{
+		Ip:         log.IP,
+		User:       log.User,
+		Timestamp:  timestamppb.New(log.Timestamp),
+		Method:     log.Action,
+		Section:    log.Section,
+		Resource:   log.Resource,
+		Protocol:   log.Protocol,
+		StatusCode: int32(log.StatusCode),
+		Size:       int64(log.Size),
+		Latency:    durationpb.New(log.Latency),
+		Referrer:   log.Referrer,
+		UserAgent:  log.UserAgent,
+		Bot:        log.Bot,
+		Pod:        log.Pod,
+		Country:    log.Country,
+		City:       log.City,
+		Group:      log.Group,
+		Vhost:      log.VHost,
+		Source:     log.Source,
+		Attack:     log.Attack,
+	}
+}
+
+// Protobuf snapshot of the stats
+func (s *stats) snapshotMessage(n int, firing []string) *monitorpb.Snapshot {
+	snapshot := &monitorpb.Snapshot{
+		Time:          timestamppb.New(time.Now()),
+		ResponseCodes: make(map[string]int64),
+		FiringAlerts:  firing,
+	}
+	for class, count := range s.scaled(s.httpResponseCodes) {
+		snapshot.ResponseCodes[class] = int64(count)
+	}
+	for _, v := range topCounts(s.scaled(s.sectionCounts), n) {
+		snapshot.TopSections = append(snapshot.TopSections, &monitorpb.Count{Key: v.key, Count: int64(v.count)})
+	}
+	for _, v := range topCounts(s.scaled(s.ipCounts), n) {
+		snapshot.TopIps = append(snapshot.TopIps, &monitorpb.Count{Key: v.key, Count: int64(v.count)})
+	}
+	if qps, err := s.getQueryRate(); err == nil {
+		snapshot.Qps = qps
+	}
+	return snapshot
+}
+
+// Names of the alerts currently firing, sorted
+func (a *alertTracker) firingNames() []string {
+	var names []string
+	for name, firing := range a.firing {
+		if firing {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
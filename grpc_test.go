@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/falfaro/http_monitor/monitorpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestGRPCStreams(t *testing.T) {
+	g := newGRPCServer()
+	if err := g.start("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := grpc.NewClient(g.addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := monitorpb.NewMonitorClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	records, err := client.StreamRecords(ctx, &monitorpb.StreamRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alerts, err := client.StreamAlerts(ctx, &monitorpb.StreamRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for both streams to be subscribed before publishing
+	for subscribed(g.records) == 0 || subscribed(g.alerts) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ts := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	g.records.publish(recordMessage(&logRecord{IP: "10.0.0.1", Timestamp: ts, Action: "GET", Section: "/api", StatusCode: 200}))
+	g.alertChanged(alertChange{Name: "High-traffic", Firing: true, Detail: "at 20.000000 queries per second on average"})
+
+	record, err := records.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Ip != "10.0.0.1" || record.Section != "/api" || record.StatusCode != 200 || !record.Timestamp.AsTime().Equal(ts) {
+		t.Errorf("Unexpected record %v", record)
+	}
+
+	alert, err := alerts.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alert.Name != "High-traffic" || !alert.Firing {
+		t.Errorf("Unexpected alert event %v", alert)
+	}
+}
+
+// Number of subscribers of a broker
+func subscribed(b *broker) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return len(b.subscribers)
+}
+
+func TestSnapshotMessage(t *testing.T) {
+	s := newStats()
+	ts := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	s.updateStats(&logRecord{IP: "10.0.0.1", Timestamp: ts, StatusCode: 200, Section: "/api"})
+	s.updateStats(&logRecord{IP: "10.0.0.1", Timestamp: ts.Add(time.Second), StatusCode: 404, Section: "/api"})
+
+	snapshot := s.snapshotMessage(5, []string{"High-traffic"})
+	if snapshot.ResponseCodes["2XX"] != 1 || snapshot.ResponseCodes["4XX"] != 1 {
+		t.Errorf("Unexpected response codes %v", snapshot.ResponseCodes)
+	}
+	if len(snapshot.TopSections) != 1 || snapshot.TopSections[0].Count != 2 || snapshot.Qps != 2 {
+		t.Errorf("Unexpected snapshot %v", snapshot)
+	}
+}
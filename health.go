@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Command-line flags to tell how far behind a tailed file is still ready,
+// and how long the processing loop may stall while still live
+var readyMaxLag = flag.Int64("ready-max-lag", 1<<20, "Bytes a tailed file may be left unread before /readyz reports not ready")
+var liveMaxStall = flag.Duration("live-max-stall", time.Minute, "How long the processing loop may go without making progress before /healthz fails (0 disables)")
+
+// How often the processing loop reports making progress
+const heartbeatInterval = 5 * time.Second
+
+// Last time the processing loop made progress, zero until it started
+type heartbeat struct {
+	mutex sync.Mutex
+	last  time.Time
+}
+
+func (b *heartbeat) beat(now time.Time) {
+	b.mutex.Lock()
+	b.last = now
+	b.mutex.Unlock()
+}
+
+func (b *heartbeat) get() time.Time {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.last
+}
+
+// Whether a tailer is attached to its file and how far it has read into it
+type tailStatus struct {
+	mutex    sync.Mutex
+	attached bool
+	offset   int64
+}
+
+func (t *tailStatus) update(attached bool, offset int64) {
+	t.mutex.Lock()
+	t.attached = attached
+	t.offset = offset
+	t.mutex.Unlock()
+}
+
+func (t *tailStatus) get() (bool, int64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.attached, t.offset
+}
+
+// Health of a tailed file, as reported by /healthz and /readyz
+type tailHealth struct {
+	Path     string `json:"path"`
+	Attached bool   `json:"attached"`
+	Lag      int64  `json:"lag_bytes"`
+}
+
+// Health of the monitor, as reported by /healthz and /readyz
+type health struct {
+	Status       string            `json:"status"`
+	LastProgress *time.Time        `json:"last_progress,omitempty"`
+	Files        []tailHealth      `json:"files,omitempty"`
+	Sinks        map[string]string `json:"sinks,omitempty"`
+
+	live  bool // The processing loop makes progress and every tailer is attached
+	ready bool // Live, tailers are caught up and sinks accept writes
+}
+
+// Check the processing loop, tailed files and the last write to every sink
+func (m *monitor) health(inputs []input) health {
+	h := health{live: true, ready: true}
+	if last := m.loop.get(); !last.IsZero() {
+		h.LastProgress = &last
+		if *liveMaxStall > 0 && time.Since(last) > *liveMaxStall {
+			h.live = false
+		}
+	}
+	for _, in := range inputs {
+		file, ok := in.(*fileInput)
+		if !ok {
+			continue
+		}
+		attached, offset := file.status.get()
+		t := tailHealth{Path: file.path, Attached: attached}
+		if info, err := os.Stat(file.path); err == nil && info.Size() > offset {
+			t.Lag = info.Size() - offset
+		}
+		if !attached {
+			h.live = false
+		}
+		if t.Lag > *readyMaxLag {
+			h.ready = false
+		}
+		h.Files = append(h.Files, t)
+	}
+	sort.Slice(h.Files, func(i, j int) bool { return h.Files[i].Path < h.Files[j].Path })
+
+	m.sinksMutex.Lock()
+	for name, err := range m.sinkErrors {
+		if h.Sinks == nil {
+			h.Sinks = make(map[string]string)
+		}
+		h.Sinks[name] = "ok"
+		if err != nil {
+			h.Sinks[name] = err.Error()
+			h.ready = false
+		}
+	}
+	m.sinksMutex.Unlock()
+
+	h.ready = h.ready && h.live
+	return h
+}
+
+// Serve /healthz, failing when the monitor is wedged and should be
+// restarted, and /readyz, failing when it is behind or cannot write out
+func (m *monitor) registerHealth(inputs []input) {
+	serve := func(w http.ResponseWriter, ok func(health) bool) {
+		h := m.health(inputs)
+		h.Status = "ok"
+		if !ok(h) {
+			h.Status = "failing"
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		writeJSON(w, h)
+	}
+	httpMux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, func(h health) bool { return h.live })
+	})
+	httpMux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, func(h health) bool { return h.ready })
+	})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMonitorHealth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	if err := os.WriteFile(path, make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file := &fileInput{path: path}
+	m := &monitor{}
+	inputs := []input{file}
+
+	if h := m.health(inputs); h.live || h.ready {
+		t.Errorf("Detached tailer reported healthy: %+v", h)
+	}
+
+	file.status.update(true, 40)
+	h := m.health(inputs)
+	if !h.live || !h.ready {
+		t.Errorf("Attached tailer reported unhealthy: %+v", h)
+	}
+	if len(h.Files) != 1 || h.Files[0].Lag != 60 {
+		t.Errorf("Expected a lag of 60 bytes: %+v", h.Files)
+	}
+
+	defer func(lag int64) { *readyMaxLag = lag }(*readyMaxLag)
+	*readyMaxLag = 50
+	if h := m.health(inputs); !h.live || h.ready {
+		t.Errorf("Lagging tailer reported ready: %+v", h)
+	}
+	*readyMaxLag = 100
+
+	m.sinkErrors = map[string]error{"influx": errors.New("Connection refused"), "sqlite": nil}
+	h = m.health(inputs)
+	if !h.live || h.ready {
+		t.Errorf("Failing sink reported ready: %+v", h)
+	}
+	if h.Sinks["influx"] != "Connection refused" || h.Sinks["sqlite"] != "ok" {
+		t.Errorf("Unexpected sink health: %+v", h.Sinks)
+	}
+}
+
+func TestMonitorHealthStall(t *testing.T) {
+	m := &monitor{}
+	if h := m.health(nil); !h.live || h.LastProgress != nil {
+		t.Errorf("Monitor not started yet reported unhealthy: %+v", h)
+	}
+
+	m.loop.beat(time.Now())
+	if h := m.health(nil); !h.live || h.LastProgress == nil {
+		t.Errorf("Progressing monitor reported unhealthy: %+v", h)
+	}
+
+	m.loop.beat(time.Now().Add(-2 * *liveMaxStall))
+	if h := m.health(nil); h.live || h.ready {
+		t.Errorf("Stalled monitor reported healthy: %+v", h)
+	}
+
+	defer func(stall time.Duration) { *liveMaxStall = stall }(*liveMaxStall)
+	*liveMaxStall = 0
+	if h := m.health(nil); !h.live {
+		t.Errorf("Stall reported while disabled: %+v", h)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Days of the week, in the order heatmaps show them
+var heatmapDays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+
+// Characters shading text heatmap cells, from the lowest value to the highest
+var heatmapShades = []string{"  ", "░░", "▒▒", "▓▓", "██"}
+
+// Requests and 5XX responses by day of the week and hour of the day, in the
+// display time zone, showing the weekly shape of traffic
+type heatmap struct {
+	requests [7][24]int // Indexed by time.Weekday, then hour
+	errors   [7][24]int
+}
+
+// Heatmap of the requests in analyzed intervals, each of them attributed to
+// the hour it started in
+func newHeatmap(intervals []*interval) *heatmap {
+	h := &heatmap{}
+	for _, i := range intervals {
+		t := displayTime(i.Start)
+		h.requests[t.Weekday()][t.Hour()] += i.Requests
+		h.errors[t.Weekday()][t.Hour()] += i.ResponseCodes["5XX"]
+	}
+	return h
+}
+
+// Percentage of 5XX responses in an hour of a day
+func (h *heatmap) errorRate(day time.Weekday, hour int) float64 {
+	if h.requests[day][hour] == 0 {
+		return 0
+	}
+	return float64(h.errors[day][hour]) * 100 / float64(h.requests[day][hour])
+}
+
+// Highest request count and error rate of any hour
+func (h *heatmap) max() (int, float64) {
+	requests, rate := 0, 0.0
+	for day := range h.requests {
+		for hour := range h.requests[day] {
+			if h.requests[day][hour] > requests {
+				requests = h.requests[day][hour]
+			}
+			rate = math.Max(rate, h.errorRate(time.Weekday(day), hour))
+		}
+	}
+	return requests, rate
+}
+
+// Level of a value relative to the highest one, from 0 (none) to levels-1
+func heatLevel(value, max float64, levels int) int {
+	if value <= 0 || max <= 0 {
+		return 0
+	}
+	return 1 + int(math.Min(value/max*float64(levels-1), float64(levels-2)))
+}
+
+// Write request volume and error rate heatmaps shaded with block characters
+func (h *heatmap) writeText(w io.Writer, weight float64) {
+	maxRequests, maxRate := h.max()
+	grid := func(title string, value func(day time.Weekday, hour int) float64, max float64) {
+		fmt.Fprintf(w, "%s\n    ", title)
+		for hour := 0; hour < 24; hour += 3 {
+			fmt.Fprintf(w, "%-6s", fmt.Sprintf("%02d", hour))
+		}
+		fmt.Fprintln(w)
+		for _, day := range heatmapDays {
+			fmt.Fprintf(w, "%s ", day.String()[:3])
+			for hour := 0; hour < 24; hour++ {
+				fmt.Fprint(w, heatmapShades[heatLevel(value(day, hour), max, len(heatmapShades))])
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	grid(fmt.Sprintf("Requests by hour (darkest: %d):", int(math.Round(float64(maxRequests)*weight))), func(day time.Weekday, hour int) float64 {
+		return float64(h.requests[day][hour])
+	}, float64(maxRequests))
+	grid(fmt.Sprintf("5XX rate by hour (darkest: %.2f%%):", maxRate), h.errorRate, maxRate)
+}
+
+// HTML tables of request volume and error rate, with cells colored by
+// their value
+func (h *heatmap) html(weight float64) template.HTML {
+	maxRequests, maxRate := h.max()
+	var out strings.Builder
+	grid := func(title string, value func(day time.Weekday, hour int) float64, max float64, color string, label func(day time.Weekday, hour int) string) {
+		fmt.Fprintf(&out, "<h3>%s</h3>\n<table class=\"heatmap\">\n<tr><th></th>", template.HTMLEscapeString(title))
+		for hour := 0; hour < 24; hour++ {
+			fmt.Fprintf(&out, "<th>%02d</th>", hour)
+		}
+		out.WriteString("</tr>\n")
+		for _, day := range heatmapDays {
+			fmt.Fprintf(&out, "<tr><th>%s</th>", day.String()[:3])
+			for hour := 0; hour < 24; hour++ {
+				opacity := 0.0
+				if max > 0 {
+					opacity = value(day, hour) / max
+				}
+				fmt.Fprintf(&out, `<td style="background: %s; opacity: %.2f" title="%s"></td>`, color, 0.05+0.95*opacity, template.HTMLEscapeString(label(day, hour)))
+			}
+			out.WriteString("</tr>\n")
+		}
+		out.WriteString("</table>\n")
+	}
+	grid("Requests", func(day time.Weekday, hour int) float64 {
+		return float64(h.requests[day][hour])
+	}, float64(maxRequests), "#1976d2", func(day time.Weekday, hour int) string {
+		return fmt.Sprintf("%s %02d:00: %d requests", day, hour, int(math.Round(float64(h.requests[day][hour])*weight)))
+	})
+	grid("5XX rate", h.errorRate, maxRate, "#f44336", func(day time.Weekday, hour int) string {
+		return fmt.Sprintf("%s %02d:00: %.2f%% 5XX", day, hour, h.errorRate(day, hour))
+	})
+	return template.HTML(out.String())
+}
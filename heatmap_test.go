@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeatLevel(t *testing.T) {
+	tests := []struct {
+		value    float64
+		max      float64
+		expected int
+	}{
+		{0, 0, 0},
+		{0, 10, 0},
+		{0.1, 10, 1},
+		{5, 10, 3},
+		{10, 10, 4},
+	}
+	for _, test := range tests {
+		if level := heatLevel(test.value, test.max, 5); level != test.expected {
+			t.Errorf("%g/%g: %+v != %+v", test.value, test.max, level, test.expected)
+		}
+	}
+}
+
+func TestHeatmap(t *testing.T) {
+	// Wednesday at 9:00 and 14:00, the latter failing now and then
+	busy := newInterval(time.Date(2018, 5, 9, 9, 0, 0, 0, time.UTC))
+	busy.Requests = 100
+	failing := newInterval(time.Date(2018, 5, 9, 14, 30, 0, 0, time.UTC))
+	failing.Requests = 40
+	failing.ResponseCodes["5XX"] = 10
+	again := newInterval(time.Date(2018, 5, 9, 14, 40, 0, 0, time.UTC))
+	again.Requests = 10
+
+	h := newHeatmap([]*interval{busy, failing, again})
+	if h.requests[time.Wednesday][9] != 100 || h.requests[time.Wednesday][14] != 50 || h.errors[time.Wednesday][14] != 10 {
+		t.Errorf("Unexpected heatmap: %+v", *h)
+	}
+	if rate := h.errorRate(time.Wednesday, 14); rate != 20 {
+		t.Errorf("%+v != %+v", rate, 20)
+	}
+
+	var out bytes.Buffer
+	h.writeText(&out, 1)
+	lines := strings.Split(out.String(), "\n")
+	expected := []string{
+		"Requests by hour (darkest: 100):",
+		"    00    03    06    09    12    15    18    21    ",
+		"Mon                                                 ",
+		"Tue                                                 ",
+		"Wed                   ██        ▓▓                  ",
+	}
+	for i, line := range expected {
+		if lines[i] != line {
+			t.Errorf("%q != %q", lines[i], line)
+		}
+	}
+	if !strings.Contains(out.String(), "5XX rate by hour (darkest: 20.00%):") {
+		t.Errorf("Expected error rates in:\n%s", out.String())
+	}
+
+	html := string(h.html(1))
+	if !strings.Contains(html, `title="Wednesday 14:00: 50 requests"`) || !strings.Contains(html, `title="Wednesday 14:00: 20.00% 5XX"`) {
+		t.Errorf("Unexpected HTML heatmap:\n%s", html)
+	}
+}
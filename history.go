@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"time"
+)
+
+// Command-line flag to control how much history is kept in memory
+var historyRetention = flag.Duration("history", time.Hour, "How long per-interval aggregates are kept for historical queries")
+
+// Aggregates of the requests seen between two stats dumps
+type interval struct {
+	Start         time.Time      `json:"start"`
+	End           time.Time      `json:"end"`
+	Requests      int            `json:"requests"`
+	Bytes         int            `json:"bytes"`
+	ResponseCodes map[string]int `json:"response_codes"` // Requests per response class
+	Sections      map[string]int `json:"sections"`       // Requests per section
+}
+
+func newInterval(start time.Time) *interval {
+	return &interval{
+		Start:         start,
+		ResponseCodes: make(map[string]int),
+		Sections:      make(map[string]int),
+	}
+}
+
+// Account for a record in the interval
+func (i *interval) add(log *logRecord) {
+	i.Requests++
+	i.Bytes += log.Size
+	i.ResponseCodes[statusClass(log.StatusCode)]++
+	i.Sections[log.Section]++
+}
+
+// Add the counters of another interval
+func (i *interval) merge(other *interval) {
+	i.Requests += other.Requests
+	i.Bytes += other.Bytes
+	for class, count := range other.ResponseCodes {
+		i.ResponseCodes[class] += count
+	}
+	for section, count := range other.Sections {
+		i.Sections[section] += count
+	}
+}
+
+// Average query rate over the interval
+func (i *interval) qps() float64 {
+	if seconds := i.End.Sub(i.Start).Seconds(); seconds > 0 {
+		return float64(i.Requests) / seconds
+	}
+	return 0
+}
+
+// Closed intervals over the retention period, plus the current one
+type history struct {
+	retention time.Duration
+	intervals []*interval // Oldest first
+	current   *interval
+}
+
+func newHistory(retention time.Duration, now time.Time) *history {
+	return &history{retention: retention, current: newInterval(now)}
+}
+
+// Close the current interval and start a new one, returning the closed one
+func (h *history) rotate(now time.Time) *interval {
+	closed := h.current
+	closed.End = now
+	h.intervals = append(h.intervals, closed)
+	h.current = newInterval(now)
+
+	for len(h.intervals) > 0 && now.Sub(h.intervals[0].End) > h.retention {
+		h.intervals = h.intervals[1:]
+	}
+	return closed
+}
+
+// Closed intervals ending after the given time
+func (h *history) since(t time.Time) []*interval {
+	for i, interval := range h.intervals {
+		if interval.End.After(t) {
+			return h.intervals[i:]
+		}
+	}
+	return nil
+}
+
+// Aggregates of every interval, including the current one, ending after
+// the given time
+func (h *history) total(since time.Time, now time.Time) *interval {
+	total := newInterval(since)
+	total.End = now
+	for _, interval := range h.since(since) {
+		total.merge(interval)
+	}
+	total.merge(h.current)
+	return total
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	start := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	h := newHistory(time.Minute, start)
+
+	// One interval every 10 seconds, the n-th one with n requests to /api
+	for n := 1; n <= 9; n++ {
+		for i := 0; i < n; i++ {
+			h.current.add(&logRecord{StatusCode: 200, Section: "/api", Size: 100})
+		}
+		closed := h.rotate(start.Add(time.Duration(n) * 10 * time.Second))
+		if closed.Requests != n || closed.Bytes != n*100 {
+			t.Errorf("%d != %d", n, closed.Requests)
+		}
+	}
+	h.current.add(&logRecord{StatusCode: 500, Section: "/report"})
+
+	// Intervals older than a minute are forgotten
+	if len(h.intervals) != 7 || h.intervals[0].Requests != 3 {
+		t.Errorf("Unexpected intervals after pruning: %d", len(h.intervals))
+	}
+
+	now := start.Add(90 * time.Second)
+	total := h.total(now.Add(-30*time.Second), now)
+	if total.Requests != 7+8+9+1 || total.Sections["/api"] != 7+8+9 || total.ResponseCodes["5XX"] != 1 {
+		t.Errorf("Unexpected total %+v", *total)
+	}
+	if qps := h.intervals[6].qps(); qps != 0.9 {
+		t.Errorf("%f != %f", 0.9, qps)
+	}
+}
@@ -3,17 +3,18 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"os"
+	"os/signal"
 	"regexp"
 	"sort"
 	"strconv"
 	"sync"
+	"syscall"
 	"text/tabwriter"
 	"time"
-
-	"github.com/hpcloud/tail"
 )
 
 // Timestamp format used in W3C-formatted access logs
@@ -22,6 +23,12 @@ const strftime = "_2/Jan/2006:15:04:05 -0700"
 // Command-line flag to override average QPS threshold for high-traffic alerts
 var qpsThreshold = flag.Float64("qps", 10.0, "Average QPS threshold for high-traffic alerts")
 
+// Command-line flag to choose the clock the alerting window is evaluated against
+var windowClock = flag.String("window-clock", "log", "Clock the alerting window follows: log (record timestamps) or wall (time records are read at, so entries age out even when no lines arrive)")
+
+// Length of the alerting window
+const alertingWindow = 2 * time.Minute
+
 // Command-line flag to override N when printing top(N) sections
 var topN = flag.Int("top", 5, "Dump top N sections")
 
@@ -30,79 +37,184 @@ var fileName = flag.String("filename", "access.log", "Pathname to the access log
 
 // Log record
 type logRecord struct {
-	IP         string
-	Identity   string
-	User       string
-	Timestamp  time.Time
-	Action     string
-	Section    string
-	Resource   string
-	Protocol   string
-	StatusCode int
-	Size       int
+	IP          string
+	Identity    string
+	User        string
+	Timestamp   time.Time
+	Action      string
+	Section     string
+	Resource    string
+	Protocol    string
+	StatusCode  int
+	Size        int
+	Latency     time.Duration
+	Pod         string
+	Country     string
+	City        string
+	Group       string
+	Referrer    string
+	UserAgent   string
+	Bot         bool
+	Attack      string
+	Spam        bool // Whether the referrer domain matches spam patterns
+	VHost       string
+	Source      string
+	CacheStatus string
+	TLSVersion  string // e.g. TLSv1.2, empty for plain HTTP or when not logged
+	TLSCipher   string
+	Received    time.Time // When the record was read, as opposed to logged
+	Format      string    // Format the line was parsed as, when parsing leniently
 }
 
 // Internal stats
 type stats struct {
-	httpResponseCodes map[string]int // Keeps counters for each HTTP response code
-	sectionCounts     map[string]int // Keeps counters for each seen section
-	logsInWindow      []*logRecord   // Stores last seen records in the high-traffic alerting window
-	alerting          bool           // Currently alerting?
+	httpResponseCodes map[string]int             // Keeps counters for each HTTP response code
+	sectionCounts     map[string]int             // Keeps counters for each seen section
+	podCounts         map[string]int             // Keeps counters for each Kubernetes pod
+	countryCounts     map[string]int             // Keeps counters for each client country
+	ipCounts          map[string]int             // Keeps counters for each client IP
+	userTraffic       map[string]*userTraffic    // Keeps counters for each authenticated user
+	groupCounts       map[string]int             // Keeps counters for each client group
+	clientTypeCounts  map[string]int             // Keeps counters for bots and humans
+	attackCounts      map[string]int             // Keeps counters for each attack signature seen
+	attackerCounts    map[string]int             // Keeps counters of attacks for each client IP
+	spamReferrers     map[string]int             // Keeps counters for each referrer domain matching spam patterns
+	vhostCounts       map[string]int             // Keeps counters for each virtual host
+	vhostSections     map[string]map[string]int  // Keeps section counters for each virtual host
+	sources           map[string]*stats          // Keeps separate stats for each labeled source
+	agentCounts       map[string]int             // Keeps counters for each agent shipping aggregates
+	fleetBuckets      map[int64]int              // Keeps per-second counters shipped by agents in the alerting window
+	fleetReceived     map[int64]time.Time        // Keeps the time each per-second counter was last shipped
+	sectionLatencies  map[string][]time.Duration // Keeps latencies seen in the current interval for each section
+	pathTraffic       map[string]*pathTraffic    // Keeps requests and bytes served in the current interval for each path
+	cacheCounts       map[string]int             // Keeps counters for each cache result
+	cacheSections     map[string]map[string]int  // Keeps cache result counters for each section
+	formatCounts      map[string]int             // Keeps counters for each log format lines were parsed as
+	protocolCounts    map[string]int             // Keeps counters for each HTTP protocol version
+	tlsVersions       map[string]int             // Keeps counters for each TLS version
+	tlsCiphers        map[string]int             // Keeps counters for each TLS cipher
+	rejected          int                        // Keeps a counter of lines which could not be parsed
+	totalCodes        map[string]int             // Keeps counters for each HTTP response code up to the last reset, in interval mode
+	totalSections     map[string]int             // Keeps counters for each section up to the last reset, in interval mode
+	rates             *rateCounter               // Keeps per-second counters for rolling QPS averages
+	errors            *errorCounter              // Keeps per-minute request and 5XX counters for SLO burn rates
+	sizes             *sizeHistogram             // Keeps a histogram of response sizes
+	sessions          *sessionTracker            // Keeps client sessions, if enabled
+	transitions       map[string]int             // Keeps counters for each section to section transition within sessions
+	history           *history                   // Keeps per-interval aggregates, if enabled
+	resolver          *reverseDNS                // Resolves client IPs to hostnames in reports, if enabled
+	sampleRate        float64                    // Fraction of the requests being processed, if sampling
+	logsInWindow      []*logRecord               // Stores last seen records in the high-traffic alerting window
+	alerting          bool                       // Currently alerting?
+}
+
+// Create empty stats
+func newStats() *stats {
+	return &stats{
+		sectionCounts:    make(map[string]int),
+		podCounts:        make(map[string]int),
+		countryCounts:    make(map[string]int),
+		ipCounts:         make(map[string]int),
+		userTraffic:      make(map[string]*userTraffic),
+		groupCounts:      make(map[string]int),
+		clientTypeCounts: make(map[string]int),
+		attackCounts:     make(map[string]int),
+		attackerCounts:   make(map[string]int),
+		spamReferrers:    make(map[string]int),
+		vhostCounts:      make(map[string]int),
+		vhostSections:    make(map[string]map[string]int),
+		sources:          make(map[string]*stats),
+		agentCounts:      make(map[string]int),
+		fleetBuckets:     make(map[int64]int),
+		fleetReceived:    make(map[int64]time.Time),
+		sectionLatencies: make(map[string][]time.Duration),
+		pathTraffic:      make(map[string]*pathTraffic),
+		cacheCounts:      make(map[string]int),
+		cacheSections:    make(map[string]map[string]int),
+		formatCounts:     make(map[string]int),
+		protocolCounts:   make(map[string]int),
+		tlsVersions:      make(map[string]int),
+		tlsCiphers:       make(map[string]int),
+		rates:            newRateCounter(),
+		errors:           newErrorCounter(),
+		sizes:            &sizeHistogram{},
+		sessions:         newSessionTracker(*sessionTimeout),
+		transitions:      make(map[string]int),
+		httpResponseCodes: map[string]int{
+			"1XX": 0,
+			"2XX": 0,
+			"3XX": 0,
+			"4XX": 0,
+			"5XX": 0,
+		},
+	}
 }
 
 // Regular expression for matching (and parsing) W3C-formatted access logs
 var logLineRegExp = regexp.MustCompile(`([^ ]+) ` +
 	// Identity
-	`(-) ` +
-	// User
-	`([0-9A-Za-z-]+) ` +
+	`([^ ]+) ` +
 	// User
+	`([^ ]+) ` +
+	// Timestamp
 	`\[(\d{2}/(Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4})\]` +
-	// Methpd
-	` \"(GET|POST|PUT|HEAD|DELETE|OPTIONS) ` +
+	// Method, unless the request line is missing altogether ("-")
+	` \"(?:([A-Z]+) ` +
 	// Section
-	`(/[^/ ]*)` +
+	`(/[^/ ]*)?` +
 	// Resource
-	`([^ ]*) ` +
-	// Protocol
-	`(HTTP/\d\.\d)" ` +
-	// Status code
-	`(\d{3}) ` +
+	`([^ ]*)` +
+	// Protocol, missing from HTTP/0.9 requests
+	`(?: (HTTP/\d(?:\.\d)?))?|-)" ` +
+	// Status code, missing from some aborted requests
+	`(\d{3}|-) ` +
 	// Size
-	`([0-9-]+)`)
+	`(\d+|-)`)
 
 // Parse a W3C-formatted access log
 func parseLogLine(s string) (*logRecord, error) {
+	matched := logLineRegExp.FindStringSubmatch(s)
+	if len(matched) < 11 {
+		return nil, fmt.Errorf("Error parsing log line: %s", s)
+	}
+	return recordFromMatch(matched)
+}
+
+// Build a log record out of the groups matched by logLineRegExp
+func recordFromMatch(matched []string) (*logRecord, error) {
 	var ts time.Time
 	var err error
 	var statusCode int
 	var size int
 
-	matched := logLineRegExp.FindStringSubmatch(s)
-	if len(matched) < 11 {
-		log.Panicf("Error parsing log line: %s", s)
-	}
-
+	// The logged offset is honored: UTC only names the zone of timestamps
+	// logged with a +0000 offset
 	if ts, err = time.ParseInLocation(strftime, matched[4], time.UTC); err != nil {
 		return nil, err
 	}
 
-	if statusCode, err = strconv.Atoi(matched[10]); err != nil {
-		return nil, err
+	// Requests aborted before a response was sent lack a status code
+	if matched[10] != "-" {
+		if statusCode, err = strconv.Atoi(matched[10]); err != nil {
+			return nil, err
+		}
 	}
 
 	if size, err = strconv.Atoi(matched[11]); err != nil {
 		size = 0
 	}
 
+	// Sections may span more than the path component matched
+	section, resource := splitSection(requestURI(matched[7] + matched[8]))
+
 	return &logRecord{
 		IP:         matched[1],
 		Identity:   matched[2],
 		User:       matched[3],
 		Timestamp:  ts,
 		Action:     matched[6],
-		Section:    matched[7],
-		Resource:   matched[8],
+		Section:    section,
+		Resource:   resource,
 		Protocol:   matched[9],
 		StatusCode: statusCode,
 		Size:       size,
@@ -113,6 +225,9 @@ func parseLogLine(s string) (*logRecord, error) {
 // log record inside the existing window
 func (s *stats) getDelta() float64 {
 	n := len(s.logsInWindow)
+	if n > 0 && *windowClock == "wall" {
+		return alertingWindow.Seconds()
+	}
 	if n > 0 {
 		start := s.logsInWindow[0]
 		end := s.logsInWindow[n-1]
@@ -123,76 +238,314 @@ func (s *stats) getDelta() float64 {
 
 // Update stats used to trigger high-traffic alerting
 func (s *stats) updateAlerting(log *logRecord) {
-	s.logsInWindow = append(s.logsInWindow, log)
+	s.insertInWindow(log)
+	s.expireWindow(log.Received)
+}
+
+// Add a record to the window, which is kept sorted by timestamp so that
+// lines written slightly out of order (e.g. by several workers) do not
+// skew its first and last records. Records are already sorted by arrival
+// with a wall clock
+func (s *stats) insertInWindow(log *logRecord) {
+	n := len(s.logsInWindow)
+	if *windowClock == "wall" || n == 0 || !log.Timestamp.Before(s.logsInWindow[n-1].Timestamp) {
+		s.logsInWindow = append(s.logsInWindow, log)
+		return
+	}
+	i := sort.Search(n, func(i int) bool {
+		return s.logsInWindow[i].Timestamp.After(log.Timestamp)
+	})
+	s.logsInWindow = append(s.logsInWindow, nil)
+	copy(s.logsInWindow[i+1:], s.logsInWindow[i:])
+	s.logsInWindow[i] = log
+}
 
-	// Pop log records from the beginning of the window until the size of
-	// window is less or equal to 2 minutes (120 seconds)
-	for len(s.logsInWindow) > 0 && s.getDelta() > 120.0 {
-		s.logsInWindow = s.logsInWindow[1:]
+// Pop log records from the beginning of the window until the size of
+// window is less or equal to 2 minutes, then check for high traffic. With
+// a wall clock, records received more than 2 minutes before now are popped
+func (s *stats) expireWindow(now time.Time) {
+	if *windowClock == "wall" {
+		for len(s.logsInWindow) > 0 && now.Sub(s.logsInWindow[0].Received) > alertingWindow {
+			s.logsInWindow = s.logsInWindow[1:]
+		}
+	} else {
+		for len(s.logsInWindow) > 0 && s.getDelta() > alertingWindow.Seconds() {
+			s.logsInWindow = s.logsInWindow[1:]
+		}
 	}
 
 	// Alert if QPS > average QPS threshold
 	if qps, err := s.getQueryRate(); err == nil {
 		s.alerting = (qps > *qpsThreshold)
+	} else if *windowClock == "wall" {
+		// Nothing received lately
+		s.alerting = false
 	}
 }
 
-// Update stats
+// Generate a 1XX, 2XX, 3XX, 4XX or 5XX string from the response code, or
+// "unknown" for records without a valid one, e.g. given as a placeholder
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%cXX", strconv.Itoa(code)[0])
+}
+
+// Update stats with a record
 func (s *stats) updateStats(log *logRecord) {
-	// Generate a 1XX, 2XX, 3XX, 4XX or 5XX string from the response code
-	responseCode := fmt.Sprintf("%d", log.StatusCode)
-	responseCode = fmt.Sprintf("%cXX", responseCode[0])
-	s.httpResponseCodes[responseCode]++
+	s.httpResponseCodes[statusClass(log.StatusCode)]++
 	s.sectionCounts[log.Section]++
-	s.updateAlerting(log)
+	s.ipCounts[log.IP]++
+	s.updateUsers(log)
+	if log.Pod != "" {
+		s.podCounts[log.Pod]++
+	}
+	if log.Country != "" {
+		s.countryCounts[log.Country]++
+	}
+	if log.Group != "" {
+		s.groupCounts[log.Group]++
+	}
+	if clientType := clientType(log); clientType != "" {
+		s.clientTypeCounts[clientType]++
+	}
+	if log.VHost != "" {
+		s.vhostCounts[log.VHost]++
+		if s.vhostSections[log.VHost] == nil {
+			s.vhostSections[log.VHost] = make(map[string]int)
+		}
+		s.vhostSections[log.VHost][log.Section]++
+	}
+	if log.CacheStatus != "" {
+		result := cacheResult(log.CacheStatus)
+		s.cacheCounts[result]++
+		if s.cacheSections[log.Section] == nil {
+			s.cacheSections[log.Section] = make(map[string]int)
+		}
+		s.cacheSections[log.Section][result]++
+	}
+	if log.Format != "" {
+		s.formatCounts[log.Format]++
+	}
+	if log.Protocol != "" && s.protocolCounts != nil {
+		s.protocolCounts[log.Protocol]++
+	}
+	if log.TLSVersion != "" && s.tlsVersions != nil {
+		s.tlsVersions[log.TLSVersion]++
+	}
+	if log.TLSCipher != "" && s.tlsCiphers != nil {
+		s.tlsCiphers[log.TLSCipher]++
+	}
+	if log.Latency > 0 {
+		s.sectionLatencies[log.Section] = append(s.sectionLatencies[log.Section], log.Latency)
+	}
+	if s.pathTraffic != nil {
+		path := resourcePath(log.Resource)
+		if s.pathTraffic[path] == nil {
+			s.pathTraffic[path] = &pathTraffic{}
+		}
+		s.pathTraffic[path].requests++
+		s.pathTraffic[path].bytes += log.Size
+	}
+	if log.Attack != "" {
+		s.attackCounts[log.Attack]++
+		s.attackerCounts[log.IP]++
+	}
+	if log.Spam && s.spamReferrers != nil {
+		s.spamReferrers[referrerHost(log.Referrer)]++
+	}
+	if !excludedFromAlerting(log) {
+		s.updateAlerting(log)
+	}
+	if s.history != nil {
+		s.history.current.add(log)
+	}
+	if s.rates != nil {
+		s.rates.add(log.Timestamp)
+	}
+	if s.errors != nil {
+		s.errors.add(log.Timestamp, log.StatusCode >= 500)
+	}
+	if s.sizes != nil {
+		s.sizes.add(log.Size)
+	}
+	if s.sessions != nil {
+		s.updateTransitions(s.sessions.add(log), log.Section)
+	}
+	if log.Source != "" && s.sources != nil {
+		s.sourceStats(log.Source).updateStats(log)
+	}
+}
+
+// Whether a record is ignored by alerting
+func excludedFromAlerting(log *logRecord) bool {
+	return alertExcludeGroups[log.Group] || (*alertExcludeBots && log.Bot)
 }
 
 // Dump stats to standard output
 func (s *stats) dumpStats() {
+	s.writeStats(os.Stdout)
+}
+
+// Write stats in the format of the periodic dump
+func (s *stats) writeStats(out io.Writer) {
 	var w = new(tabwriter.Writer)
-	w.Init(os.Stdout, 8, 0, 1, ' ', tabwriter.AlignRight)
+	w.Init(out, 8, 0, 1, ' ', tabwriter.AlignRight)
+	if s.weight() != 1 {
+		fmt.Fprintf(w, "Estimated from a %g%% sample\n", s.sampleRate*100)
+	}
+	s.dumpRates(w)
+	if *trendIntervals > 0 {
+		s.dumpTrend(w, *trendIntervals)
+	}
 	s.dumpResponseCodes(w)
 	s.dumpTopSections(w, *topN)
+	s.dumpTotals(w, *topN)
+	s.dumpTopIPs(w, *topN)
+	s.dumpTopUsers(w, *topN)
+	s.dumpSessions(w)
+	if len(s.transitions) > 0 {
+		dumpTopCounts(w, "navigation flows", s.scaled(s.transitions), *topN)
+	}
+	s.dumpSizes(w)
+	s.dumpLargestPaths(w, *topN)
+	dumpCounts(w, "Requests per pod", s.scaled(s.podCounts))
+	dumpCounts(w, "Requests per agent", s.agentCounts)
+	s.dumpVHosts(w, *topN)
+	dumpCounts(w, "Requests per client group", s.scaled(s.groupCounts))
+	dumpCounts(w, "Requests per client type", s.scaled(s.clientTypeCounts))
+	if len(s.formatCounts) > 1 {
+		// Only worth showing for logs mixing formats
+		dumpCounts(w, "Requests per log format", s.scaled(s.formatCounts))
+	}
+	if len(s.protocolCounts) > 1 {
+		// Only worth showing once some clients moved to HTTP/2 or HTTP/3
+		dumpCounts(w, "Requests per protocol", s.scaled(s.protocolCounts))
+	}
+	s.dumpTLS(w, *topN)
+	if len(s.countryCounts) > 0 {
+		dumpTopCounts(w, "countries", s.scaled(s.countryCounts), *topN)
+	}
+	if len(s.sectionLatencies) > 0 {
+		s.dumpSlowestSections(w, *topN)
+		s.dumpApdex(w, *topN, *apdexT)
+	}
+	if len(s.cacheCounts) > 0 {
+		s.dumpCacheResults(w, *topN)
+	}
+	if len(s.attackCounts) > 0 {
+		dumpCounts(w, "Attacks seen", s.scaled(s.attackCounts))
+		dumpTopCounts(w, "attackers", s.scaled(s.attackerCounts), *topN)
+	}
+	s.dumpSpamReferrers(w, *topN)
+	if s.rejected > 0 {
+		fmt.Fprintf(w, "Rejected lines: %d\n", s.rejected)
+	}
 	fmt.Fprint(w, "---\n")
 	w.Flush()
 }
 
 // Dump HTTP response codes to standard output
 func (s *stats) dumpResponseCodes(w *tabwriter.Writer) {
-	fmt.Printf("Response codes:\n")
+	fmt.Fprintf(w, "Response codes:\n")
 
+	responseCodes := s.scaled(s.httpResponseCodes)
 	var keys []string
-	for k := range s.httpResponseCodes {
+	for k := range responseCodes {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
 
 	for _, k := range keys {
-		fmt.Fprintf(w, "%d\t(HTTP/%s)\t", s.httpResponseCodes[k], k)
+		color := statusColor(k)
+		fmt.Fprintf(w, "%s\t%s\t", colorize(color, strconv.Itoa(responseCodes[k])), colorize(color, "(HTTP/"+k+")"))
 	}
 	fmt.Fprintln(w)
 }
 
 // Dumps the top N sections to standard output
 func (s *stats) dumpTopSections(w *tabwriter.Writer, n int) {
-	type sectionCountPair struct {
-		count   int
-		section string
-	}
+	dumpTopCounts(w, "sections", s.scaled(s.sectionCounts), n)
+}
+
+// Key along with its counter
+type keyCountPair struct {
+	count int
+	key   string
+}
 
-	counts := make([]sectionCountPair, 0, len(s.sectionCounts))
-	for section, count := range s.sectionCounts {
-		counts = append(counts, sectionCountPair{count: count, section: section})
+// Compute the N keys with the highest counters, in decreasing order
+func topCounts(counters map[string]int, n int) []keyCountPair {
+	counts := make([]keyCountPair, 0, len(counters))
+	for key, count := range counters {
+		counts = append(counts, keyCountPair{count: count, key: key})
 	}
 	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].count == counts[j].count {
+			return counts[i].key < counts[j].key
+		}
 		return counts[i].count > counts[j].count
 	})
-	fmt.Fprintf(w, "Top %d sections:\n", n)
-	for i, v := range counts {
-		if i >= n {
-			break
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// Dumps the N keys with the highest counters to standard output
+func dumpTopCounts(w *tabwriter.Writer, what string, counters map[string]int, n int) {
+	fmt.Fprintf(w, "Top %d %s:\n", n, what)
+	for _, v := range topCounts(counters, n) {
+		fmt.Fprintf(w, "%d\t %s\n", v.count, v.key)
+	}
+}
+
+// Dump requests and top N sections for each virtual host
+func (s *stats) dumpVHosts(w *tabwriter.Writer, n int) {
+	dumpCounts(w, "Requests per virtual host", s.scaled(s.vhostCounts))
+
+	var vhosts []string
+	for vhost := range s.vhostSections {
+		vhosts = append(vhosts, vhost)
+	}
+	sort.Strings(vhosts)
+	for _, vhost := range vhosts {
+		dumpTopCounts(w, "sections for "+vhost, s.scaled(s.vhostSections[vhost]), n)
+	}
+}
+
+// Dumps the top N client IPs to standard output, along with their hostnames
+// when reverse DNS resolution is enabled
+func (s *stats) dumpTopIPs(w *tabwriter.Writer, n int) {
+	fmt.Fprintf(w, "Top %d client IPs:\n", n)
+	for _, v := range topCounts(s.scaled(s.ipCounts), n) {
+		if s.resolver != nil {
+			if host := s.resolver.name(v.key); host != "" {
+				fmt.Fprintf(w, "%d\t %s (%s)\n", v.count, v.key, host)
+				continue
+			}
 		}
-		fmt.Fprintf(w, "%d\t %s\n", v.count, v.section)
+		fmt.Fprintf(w, "%d\t %s\n", v.count, v.key)
+	}
+}
+
+// Dumps every counter, sorted by key, to standard output. Nothing is dumped
+// when there are no counters
+func dumpCounts(w *tabwriter.Writer, title string, counters map[string]int) {
+	if len(counters) == 0 {
+		return
+	}
+
+	var keys []string
+	for key := range counters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%d\t %s\n", counters[key], key)
 	}
 }
 
@@ -200,64 +553,262 @@ func (s *stats) dumpTopSections(w *tabwriter.Writer, n int) {
 func (s *stats) getQueryRate() (float64, error) {
 	n := len(s.logsInWindow)
 	if n > 0 {
-		delta := s.logsInWindow[n-1].Timestamp.Sub(s.logsInWindow[0].Timestamp).Seconds()
-		return float64(n) / delta, nil
+		return float64(n) * s.weight() / s.getDelta(), nil
 	}
 	return math.Inf(1), fmt.Errorf("Logs window is empty")
 }
 
+// Average query rate in the window for a number of records in it. Windows
+// spanning less than a second, e.g. holding a single record, count as one
+// second, not to report infinite rates
+func (s *stats) windowRate(count int) float64 {
+	return float64(count) * s.weight() / s.windowSpan()
+}
+
+// Seconds spanned by the window, at least one
+func (s *stats) windowSpan() float64 {
+	return math.Max(s.getDelta(), 1)
+}
+
 func main() {
-	// Parse command-line flags
+	// Parse command-line flags, then those of the subcommand if any
+	flag.Usage = usage
 	flag.Parse()
+	name, args := "tail", flag.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	switch name {
+	case "generate":
+		if err := runGenerate(args); err != nil {
+			log.Panic(err)
+		}
+		return
+	case "bench":
+		if err := runBench(args); err != nil {
+			log.Panic(err)
+		}
+		return
+	case "rollups":
+		if err := runRollups(args); err != nil {
+			log.Panic(err)
+		}
+		return
+	case "tail", "analyze", "replay", "serve":
+		if err := parseMonitorCommand(name, args); err != nil {
+			log.Panic(err)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", name)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *configFile != "" {
+		if err := loadConfig(); err != nil {
+			log.Panic(err)
+		}
+	}
 
-	s := &stats{
-		sectionCounts: make(map[string]int),
-		httpResponseCodes: map[string]int{
-			"1XX": 0,
-			"2XX": 0,
-			"3XX": 0,
-			"4XX": 0,
-			"5XX": 0,
-		},
+	if *signalName != "" {
+		if err := signalDaemon(*pidFile, *signalName); err != nil {
+			log.Panic(err)
+		}
+		return
+	}
+	if *daemon && !detached() {
+		pid, err := daemonize()
+		if err != nil {
+			log.Panic(err)
+		}
+		fmt.Printf("Running in the background with PID %d\n", pid)
+		return
+	}
+	if *logFile != "" {
+		f, err := os.OpenFile(*logFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			log.Panic(err)
+		}
+		log.SetOutput(f)
+	}
+	colorOutput = colorSupported(os.Stdout)
+	if *pidFile != "" {
+		if err := writePidFile(*pidFile); err != nil {
+			log.Panic(err)
+		}
+		defer os.Remove(*pidFile)
+	}
+
+	s := newStats()
+	s.sampleRate = *sampleRate
+	s.history = newHistory(*historyRetention, time.Now())
+	if *resolveIPs {
+		s.resolver = newReverseDNS(*resolveConcurrency)
+	}
+
+	m := &monitor{stats: s, mutex: &sync.Mutex{}}
+
+	m.alerts = newAlertTracker(configuredAlertRules())
+	if *banFile != "" {
+		m.alerts.bans = newBanList(*banFile)
+	}
+
+	if *listenGRPC != "" {
+		m.grpcAPI = newGRPCServer()
+		if err := m.grpcAPI.start(*listenGRPC); err != nil {
+			log.Panic(err)
+		}
+	}
+
+	if err := m.configureOutputs(); err != nil {
+		log.Panic(err)
 	}
 
-	mutex := &sync.Mutex{}
+	var err error
+	if m.parser, err = configuredLogParser(); err != nil {
+		log.Panic(err)
+	}
+	if displayLocation, err = loadDisplayLocation(*displayZone); err != nil {
+		log.Panic(err)
+	}
+	if *sloObjective < 0 || *sloObjective >= 100 {
+		log.Panicf("SLO objective must be between 0 and 100%%: %g", *sloObjective)
+	}
+	if err := checkSampleRate(*sampleRate); err != nil {
+		log.Panic(err)
+	}
+	if err := checkSectionDepth(*sectionDepth); err != nil {
+		log.Panic(err)
+	}
+	if err := checkStatsMode(*statsMode); err != nil {
+		log.Panic(err)
+	}
+	if err := checkVerbosity(); err != nil {
+		log.Panic(err)
+	}
+	if *windowClock != "log" && *windowClock != "wall" {
+		log.Panicf("Unknown window clock: %s", *windowClock)
+	}
+
+	if *botList != "" {
+		if extraBotPatterns, err = loadBotList(*botList); err != nil {
+			log.Panic(err)
+		}
+	}
+
+	if *geoIPDatabase != "" {
+		if m.geo, err = openGeoIP(*geoIPDatabase); err != nil {
+			log.Panic(err)
+		}
+		go m.geo.watch()
+	}
+
+	if *analyzeMode {
+		if err := m.runAnalysis(); err != nil {
+			log.Panic(err)
+		}
+		return
+	}
 
 	// Gorutine that periodically dumps stats to standard output, as well as
-	// signaling when a high-traffic condition is triggered or abandoned
+	// signaling when alert conditions are triggered or abandoned
 	go func() {
-		alerting := s.alerting
 		for {
-			mutex.Lock()
+			m.report()
+			time.Sleep(10 * time.Second)
+		}
+	}()
+
+	if *checkpointFile != "" {
+		if checkpoints, err = openCheckpointStore(*checkpointFile); err != nil {
+			log.Panic(err)
+		}
+		go checkpoints.run(*checkpointInterval)
+	}
 
-			s.dumpStats()
+	inputs, err := configuredInputs()
+	if err != nil {
+		log.Panic(err)
+	}
 
-			// Display changes in high-traffic alerting
-			if alerting && !s.alerting {
-				fmt.Printf("High-traffic alerting not firing anymore\n")
-			}
-			if !alerting && s.alerting {
-				qps, _ := s.getQueryRate()
-				fmt.Printf("High-traffic alerting is firing at %f queries per second on average\n", qps)
-			}
+	if *aggregatorURL != "" {
+		m.shipper = newAggregateShipper(*aggregatorURL, *clusterToken, *agentName, *aggregateTopK)
+		go m.shipper.run(*aggregateInterval)
+	}
+	if *acceptAggregates {
+		if *listenHTTP == "" {
+			log.Panic("Accepting aggregates requires -listen-http")
+		}
+		httpMux.Handle("/aggregate", aggregateHandler(s, m.mutex, *clusterToken))
+	}
 
-			mutex.Unlock()
-			time.Sleep(10 * time.Second)
+	if *listenHTTP != "" {
+		registerAPI(s, m.mutex, m.alerts)
+		registerSilenceAPI(*silenceToken)
+		m.registerHealth(inputs)
+	}
+	startHTTPListener()
+
+	if *debugAddr != "" {
+		publishExpvars(s, m.mutex, m.alerts)
+	}
+	startDebugListener()
+
+	// Dump stats on demand, e.g. while investigating an incident
+	dumps := make(chan os.Signal, 1)
+	notifyDump(dumps)
+	go func() {
+		for range dumps {
+			m.dump()
 		}
 	}()
 
-	// Tail through the access log file
-	t, err := tail.TailFile(*fileName, tail.Config{Follow: true})
-	if err != nil {
-		log.Panicf("Cannot tail file: %s", *fileName)
+	// Stop cleanly on SIGINT/SIGTERM and reload on SIGHUP, between two lines
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+	// Read lines from every configured input
+	lines := make(chan inputLine)
+	for _, in := range inputs {
+		go func(in input) {
+			if err := in.run(lines); err != nil {
+				log.Panic(err)
+			}
+		}(in)
 	}
-	for line := range t.Lines {
-		parsedLog, err := parseLogLine(line.Text)
-		if err != nil {
-			log.Panicf("Cannot parse log line: %s", line.Text)
+
+	// Tell systemd we are up, then keep its watchdog at bay for as long as
+	// the processing loop below makes progress
+	sdNotify("READY=1")
+	var watchdog <-chan time.Time
+	if interval := watchdogInterval(); interval > 0 {
+		watchdog = time.NewTicker(interval / 2).C
+	}
+	alive := time.NewTicker(heartbeatInterval)
+	m.loop.beat(time.Now())
+
+	for {
+		select {
+		case <-watchdog:
+			sdNotify("WATCHDOG=1")
+		case now := <-alive.C:
+			m.loop.beat(now)
+		case line := <-lines:
+			// Inputs such as /ingest forward whatever they are sent, so
+			// that malformed lines are counted rather than fatal
+			if err := m.process(line); err != nil && verbosity() > verbosityQuiet {
+				log.Print(err)
+			}
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				sdNotify("RELOADING=1")
+				m.reload()
+				sdNotify("READY=1")
+				continue
+			}
+			log.Printf("Received %s, shutting down", sig)
+			sdNotify("STOPPING=1")
+			m.shutdown()
+			return
 		}
-		mutex.Lock()
-		s.updateStats(parsedLog)
-		mutex.Unlock()
 	}
 }
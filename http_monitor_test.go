@@ -42,6 +42,45 @@ func TestParseLogLine(t *testing.T) {
 				Size:       123,
 			},
 		},
+		{
+			// Client closed the connection before sending a request
+			`127.0.0.1 - - [09/May/2018:16:00:39 +0000] "-" 400 0`,
+			&logRecord{
+				IP:         "127.0.0.1",
+				Identity:   "-",
+				User:       "-",
+				Timestamp:  time.Date(2018, 5, 9, 16, 00, 39, 0, time.UTC),
+				Section:    "-",
+				StatusCode: 400,
+			},
+		},
+		{
+			// Aborted request, without status code nor size
+			`127.0.0.1 frank jill@example.com [09/May/2018:16:00:39 +0000] "PATCH /api/user/42 HTTP/2" - -`,
+			&logRecord{
+				IP:        "127.0.0.1",
+				Identity:  "frank",
+				User:      "jill@example.com",
+				Timestamp: time.Date(2018, 5, 9, 16, 00, 39, 0, time.UTC),
+				Action:    "PATCH",
+				Section:   "/api",
+				Resource:  "/user/42",
+				Protocol:  "HTTP/2",
+			},
+		},
+		{
+			// HTTP/0.9 request, without protocol
+			`127.0.0.1 - - [09/May/2018:16:00:39 +0000] "GET /" 200 -`,
+			&logRecord{
+				IP:         "127.0.0.1",
+				Identity:   "-",
+				User:       "-",
+				Timestamp:  time.Date(2018, 5, 9, 16, 00, 39, 0, time.UTC),
+				Action:     "GET",
+				Section:    "/",
+				StatusCode: 200,
+			},
+		},
 	}
 
 	for _, elem := range x {
@@ -149,3 +188,78 @@ func TestUpdateAlertinFlap(t *testing.T) {
 		t.Errorf("Unexpected alerting triggered")
 	}
 }
+
+// Test records written slightly out of order do not skew the window
+func TestUpdateAlertingOutOfOrder(t *testing.T) {
+	s := &stats{}
+
+	s.updateAlerting(&logRecord{Timestamp: time.Date(2019, 01, 01, 10, 00, 10, 0, time.UTC)})
+	s.updateAlerting(&logRecord{Timestamp: time.Date(2019, 01, 01, 10, 00, 12, 0, time.UTC)})
+	s.updateAlerting(&logRecord{Timestamp: time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)})
+	s.updateAlerting(&logRecord{Timestamp: time.Date(2019, 01, 01, 10, 00, 11, 0, time.UTC)})
+	s.updateAlerting(&logRecord{Timestamp: time.Date(2019, 01, 01, 10, 00, 20, 0, time.UTC)})
+
+	if delta := s.getDelta(); delta != 20 {
+		t.Errorf("%+v != %+v", 20, delta)
+	}
+	for i := 1; i < len(s.logsInWindow); i++ {
+		if s.logsInWindow[i].Timestamp.Before(s.logsInWindow[i-1].Timestamp) {
+			t.Errorf("Window not sorted at %d", i)
+		}
+	}
+
+	// Records older than the window are dropped right away
+	s.updateAlerting(&logRecord{Timestamp: time.Date(2019, 01, 01, 9, 00, 00, 0, time.UTC)})
+	if len(s.logsInWindow) != 5 || s.getDelta() != 20 {
+		t.Errorf("Stale record kept in the window")
+	}
+}
+
+// Test entries age out of a wall-clock window even when no lines arrive
+func TestUpdateAlertingWallClock(t *testing.T) {
+	defer func(clock string) { *windowClock = clock }(*windowClock)
+	*windowClock = "wall"
+	s := &stats{}
+
+	// Timestamps written long ago do not matter, only when records are read
+	received := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	for i := 0; i < 1201; i++ {
+		s.updateAlerting(&logRecord{Timestamp: received.Add(-time.Hour), Received: received})
+	}
+	if !s.alerting {
+		t.Errorf("Expected alerting to be triggered")
+	}
+
+	s.expireWindow(received.Add(time.Minute))
+	if !s.alerting {
+		t.Errorf("Expected alerting to still be triggered")
+	}
+
+	s.expireWindow(received.Add(3 * time.Minute))
+	if s.alerting || len(s.logsInWindow) != 0 {
+		t.Errorf("Expected the window to be empty and alerting to stop")
+	}
+}
+
+// Test requests read from Kubernetes pods are counted per pod
+func TestUpdateStatsPods(t *testing.T) {
+	s := newStats()
+
+	ts := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	s.updateStats(&logRecord{Timestamp: ts, StatusCode: 200, Section: "/api", Pod: "web-1"})
+	s.updateStats(&logRecord{Timestamp: ts, StatusCode: 200, Section: "/api", Pod: "web-1"})
+	s.updateStats(&logRecord{Timestamp: ts, StatusCode: 500, Section: "/api", Pod: "web-2"})
+	s.updateStats(&logRecord{Timestamp: ts, StatusCode: 200, Section: "/api"})
+
+	if s.podCounts["web-1"] != 2 || s.podCounts["web-2"] != 1 || len(s.podCounts) != 2 {
+		t.Errorf("Unexpected pod counters %v", s.podCounts)
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	for code, expected := range map[int]string{200: "2XX", 404: "4XX", 503: "5XX", 0: "unknown", -1: "unknown", 999: "unknown"} {
+		if class := statusClass(code); class != expected {
+			t.Errorf("%d: %q != %q", code, expected, class)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hpcloud/tail"
+)
+
+// Raw access log line, along with the dimensions attached by its input
+type inputLine struct {
+	text   string
+	pod    string     // Kubernetes pod the line was read from, if any
+	source string     // Label of the source the line was read from, if any
+	record *logRecord // Already parsed record, for inputs carrying structured data
+}
+
+// Source of raw access log lines
+type input interface {
+	// Feed lines into the given channel until the input is exhausted
+	run(lines chan<- inputLine) error
+}
+
+// Read up to and including a delimiter, failing on data longer than the
+// limit rather than buffering it all, as clients may never send the
+// delimiter
+func readDelimited(r *bufio.Reader, delim byte, limit int) ([]byte, error) {
+	var data []byte
+	for {
+		chunk, err := r.ReadSlice(delim)
+		if len(data)+len(chunk) > limit {
+			return nil, fmt.Errorf("Message longer than %d bytes", limit)
+		}
+		data = append(data, chunk...)
+		if err != bufio.ErrBufferFull {
+			return data, err
+		}
+	}
+}
+
+// Input tailing an access log file
+type fileInput struct {
+	path        string
+	label       string
+	checkpoints *checkpointStore // Records the position in the file, if enabled
+	status      tailStatus
+}
+
+func (in *fileInput) run(lines chan<- inputLine) error {
+	config := tail.Config{Follow: true}
+
+	// Resume from the recorded position, if any. Offsets are tracked from
+	// the lines already handed over to the pipeline
+	var position checkpoint
+	if in.checkpoints != nil {
+		info, err := os.Stat(in.path)
+		if err != nil {
+			return err
+		}
+		position.Inode = fileInode(info)
+		if recorded, ok := in.checkpoints.get(in.path); ok {
+			position.Offset = resumeOffset(info, recorded)
+		}
+		config.Location = &tail.SeekInfo{Offset: position.Offset, Whence: io.SeekStart}
+	}
+
+	t, err := tail.TailFile(in.path, config)
+	if err != nil {
+		return fmt.Errorf("Cannot tail file: %s", in.path)
+	}
+	in.status.update(true, position.Offset)
+	defer in.status.update(false, position.Offset)
+	for line := range t.Lines {
+		lines <- inputLine{text: line.Text, source: in.label}
+		position.Offset += int64(len(line.Text)) + 1
+		in.status.update(true, position.Offset)
+		if in.checkpoints != nil {
+			in.checkpoints.set(in.path, position)
+		}
+	}
+	return t.Err()
+}
+
+// Whether a command-line flag was explicitly set
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// Build the inputs selected through command-line flags. The access log file
+// is tailed unless some other input is configured, in which case it must be
+// requested explicitly with -filename
+func configuredInputs() ([]input, error) {
+	var inputs []input
+
+	// Replaying stands for every other input
+	if command == "replay" {
+		in, err := newReplayInput(analyzedFiles(), replaySpeed)
+		if err != nil {
+			return nil, err
+		}
+		return append(inputs, in), nil
+	}
+
+	if *listenSyslog != "" {
+		in, err := newSyslogInput(*listenSyslog)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, in)
+	}
+
+	if *kafkaBrokers != "" {
+		inputs = append(inputs, newKafkaInput(*kafkaBrokers, *kafkaTopic, *kafkaGroup))
+	}
+
+	if *journalUnit != "" || *journalIdentifier != "" {
+		inputs = append(inputs, &journalInput{unit: *journalUnit, identifier: *journalIdentifier})
+	}
+
+	if *dockerContainers != "" || *dockerLabel != "" {
+		in, err := newDockerInput(*dockerHost, *dockerContainers, *dockerLabel)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, in)
+	}
+
+	if *k8sSelector != "" {
+		in, err := newK8sInput(*k8sAPI, *k8sNamespace, *k8sSelector, *k8sContainer)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, in)
+	}
+
+	if *ingestHTTP {
+		inputs = append(inputs, &httpIngestInput{token: *ingestToken, maxBody: *ingestMaxBody})
+	}
+
+	if *listenForward != "" {
+		inputs = append(inputs, &forwardInput{addr: *listenForward, key: *forwardKey})
+	}
+
+	if *listenGELF != "" {
+		in, err := newGELFInput(*listenGELF, *gelfFields)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, in)
+	}
+
+	if *s3Location != "" {
+		in, err := newS3Input(*s3Location, *s3Interval, *s3State)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, in)
+	}
+
+	for _, file := range sourceFiles {
+		inputs = append(inputs, &fileInput{path: file.path, label: file.label, checkpoints: checkpoints})
+	}
+
+	// Aggregators and servers may do without local inputs
+	if (len(inputs) == 0 && !*acceptAggregates && command != "serve") || isFlagSet("filename") {
+		inputs = append(inputs, &fileInput{path: *fileName, checkpoints: checkpoints})
+	}
+	return inputs, nil
+}
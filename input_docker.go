@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Command-line flags to stream access logs from Docker containers
+var dockerHost = flag.String("docker-host", "unix:///var/run/docker.sock", "Docker API endpoint")
+var dockerContainers = flag.String("docker-containers", "", "Comma-separated list of Docker container names to stream access logs from")
+var dockerLabel = flag.String("docker-label", "", "Stream access logs from Docker containers carrying this label (key or key=value)")
+
+// How often the set of matching containers is refreshed
+const dockerPollInterval = 5 * time.Second
+
+// Input streaming the standard output of Docker containers through the Docker
+// API. Containers are matched by name or label and re-attached whenever they
+// are restarted or new matching ones show up
+type dockerInput struct {
+	client  *http.Client
+	baseURL string
+	filters map[string][]string
+
+	mutex    sync.Mutex
+	attached map[string]bool      // Containers whose logs are currently streamed
+	since    map[string]time.Time // Time logs were last read for each container
+}
+
+// Build a Docker input talking to the given unix:// or tcp:// endpoint
+func newDockerInput(host, names, label string) (*dockerInput, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, err
+	}
+
+	in := &dockerInput{
+		client:   &http.Client{},
+		filters:  make(map[string][]string),
+		attached: make(map[string]bool),
+		since:    make(map[string]time.Time),
+	}
+	switch u.Scheme {
+	case "unix":
+		in.baseURL = "http://docker"
+		in.client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", u.Path)
+			},
+		}
+	case "tcp", "http":
+		in.baseURL = "http://" + u.Host
+	default:
+		return nil, fmt.Errorf("Unsupported Docker host scheme: %s", u.Scheme)
+	}
+
+	if names != "" {
+		for _, name := range strings.Split(names, ",") {
+			in.filters["name"] = append(in.filters["name"], "^/"+name+"$")
+		}
+	}
+	if label != "" {
+		in.filters["label"] = []string{label}
+	}
+	return in, nil
+}
+
+func (in *dockerInput) run(lines chan<- inputLine) error {
+	for {
+		if err := in.refresh(lines); err != nil {
+			log.Printf("Cannot list Docker containers: %s", err)
+		}
+		time.Sleep(dockerPollInterval)
+	}
+}
+
+// Follow the logs of containers which started running, and forget about
+// where logs were left for those which stopped or went away
+func (in *dockerInput) refresh(lines chan<- inputLine) error {
+	ids, err := in.runningContainers()
+	if err != nil {
+		return err
+	}
+	running := make(map[string]bool, len(ids))
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+	for _, id := range ids {
+		running[id] = true
+		if !in.attached[id] {
+			in.attached[id] = true
+			go in.follow(id, lines)
+		}
+	}
+	for id := range in.since {
+		if !running[id] && !in.attached[id] {
+			delete(in.since, id)
+		}
+	}
+	return nil
+}
+
+// List the identifiers of running containers matching the filters
+func (in *dockerInput) runningContainers() ([]string, error) {
+	filters, err := json.Marshal(in.filters)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := in.client.Get(in.baseURL + "/containers/json?filters=" + url.QueryEscape(string(filters)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Docker API returned %s", resp.Status)
+	}
+
+	var containers []struct {
+		ID string `json:"Id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(containers))
+	for _, c := range containers {
+		ids = append(ids, c.ID)
+	}
+	return ids, nil
+}
+
+// Stream the logs of a container until it stops. Logs are resumed from where
+// they were left whenever the container is restarted
+func (in *dockerInput) follow(id string, lines chan<- inputLine) {
+	defer func() {
+		in.mutex.Lock()
+		in.since[id] = time.Now()
+		delete(in.attached, id)
+		in.mutex.Unlock()
+	}()
+
+	tty, err := in.hasTTY(id)
+	if err != nil {
+		log.Printf("Cannot inspect Docker container %.12s: %s", id, err)
+		return
+	}
+
+	in.mutex.Lock()
+	since := in.since[id]
+	in.mutex.Unlock()
+	if since.IsZero() {
+		since = time.Now()
+	}
+
+	query := url.Values{
+		"follow": {"1"},
+		"stdout": {"1"},
+		"since":  {strconv.FormatInt(since.Unix(), 10)},
+	}
+	resp, err := in.client.Get(in.baseURL + "/containers/" + id + "/logs?" + query.Encode())
+	if err != nil {
+		log.Printf("Cannot stream logs of Docker container %.12s: %s", id, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	var r io.Reader = resp.Body
+	if !tty {
+		r = &dockerStreamReader{r: resp.Body}
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines <- inputLine{text: scanner.Text()}
+	}
+}
+
+// Whether the container was started with a TTY, in which case its logs are
+// not multiplexed
+func (in *dockerInput) hasTTY(id string) (bool, error) {
+	resp, err := in.client.Get(in.baseURL + "/containers/" + id + "/json")
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	var container struct {
+		Config struct {
+			Tty bool
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&container); err != nil {
+		return false, err
+	}
+	return container.Config.Tty, nil
+}
+
+// Reader demultiplexing a Docker log stream, where each frame is prefixed by
+// an 8-byte header holding the stream type and the payload size
+type dockerStreamReader struct {
+	r         io.Reader
+	remaining uint32 // Bytes left in the current frame
+}
+
+func (d *dockerStreamReader) Read(p []byte) (int, error) {
+	for d.remaining == 0 {
+		var header [8]byte
+		if _, err := io.ReadFull(d.r, header[:]); err != nil {
+			return 0, err
+		}
+		d.remaining = binary.BigEndian.Uint32(header[4:])
+	}
+	if uint32(len(p)) > d.remaining {
+		p = p[:d.remaining]
+	}
+	n, err := d.r.Read(p)
+	d.remaining -= uint32(n)
+	return n, err
+}
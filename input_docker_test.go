@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Test lines split across multiplexed frames are reassembled
+func TestDockerStreamReader(t *testing.T) {
+	var stream bytes.Buffer
+	for _, payload := range []string{"GET /api", " 200\nGET /report 404\n"} {
+		header := []byte{1, 0, 0, 0, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+		stream.Write(header)
+		stream.WriteString(payload)
+	}
+
+	scanner := bufio.NewScanner(&dockerStreamReader{r: &stream})
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 2 || lines[0] != "GET /api 200" || lines[1] != "GET /report 404" {
+		t.Errorf("Unexpected lines %q", lines)
+	}
+}
+
+// Fake Docker API listing containers and serving two log lines per container
+func fakeDockerAPI(t *testing.T, containers func() []string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/containers/json":
+			if r.URL.Query().Get("filters") != `{"name":["^/web$"]}` {
+				t.Errorf("Unexpected container list query %s", r.URL.RawQuery)
+			}
+			var list []map[string]string
+			for _, id := range containers() {
+				list = append(list, map[string]string{"Id": id})
+			}
+			json.NewEncoder(w).Encode(list)
+		case strings.HasSuffix(r.URL.Path, "/json"):
+			fmt.Fprint(w, `{"Config": {"Tty": true}}`)
+		case strings.HasSuffix(r.URL.Path, "/logs"):
+			if r.URL.Query().Get("follow") != "1" || r.URL.Query().Get("since") == "" {
+				t.Errorf("Unexpected log query %s", r.URL.RawQuery)
+			}
+			id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/containers/"), "/logs")
+			fmt.Fprintf(w, "%s line 1\n%s line 2\n", id, id)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestDockerInput(t *testing.T) {
+	var mutex sync.Mutex
+	running := []string{"abc"}
+	server := fakeDockerAPI(t, func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return running
+	})
+	defer server.Close()
+
+	in, err := newDockerInput("tcp://"+strings.TrimPrefix(server.URL, "http://"), "web", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := make(chan inputLine, 10)
+	if err := in.refresh(lines); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"abc line 1", "abc line 2"} {
+		select {
+		case line := <-lines:
+			if line.text != expected {
+				t.Errorf("Unexpected line %+v", line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for %q", expected)
+		}
+	}
+
+	// The stream ends, as when the container stops, and the container goes away
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		in.mutex.Lock()
+		detached := !in.attached["abc"]
+		in.mutex.Unlock()
+		if detached {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Container still attached after its logs ended")
+		}
+	}
+	if in.since["abc"].IsZero() {
+		t.Errorf("Time logs were left not recorded")
+	}
+	mutex.Lock()
+	running = nil
+	mutex.Unlock()
+	if err := in.refresh(lines); err != nil {
+		t.Fatal(err)
+	}
+	if len(in.since) != 0 || len(in.attached) != 0 {
+		t.Errorf("Container not forgotten: %v %v", in.since, in.attached)
+	}
+
+	server.Close()
+	if err := in.refresh(lines); err == nil {
+		t.Errorf("Expected error when the API is unreachable")
+	}
+}
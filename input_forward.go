@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Command-line flags to receive access logs from fluentd/fluent-bit agents
+var listenForward = flag.String("listen-forward", "", "Receive access logs over the fluentd forward protocol on the given address, e.g. :24224")
+var forwardKey = flag.String("forward-key", "log", "Record key holding the access log line; records without it are passed on as JSON")
+
+func init() {
+	msgpack.RegisterExt(0, (*eventTime)(nil))
+}
+
+// Fluentd EventTime extension type, carrying nanosecond precision timestamps
+type eventTime struct {
+	seconds     uint32
+	nanoseconds uint32
+}
+
+func (t *eventTime) MarshalMsgpack() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, t.seconds)
+	binary.BigEndian.PutUint32(b[4:], t.nanoseconds)
+	return b, nil
+}
+
+func (t *eventTime) UnmarshalMsgpack(b []byte) error {
+	if len(b) != 8 {
+		return fmt.Errorf("Invalid EventTime length: %d", len(b))
+	}
+	t.seconds = binary.BigEndian.Uint32(b)
+	t.nanoseconds = binary.BigEndian.Uint32(b[4:])
+	return nil
+}
+
+// Input implementing the server side of the fluentd forward protocol
+// (msgpack over TCP), in Message, Forward and (Compressed)PackedForward modes
+type forwardInput struct {
+	addr string
+	key  string
+}
+
+func (in *forwardInput) run(lines chan<- inputLine) error {
+	ln, err := net.Listen("tcp", in.addr)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			if err := in.serve(conn, lines); err != nil && err != io.EOF {
+				log.Printf("Error reading forward stream from %s: %s", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// Decode forward messages from a connection until it is closed, sending
+// acknowledgements when requested
+func (in *forwardInput) serve(conn io.ReadWriter, lines chan<- inputLine) error {
+	dec := msgpack.NewDecoder(bufio.NewReader(conn))
+	enc := msgpack.NewEncoder(conn)
+	for {
+		var msg []interface{}
+		if err := dec.Decode(&msg); err != nil {
+			return err
+		}
+		chunk, err := in.handle(msg, lines)
+		if err != nil {
+			return err
+		}
+		if chunk != "" {
+			if err := enc.Encode(map[string]interface{}{"ack": chunk}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Feed the records of a single forward message into the pipeline, returning
+// the chunk identifier to acknowledge (if any)
+func (in *forwardInput) handle(msg []interface{}, lines chan<- inputLine) (string, error) {
+	if len(msg) < 2 {
+		return "", fmt.Errorf("Invalid forward message with %d elements", len(msg))
+	}
+
+	var options map[string]interface{}
+	if opts, ok := msg[len(msg)-1].(map[string]interface{}); ok && len(msg) > 2 {
+		options = opts
+	}
+	chunk, _ := options["chunk"].(string)
+
+	switch entries := msg[1].(type) {
+	case []interface{}:
+		// Forward mode: [tag, [[time, record], ...], option]
+		for _, entry := range entries {
+			if err := in.emitEntry(entry, lines); err != nil {
+				return "", err
+			}
+		}
+	case string, []byte:
+		// PackedForward mode: [tag, msgpack stream of entries, option]
+		var packed []byte
+		if s, ok := entries.(string); ok {
+			packed = []byte(s)
+		} else {
+			packed = entries.([]byte)
+		}
+		var r io.Reader = bytes.NewReader(packed)
+		if options["compressed"] == "gzip" {
+			gz, err := gzip.NewReader(r)
+			if err != nil {
+				return "", err
+			}
+			defer gz.Close()
+			r = gz
+		}
+		dec := msgpack.NewDecoder(r)
+		for {
+			var entry interface{}
+			if err := dec.Decode(&entry); err == io.EOF {
+				break
+			} else if err != nil {
+				return "", err
+			}
+			if err := in.emitEntry(entry, lines); err != nil {
+				return "", err
+			}
+		}
+	default:
+		// Message mode: [tag, time, record, option]
+		if len(msg) < 3 {
+			return "", fmt.Errorf("Invalid forward message with %d elements", len(msg))
+		}
+		if err := in.emitRecord(msg[2], lines); err != nil {
+			return "", err
+		}
+	}
+	return chunk, nil
+}
+
+// Feed a [time, record] entry into the pipeline
+func (in *forwardInput) emitEntry(entry interface{}, lines chan<- inputLine) error {
+	pair, ok := entry.([]interface{})
+	if !ok || len(pair) != 2 {
+		return fmt.Errorf("Invalid forward entry: %v", entry)
+	}
+	return in.emitRecord(pair[1], lines)
+}
+
+// Feed a record into the pipeline, either as the access log line held in
+// the configured key or as a JSON document
+func (in *forwardInput) emitRecord(record interface{}, lines chan<- inputLine) error {
+	fields, ok := record.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Invalid forward record: %v", record)
+	}
+	switch line := fields[in.key].(type) {
+	case string:
+		lines <- inputLine{text: line}
+		return nil
+	case []byte:
+		lines <- inputLine{text: string(line)}
+		return nil
+	}
+	doc, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	lines <- inputLine{text: string(doc)}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Connection replaying a canned client stream and recording server replies
+type fakeConn struct {
+	in  io.Reader
+	out bytes.Buffer
+}
+
+func (c *fakeConn) Read(p []byte) (int, error) {
+	return c.in.Read(p)
+}
+
+func (c *fakeConn) Write(p []byte) (int, error) {
+	return c.out.Write(p)
+}
+
+func TestForwardInput(t *testing.T) {
+	var packed bytes.Buffer
+	enc := msgpack.NewEncoder(&packed)
+	enc.Encode([]interface{}{&eventTime{seconds: 1}, map[string]interface{}{"log": "packed"}})
+
+	var stream bytes.Buffer
+	enc = msgpack.NewEncoder(&stream)
+	// Message mode
+	enc.Encode([]interface{}{"nginx", 1, map[string]interface{}{"log": "message"}})
+	// Forward mode, requesting an acknowledgement
+	enc.Encode([]interface{}{"nginx", []interface{}{
+		[]interface{}{1, map[string]interface{}{"log": "forward"}},
+		[]interface{}{1, map[string]interface{}{"status": 200}},
+	}, map[string]interface{}{"chunk": "abc"}})
+	// PackedForward mode
+	enc.Encode([]interface{}{"nginx", packed.Bytes()})
+
+	conn := &fakeConn{in: &stream}
+	lines := make(chan inputLine, 10)
+	if err := (&forwardInput{key: "log"}).serve(conn, lines); err != io.EOF {
+		t.Errorf("Unexpected error %v", err)
+	}
+	close(lines)
+
+	var received []string
+	for line := range lines {
+		received = append(received, line.text)
+	}
+	expected := []string{"message", "forward", `{"status":200}`, "packed"}
+	if len(received) != len(expected) {
+		t.Fatalf("%q != %q", expected, received)
+	}
+	for i := range expected {
+		if received[i] != expected[i] {
+			t.Errorf("%q != %q", expected[i], received[i])
+		}
+	}
+
+	var ack map[string]interface{}
+	if err := msgpack.NewDecoder(&conn.out).Decode(&ack); err != nil || ack["ack"] != "abc" {
+		t.Errorf("Unexpected acknowledgement %v (%v)", ack, err)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Command-line flags to receive access logs as GELF messages
+var listenGELF = flag.String("listen-gelf", "", "Receive access logs as GELF messages on the given address, e.g. udp://0.0.0.0:12201")
+var gelfFields = flag.Bool("gelf-fields", false, "Build records out of the GELF additional fields instead of parsing short_message")
+
+// GELF chunked messages must be fully received within this time
+const gelfChunkTimeout = 5 * time.Second
+
+// Largest GELF message accepted, once decompressed or over TCP
+const maxGELFMessage = 1 << 20
+
+// Input receiving Graylog Extended Log Format messages over UDP (optionally
+// chunked and compressed) or TCP (null-byte delimited)
+type gelfInput struct {
+	network string // Either "udp" or "tcp"
+	addr    string
+	fields  bool // Use additional fields rather than short_message
+}
+
+// Partially received chunked message
+type gelfChunks struct {
+	parts    [][]byte
+	received int
+	first    time.Time
+}
+
+// Build a GELF input from a udp:// or tcp:// URL
+func newGELFInput(rawurl string, fields bool) (*gelfInput, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "udp" && u.Scheme != "tcp" {
+		return nil, fmt.Errorf("Unsupported GELF listener scheme: %s", u.Scheme)
+	}
+	return &gelfInput{network: u.Scheme, addr: u.Host, fields: fields}, nil
+}
+
+func (in *gelfInput) run(lines chan<- inputLine) error {
+	if in.network == "udp" {
+		return in.runUDP(lines)
+	}
+	return in.runTCP(lines)
+}
+
+// Receive one message (or message chunk) per datagram
+func (in *gelfInput) runUDP(lines chan<- inputLine) error {
+	conn, err := net.ListenPacket("udp", in.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	pending := make(map[string]*gelfChunks)
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		packet := append([]byte(nil), buf[:n]...)
+
+		if len(packet) > 12 && packet[0] == 0x1e && packet[1] == 0x0f {
+			if packet = reassembleGELF(pending, packet); packet == nil {
+				continue
+			}
+		}
+		msg, err := decompressGELF(packet)
+		if err != nil {
+			log.Printf("Ignoring GELF message: %s", err)
+			continue
+		}
+		in.emit(msg, lines)
+	}
+}
+
+// Receive null-byte delimited messages over TCP connections
+func (in *gelfInput) runTCP(lines chan<- inputLine) error {
+	ln, err := net.Listen("tcp", in.addr)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for {
+				msg, err := readDelimited(r, 0, maxGELFMessage)
+				if len(msg) > 1 {
+					in.emit(bytes.TrimSuffix(msg, []byte{0}), lines)
+				}
+				if err != nil {
+					if err != io.EOF {
+						log.Printf("Error reading GELF stream from %s: %s", conn.RemoteAddr(), err)
+					}
+					return
+				}
+			}
+		}()
+	}
+}
+
+// Store a chunk, returning the whole message once all its chunks arrived
+func reassembleGELF(pending map[string]*gelfChunks, packet []byte) []byte {
+	now := time.Now()
+	for id, chunks := range pending {
+		if now.Sub(chunks.first) > gelfChunkTimeout {
+			delete(pending, id)
+		}
+	}
+
+	id := string(packet[2:10])
+	seq, count := int(packet[10]), int(packet[11])
+	if count == 0 || seq >= count {
+		return nil
+	}
+	chunks, ok := pending[id]
+	if !ok {
+		chunks = &gelfChunks{parts: make([][]byte, count), first: now}
+		pending[id] = chunks
+	}
+	if len(chunks.parts) != count || chunks.parts[seq] != nil {
+		return nil
+	}
+	chunks.parts[seq] = packet[12:]
+	chunks.received++
+	if chunks.received < count {
+		return nil
+	}
+	delete(pending, id)
+	return bytes.Join(chunks.parts, nil)
+}
+
+// Decompress a GELF payload, which is either gzip, zlib or plain JSON
+func decompressGELF(payload []byte) ([]byte, error) {
+	var r io.ReadCloser
+	var err error
+	switch {
+	case len(payload) > 1 && payload[0] == 0x1f && payload[1] == 0x8b:
+		r, err = gzip.NewReader(bytes.NewReader(payload))
+	case len(payload) > 0 && payload[0] == 0x78:
+		r, err = zlib.NewReader(bytes.NewReader(payload))
+	default:
+		return payload, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	msg, err := io.ReadAll(io.LimitReader(r, maxGELFMessage+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(msg) > maxGELFMessage {
+		return nil, fmt.Errorf("Decompressed message larger than %d bytes", maxGELFMessage)
+	}
+	return msg, nil
+}
+
+// Feed a GELF message into the pipeline, either as its short_message or as a
+// record built from its additional fields
+func (in *gelfInput) emit(msg []byte, lines chan<- inputLine) {
+	line, err := gelfLine(msg, in.fields)
+	if err != nil {
+		log.Printf("Ignoring GELF message: %s", err)
+		return
+	}
+	lines <- line
+}
+
+// Turn a GELF message into an input line
+func gelfLine(msg []byte, structured bool) (inputLine, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(msg, &doc); err != nil {
+		return inputLine{}, err
+	}
+
+	if !structured {
+		short, ok := doc["short_message"].(string)
+		if !ok {
+			return inputLine{}, fmt.Errorf("Missing short_message: %s", msg)
+		}
+		return inputLine{text: short}, nil
+	}
+
+	// Additional fields are prefixed with an underscore
+	fields := make(map[string]interface{})
+	for k, v := range doc {
+		if strings.HasPrefix(k, "_") {
+			fields[k[1:]] = v
+		}
+	}
+	if _, ok := fields["time"]; !ok {
+		if ts, ok := doc["timestamp"].(float64); ok {
+			sec := int64(ts)
+			fields["time"] = time.Unix(sec, int64((ts-float64(sec))*1e9)).UTC().Format(time.RFC3339Nano)
+		}
+	}
+	record, err := recordFromFields(fields)
+	if err != nil {
+		return inputLine{}, err
+	}
+	return inputLine{text: string(msg), record: record}, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGELFLine(t *testing.T) {
+	msg := []byte(`{"version":"1.1","host":"web01","short_message":"GET /api 200","timestamp":1525881641.5,` +
+		`"_remote_addr":"127.0.0.1","_request":"GET /api/user HTTP/1.1","_status":200,"_body_bytes_sent":234}`)
+
+	line, err := gelfLine(msg, false)
+	if err != nil || line.text != "GET /api 200" || line.record != nil {
+		t.Errorf("Unexpected line %+v (%v)", line, err)
+	}
+
+	line, err = gelfLine(msg, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedLog := &logRecord{
+		IP:         "127.0.0.1",
+		Identity:   "-",
+		User:       "-",
+		Timestamp:  time.Date(2018, 5, 9, 16, 00, 41, 500000000, time.UTC),
+		Action:     "GET",
+		Section:    "/api",
+		Resource:   "/user",
+		Protocol:   "HTTP/1.1",
+		StatusCode: 200,
+		Size:       234,
+	}
+	if *line.record != *expectedLog {
+		t.Errorf("%+v != %+v", expectedLog, line.record)
+	}
+}
+
+// Test chunked messages are reassembled regardless of arrival order
+func TestReassembleGELF(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(`{"short_message":"hello"}`))
+	gz.Close()
+	payload := compressed.Bytes()
+	half := len(payload) / 2
+
+	chunk := func(seq byte, data []byte) []byte {
+		return append([]byte{0x1e, 0x0f, 1, 2, 3, 4, 5, 6, 7, 8, seq, 2}, data...)
+	}
+
+	pending := make(map[string]*gelfChunks)
+	if msg := reassembleGELF(pending, chunk(1, payload[half:])); msg != nil {
+		t.Errorf("Unexpected message before all chunks arrived")
+	}
+	msg := reassembleGELF(pending, chunk(0, payload[:half]))
+	if msg == nil {
+		t.Fatalf("Expected message once all chunks arrived")
+	}
+	doc, err := decompressGELF(msg)
+	if err != nil || string(doc) != `{"short_message":"hello"}` {
+		t.Errorf("Unexpected message %q (%v)", doc, err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("Expected no pending chunks")
+	}
+}
+
+func TestDecompressGELFLimit(t *testing.T) {
+	var payload bytes.Buffer
+	gz := gzip.NewWriter(&payload)
+	gz.Write(make([]byte, maxGELFMessage+1))
+	gz.Close()
+	if _, err := decompressGELF(payload.Bytes()); err == nil {
+		t.Errorf("Expected error for a message expanding beyond %d bytes", maxGELFMessage)
+	}
+}
+
+// Test messages over TCP are bounded, as clients may never send a null byte
+func TestReadGELFStream(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("{}\x00" + strings.Repeat("x", maxGELFMessage+1)))
+	if msg, err := readDelimited(r, 0, maxGELFMessage); err != nil || string(msg) != "{}\x00" {
+		t.Errorf("Unexpected message %q (%v)", msg, err)
+	}
+	if _, err := readDelimited(r, 0, maxGELFMessage); err == nil {
+		t.Errorf("Expected error for a message over %d bytes", maxGELFMessage)
+	}
+}
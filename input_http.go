@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+)
+
+// Command-line flags to accept access log lines pushed over HTTP
+var ingestHTTP = flag.Bool("ingest", false, "Accept access log lines on POST /ingest (requires -listen-http)")
+var ingestToken = flag.String("ingest-token", "", "Bearer token required by POST /ingest")
+var ingestMaxBody = flag.Int64("ingest-max-body", 16<<20, "Largest POST /ingest body accepted, in bytes once decompressed")
+
+// Input accepting newline-delimited batches of access log lines pushed by
+// remote hosts to the HTTP listener
+type httpIngestInput struct {
+	token   string
+	maxBody int64
+}
+
+func (in *httpIngestInput) run(lines chan<- inputLine) error {
+	if *listenHTTP == "" {
+		return fmt.Errorf("HTTP ingestion requires -listen-http")
+	}
+	httpMux.Handle("/ingest", in.handler(lines))
+	select {}
+}
+
+// Handler feeding the lines of each request body into the pipeline
+func (in *httpIngestInput) handler(lines chan<- inputLine) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if in.token != "" && r.Header.Get("Authorization") != "Bearer "+in.token {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body := http.MaxBytesReader(w, r.Body, in.maxBody)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			defer gz.Close()
+			body = http.MaxBytesReader(w, gz, in.maxBody)
+		}
+
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			// A body cut short still yields its partial last line; drop it
+			if scanner.Err() != nil {
+				break
+			}
+			if line := scanner.Text(); line != "" {
+				lines <- inputLine{text: line}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			code := http.StatusBadRequest
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				code = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPIngest(t *testing.T) {
+	lines := make(chan inputLine, 10)
+	h := (&httpIngestInput{token: "secret", maxBody: 1024}).handler(lines)
+
+	body := "first line\n\nsecond line\n"
+
+	req := httptest.NewRequest("POST", "/ingest", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected unauthorized request, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/ingest", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Unexpected status code %d", w.Code)
+	}
+	close(lines)
+
+	var received []string
+	for line := range lines {
+		received = append(received, line.text)
+	}
+	if len(received) != 2 || received[0] != "first line" || received[1] != "second line" {
+		t.Errorf("Unexpected lines %q", received)
+	}
+}
+
+func TestHTTPIngestTooLarge(t *testing.T) {
+	lines := make(chan inputLine, 10)
+	h := (&httpIngestInput{maxBody: 1024}).handler(lines)
+
+	long := strings.Repeat("a", 2048) + "\n"
+
+	req := httptest.NewRequest("POST", "/ingest", strings.NewReader(long))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected oversized body to be refused, got %d", w.Code)
+	}
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(long))
+	gz.Close()
+	if compressed.Len() >= 1024 {
+		t.Fatalf("Compressed body unexpectedly large: %d bytes", compressed.Len())
+	}
+	req = httptest.NewRequest("POST", "/ingest", &compressed)
+	req.Header.Set("Content-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected oversized decompressed body to be refused, got %d", w.Code)
+	}
+	if len(lines) != 0 {
+		t.Errorf("Unexpected lines forwarded: %d", len(lines))
+	}
+}
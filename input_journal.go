@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os/exec"
+)
+
+// Command-line flags to read access logs from the systemd journal
+var journalUnit = flag.String("journal-unit", "", "Read access logs from the systemd journal for the given unit (e.g. nginx.service)")
+var journalIdentifier = flag.String("journal-identifier", "", "Read access logs from the systemd journal for the given syslog identifier")
+
+// Input following the systemd journal through journalctl, for services
+// logging exclusively through journald
+type journalInput struct {
+	unit       string
+	identifier string
+}
+
+// Arguments passed to journalctl. Only new entries are followed, as the
+// journal usually holds far more history than is worth replaying
+func (in *journalInput) args() []string {
+	args := []string{"--follow", "--lines=0", "--output=json"}
+	if in.unit != "" {
+		args = append(args, "--unit="+in.unit)
+	}
+	if in.identifier != "" {
+		args = append(args, "--identifier="+in.identifier)
+	}
+	return args
+}
+
+func (in *journalInput) run(lines chan<- inputLine) error {
+	cmd := exec.Command("journalctl", in.args()...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("Cannot run journalctl: %s", err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		msg, err := journalMessage(scanner.Bytes())
+		if err != nil {
+			log.Printf("Ignoring journal entry: %s", err)
+			continue
+		}
+		lines <- inputLine{text: msg}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return cmd.Wait()
+}
+
+// Extract the MESSAGE field of a journal entry exported as JSON. Messages
+// which are not valid UTF-8 are exported as an array of bytes
+func journalMessage(entry []byte) (string, error) {
+	var fields struct {
+		Message json.RawMessage `json:"MESSAGE"`
+	}
+	if err := json.Unmarshal(entry, &fields); err != nil {
+		return "", err
+	}
+
+	var msg string
+	if err := json.Unmarshal(fields.Message, &msg); err == nil {
+		return msg, nil
+	}
+	var raw []byte
+	var bytes []int
+	if err := json.Unmarshal(fields.Message, &bytes); err != nil {
+		return "", fmt.Errorf("Unexpected MESSAGE field: %s", fields.Message)
+	}
+	for _, b := range bytes {
+		raw = append(raw, byte(b))
+	}
+	return string(raw), nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestJournalMessage(t *testing.T) {
+	x := map[string]string{
+		`{"_SYSTEMD_UNIT":"nginx.service","MESSAGE":"127.0.0.1 - - [09/May/2018:16:00:41 +0000] \"GET /api HTTP/1.0\" 200 1"}`: `127.0.0.1 - - [09/May/2018:16:00:41 +0000] "GET /api HTTP/1.0" 200 1`,
+		// Non UTF-8 messages are exported as byte arrays
+		`{"MESSAGE":[71,69,84]}`: "GET",
+	}
+
+	for entry, expected := range x {
+		msg, err := journalMessage([]byte(entry))
+		if err != nil {
+			t.Errorf("Error %s while parsing journal entry %s", err, entry)
+		}
+		if msg != expected {
+			t.Errorf("%q != %q", expected, msg)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Command-line flags to stream access logs from Kubernetes pods
+var k8sSelector = flag.String("k8s", "", "Stream access logs from Kubernetes pods matching this label selector (e.g. app=web)")
+var k8sNamespace = flag.String("k8s-namespace", "", "Kubernetes namespace of the pods (defaults to the one of the service account)")
+var k8sContainer = flag.String("k8s-container", "", "Container to stream logs from, for multi-container pods")
+var k8sAPI = flag.String("k8s-api", "", "Kubernetes API endpoint (e.g. http://127.0.0.1:8001 for kubectl proxy); in-cluster configuration is used when empty")
+
+// Location of the credentials mounted into pods for their service account
+const k8sServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// How often the set of matching pods is refreshed
+const k8sPollInterval = 5 * time.Second
+
+// Input streaming the logs of every running pod matching a label selector
+// through the Kubernetes API. Lines are tagged with the name of their pod
+type k8sInput struct {
+	client    *http.Client
+	baseURL   string
+	token     string
+	namespace string
+	selector  string
+	container string
+
+	mutex    sync.Mutex
+	attached map[string]bool      // Pods whose logs are currently streamed
+	since    map[string]time.Time // Time logs were last read for each pod
+}
+
+// Build a Kubernetes input, either using the in-cluster service account or
+// an explicit (typically kubectl proxy) API endpoint
+func newK8sInput(api, namespace, selector, container string) (*k8sInput, error) {
+	in := &k8sInput{
+		client:    &http.Client{},
+		baseURL:   api,
+		namespace: namespace,
+		selector:  selector,
+		container: container,
+		attached:  make(map[string]bool),
+		since:     make(map[string]time.Time),
+	}
+
+	if api == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("Not running inside a Kubernetes cluster, use -k8s-api")
+		}
+		in.baseURL = "https://" + host + ":" + port
+
+		token, err := os.ReadFile(k8sServiceAccountDir + "/token")
+		if err != nil {
+			return nil, err
+		}
+		in.token = strings.TrimSpace(string(token))
+
+		ca, err := os.ReadFile(k8sServiceAccountDir + "/ca.crt")
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(ca)
+		in.client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+
+		if in.namespace == "" {
+			ns, err := os.ReadFile(k8sServiceAccountDir + "/namespace")
+			if err != nil {
+				return nil, err
+			}
+			in.namespace = strings.TrimSpace(string(ns))
+		}
+	}
+	if in.namespace == "" {
+		in.namespace = "default"
+	}
+	return in, nil
+}
+
+func (in *k8sInput) run(lines chan<- inputLine) error {
+	for {
+		if err := in.refresh(lines); err != nil {
+			log.Printf("Cannot list Kubernetes pods: %s", err)
+		}
+		time.Sleep(k8sPollInterval)
+	}
+}
+
+// Follow the logs of pods which started running, and forget about where
+// logs were left for those which went away
+func (in *k8sInput) refresh(lines chan<- inputLine) error {
+	pods, err := in.runningPods()
+	if err != nil {
+		return err
+	}
+	running := make(map[string]bool, len(pods))
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+	for _, pod := range pods {
+		running[pod] = true
+		if !in.attached[pod] {
+			in.attached[pod] = true
+			go in.follow(pod, lines)
+		}
+	}
+	for pod := range in.since {
+		if !running[pod] && !in.attached[pod] {
+			delete(in.since, pod)
+		}
+	}
+	return nil
+}
+
+// Issue a GET request against the Kubernetes API
+func (in *k8sInput) get(path string, query url.Values) (*http.Response, error) {
+	req, err := http.NewRequest("GET", in.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if in.token != "" {
+		req.Header.Set("Authorization", "Bearer "+in.token)
+	}
+	resp, err := in.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Kubernetes API returned %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// List the names of running pods matching the label selector
+func (in *k8sInput) runningPods() ([]string, error) {
+	resp, err := in.get("/api/v1/namespaces/"+in.namespace+"/pods", url.Values{
+		"labelSelector": {in.selector},
+		"fieldSelector": {"status.phase=Running"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var podList struct {
+		Items []struct {
+			Metadata struct {
+				Name string
+			}
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&podList); err != nil {
+		return nil, err
+	}
+	pods := make([]string, 0, len(podList.Items))
+	for _, item := range podList.Items {
+		pods = append(pods, item.Metadata.Name)
+	}
+	return pods, nil
+}
+
+// Stream the logs of a pod until it goes away or its container restarts, in
+// which case logs are resumed from where they were left
+func (in *k8sInput) follow(pod string, lines chan<- inputLine) {
+	defer func() {
+		in.mutex.Lock()
+		in.since[pod] = time.Now()
+		delete(in.attached, pod)
+		in.mutex.Unlock()
+	}()
+
+	in.mutex.Lock()
+	since := in.since[pod]
+	in.mutex.Unlock()
+	if since.IsZero() {
+		since = time.Now()
+	}
+
+	query := url.Values{
+		"follow":    {"true"},
+		"sinceTime": {since.UTC().Format(time.RFC3339)},
+	}
+	if in.container != "" {
+		query.Set("container", in.container)
+	}
+	resp, err := in.get("/api/v1/namespaces/"+in.namespace+"/pods/"+pod+"/log", query)
+	if err != nil {
+		log.Printf("Cannot stream logs of pod %s: %s", pod, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines <- inputLine{text: scanner.Text(), pod: pod}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Fake Kubernetes API listing pods and serving two log lines per pod
+func fakeK8sAPI(t *testing.T, pods func() []string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		query := r.URL.Query()
+		switch {
+		case r.URL.Path == "/api/v1/namespaces/web/pods":
+			if query.Get("labelSelector") != "app=web" || query.Get("fieldSelector") != "status.phase=Running" {
+				t.Errorf("Unexpected pod list query %s", r.URL.RawQuery)
+			}
+			var list struct {
+				Items []map[string]map[string]string `json:"items"`
+			}
+			for _, pod := range pods() {
+				list.Items = append(list.Items, map[string]map[string]string{"metadata": {"name": pod}})
+			}
+			json.NewEncoder(w).Encode(list)
+		case strings.HasPrefix(r.URL.Path, "/api/v1/namespaces/web/pods/") && strings.HasSuffix(r.URL.Path, "/log"):
+			if query.Get("follow") != "true" || query.Get("container") != "nginx" || query.Get("sinceTime") == "" {
+				t.Errorf("Unexpected log query %s", r.URL.RawQuery)
+			}
+			pod := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/namespaces/web/pods/"), "/log")
+			fmt.Fprintf(w, "%s line 1\n%s line 2\n", pod, pod)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestK8sInput(t *testing.T) {
+	var mutex sync.Mutex
+	running := []string{"web-1"}
+	server := fakeK8sAPI(t, func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return running
+	})
+	defer server.Close()
+
+	in, err := newK8sInput(server.URL, "web", "app=web", "nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.token = "secret"
+
+	lines := make(chan inputLine, 10)
+	if err := in.refresh(lines); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"web-1 line 1", "web-1 line 2"} {
+		select {
+		case line := <-lines:
+			if line.text != expected || line.pod != "web-1" {
+				t.Errorf("Unexpected line %+v", line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for %q", expected)
+		}
+	}
+
+	// The stream ends, as when the container restarts, and the pod goes away
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		in.mutex.Lock()
+		detached := !in.attached["web-1"]
+		in.mutex.Unlock()
+		if detached {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Pod still attached after its logs ended")
+		}
+	}
+	if in.since["web-1"].IsZero() {
+		t.Errorf("Time logs were left not recorded")
+	}
+	mutex.Lock()
+	running = nil
+	mutex.Unlock()
+	if err := in.refresh(lines); err != nil {
+		t.Fatal(err)
+	}
+	if len(in.since) != 0 || len(in.attached) != 0 {
+		t.Errorf("Pod not forgotten: %v %v", in.since, in.attached)
+	}
+
+	in.token = "wrong"
+	if err := in.refresh(lines); err == nil {
+		t.Errorf("Expected error when the API rejects the token")
+	}
+}
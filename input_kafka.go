@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Command-line flags to consume access logs from a Kafka topic
+var kafkaBrokers = flag.String("kafka-brokers", "", "Comma-separated list of Kafka brokers to consume access logs from")
+var kafkaTopic = flag.String("kafka-topic", "access-logs", "Kafka topic carrying access log lines (or JSON records, see -format=json)")
+var kafkaGroup = flag.String("kafka-group", "http_monitor", "Kafka consumer group used to track offsets")
+
+// Consumer of Kafka messages, as implemented by kafka.Reader
+type kafkaConsumer interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Input consuming access log lines from a Kafka topic as part of a consumer
+// group, so offsets survive restarts and partitions are shared between
+// monitor instances
+type kafkaInput struct {
+	reader kafkaConsumer
+}
+
+func newKafkaInput(brokers, topic, group string) *kafkaInput {
+	return &kafkaInput{
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers: strings.Split(brokers, ","),
+			Topic:   topic,
+			GroupID: group,
+		}),
+	}
+}
+
+// Lines carried by a message, which may hold a batch of them
+func kafkaLines(value []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(string(value), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func (in *kafkaInput) run(lines chan<- inputLine) error {
+	defer in.reader.Close()
+
+	ctx := context.Background()
+	for {
+		m, err := in.reader.FetchMessage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, line := range kafkaLines(m.Value) {
+			lines <- inputLine{text: line}
+		}
+		// Only commit once the message made it into the pipeline
+		if err := in.reader.CommitMessages(ctx, m); err != nil {
+			return err
+		}
+	}
+}
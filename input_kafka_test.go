@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Consumer handing out messages until none is left
+type fakeKafkaConsumer struct {
+	messages  []kafka.Message
+	committed []int64
+	closed    bool
+}
+
+func (c *fakeKafkaConsumer) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	if len(c.messages) == 0 {
+		return kafka.Message{}, io.EOF
+	}
+	m := c.messages[0]
+	c.messages = c.messages[1:]
+	return m, nil
+}
+
+func (c *fakeKafkaConsumer) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	for _, m := range msgs {
+		c.committed = append(c.committed, m.Offset)
+	}
+	return nil
+}
+
+func (c *fakeKafkaConsumer) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestKafkaLines(t *testing.T) {
+	lines := kafkaLines([]byte("first\r\n\nsecond\n"))
+	if expected := []string{"first", "second"}; !reflect.DeepEqual(lines, expected) {
+		t.Errorf("%q != %q", expected, lines)
+	}
+	if lines := kafkaLines(nil); len(lines) != 0 {
+		t.Errorf("Unexpected lines in empty message: %q", lines)
+	}
+}
+
+func TestKafkaInput(t *testing.T) {
+	consumer := &fakeKafkaConsumer{messages: []kafka.Message{
+		{Offset: 1, Value: []byte("single")},
+		{Offset: 2, Value: []byte("batched 1\nbatched 2\n")},
+	}}
+	in := &kafkaInput{reader: consumer}
+
+	lines := make(chan inputLine, 10)
+	if err := in.run(lines); err != io.EOF {
+		t.Errorf("Expected the consumer error, got %v", err)
+	}
+	close(lines)
+	var received []string
+	for line := range lines {
+		received = append(received, line.text)
+	}
+	if expected := []string{"single", "batched 1", "batched 2"}; !reflect.DeepEqual(received, expected) {
+		t.Errorf("%q != %q", expected, received)
+	}
+	if !reflect.DeepEqual(consumer.committed, []int64{1, 2}) || !consumer.closed {
+		t.Errorf("Unexpected commits %v, closed: %v", consumer.committed, consumer.closed)
+	}
+}
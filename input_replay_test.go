@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReplayInput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	data := `127.0.0.1 - - [09/May/2018:16:00:00 +0000] "GET /api/user HTTP/1.0" 200 123
+127.0.0.1 - - [09/May/2018:16:00:01 +0000] "GET /api/user HTTP/1.0" 200 123
+garbage
+127.0.0.1 - - [09/May/2018:16:00:02 +0000] "GET /api/user HTTP/1.0" 200 123
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var waits []time.Duration
+	in := &replayInput{
+		files:  []labeledFile{{label: "web", path: path}},
+		parser: w3cParser{strict: true},
+		speed:  10,
+		sleep:  func(d time.Duration) { waits = append(waits, d) },
+	}
+	lines := make(chan inputLine, 10)
+	if err := in.run(lines); err != nil {
+		t.Fatal(err)
+	}
+	close(lines)
+
+	var parsed []bool
+	for line := range lines {
+		parsed = append(parsed, line.record != nil)
+		if line.source != "web" {
+			t.Errorf("%+v != %+v", line.source, "web")
+		}
+	}
+	if expected := []bool{true, true, false, true}; len(parsed) != len(expected) || parsed[2] || !parsed[3] {
+		t.Errorf("%+v != %+v", parsed, expected)
+	}
+
+	// Seconds apart in the log, tenths of a second apart when replayed
+	if len(waits) != 2 || waits[0] <= 50*time.Millisecond || waits[0] > 100*time.Millisecond || waits[1] <= 150*time.Millisecond || waits[1] > 200*time.Millisecond {
+		t.Errorf("Unexpected waits: %+v", waits)
+	}
+}
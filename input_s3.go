@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Command-line flags to poll access logs delivered to an S3 bucket
+var s3Location = flag.String("s3", "", "Poll access log objects under the given S3 prefix, e.g. s3://bucket/AWSLogs/")
+var s3Interval = flag.Duration("s3-interval", time.Minute, "How often to list the S3 prefix for new objects")
+var s3State = flag.String("s3-state", "", "File recording processed S3 keys, so they are not processed again after a restart")
+
+// Input periodically listing an S3 prefix (where ALB, CloudFront or S3
+// access logs land) and feeding every new object through the parser
+type s3Input struct {
+	client    *s3.Client
+	bucket    string
+	prefix    string
+	interval  time.Duration
+	statePath string
+	processed map[string]bool // Keys already fed into the pipeline
+}
+
+// Build an S3 input out of a s3://bucket/prefix URL, using the default AWS
+// credential chain
+func newS3Input(location string, interval time.Duration, statePath string) (*s3Input, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "s3" {
+		return nil, fmt.Errorf("Unsupported S3 location: %s", location)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	in := &s3Input{
+		client:    s3.NewFromConfig(cfg),
+		bucket:    u.Host,
+		prefix:    strings.TrimPrefix(u.Path, "/"),
+		interval:  interval,
+		statePath: statePath,
+		processed: make(map[string]bool),
+	}
+	if err := in.loadState(); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// Load the keys processed in previous runs
+func (in *s3Input) loadState() error {
+	if in.statePath == "" {
+		return nil
+	}
+	f, err := os.Open(in.statePath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		in.processed[scanner.Text()] = true
+	}
+	return scanner.Err()
+}
+
+// Record a key as processed
+func (in *s3Input) markProcessed(key string) error {
+	in.processed[key] = true
+	if in.statePath == "" {
+		return nil
+	}
+	f, err := os.OpenFile(in.statePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintln(f, key)
+	return err
+}
+
+func (in *s3Input) run(lines chan<- inputLine) error {
+	ctx := context.Background()
+	for {
+		keys, err := in.newKeys(ctx)
+		if err != nil {
+			log.Printf("Cannot list s3://%s/%s: %s", in.bucket, in.prefix, err)
+		}
+		for _, key := range keys {
+			if err := in.feed(ctx, key, lines); err != nil {
+				log.Printf("Cannot read s3://%s/%s: %s", in.bucket, key, err)
+				continue
+			}
+			if err := in.markProcessed(key); err != nil {
+				return err
+			}
+		}
+		time.Sleep(in.interval)
+	}
+}
+
+// List the keys not processed yet, in lexicographic order (which for AWS
+// log deliveries is also chronological order)
+func (in *s3Input) newKeys(ctx context.Context) ([]string, error) {
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(in.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(in.bucket),
+		Prefix: aws.String(in.prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			if key := aws.ToString(object.Key); !in.processed[key] {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Feed every line of an object into the pipeline. The object is read as a
+// whole first, so that failing halfway through does not feed the same lines
+// again when it is retried
+func (in *s3Input) feed(ctx context.Context, key string, lines chan<- inputLine) error {
+	object, err := in.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(in.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+	defer object.Body.Close()
+
+	objectLines, err := readObjectLines(object.Body, strings.HasSuffix(key, ".gz"))
+	if err != nil {
+		return err
+	}
+	for _, line := range objectLines {
+		lines <- inputLine{text: line}
+	}
+	return nil
+}
+
+// Read the lines of an object, decompressing it on the fly
+func readObjectLines(body io.Reader, gzipped bool) ([]string, error) {
+	r := body
+	if gzipped {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var objectLines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		objectLines = append(objectLines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return objectLines, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Test processed keys survive a restart through the state file
+func TestS3InputState(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "s3.state")
+
+	in := &s3Input{statePath: statePath, processed: make(map[string]bool)}
+	if err := in.loadState(); err != nil {
+		t.Fatalf("Unexpected error loading missing state: %s", err)
+	}
+	in.markProcessed("logs/2019/01/01/a.log.gz")
+	in.markProcessed("logs/2019/01/01/b.log.gz")
+
+	restarted := &s3Input{statePath: statePath, processed: make(map[string]bool)}
+	if err := restarted.loadState(); err != nil {
+		t.Fatal(err)
+	}
+	if len(restarted.processed) != 2 || !restarted.processed["logs/2019/01/01/b.log.gz"] {
+		t.Errorf("Unexpected processed keys %v", restarted.processed)
+	}
+
+	if _, err := os.Stat(statePath); err != nil {
+		t.Error(err)
+	}
+}
+
+// Reader failing after some data, as a dropped connection would
+type failingReader struct {
+	data string
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// Test objects only yield lines once read as a whole
+func TestReadObjectLines(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte("first\nsecond\n"))
+	gz.Close()
+	lines, err := readObjectLines(&compressed, true)
+	if err != nil || !reflect.DeepEqual(lines, []string{"first", "second"}) {
+		t.Errorf("Unexpected lines %q (%v)", lines, err)
+	}
+
+	lines, err = readObjectLines(&failingReader{data: "first\nsecond\n"}, false)
+	if err == nil || len(lines) != 0 {
+		t.Errorf("Expected no lines from a truncated object, got %q (%v)", lines, err)
+	}
+}
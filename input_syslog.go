@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Command-line flag to receive access logs via syslog instead of tailing a file
+var listenSyslog = flag.String("listen-syslog", "", "Receive access logs via syslog on the given address, e.g. udp://0.0.0.0:5140")
+
+// Input receiving access log lines embedded in syslog (RFC3164/RFC5424)
+// messages
+type syslogInput struct {
+	network string // Either "udp" or "tcp"
+	addr    string
+}
+
+// Build a syslog input from a udp:// or tcp:// URL
+func newSyslogInput(rawurl string) (*syslogInput, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "udp" && u.Scheme != "tcp" {
+		return nil, fmt.Errorf("Unsupported syslog listener scheme: %s", u.Scheme)
+	}
+	return &syslogInput{network: u.Scheme, addr: u.Host}, nil
+}
+
+func (in *syslogInput) run(lines chan<- inputLine) error {
+	if in.network == "udp" {
+		return in.runUDP(lines)
+	}
+	return in.runTCP(lines)
+}
+
+// Receive one syslog message per datagram
+func (in *syslogInput) runUDP(lines chan<- inputLine) error {
+	conn, err := net.ListenPacket("udp", in.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		in.emit(string(buf[:n]), lines)
+	}
+}
+
+// Receive syslog messages over TCP connections
+func (in *syslogInput) runTCP(lines chan<- inputLine) error {
+	ln, err := net.Listen("tcp", in.addr)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for {
+				msg, err := readSyslogFrame(r)
+				if err != nil {
+					if err != io.EOF {
+						log.Printf("Error reading syslog stream from %s: %s", conn.RemoteAddr(), err)
+					}
+					return
+				}
+				in.emit(msg, lines)
+			}
+		}()
+	}
+}
+
+// Extract the access log line from a syslog message and feed it to the pipeline
+func (in *syslogInput) emit(packet string, lines chan<- inputLine) {
+	msg, err := syslogMessage(packet)
+	if err != nil {
+		log.Printf("Ignoring syslog message: %s", err)
+		return
+	}
+	lines <- inputLine{text: msg}
+}
+
+// Largest syslog frame accepted over TCP, as datagrams are
+const maxSyslogFrame = 65536
+
+// Read a single message from a TCP syslog stream, which is framed either by
+// octet counting or by newlines (RFC6587)
+func readSyslogFrame(r *bufio.Reader) (string, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return "", err
+	}
+	if first[0] >= '0' && first[0] <= '9' {
+		length, err := readDelimited(r, ' ', 10)
+		if err != nil {
+			return "", err
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(string(length), " "))
+		if err != nil {
+			return "", err
+		}
+		if n < 0 || n > maxSyslogFrame {
+			return "", fmt.Errorf("Invalid syslog frame length: %d", n)
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", err
+		}
+		return string(buf), nil
+	}
+	frame, err := readDelimited(r, '\n', maxSyslogFrame)
+	return string(frame), err
+}
+
+// Extract the MSG part of a RFC3164 or RFC5424 syslog message
+func syslogMessage(packet string) (string, error) {
+	packet = strings.TrimRight(packet, "\r\n\x00")
+	end := strings.IndexByte(packet, '>')
+	if !strings.HasPrefix(packet, "<") || end < 2 || end > 4 {
+		return "", fmt.Errorf("Invalid syslog priority: %s", packet)
+	}
+	rest := packet[end+1:]
+	if strings.HasPrefix(rest, "1 ") {
+		return rfc5424Message(rest[2:])
+	}
+	return rfc3164Message(rest), nil
+}
+
+// Skip TIMESTAMP HOSTNAME APP-NAME PROCID MSGID and STRUCTURED-DATA
+func rfc5424Message(s string) (string, error) {
+	for i := 0; i < 5; i++ {
+		j := strings.IndexByte(s, ' ')
+		if j < 0 {
+			return "", fmt.Errorf("Truncated RFC5424 header: %s", s)
+		}
+		s = s[j+1:]
+	}
+
+	if strings.HasPrefix(s, "-") {
+		s = s[1:]
+	} else {
+		for strings.HasPrefix(s, "[") {
+			end := sdElementEnd(s)
+			if end < 0 {
+				return "", fmt.Errorf("Unterminated structured data: %s", s)
+			}
+			s = s[end+1:]
+		}
+	}
+
+	s = strings.TrimPrefix(s, " ")
+	return strings.TrimPrefix(s, "\ufeff"), nil
+}
+
+// Index of the closing bracket of the structured data element starting s,
+// honoring quoted and escaped parameter values
+func sdElementEnd(s string) int {
+	quoted := false
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ']':
+			if !quoted {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Skip the optional TIMESTAMP HOSTNAME header and the TAG
+func rfc3164Message(s string) string {
+	if len(s) > len(time.Stamp) && s[len(time.Stamp)] == ' ' {
+		if _, err := time.Parse(time.Stamp, s[:len(time.Stamp)]); err == nil {
+			s = s[len(time.Stamp)+1:]
+			if j := strings.IndexByte(s, ' '); j >= 0 {
+				s = s[j+1:]
+			}
+		}
+	}
+	if j := strings.IndexByte(s, ' '); j > 0 && s[j-1] == ':' {
+		s = s[j+1:]
+	}
+	return s
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestSyslogMessage(t *testing.T) {
+	const line = `127.0.0.1 - jill [09/May/2018:16:00:41 +0000] "GET /api/user HTTP/1.0" 200 234`
+
+	x := []string{
+		// RFC3164, as emitted by nginx
+		`<190>May  9 16:00:41 web01 nginx: ` + line,
+		// RFC3164 with PID in the tag
+		`<190>May  9 16:00:41 web01 nginx[1234]: ` + line + "\n",
+		// RFC5424 without structured data
+		`<165>1 2018-05-09T16:00:41.003Z web01 nginx 1234 - - ` + line,
+		// RFC5424 with structured data containing an escaped bracket
+		`<165>1 2018-05-09T16:00:41.003Z web01 nginx 1234 ID47 [meta a="x\]y"][other b="2"] ` + line,
+	}
+
+	for _, packet := range x {
+		msg, err := syslogMessage(packet)
+		if err != nil {
+			t.Errorf("Error %s while parsing syslog message %s", err, packet)
+		}
+		if msg != line {
+			t.Errorf("%q != %q", line, msg)
+		}
+	}
+
+	if _, err := syslogMessage("no priority"); err == nil {
+		t.Errorf("Expected error for message without priority")
+	}
+}
+
+// Test both octet-counted and newline-delimited TCP framing
+func TestReadSyslogFrame(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("11 <13>hello w<13>second\n"))
+
+	frame, err := readSyslogFrame(r)
+	if err != nil || frame != "<13>hello w" {
+		t.Errorf("Unexpected octet-counted frame %q (%v)", frame, err)
+	}
+	frame, err = readSyslogFrame(r)
+	if err != nil || frame != "<13>second\n" {
+		t.Errorf("Unexpected newline-delimited frame %q (%v)", frame, err)
+	}
+}
+
+func TestReadSyslogFrameLength(t *testing.T) {
+	for _, frame := range []string{"999999999999999999 x", "65537 x"} {
+		if _, err := readSyslogFrame(bufio.NewReader(strings.NewReader(frame))); err == nil {
+			t.Errorf("Expected error for frame %q", frame)
+		}
+	}
+}
+
+func TestReadSyslogFrameUnterminated(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("<13>" + strings.Repeat("x", maxSyslogFrame) + "\n"))
+	if _, err := readSyslogFrame(r); err == nil {
+		t.Errorf("Expected error for a newline-delimited frame over %d bytes", maxSyslogFrame)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net"
+	"strings"
+)
+
+// Canonical form of a client address, so that every spelling of an IP is
+// counted as one: brackets and ports some servers log around IPv6
+// addresses (e.g. [2001:db8::1]:443) are dropped, IPv6 addresses are
+// compressed and IPv4-mapped ones turned into IPv4. Anything else, such as
+// hostnames, is kept as is
+func canonicalIP(ip string) string {
+	host := ip
+	if strings.HasPrefix(host, "[") {
+		if i := strings.IndexByte(host, ']'); i > 0 {
+			host = host[1:i]
+		}
+	} else if strings.Count(host, ":") == 1 {
+		// IPv4 address with a port
+		host = host[:strings.IndexByte(host, ':')]
+	}
+	if addr := net.ParseIP(host); addr != nil {
+		return addr.String()
+	}
+	return ip
+}
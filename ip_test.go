@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCanonicalIP(t *testing.T) {
+	tests := map[string]string{
+		"127.0.0.1":            "127.0.0.1",
+		"127.0.0.1:8080":       "127.0.0.1",
+		"2001:db8::1":          "2001:db8::1",
+		"2001:DB8:0:0:0:0:0:1": "2001:db8::1",
+		"[2001:db8::1]":        "2001:db8::1",
+		"[2001:db8::1]:443":    "2001:db8::1",
+		"::ffff:192.0.2.1":     "192.0.2.1",
+		"::1":                  "::1",
+		"www.example.com":      "www.example.com",
+		"-":                    "-",
+	}
+	for ip, expected := range tests {
+		if canonical := canonicalIP(ip); canonical != expected {
+			t.Errorf("%s: %+v != %+v", ip, expected, canonical)
+		}
+	}
+}
+
+func TestIPv6LogLine(t *testing.T) {
+	lines := map[string]string{
+		`2001:db8::1 - jill [09/May/2018:16:00:41 +0000] "GET /api/user HTTP/1.0" 200 234`:   "2001:db8::1",
+		`[2001:db8::2] - jill [09/May/2018:16:00:41 +0000] "GET /api/user HTTP/1.0" 200 234`: "2001:db8::2",
+	}
+	m := &monitor{parser: w3cParser{}}
+	for line, expected := range lines {
+		r, err := m.record(inputLine{text: line})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.IP != expected {
+			t.Errorf("%+v != %+v", expected, r.IP)
+		}
+	}
+}
+
+func TestIPv6Groups(t *testing.T) {
+	var groups cidrGroups
+	if err := groups.Set("office=10.1.0.0/16,2001:db8:1::/48"); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"2001:db8:1::42":  "office",
+		"2001:db8:2::42":  "",
+		"::ffff:10.1.2.3": "office",
+		"10.1.2.3":        "office",
+	}
+	for ip, expected := range tests {
+		if group := groups.match(ip); group != expected {
+			t.Errorf("%s: %+v != %+v", ip, expected, group)
+		}
+	}
+}
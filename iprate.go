@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Command-line flags to alert on individual clients sending too much traffic
+var ipQPS = flag.Float64("ip-qps", 0, "Alert on clients exceeding this average QPS in the alerting window (0 disables)")
+var ipRequests = flag.Int("ip-requests", 0, "Alert on clients sending more than this many requests in the alerting window (0 disables)")
+
+// Alert firing when single clients exceed a query rate or a number of
+// requests in the window, regardless of overall traffic
+type clientTrafficRule struct {
+	qps      float64
+	requests int
+}
+
+func (r *clientTrafficRule) name() string {
+	return "Client-traffic"
+}
+
+func (r *clientTrafficRule) evaluate(s *stats) (bool, string) {
+	counts := r.counts(s)
+	offenders := r.offenders(s)
+
+	var details []string
+	for _, ip := range offenders {
+		requests := math.Round(float64(counts[ip]) * s.weight())
+		details = append(details, fmt.Sprintf("%s (%.0f requests, %f queries per second on average)", ip, requests, s.windowRate(counts[ip])))
+	}
+	return len(offenders) > 0, fmt.Sprintf("from %s", strings.Join(details, ", "))
+}
+
+// Number of requests sent by each client in the window
+func (r *clientTrafficRule) counts(s *stats) map[string]int {
+	counts := make(map[string]int)
+	for _, record := range s.logsInWindow {
+		counts[record.IP]++
+	}
+	return counts
+}
+
+// IPs of the clients over either limit, sorted
+func (r *clientTrafficRule) offenders(s *stats) []string {
+	var offenders []string
+	for ip, count := range r.counts(s) {
+		requests := float64(count) * s.weight()
+		if (r.qps > 0 && s.windowRate(count) > r.qps) || (r.requests > 0 && requests > float64(r.requests)) {
+			offenders = append(offenders, ip)
+		}
+	}
+	sort.Strings(offenders)
+	return offenders
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientTrafficRule(t *testing.T) {
+	s := newStats()
+	ts := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+
+	// Over 10 seconds, one client sends 21 requests and another one 3
+	for i := 0; i <= 20; i++ {
+		at := ts.Add(time.Duration(i) * 500 * time.Millisecond)
+		s.updateAlerting(&logRecord{IP: "10.0.0.1", Timestamp: at, StatusCode: 200, Section: "/"})
+		if i%10 == 0 {
+			s.updateAlerting(&logRecord{IP: "10.0.0.2", Timestamp: at, StatusCode: 200, Section: "/"})
+		}
+	}
+
+	type testData struct {
+		rule     *clientTrafficRule
+		firing   bool
+		expected string
+	}
+
+	x := []testData{
+		{&clientTrafficRule{qps: 1}, true, "from 10.0.0.1 (21 requests, 2.100000 queries per second on average)"},
+		{&clientTrafficRule{qps: 5}, false, "from "},
+		{&clientTrafficRule{requests: 2}, true, "from 10.0.0.1 (21 requests, 2.100000 queries per second on average), 10.0.0.2 (3 requests, 0.300000 queries per second on average)"},
+		{&clientTrafficRule{requests: 50}, false, "from "},
+	}
+	for _, elem := range x {
+		firing, detail := elem.rule.evaluate(s)
+		if firing != elem.firing || detail != elem.expected {
+			t.Errorf("%+v: %v %q != %v %q", *elem.rule, elem.firing, elem.expected, firing, detail)
+		}
+	}
+}
+
+// Test a single request does not make for an infinite rate
+func TestClientTrafficRuleSingleRequest(t *testing.T) {
+	s := newStats()
+	s.updateAlerting(&logRecord{IP: "10.0.0.9", Timestamp: time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC), StatusCode: 200, Section: "/"})
+
+	if offenders := (&clientTrafficRule{qps: 5}).offenders(s); len(offenders) != 0 {
+		t.Errorf("Unexpected offenders %v", offenders)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Requests and bytes served for a path
+type pathTraffic struct {
+	requests int
+	bytes    int
+}
+
+// Average response size
+func (t *pathTraffic) average() int {
+	if t.requests == 0 {
+		return 0
+	}
+	return t.bytes / t.requests
+}
+
+// Path of a resource, without its query string
+func resourcePath(resource string) string {
+	if i := strings.IndexByte(resource, '?'); i >= 0 {
+		return resource[:i]
+	}
+	return resource
+}
+
+// Path along with its traffic
+type pathBytes struct {
+	path string
+	pathTraffic
+}
+
+// The N paths with the most bytes served in the current interval, ranked
+// by total bytes or by average response size
+func (s *stats) largestPaths(n int, byAverage bool) []pathBytes {
+	var largest []pathBytes
+	for path, traffic := range s.pathTraffic {
+		if traffic.bytes > 0 {
+			largest = append(largest, pathBytes{path: path, pathTraffic: *traffic})
+		}
+	}
+	key := func(i int) int {
+		if byAverage {
+			return largest[i].average()
+		}
+		return largest[i].bytes
+	}
+	sort.Slice(largest, func(i, j int) bool {
+		if key(i) == key(j) {
+			return largest[i].path < largest[j].path
+		}
+		return key(i) > key(j)
+	})
+	if len(largest) > n {
+		largest = largest[:n]
+	}
+	return largest
+}
+
+// Dumps the N paths serving the most bytes in the current interval, in
+// total and on average
+func (s *stats) dumpLargestPaths(w *tabwriter.Writer, n int) {
+	total := s.largestPaths(n, false)
+	if len(total) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Top %d paths by bytes served:\n", n)
+	for _, v := range total {
+		fmt.Fprintf(w, "%s\t %s (%d requests)\n", formatSize(int(float64(v.bytes)*s.weight()+0.5)), v.path, int(float64(v.requests)*s.weight()+0.5))
+	}
+	fmt.Fprintf(w, "Top %d paths by average response size:\n", n)
+	for _, v := range s.largestPaths(n, true) {
+		fmt.Fprintf(w, "%s\t %s\n", formatSize(v.average()), v.path)
+	}
+}
+
+// Forget bytes served per path once an interval is over
+func (s *stats) resetPathTraffic() {
+	s.pathTraffic = make(map[string]*pathTraffic)
+	for _, source := range s.sources {
+		source.resetPathTraffic()
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLargestPaths(t *testing.T) {
+	s := newStats()
+	records := []struct {
+		resource string
+		size     int
+	}{
+		{"/video/intro.mp4", 50000000},
+		{"/static/app.js?v=1", 900000},
+		{"/static/app.js?v=2", 900000},
+		{"/static/app.js", 900000},
+		{"/api/users", 2000},
+		{"/api/users", 3000},
+		{"/health", 0},
+	}
+	for _, r := range records {
+		s.updateStats(&logRecord{Section: "/", Resource: r.resource, StatusCode: 200, Size: r.size})
+	}
+
+	tests := []struct {
+		byAverage bool
+		expected  []string
+	}{
+		{false, []string{"/video/intro.mp4", "/static/app.js", "/api/users"}},
+		{true, []string{"/video/intro.mp4", "/static/app.js", "/api/users"}},
+	}
+	for _, test := range tests {
+		var paths []string
+		for _, v := range s.largestPaths(3, test.byAverage) {
+			paths = append(paths, v.path)
+		}
+		if !reflect.DeepEqual(paths, test.expected) {
+			t.Errorf("%+v != %+v", paths, test.expected)
+		}
+	}
+
+	largest := s.largestPaths(3, false)
+	if largest[1].requests != 3 || largest[1].bytes != 2700000 || largest[2].average() != 2500 {
+		t.Errorf("Unexpected traffic: %+v", largest)
+	}
+
+	var out bytes.Buffer
+	s.writeStats(&out)
+	for _, expected := range []string{"Top 5 paths by bytes served:", "2.6MB /static/app.js (3 requests)", "Top 5 paths by average response size:", "2.4KB /api/users"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, out.String())
+		}
+	}
+
+	s.resetPathTraffic()
+	if largest := s.largestPaths(3, false); len(largest) != 0 {
+		t.Errorf("Expected no paths after a reset, got %+v", largest)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Latency below which the given fraction of the samples fall
+func percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// Section along with its 95th percentile latency
+type sectionLatency struct {
+	section string
+	p95     time.Duration
+}
+
+// The N sections with the highest 95th percentile latency in the current
+// interval, slowest first
+func (s *stats) slowestSections(n int) []sectionLatency {
+	var slowest []sectionLatency
+	for section, latencies := range s.sectionLatencies {
+		slowest = append(slowest, sectionLatency{section: section, p95: percentile(latencies, 0.95)})
+	}
+	sort.Slice(slowest, func(i, j int) bool {
+		if slowest[i].p95 == slowest[j].p95 {
+			return slowest[i].section < slowest[j].section
+		}
+		return slowest[i].p95 > slowest[j].p95
+	})
+	if len(slowest) > n {
+		slowest = slowest[:n]
+	}
+	return slowest
+}
+
+// Dumps the N slowest sections in the current interval
+func (s *stats) dumpSlowestSections(w *tabwriter.Writer, n int) {
+	fmt.Fprintf(w, "Top %d slowest sections (p95):\n", n)
+	for _, v := range s.slowestSections(n) {
+		fmt.Fprintf(w, "%s\t %s\n", v.p95, v.section)
+	}
+}
+
+// Forget latencies once an interval is over
+func (s *stats) resetLatencies() {
+	s.sectionLatencies = make(map[string][]time.Duration)
+	for _, source := range s.sources {
+		source.resetLatencies()
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	var samples []time.Duration
+	for i := 100; i >= 1; i-- {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+	tests := map[float64]time.Duration{
+		0.5:  50 * time.Millisecond,
+		0.95: 95 * time.Millisecond,
+		1:    100 * time.Millisecond,
+		0:    1 * time.Millisecond,
+	}
+	for p, expected := range tests {
+		if latency := percentile(samples, p); latency != expected {
+			t.Errorf("%+v != %+v", expected, latency)
+		}
+	}
+	if latency := percentile(nil, 0.95); latency != 0 {
+		t.Errorf("%+v != %+v", 0, latency)
+	}
+}
+
+func TestSlowestSections(t *testing.T) {
+	s := newStats()
+	for i := 1; i <= 20; i++ {
+		s.updateStats(&logRecord{Section: "/api", StatusCode: 200, Latency: time.Duration(i) * 10 * time.Millisecond})
+		s.updateStats(&logRecord{Section: "/static", StatusCode: 200, Latency: time.Millisecond})
+		s.updateStats(&logRecord{Section: "/search", StatusCode: 200, Latency: time.Second})
+	}
+	s.updateStats(&logRecord{Section: "/health", StatusCode: 200})
+
+	slowest := s.slowestSections(2)
+	expected := []sectionLatency{{"/search", time.Second}, {"/api", 190 * time.Millisecond}}
+	if len(slowest) != len(expected) {
+		t.Fatalf("%+v != %+v", expected, slowest)
+	}
+	for i := range expected {
+		if slowest[i] != expected[i] {
+			t.Errorf("%+v != %+v", expected[i], slowest[i])
+		}
+	}
+
+	s.resetLatencies()
+	if slowest := s.slowestSections(2); len(slowest) != 0 {
+		t.Errorf("Latencies not reset: %+v", slowest)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+)
+
+// Command-line flags to enable the HTTP listener
+var listenHTTP = flag.String("listen-http", "", "Address for the HTTP listener, e.g. :8080")
+var listenHTTPCert = flag.String("listen-http-cert", "", "TLS certificate file, to serve HTTPS on the HTTP listener")
+var listenHTTPKey = flag.String("listen-http-key", "", "TLS private key file, to serve HTTPS on the HTTP listener")
+
+// Handlers served by the HTTP listener
+var httpMux = http.NewServeMux()
+
+// Start serving the HTTP listener in the background, if enabled
+func startHTTPListener() {
+	if *listenHTTP == "" {
+		return
+	}
+	go func() {
+		if *listenHTTPCert != "" {
+			log.Panic(http.ListenAndServeTLS(*listenHTTP, *listenHTTPCert, *listenHTTPKey, httpMux))
+		}
+		log.Panic(http.ListenAndServe(*listenHTTP, httpMux))
+	}()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+// Command-line flags to alert when traffic drops, or stops altogether
+var lowTrafficPeriod = flag.Duration("low-traffic-period", 0, "Alert when traffic stays below -min-qps for this long, e.g. 5m (0 disables)")
+var minQPS = flag.Float64("min-qps", 0, "Average QPS below which traffic is deemed too low (0 only alerts when no requests arrive at all)")
+
+// Number of requests seen at some point in time
+type trafficSample struct {
+	time     time.Time
+	requests float64
+}
+
+// Alert firing when the query rate over a period falls below a threshold,
+// or to zero. Time is taken from the clock alerts are checked against, so
+// that the alert fires even when no lines arrive
+type lowTrafficRule struct {
+	threshold float64
+	period    time.Duration
+	now       func() time.Time
+	samples   []trafficSample // Taken every check, spanning the period
+	qps       float64         // As of the last check
+}
+
+func (r *lowTrafficRule) name() string {
+	return "Low-traffic"
+}
+
+func (r *lowTrafficRule) setClock(now func() time.Time) {
+	r.now = now
+}
+
+func (r *lowTrafficRule) evaluate(s *stats) (bool, string) {
+	requests := float64(s.totalRequests()) * s.weight()
+	now := r.now()
+	r.samples = append(r.samples, trafficSample{time: now, requests: requests})
+
+	// Drop samples until the oldest one is the latest taken a period ago
+	for len(r.samples) > 1 && now.Sub(r.samples[1].time) >= r.period {
+		r.samples = r.samples[1:]
+	}
+	oldest := r.samples[0]
+	elapsed := now.Sub(oldest.time)
+	if elapsed < r.period {
+		// Not watching for long enough yet
+		return false, ""
+	}
+
+	received := requests - oldest.requests
+	qps := received / elapsed.Seconds()
+	r.qps = qps
+	return received == 0 || qps < r.threshold, fmt.Sprintf("at %f queries per second on average over the last %s", qps, elapsed)
+}
+
+func (r *lowTrafficRule) measure(s *stats) (float64, float64) {
+	return r.qps, r.threshold
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLowTrafficRule(t *testing.T) {
+	now := time.Date(2018, 5, 9, 16, 0, 0, 0, time.UTC)
+	rule := &lowTrafficRule{threshold: 1, period: time.Minute, now: func() time.Time { return now }}
+	s := newStats()
+
+	// Requests received since the previous check, and whether the alert
+	// fires after checking 10 seconds later
+	steps := []struct {
+		requests int
+		firing   bool
+	}{
+		{0, false},
+		{100, false},
+		{100, false},
+		{100, false},
+		{100, false},
+		{100, false},
+		{100, false},
+		{10, false},
+		{0, false},
+		{0, false},
+		{0, false},
+		{0, false},
+		{0, true},
+		{0, true},
+		{200, false},
+	}
+	for i, step := range steps {
+		s.httpResponseCodes["2XX"] += step.requests
+		if firing, _ := rule.evaluate(s); firing != step.firing {
+			t.Errorf("Check %d: %+v != %+v", i, firing, step.firing)
+		}
+		now = now.Add(10 * time.Second)
+	}
+}
+
+func TestLowTrafficRuleZero(t *testing.T) {
+	now := time.Date(2018, 5, 9, 16, 0, 0, 0, time.UTC)
+	rule := &lowTrafficRule{period: time.Minute, now: func() time.Time { return now }}
+	s := newStats()
+	s.httpResponseCodes["2XX"] = 1
+
+	rule.evaluate(s)
+	now = now.Add(time.Minute)
+	s.httpResponseCodes["2XX"]++
+	if firing, _ := rule.evaluate(s); firing {
+		t.Errorf("Alert firing with traffic and no threshold")
+	}
+	now = now.Add(time.Minute)
+	if firing, _ := rule.evaluate(s); !firing {
+		t.Errorf("Alert not firing without traffic")
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Everything records go through once read: parsing, enrichment, stats,
+// alerting and outputs
+type monitor struct {
+	stats  *stats
+	mutex  *sync.Mutex // Guards stats and alerts
+	alerts *alertTracker
+	parser logParser
+
+	geo     *geoIP            // Looks up client locations, if enabled
+	shipper *aggregateShipper // Ships aggregates to the aggregator, if enabled
+	grpcAPI *grpcServer       // Streams records, snapshots and alerts, if enabled
+
+	notifiers   []*notifierQueue // Guarded by mutex
+	recordSinks []recordSink     // Guarded by mutex
+
+	sinksMutex sync.Mutex // Held while writing to sinks, so they can be replaced
+	sinks      []sink
+	sinkErrors map[string]error // Outcome of the last write to each sink
+
+	loop heartbeat // Last progress of the processing loop, for /healthz
+}
+
+// Open the configured sinks and notifiers, replacing (and closing) the
+// current ones, and subscribe outputs to alert changes. Notifiers are left
+// out when analyzing, not to page anyone about past alerts
+func (m *monitor) configureOutputs() error {
+	sinks, err := configuredSinks()
+	if err != nil {
+		return err
+	}
+	var notifiers []*notifierQueue
+	if !*analyzeMode {
+		if notifiers, err = configuredNotifiers(); err != nil {
+			closeSinks(sinks)
+			return err
+		}
+	}
+
+	m.mutex.Lock()
+	previousNotifiers := m.notifiers
+	inheritNotified(notifiers, previousNotifiers)
+	m.notifiers = notifiers
+	m.recordSinks = nil
+	m.alerts.subscribers = nil
+	if m.grpcAPI != nil {
+		m.alerts.subscribers = append(m.alerts.subscribers, m.grpcAPI.alertChanged)
+	}
+	for _, sink := range sinks {
+		if sink, ok := sink.(alertSink); ok {
+			m.alerts.subscribers = append(m.alerts.subscribers, sink.alertChanged)
+		}
+		if sink, ok := sink.(recordSink); ok {
+			m.recordSinks = append(m.recordSinks, sink)
+		}
+	}
+	for _, n := range notifiers {
+		m.alerts.subscribers = append(m.alerts.subscribers, n.alertChanged)
+	}
+	m.mutex.Unlock()
+	closeNotifiers(previousNotifiers)
+
+	m.sinksMutex.Lock()
+	previous := m.sinks
+	m.sinks = sinks
+	m.sinkErrors = nil
+	m.sinksMutex.Unlock()
+	closeSinks(previous)
+	return nil
+}
+
+// Re-read the configuration file, applying thresholds, filters and outputs.
+// Stats, including the current window, and inputs are left untouched
+func (m *monitor) reload() {
+	if *configFile == "" {
+		log.Printf("No configuration file to reload")
+		return
+	}
+
+	m.mutex.Lock()
+	depth := *sectionDepth
+	err := loadConfig()
+	if err == nil {
+		if err = checkSampleRate(*sampleRate); err == nil {
+			err = checkSectionDepth(*sectionDepth)
+		}
+		if err != nil {
+			*sampleRate = m.stats.sampleRate
+			*sectionDepth = depth
+		}
+	}
+	if err == nil {
+		m.alerts.rules = configuredAlertRules()
+		m.stats.sampleRate = *sampleRate
+	}
+	m.mutex.Unlock()
+	if err != nil {
+		log.Printf("Cannot reload %s: %s", *configFile, err)
+		return
+	}
+
+	if *botList != "" {
+		if extraBotPatterns, err = loadBotList(*botList); err != nil {
+			log.Printf("Cannot reload %s: %s", *botList, err)
+		}
+	} else {
+		extraBotPatterns = nil
+	}
+	if err := m.configureOutputs(); err != nil {
+		log.Printf("Cannot reconfigure sinks: %s", err)
+	}
+	log.Printf("Reloaded %s", *configFile)
+}
+
+// Parse, enrich and account for an input line, counting lines which cannot
+// be parsed
+func (m *monitor) process(line inputLine) error {
+	parsedLog, err := m.record(line)
+	if err != nil {
+		m.mutex.Lock()
+		m.stats.rejected++
+		m.mutex.Unlock()
+		return err
+	}
+	if parsedLog != nil {
+		m.account(parsedLog)
+	}
+	return nil
+}
+
+// Parse and enrich an input line. No record is returned for lines sampled
+// or filtered out, as well as for directives
+func (m *monitor) record(line inputLine) (*logRecord, error) {
+	// Directives such as IIS' #Fields are never sampled out
+	if !sampled() && !strings.HasPrefix(line.text, "#") {
+		return nil, nil
+	}
+	parsedLog := line.record
+	if parsedLog == nil {
+		var err error
+		if parsedLog, err = m.parser.parse(line.text); err != nil {
+			return nil, fmt.Errorf("Cannot parse log line: %s", line.text)
+		}
+	}
+	if parsedLog == nil || filtered(parsedLog) {
+		return nil, nil
+	}
+	parsedLog.IP = canonicalIP(parsedLog.IP)
+	parsedLog.Protocol = canonicalProtocol(parsedLog.Protocol)
+	parsedLog.Pod = line.pod
+	parsedLog.Source = line.source
+	if m.geo != nil {
+		parsedLog.Country, parsedLog.City = m.geo.lookup(parsedLog.IP)
+	}
+	parsedLog.Group = clientGroups.match(parsedLog.IP)
+	if parsedLog.UserAgent != "" {
+		parsedLog.Bot = isBot(parsedLog.UserAgent)
+	}
+	parsedLog.Attack = detectAttack(parsedLog.Section + parsedLog.Resource)
+	parsedLog.Spam = isSpamReferrer(referrerHost(parsedLog.Referrer))
+	return parsedLog, nil
+}
+
+// Account for a record in stats and outputs
+func (m *monitor) account(parsedLog *logRecord) {
+	parsedLog.Received = time.Now()
+	m.mutex.Lock()
+	m.stats.updateStats(parsedLog)
+	for _, sink := range m.recordSinks {
+		sink.record(parsedLog)
+	}
+	weight := m.stats.weight()
+	m.mutex.Unlock()
+	if m.shipper != nil {
+		m.shipper.add(parsedLog, weight)
+	}
+	if m.grpcAPI != nil {
+		m.grpcAPI.records.publish(recordMessage(parsedLog))
+	}
+}
+
+// Dump stats right away, without waiting for the next report
+func (m *monitor) dump() {
+	m.mutex.Lock()
+	m.stats.dumpStats()
+	m.stats.dumpSources()
+	m.mutex.Unlock()
+}
+
+// Dump stats, signal changes in alerting and close the current interval
+func (m *monitor) report() {
+	m.mutex.Lock()
+
+	if verbosity() > verbosityQuiet {
+		m.stats.dumpStats()
+		m.stats.dumpSources()
+	}
+
+	// Display changes in alerting, aging out entries that are no longer
+	// recent when the window follows the wall clock
+	if *windowClock == "wall" {
+		m.stats.expireWindow(time.Now())
+	}
+	m.alerts.check(m.stats)
+	for _, q := range m.notifiers {
+		q.releaseSilenced(time.Now())
+	}
+
+	closed := m.stats.history.rotate(time.Now())
+	m.stats.resetLatencies()
+	m.stats.resetPathTraffic()
+	m.stats.endSessionInterval()
+	if verbosity() >= verbosityVerbose {
+		fmt.Println(intervalSummary(closed, m.stats.weight()))
+	}
+
+	if m.grpcAPI != nil {
+		m.grpcAPI.snapshots.publish(m.stats.snapshotMessage(*topN, m.alerts.firingNames()))
+	}
+	if *statsMode == "interval" {
+		m.stats.resetCounters()
+	}
+	written := closed.scaled(m.stats.weight())
+
+	m.mutex.Unlock()
+
+	// Closed intervals are never modified, so sinks can be slow without
+	// holding back the pipeline
+	m.sinksMutex.Lock()
+	m.sinkErrors = writeSinks(m.sinks, written)
+	m.sinksMutex.Unlock()
+}
+
+// Dump a final report, then flush and close outputs
+func (m *monitor) shutdown() {
+	m.report()
+	if checkpoints != nil {
+		if err := checkpoints.save(); err != nil {
+			log.Printf("Cannot save checkpoint %s: %s", checkpoints.path, err)
+		}
+	}
+	m.sinksMutex.Lock()
+	closeSinks(m.sinks)
+	m.sinksMutex.Unlock()
+	m.mutex.Lock()
+	notifiers := m.notifiers
+	m.notifiers = nil
+	m.mutex.Unlock()
+	closeNotifiers(notifiers)
+}
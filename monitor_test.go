@@ -0,0 +1,95 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Sink recording what it was given
+type recordingSink struct {
+	intervals []*interval
+	closed    bool
+}
+
+func (s *recordingSink) name() string { return "recording" }
+
+func (s *recordingSink) write(i *interval) error {
+	s.intervals = append(s.intervals, i)
+	return nil
+}
+
+func (s *recordingSink) close() error {
+	s.closed = true
+	return nil
+}
+
+func TestMonitorShutdown(t *testing.T) {
+	s := newStats()
+	s.history = newHistory(time.Hour, time.Now())
+	recorder := &recordingSink{}
+	m := &monitor{
+		stats:  s,
+		mutex:  &sync.Mutex{},
+		alerts: newAlertTracker(nil),
+		parser: w3cParser{},
+		sinks:  []sink{recorder},
+	}
+
+	lines := []string{
+		`127.0.0.1 - jill [09/May/2018:16:00:41 +0000] "GET /api/user HTTP/1.0" 200 234`,
+		`127.0.0.1 - james [09/May/2018:16:00:39 +0000] "GET /report HTTP/1.0" 503 12`,
+	}
+	for _, line := range lines {
+		if err := m.process(inputLine{text: line}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m.shutdown()
+	if !recorder.closed {
+		t.Errorf("Sink was not closed")
+	}
+	if len(recorder.intervals) != 1 || recorder.intervals[0].Requests != 2 {
+		t.Errorf("Final interval with 2 requests not written: %+v", recorder.intervals)
+	}
+}
+
+func TestProcessCountsRejectedLines(t *testing.T) {
+	m := &monitor{
+		stats:  newStats(),
+		mutex:  &sync.Mutex{},
+		alerts: newAlertTracker(nil),
+		parser: w3cParser{strict: true},
+	}
+
+	if err := m.process(inputLine{text: "garbage line"}); err == nil {
+		t.Errorf("Malformed line accepted")
+	}
+	if err := m.process(inputLine{text: `127.0.0.1 - jill [09/May/2018:16:00:41 +0000] "GET /api/user HTTP/1.0" 200 234`}); err != nil {
+		t.Fatal(err)
+	}
+	if m.stats.rejected != 1 || m.stats.httpResponseCodes["2XX"] != 1 {
+		t.Errorf("Expected 1 rejected and 1 accepted line, got %d rejected and %v", m.stats.rejected, m.stats.httpResponseCodes)
+	}
+}
+
+func TestMonitorWritesScaledIntervals(t *testing.T) {
+	s := newStats()
+	s.history = newHistory(time.Hour, time.Now())
+	s.sampleRate = 0.5
+	recorder := &recordingSink{}
+	m := &monitor{
+		stats:  s,
+		mutex:  &sync.Mutex{},
+		alerts: newAlertTracker(nil),
+		parser: w3cParser{},
+		sinks:  []sink{recorder},
+	}
+	m.account(&logRecord{IP: "127.0.0.1", Timestamp: time.Now(), StatusCode: 200, Section: "/api", Size: 100})
+
+	m.report()
+	if len(recorder.intervals) != 1 || recorder.intervals[0].Requests != 2 || recorder.intervals[0].Bytes != 200 {
+		t.Errorf("Interval not scaled up by the sample rate: %+v", recorder.intervals)
+	}
+}
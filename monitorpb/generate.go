@@ -0,0 +1,4 @@
+// Package monitorpb holds the Go bindings of http_monitor's streaming API
+package monitorpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative monitor.proto
@@ -0,0 +1,589 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: monitor.proto
+
+// Streaming API of http_monitor, served on -listen-grpc
+
+package monitorpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StreamRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamRequest) Reset() {
+	*x = StreamRequest{}
+	mi := &file_monitor_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamRequest) ProtoMessage() {}
+
+func (x *StreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamRequest.ProtoReflect.Descriptor instead.
+func (*StreamRequest) Descriptor() ([]byte, []int) {
+	return file_monitor_proto_rawDescGZIP(), []int{0}
+}
+
+// Access log record, along with the dimensions attached to it
+type Record struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ip            string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	User          string                 `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Method        string                 `protobuf:"bytes,4,opt,name=method,proto3" json:"method,omitempty"`
+	Section       string                 `protobuf:"bytes,5,opt,name=section,proto3" json:"section,omitempty"`
+	Resource      string                 `protobuf:"bytes,6,opt,name=resource,proto3" json:"resource,omitempty"`
+	Protocol      string                 `protobuf:"bytes,7,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	StatusCode    int32                  `protobuf:"varint,8,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
+	Size          int64                  `protobuf:"varint,9,opt,name=size,proto3" json:"size,omitempty"`
+	Latency       *durationpb.Duration   `protobuf:"bytes,10,opt,name=latency,proto3" json:"latency,omitempty"`
+	Referrer      string                 `protobuf:"bytes,11,opt,name=referrer,proto3" json:"referrer,omitempty"`
+	UserAgent     string                 `protobuf:"bytes,12,opt,name=user_agent,json=userAgent,proto3" json:"user_agent,omitempty"`
+	Bot           bool                   `protobuf:"varint,13,opt,name=bot,proto3" json:"bot,omitempty"`
+	Pod           string                 `protobuf:"bytes,14,opt,name=pod,proto3" json:"pod,omitempty"`
+	Country       string                 `protobuf:"bytes,15,opt,name=country,proto3" json:"country,omitempty"`
+	City          string                 `protobuf:"bytes,16,opt,name=city,proto3" json:"city,omitempty"`
+	Group         string                 `protobuf:"bytes,17,opt,name=group,proto3" json:"group,omitempty"`
+	Vhost         string                 `protobuf:"bytes,18,opt,name=vhost,proto3" json:"vhost,omitempty"`
+	Source        string                 `protobuf:"bytes,19,opt,name=source,proto3" json:"source,omitempty"`
+	Attack        string                 `protobuf:"bytes,20,opt,name=attack,proto3" json:"attack,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Record) Reset() {
+	*x = Record{}
+	mi := &file_monitor_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Record) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Record) ProtoMessage() {}
+
+func (x *Record) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Record.ProtoReflect.Descriptor instead.
+func (*Record) Descriptor() ([]byte, []int) {
+	return file_monitor_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Record) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *Record) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *Record) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *Record) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *Record) GetSection() string {
+	if x != nil {
+		return x.Section
+	}
+	return ""
+}
+
+func (x *Record) GetResource() string {
+	if x != nil {
+		return x.Resource
+	}
+	return ""
+}
+
+func (x *Record) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *Record) GetStatusCode() int32 {
+	if x != nil {
+		return x.StatusCode
+	}
+	return 0
+}
+
+func (x *Record) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *Record) GetLatency() *durationpb.Duration {
+	if x != nil {
+		return x.Latency
+	}
+	return nil
+}
+
+func (x *Record) GetReferrer() string {
+	if x != nil {
+		return x.Referrer
+	}
+	return ""
+}
+
+func (x *Record) GetUserAgent() string {
+	if x != nil {
+		return x.UserAgent
+	}
+	return ""
+}
+
+func (x *Record) GetBot() bool {
+	if x != nil {
+		return x.Bot
+	}
+	return false
+}
+
+func (x *Record) GetPod() string {
+	if x != nil {
+		return x.Pod
+	}
+	return ""
+}
+
+func (x *Record) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *Record) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *Record) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *Record) GetVhost() string {
+	if x != nil {
+		return x.Vhost
+	}
+	return ""
+}
+
+func (x *Record) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Record) GetAttack() string {
+	if x != nil {
+		return x.Attack
+	}
+	return ""
+}
+
+// Counter for a key, e.g. a section or a client IP
+type Count struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Count) Reset() {
+	*x = Count{}
+	mi := &file_monitor_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Count) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Count) ProtoMessage() {}
+
+func (x *Count) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Count.ProtoReflect.Descriptor instead.
+func (*Count) Descriptor() ([]byte, []int) {
+	return file_monitor_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Count) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Count) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type Snapshot struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Time  *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	// Requests per response class (1XX to 5XX)
+	ResponseCodes map[string]int64 `protobuf:"bytes,2,rep,name=response_codes,json=responseCodes,proto3" json:"response_codes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	TopSections   []*Count         `protobuf:"bytes,3,rep,name=top_sections,json=topSections,proto3" json:"top_sections,omitempty"`
+	TopIps        []*Count         `protobuf:"bytes,4,rep,name=top_ips,json=topIps,proto3" json:"top_ips,omitempty"`
+	// Average QPS in the alerting window
+	Qps float64 `protobuf:"fixed64,5,opt,name=qps,proto3" json:"qps,omitempty"`
+	// Names of the alerts currently firing
+	FiringAlerts  []string `protobuf:"bytes,6,rep,name=firing_alerts,json=firingAlerts,proto3" json:"firing_alerts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Snapshot) Reset() {
+	*x = Snapshot{}
+	mi := &file_monitor_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Snapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Snapshot) ProtoMessage() {}
+
+func (x *Snapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Snapshot.ProtoReflect.Descriptor instead.
+func (*Snapshot) Descriptor() ([]byte, []int) {
+	return file_monitor_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Snapshot) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Snapshot) GetResponseCodes() map[string]int64 {
+	if x != nil {
+		return x.ResponseCodes
+	}
+	return nil
+}
+
+func (x *Snapshot) GetTopSections() []*Count {
+	if x != nil {
+		return x.TopSections
+	}
+	return nil
+}
+
+func (x *Snapshot) GetTopIps() []*Count {
+	if x != nil {
+		return x.TopIps
+	}
+	return nil
+}
+
+func (x *Snapshot) GetQps() float64 {
+	if x != nil {
+		return x.Qps
+	}
+	return 0
+}
+
+func (x *Snapshot) GetFiringAlerts() []string {
+	if x != nil {
+		return x.FiringAlerts
+	}
+	return nil
+}
+
+type AlertEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Firing        bool                   `protobuf:"varint,3,opt,name=firing,proto3" json:"firing,omitempty"`
+	Detail        string                 `protobuf:"bytes,4,opt,name=detail,proto3" json:"detail,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AlertEvent) Reset() {
+	*x = AlertEvent{}
+	mi := &file_monitor_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AlertEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AlertEvent) ProtoMessage() {}
+
+func (x *AlertEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AlertEvent.ProtoReflect.Descriptor instead.
+func (*AlertEvent) Descriptor() ([]byte, []int) {
+	return file_monitor_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *AlertEvent) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *AlertEvent) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *AlertEvent) GetFiring() bool {
+	if x != nil {
+		return x.Firing
+	}
+	return false
+}
+
+func (x *AlertEvent) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+var File_monitor_proto protoreflect.FileDescriptor
+
+const file_monitor_proto_rawDesc = "" +
+	"\n" +
+	"\rmonitor.proto\x12\vhttpmonitor\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x0f\n" +
+	"\rStreamRequest\"\xa3\x04\n" +
+	"\x06Record\x12\x0e\n" +
+	"\x02ip\x18\x01 \x01(\tR\x02ip\x12\x12\n" +
+	"\x04user\x18\x02 \x01(\tR\x04user\x128\n" +
+	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x16\n" +
+	"\x06method\x18\x04 \x01(\tR\x06method\x12\x18\n" +
+	"\asection\x18\x05 \x01(\tR\asection\x12\x1a\n" +
+	"\bresource\x18\x06 \x01(\tR\bresource\x12\x1a\n" +
+	"\bprotocol\x18\a \x01(\tR\bprotocol\x12\x1f\n" +
+	"\vstatus_code\x18\b \x01(\x05R\n" +
+	"statusCode\x12\x12\n" +
+	"\x04size\x18\t \x01(\x03R\x04size\x123\n" +
+	"\alatency\x18\n" +
+	" \x01(\v2\x19.google.protobuf.DurationR\alatency\x12\x1a\n" +
+	"\breferrer\x18\v \x01(\tR\breferrer\x12\x1d\n" +
+	"\n" +
+	"user_agent\x18\f \x01(\tR\tuserAgent\x12\x10\n" +
+	"\x03bot\x18\r \x01(\bR\x03bot\x12\x10\n" +
+	"\x03pod\x18\x0e \x01(\tR\x03pod\x12\x18\n" +
+	"\acountry\x18\x0f \x01(\tR\acountry\x12\x12\n" +
+	"\x04city\x18\x10 \x01(\tR\x04city\x12\x14\n" +
+	"\x05group\x18\x11 \x01(\tR\x05group\x12\x14\n" +
+	"\x05vhost\x18\x12 \x01(\tR\x05vhost\x12\x16\n" +
+	"\x06source\x18\x13 \x01(\tR\x06source\x12\x16\n" +
+	"\x06attack\x18\x14 \x01(\tR\x06attack\"/\n" +
+	"\x05Count\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\"\xe8\x02\n" +
+	"\bSnapshot\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12O\n" +
+	"\x0eresponse_codes\x18\x02 \x03(\v2(.httpmonitor.Snapshot.ResponseCodesEntryR\rresponseCodes\x125\n" +
+	"\ftop_sections\x18\x03 \x03(\v2\x12.httpmonitor.CountR\vtopSections\x12+\n" +
+	"\atop_ips\x18\x04 \x03(\v2\x12.httpmonitor.CountR\x06topIps\x12\x10\n" +
+	"\x03qps\x18\x05 \x01(\x01R\x03qps\x12#\n" +
+	"\rfiring_alerts\x18\x06 \x03(\tR\ffiringAlerts\x1a@\n" +
+	"\x12ResponseCodesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\x80\x01\n" +
+	"\n" +
+	"AlertEvent\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x16\n" +
+	"\x06firing\x18\x03 \x01(\bR\x06firing\x12\x16\n" +
+	"\x06detail\x18\x04 \x01(\tR\x06detail2\xdc\x01\n" +
+	"\aMonitor\x12B\n" +
+	"\rStreamRecords\x12\x1a.httpmonitor.StreamRequest\x1a\x13.httpmonitor.Record0\x01\x12F\n" +
+	"\x0fStreamSnapshots\x12\x1a.httpmonitor.StreamRequest\x1a\x15.httpmonitor.Snapshot0\x01\x12E\n" +
+	"\fStreamAlerts\x12\x1a.httpmonitor.StreamRequest\x1a\x17.httpmonitor.AlertEvent0\x01B+Z)github.com/falfaro/http_monitor/monitorpbb\x06proto3"
+
+var (
+	file_monitor_proto_rawDescOnce sync.Once
+	file_monitor_proto_rawDescData []byte
+)
+
+func file_monitor_proto_rawDescGZIP() []byte {
+	file_monitor_proto_rawDescOnce.Do(func() {
+		file_monitor_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_monitor_proto_rawDesc), len(file_monitor_proto_rawDesc)))
+	})
+	return file_monitor_proto_rawDescData
+}
+
+var file_monitor_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_monitor_proto_goTypes = []any{
+	(*StreamRequest)(nil),         // 0: httpmonitor.StreamRequest
+	(*Record)(nil),                // 1: httpmonitor.Record
+	(*Count)(nil),                 // 2: httpmonitor.Count
+	(*Snapshot)(nil),              // 3: httpmonitor.Snapshot
+	(*AlertEvent)(nil),            // 4: httpmonitor.AlertEvent
+	nil,                           // 5: httpmonitor.Snapshot.ResponseCodesEntry
+	(*timestamppb.Timestamp)(nil), // 6: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),   // 7: google.protobuf.Duration
+}
+var file_monitor_proto_depIdxs = []int32{
+	6,  // 0: httpmonitor.Record.timestamp:type_name -> google.protobuf.Timestamp
+	7,  // 1: httpmonitor.Record.latency:type_name -> google.protobuf.Duration
+	6,  // 2: httpmonitor.Snapshot.time:type_name -> google.protobuf.Timestamp
+	5,  // 3: httpmonitor.Snapshot.response_codes:type_name -> httpmonitor.Snapshot.ResponseCodesEntry
+	2,  // 4: httpmonitor.Snapshot.top_sections:type_name -> httpmonitor.Count
+	2,  // 5: httpmonitor.Snapshot.top_ips:type_name -> httpmonitor.Count
+	6,  // 6: httpmonitor.AlertEvent.time:type_name -> google.protobuf.Timestamp
+	0,  // 7: httpmonitor.Monitor.StreamRecords:input_type -> httpmonitor.StreamRequest
+	0,  // 8: httpmonitor.Monitor.StreamSnapshots:input_type -> httpmonitor.StreamRequest
+	0,  // 9: httpmonitor.Monitor.StreamAlerts:input_type -> httpmonitor.StreamRequest
+	1,  // 10: httpmonitor.Monitor.StreamRecords:output_type -> httpmonitor.Record
+	3,  // 11: httpmonitor.Monitor.StreamSnapshots:output_type -> httpmonitor.Snapshot
+	4,  // 12: httpmonitor.Monitor.StreamAlerts:output_type -> httpmonitor.AlertEvent
+	10, // [10:13] is the sub-list for method output_type
+	7,  // [7:10] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_monitor_proto_init() }
+func file_monitor_proto_init() {
+	if File_monitor_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_monitor_proto_rawDesc), len(file_monitor_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_monitor_proto_goTypes,
+		DependencyIndexes: file_monitor_proto_depIdxs,
+		MessageInfos:      file_monitor_proto_msgTypes,
+	}.Build()
+	File_monitor_proto = out.File
+	file_monitor_proto_goTypes = nil
+	file_monitor_proto_depIdxs = nil
+}
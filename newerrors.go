@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Command-line flags to alert on sections starting to fail
+var newErrors = flag.Int("new-errors", 0, "Alert when a section that historically returned almost no 5XX responses gets at least this many in the alerting window (0 disables)")
+var newErrorBaseline = flag.Float64("new-error-baseline", 0.1, "Percentage of 5XX responses up to which a section historically returned almost none")
+
+// Requests a section must have been seen serving before its history is
+// trusted
+const newErrorMinHistory = 100
+
+// Requests to a section, and how many of them failed with a server error
+type sectionErrors struct {
+	requests int
+	errors   int
+}
+
+// Percentage of server errors
+func (e *sectionErrors) percent() float64 {
+	if e.requests == 0 {
+		return 0
+	}
+	return float64(e.errors) * 100 / float64(e.requests)
+}
+
+// Alert firing when sections that historically returned almost no 5XX
+// responses start producing them, as after a bad deploy, however low the
+// overall error rate is. History is learnt from the records leaving the
+// window, so that a burst of errors is compared with what came before it
+type newErrorRule struct {
+	errors   int
+	baseline float64 // In percent
+	history  map[string]*sectionErrors
+	previous []*logRecord // Window as of the last check
+}
+
+func (r *newErrorRule) name() string {
+	return "New-errors"
+}
+
+func (r *newErrorRule) evaluate(s *stats) (bool, string) {
+	r.learn(s.logsInWindow)
+
+	window := make(map[string]*sectionErrors)
+	for _, record := range s.logsInWindow {
+		if window[record.Section] == nil {
+			window[record.Section] = &sectionErrors{}
+		}
+		window[record.Section].requests++
+		if record.StatusCode >= 500 {
+			window[record.Section].errors++
+		}
+	}
+
+	var details []string
+	for section, current := range window {
+		history := r.history[section]
+		if history == nil || history.requests < newErrorMinHistory || history.percent() > r.baseline {
+			continue
+		}
+		if errors := float64(current.errors) * s.weight(); errors >= float64(r.errors) {
+			details = append(details, fmt.Sprintf("%s (%d 5XX, historically %.2f%%)", section, int(errors+0.5), history.percent()))
+		}
+	}
+	sort.Strings(details)
+	return len(details) > 0, fmt.Sprintf("in %s", strings.Join(details, ", "))
+}
+
+// Add the records that left the window since the last check to the history
+// of their section
+func (r *newErrorRule) learn(window []*logRecord) {
+	if r.history == nil {
+		r.history = make(map[string]*sectionErrors)
+	}
+	current := make(map[*logRecord]bool, len(window))
+	for _, record := range window {
+		current[record] = true
+	}
+	for _, record := range r.previous {
+		if current[record] {
+			continue
+		}
+		if r.history[record.Section] == nil {
+			r.history[record.Section] = &sectionErrors{}
+		}
+		r.history[record.Section].requests++
+		if record.StatusCode >= 500 {
+			r.history[record.Section].errors++
+		}
+	}
+	r.previous = append(r.previous[:0:0], window...)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNewErrorRule(t *testing.T) {
+	s := newStats()
+	records := func(section string, requests, errors int) []*logRecord {
+		var records []*logRecord
+		for i := 0; i < requests; i++ {
+			status := 200
+			if i < errors {
+				status = 503
+			}
+			records = append(records, &logRecord{Section: section, StatusCode: status})
+		}
+		return records
+	}
+
+	// A healthy section, a section which always failed now and then, and
+	// one seen too little to have a history
+	s.logsInWindow = append(records("/api", 200, 0), records("/legacy", 200, 10)...)
+	s.logsInWindow = append(s.logsInWindow, records("/new", 10, 0)...)
+	r := &newErrorRule{errors: 10, baseline: 0.1}
+	if firing, _ := r.evaluate(s); firing {
+		t.Errorf("Expected new error alert not to fire without history")
+	}
+
+	// Every section then fails, as the first records leave the window
+	s.logsInWindow = append(records("/api", 50, 12), records("/legacy", 50, 12)...)
+	s.logsInWindow = append(s.logsInWindow, records("/new", 50, 12)...)
+	firing, detail := r.evaluate(s)
+	if !firing {
+		t.Errorf("Expected new error alert to fire")
+	}
+	if expected := "in /api (12 5XX, historically 0.00%)"; detail != expected {
+		t.Errorf("%q != %q", detail, expected)
+	}
+	if history := r.history["/api"]; history.requests != 200 || history.errors != 0 {
+		t.Errorf("Unexpected history: %+v", *history)
+	}
+
+	r = &newErrorRule{errors: 20, baseline: 0.1, history: r.history}
+	if firing, _ := r.evaluate(s); firing {
+		t.Errorf("Expected new error alert not to fire")
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// URL normalizations given on the command line: query (strip query
+// strings), decode (decode percent-encoding) and ids (collapse numeric and
+// UUID path segments into :id)
+type normalizationSet stringSet
+
+func (s normalizationSet) String() string {
+	return stringSet(s).String()
+}
+
+func (s normalizationSet) reset() {
+	stringSet(s).reset()
+}
+
+func (s normalizationSet) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "query", "decode", "ids":
+			s[name] = true
+		case "":
+		default:
+			return fmt.Errorf("Unknown URL normalization: %s", name)
+		}
+	}
+	return nil
+}
+
+// Command-line flag to normalize URLs before extracting sections
+var normalizations = normalizationSet{}
+
+func init() {
+	flag.Var(normalizations, "normalize", "Comma-separated URL normalizations: query (strip query strings), decode (decode percent-encoding) and ids (collapse numeric and UUID path segments into :id)")
+}
+
+// Path segments holding identifiers: numbers and UUIDs
+var idSegmentRegExp = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})$`)
+
+// Apply the configured normalizations to a request URI, so that requests
+// for the same endpoint are aggregated together
+func normalizeURI(uri string) string {
+	if normalizations["query"] {
+		if i := strings.IndexByte(uri, '?'); i >= 0 {
+			uri = uri[:i]
+		}
+	}
+	if normalizations["decode"] {
+		if decoded, err := url.PathUnescape(uri); err == nil {
+			uri = decoded
+		}
+	}
+	if normalizations["ids"] {
+		path, query := uri, ""
+		if i := strings.IndexByte(uri, '?'); i >= 0 {
+			path, query = uri[:i], uri[i:]
+		}
+		segments := strings.Split(path, "/")
+		for i, segment := range segments {
+			if idSegmentRegExp.MatchString(segment) {
+				segments[i] = ":id"
+			}
+		}
+		uri = strings.Join(segments, "/") + query
+	}
+	return uri
+}
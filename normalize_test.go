@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNormalizeURI(t *testing.T) {
+	tests := []struct {
+		normalizations string
+		uri            string
+		normalized     string
+	}{
+		{"", "/users/123?page=2", "/users/123?page=2"},
+		{"query", "/users/123?page=2", "/users/123"},
+		{"ids", "/users/123/orders/9f3c2a1e-4b5d-4c6e-8f70-123456789abc?page=2", "/users/:id/orders/:id?page=2"},
+		{"ids", "/v2/users/abc123", "/v2/users/abc123"},
+		{"decode", "/caf%C3%A9/menu%20du%20jour", "/café/menu du jour"},
+		{"decode", "/bad%zzencoding", "/bad%zzencoding"},
+		{"query,decode,ids", "/users/%31%32%33?page=2", "/users/:id"},
+	}
+
+	defer normalizations.reset()
+	for _, test := range tests {
+		normalizations.reset()
+		if err := normalizations.Set(test.normalizations); err != nil {
+			t.Fatal(err)
+		}
+		if normalized := normalizeURI(test.uri); normalized != test.normalized {
+			t.Errorf("%+v != %+v", test.normalized, normalized)
+		}
+	}
+
+	if err := normalizations.Set("lowercase"); err == nil {
+		t.Errorf("Expected an error for an unknown normalization")
+	}
+}
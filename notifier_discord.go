@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Command-line flags to post alert embeds to Discord
+var discordWebhook = flag.String("discord-webhook", "", "Discord webhook URL alert embeds are posted to")
+var discordSeverity = severityWarning
+
+func init() {
+	flag.Var(&discordSeverity, "discord-severity", "Least severity of alerts posted to Discord: warning or critical")
+}
+
+// Colors of Discord embeds
+const (
+	discordRed    = 0xe53935
+	discordOrange = 0xfb8c00
+	discordGreen  = 0x43a047
+)
+
+// Notifier posting embeds to a Discord webhook
+type discordNotifier struct {
+	webhook      string
+	dashboardURL string // Linked from embeds, if set
+	client       *http.Client
+}
+
+func newDiscordNotifier(webhook, dashboardURL string) *discordNotifier {
+	return &discordNotifier{
+		webhook:      webhook,
+		dashboardURL: dashboardURL,
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (d *discordNotifier) name() string {
+	return "Discord"
+}
+
+// Embed describing an alert change
+func discordEmbed(n notification, dashboardURL string) map[string]interface{} {
+	title, color := n.Name+" alert resolved", discordGreen
+	if n.Firing {
+		title, color = n.Name+" alert is firing", discordRed
+		if n.Severity == "warning" {
+			color = discordOrange
+		}
+	}
+
+	var fields []map[string]interface{}
+	if n.Severity != "" {
+		fields = append(fields, map[string]interface{}{"name": "Severity", "value": n.Severity, "inline": true})
+	}
+	if n.Value != nil {
+		fields = append(fields,
+			map[string]interface{}{"name": "Value", "value": fmt.Sprintf("%.2f", *n.Value), "inline": true},
+			map[string]interface{}{"name": "Threshold", "value": fmt.Sprintf("%g", *n.Threshold), "inline": true},
+		)
+	}
+
+	embed := map[string]interface{}{
+		"title":     title,
+		"color":     color,
+		"timestamp": n.Time.Format(time.RFC3339),
+	}
+	if text := n.text(); text != "" {
+		embed["description"] = text
+	}
+	if len(fields) > 0 {
+		embed["fields"] = fields
+	}
+	if dashboardURL != "" {
+		embed["url"] = dashboardURL
+	}
+	return embed
+}
+
+// Number of embeds Discord accepts in a single message
+const discordMaxEmbeds = 10
+
+func (d *discordNotifier) notify(n notification) error {
+	return d.notifyGroup([]notification{n})
+}
+
+func (d *discordNotifier) notifyGroup(ns []notification) error {
+	for len(ns) > 0 {
+		var embeds []interface{}
+		for len(ns) > 0 && len(embeds) < discordMaxEmbeds {
+			embeds = append(embeds, discordEmbed(ns[0], d.dashboardURL))
+			ns = ns[1:]
+		}
+		if err := postJSON(d.client, d.name(), d.webhook, nil, map[string]interface{}{
+			"username": "http_monitor",
+			"embeds":   embeds,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
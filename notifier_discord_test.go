@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDiscordNotifier(t *testing.T) {
+	var message struct {
+		Username string `json:"username"`
+		Embeds   []struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			Color       int    `json:"color"`
+			URL         string `json:"url"`
+			Fields      []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"fields"`
+		} `json:"embeds"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&message)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d := newDiscordNotifier(server.URL, "")
+	n := notification{Time: time.Now(), Name: "Scanning", Firing: true, Detail: "from 10.0.0.1 (40 errors)"}
+	if err := d.notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if message.Username != "http_monitor" || len(message.Embeds) != 1 {
+		t.Fatalf("Unexpected message: %+v", message)
+	}
+	embed := message.Embeds[0]
+	if embed.Title != "Scanning alert is firing" || embed.Color != discordRed || embed.Description != n.Detail || embed.URL != "" || len(embed.Fields) != 0 {
+		t.Errorf("Unexpected embed: %+v", embed)
+	}
+
+	if err := d.notify(notification{Time: time.Now(), Name: "Scanning"}); err != nil {
+		t.Fatal(err)
+	}
+	if embed := message.Embeds[0]; embed.Title != "Scanning alert resolved" || embed.Color != discordGreen {
+		t.Errorf("Unexpected embed: %+v", embed)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Command-line flags to run a command whenever an alert changes
+var execCommand = flag.String("exec-command", "", "Command run whenever an alert fires or resolves, with details in ALERT_* environment variables (ALERT_MESSAGE as given by -notify-template) and as JSON on standard input")
+var execTimeout = flag.Duration("exec-timeout", 30*time.Second, "Time after which the alert command is killed")
+var execSeverity = severityWarning
+
+func init() {
+	flag.Var(&execSeverity, "exec-severity", "Least severity of alerts the alert command is run for: warning or critical")
+}
+
+// Notifier running a command, e.g. a scale-up script or a traffic dump
+type execNotifier struct {
+	command string
+	timeout time.Duration
+}
+
+func (e *execNotifier) name() string {
+	return "exec"
+}
+
+func (e *execNotifier) notify(n notification) error {
+	args := strings.Fields(e.command)
+	if len(args) == 0 {
+		return fmt.Errorf("Empty command")
+	}
+	input, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(),
+		"ALERT_NAME="+n.Name,
+		"ALERT_STATE="+n.state(),
+		"ALERT_SEVERITY="+n.Severity,
+		"ALERT_DETAIL="+n.Detail,
+		"ALERT_MESSAGE="+n.text(),
+		"ALERT_TIME="+n.Time.Format(time.RFC3339),
+	)
+	cmd.Stdin = bytes.NewReader(input)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExecNotifier(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output")
+	script := filepath.Join(dir, "notify.sh")
+	if err := os.WriteFile(script, []byte("echo \"$ALERT_NAME $ALERT_STATE $ALERT_SEVERITY\" > "+output+"\ncat >> "+output+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	e := &execNotifier{command: "sh " + script, timeout: 10 * time.Second}
+	n := notification{Time: time.Now(), Name: "High-traffic", Firing: true, Severity: "critical", Detail: "at 30.000000 queries per second on average"}
+	if err := e.notify(n); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"High-traffic firing critical\n", `"detail":"at 30.000000 queries per second on average"`} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("%q not in %q", expected, string(data))
+		}
+	}
+
+	e.command = "sh -c false"
+	if err := e.notify(n); err == nil {
+		t.Errorf("Expected an error for a failing command")
+	}
+}
+
+// Test alerts notified of before a reload are resolved after it
+func TestExecNotifierReload(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output")
+	script := filepath.Join(dir, "notify.sh")
+	if err := os.WriteFile(script, []byte("echo \"$ALERT_NAME $ALERT_STATE\" >> "+output+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(command string) { *execCommand = command }(*execCommand)
+	*execCommand = "sh " + script
+
+	m := &monitor{stats: newStats(), mutex: &sync.Mutex{}, alerts: newAlertTracker(nil)}
+	notify := func(change alertChange) {
+		m.mutex.Lock()
+		for _, subscriber := range m.alerts.subscribers {
+			subscriber(change)
+		}
+		m.mutex.Unlock()
+	}
+	if err := m.configureOutputs(); err != nil {
+		t.Fatal(err)
+	}
+	notify(alertChange{Name: "High-traffic", Firing: true})
+	if err := m.configureOutputs(); err != nil {
+		t.Fatal(err)
+	}
+	notify(alertChange{Name: "High-traffic", Firing: false})
+	closeNotifiers(m.notifiers)
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "High-traffic firing\nHigh-traffic resolved\n"; string(data) != expected {
+		t.Errorf("%q != %q", expected, string(data))
+	}
+}
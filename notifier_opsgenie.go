@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Command-line flags to create and close OpsGenie alerts
+var opsgenieAPIKey = flag.String("opsgenie-api-key", "", "OpsGenie API integration key, to create and close OpsGenie alerts as alerts fire and resolve")
+var opsgenieURL = flag.String("opsgenie-url", "https://api.opsgenie.com", "OpsGenie API endpoint, e.g. https://api.eu.opsgenie.com for the EU instance")
+var opsgenieTags = flag.String("opsgenie-tags", "", "Comma-separated tags of OpsGenie alerts")
+var opsgenieSeverity = severityWarning
+
+func init() {
+	flag.Var(&opsgenieSeverity, "opsgenie-severity", "Least severity of alerts OpsGenie alerts are created for: warning or critical")
+}
+
+// Notifier creating an OpsGenie alert when an alert fires, and closing it
+// when it resolves. OpsGenie alerts are keyed by rule through their alias,
+// so that changes in severity update the same alert
+type opsgenieNotifier struct {
+	url    string
+	apiKey string
+	tags   []string
+	client *http.Client
+}
+
+func newOpsGenieNotifier(apiURL, apiKey, tags string) *opsgenieNotifier {
+	o := &opsgenieNotifier{
+		url:    strings.TrimSuffix(apiURL, "/") + "/v2/alerts",
+		apiKey: apiKey,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	if tags != "" {
+		o.tags = strings.Split(tags, ",")
+	}
+	return o
+}
+
+func (o *opsgenieNotifier) name() string {
+	return "OpsGenie"
+}
+
+// Alias of the OpsGenie alert for an alert, the same across changes
+func opsgenieAlias(n notification) string {
+	return "http_monitor: " + n.Name
+}
+
+// Priority of OpsGenie alerts: warnings are moderate, everything else is
+// critical
+func opsgeniePriority(n notification) string {
+	if n.Severity == "warning" {
+		return "P3"
+	}
+	return "P1"
+}
+
+func (o *opsgenieNotifier) notify(n notification) error {
+	header := http.Header{"Authorization": {"GenieKey " + o.apiKey}}
+	host, _ := os.Hostname()
+	if !n.Firing {
+		closeURL := o.url + "/" + url.PathEscape(opsgenieAlias(n)) + "/close?identifierType=alias"
+		return postJSON(o.client, o.name(), closeURL, header, map[string]string{
+			"source": host,
+			"note":   "Not firing anymore",
+		})
+	}
+	return postJSON(o.client, o.name(), o.url, header, map[string]interface{}{
+		"message":     n.Name + " alert is firing",
+		"alias":       opsgenieAlias(n),
+		"description": n.text(),
+		"priority":    opsgeniePriority(n),
+		"source":      host,
+		"tags":        o.tags,
+	})
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOpsGenieNotifier(t *testing.T) {
+	var requests []string
+	var payloads []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "GenieKey secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		requests = append(requests, r.URL.RequestURI())
+		payloads = append(payloads, payload)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	o := newOpsGenieNotifier(server.URL+"/", "secret", "web,prod")
+	firing := notification{Time: time.Now(), Name: "High-traffic", Firing: true, Severity: "warning", Detail: "at 12.000000 queries per second on average"}
+	if err := o.notify(firing); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.notify(notification{Time: time.Now(), Name: "High-traffic"}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"/v2/alerts", "/v2/alerts/http_monitor:%20High-traffic/close?identifierType=alias"}
+	if len(requests) != 2 || requests[0] != expected[0] || requests[1] != expected[1] {
+		t.Fatalf("%+v != %+v", requests, expected)
+	}
+	if payloads[0]["alias"] != "http_monitor: High-traffic" || payloads[0]["priority"] != "P3" || len(payloads[0]["tags"].([]interface{})) != 2 {
+		t.Errorf("Unexpected alert: %+v", payloads[0])
+	}
+
+	o.apiKey = "wrong"
+	if err := o.notify(firing); err == nil {
+		t.Errorf("Expected an error for a rejected key")
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// Command-line flags to send SNMP traps whenever an alert changes
+var snmpTarget = flag.String("snmp-target", "", "Send SNMP traps on alert changes to the given host[:port] (port 162 by default)")
+var snmpVersion = flag.String("snmp-version", "2c", "SNMP version of traps: 2c or 3")
+var snmpCommunity = flag.String("snmp-community", "public", "SNMPv2c community of traps")
+var snmpUser = flag.String("snmp-user", "", "SNMPv3 user name of traps")
+var snmpAuthProtocol = flag.String("snmp-auth-protocol", "SHA", "SNMPv3 authentication protocol: MD5, SHA, SHA256 or SHA512")
+var snmpAuthPassphrase = flag.String("snmp-auth-passphrase", "", "SNMPv3 authentication passphrase (none disables authentication)")
+var snmpPrivProtocol = flag.String("snmp-priv-protocol", "AES", "SNMPv3 privacy protocol: DES, AES or AES256")
+var snmpPrivPassphrase = flag.String("snmp-priv-passphrase", "", "SNMPv3 privacy passphrase (none disables encryption)")
+var snmpEngineID = flag.String("snmp-engine-id", "80007ed904687474706d6f6e69746f72", "SNMPv3 engine ID of http_monitor, in hexadecimal, as configured on the trap receiver")
+var snmpSeverity = severityWarning
+
+func init() {
+	flag.Var(&snmpSeverity, "snmp-severity", "Least severity of alerts SNMP traps are sent for: warning or critical")
+}
+
+// Object identifiers defined by HTTP-MONITOR-MIB, under the enterprise
+// number reserved for documentation (RFC 5612)
+const (
+	snmpTrapOID              = "1.3.6.1.6.3.1.1.4.1.0"
+	httpMonitorAlertFiring   = "1.3.6.1.4.1.32473.80.0.1"
+	httpMonitorAlertResolved = "1.3.6.1.4.1.32473.80.0.2"
+	httpMonitorAlertName     = "1.3.6.1.4.1.32473.80.1.1"
+	httpMonitorAlertSeverity = "1.3.6.1.4.1.32473.80.1.2"
+	httpMonitorAlertDetail   = "1.3.6.1.4.1.32473.80.1.3"
+)
+
+// Values of httpMonitorAlertSeverity
+var snmpSeverities = map[string]int{"": 0, "warning": 1, "critical": 2}
+
+var snmpAuthProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+	"MD5":    gosnmp.MD5,
+	"SHA":    gosnmp.SHA,
+	"SHA256": gosnmp.SHA256,
+	"SHA512": gosnmp.SHA512,
+}
+
+var snmpPrivProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+	"DES":    gosnmp.DES,
+	"AES":    gosnmp.AES,
+	"AES256": gosnmp.AES256,
+}
+
+// Notifier sending SNMPv2c or SNMPv3 traps, for NOC tooling built on SNMP
+type snmpNotifier struct {
+	host    string
+	port    uint16
+	version gosnmp.SnmpVersion
+	started time.Time // Reference of the SNMPv3 engine time
+
+	community string
+
+	user           string
+	flags          gosnmp.SnmpV3MsgFlags
+	authProtocol   gosnmp.SnmpV3AuthProtocol
+	authPassphrase string
+	privProtocol   gosnmp.SnmpV3PrivProtocol
+	privPassphrase string
+	engineID       string
+}
+
+// Validate the SNMP flags
+func newSNMPNotifier() (*snmpNotifier, error) {
+	s := &snmpNotifier{
+		host:           *snmpTarget,
+		port:           162,
+		started:        time.Now(),
+		community:      *snmpCommunity,
+		user:           *snmpUser,
+		authPassphrase: *snmpAuthPassphrase,
+		privPassphrase: *snmpPrivPassphrase,
+		flags:          gosnmp.NoAuthNoPriv,
+	}
+	if host, port, err := net.SplitHostPort(*snmpTarget); err == nil {
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid SNMP target port: %s", port)
+		}
+		s.host, s.port = host, uint16(p)
+	}
+
+	switch *snmpVersion {
+	case "2c":
+		s.version = gosnmp.Version2c
+		return s, nil
+	case "3":
+		s.version = gosnmp.Version3
+	default:
+		return nil, fmt.Errorf("Unknown SNMP version: %s", *snmpVersion)
+	}
+
+	if s.user == "" {
+		return nil, fmt.Errorf("SNMPv3 traps require -snmp-user")
+	}
+	engineID, err := hex.DecodeString(*snmpEngineID)
+	if err != nil || len(engineID) < 5 || len(engineID) > 32 {
+		return nil, fmt.Errorf("Invalid SNMPv3 engine ID: %s", *snmpEngineID)
+	}
+	s.engineID = string(engineID)
+	var ok bool
+	if s.authProtocol, ok = snmpAuthProtocols[*snmpAuthProtocol]; !ok {
+		return nil, fmt.Errorf("Unknown SNMPv3 authentication protocol: %s", *snmpAuthProtocol)
+	}
+	if s.privProtocol, ok = snmpPrivProtocols[*snmpPrivProtocol]; !ok {
+		return nil, fmt.Errorf("Unknown SNMPv3 privacy protocol: %s", *snmpPrivProtocol)
+	}
+	switch {
+	case s.privPassphrase != "" && s.authPassphrase == "":
+		return nil, fmt.Errorf("SNMPv3 privacy requires -snmp-auth-passphrase")
+	case s.privPassphrase != "":
+		s.flags = gosnmp.AuthPriv
+	case s.authPassphrase != "":
+		s.flags = gosnmp.AuthNoPriv
+	}
+	return s, nil
+}
+
+func (s *snmpNotifier) name() string {
+	return "SNMP"
+}
+
+// Client sending a single trap
+func (s *snmpNotifier) client() *gosnmp.GoSNMP {
+	client := &gosnmp.GoSNMP{
+		Target:    s.host,
+		Port:      s.port,
+		Transport: "udp",
+		Version:   s.version,
+		Community: s.community,
+		Timeout:   5 * time.Second,
+		MaxOids:   gosnmp.MaxOids,
+	}
+	if s.version == gosnmp.Version3 {
+		params := &gosnmp.UsmSecurityParameters{
+			UserName:                 s.user,
+			AuthoritativeEngineID:    s.engineID,
+			AuthoritativeEngineBoots: 1,
+			AuthoritativeEngineTime:  uint32(time.Since(s.started).Seconds()),
+		}
+		if s.flags != gosnmp.NoAuthNoPriv {
+			params.AuthenticationProtocol = s.authProtocol
+			params.AuthenticationPassphrase = s.authPassphrase
+		}
+		if s.flags == gosnmp.AuthPriv {
+			params.PrivacyProtocol = s.privProtocol
+			params.PrivacyPassphrase = s.privPassphrase
+		}
+		client.SecurityModel = gosnmp.UserSecurityModel
+		client.MsgFlags = s.flags
+		client.SecurityParameters = params
+	}
+	return client
+}
+
+// Variables of the trap for an alert change
+func snmpTrap(n notification) gosnmp.SnmpTrap {
+	trapOID := httpMonitorAlertFiring
+	if !n.Firing {
+		trapOID = httpMonitorAlertResolved
+	}
+	return gosnmp.SnmpTrap{Variables: []gosnmp.SnmpPDU{
+		{Name: snmpTrapOID, Type: gosnmp.ObjectIdentifier, Value: trapOID},
+		{Name: httpMonitorAlertName, Type: gosnmp.OctetString, Value: n.Name},
+		{Name: httpMonitorAlertSeverity, Type: gosnmp.Integer, Value: snmpSeverities[n.Severity]},
+		{Name: httpMonitorAlertDetail, Type: gosnmp.OctetString, Value: n.text()},
+	}}
+}
+
+func (s *snmpNotifier) notify(n notification) error {
+	client := s.client()
+	if err := client.Connect(); err != nil {
+		return err
+	}
+	defer client.Close()
+	_, err := client.SendTrap(snmpTrap(n))
+	return err
+}
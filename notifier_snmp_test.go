@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+func TestSNMPNotifier(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	*snmpTarget = conn.LocalAddr().String()
+	defer func() { *snmpTarget = "" }()
+	s, err := newSNMPNotifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := notification{Time: time.Now(), Name: "High-traffic", Firing: true, Severity: "critical", Detail: "at 30.000000 queries per second on average"}
+	if err := s.notify(n); err != nil {
+		t.Fatal(err)
+	}
+
+	buffer := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	size, _, err := conn.ReadFrom(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packet, err := (&gosnmp.GoSNMP{Version: gosnmp.Version2c}).UnmarshalTrap(buffer[:size], false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packet.Community != "public" {
+		t.Errorf("%+v != %+v", packet.Community, "public")
+	}
+	values := make(map[string]interface{})
+	for _, v := range packet.Variables {
+		values[v.Name] = v.Value
+	}
+	expected := map[string]interface{}{
+		"." + snmpTrapOID:              "." + httpMonitorAlertFiring,
+		"." + httpMonitorAlertName:     []byte("High-traffic"),
+		"." + httpMonitorAlertSeverity: 2,
+	}
+	for name, value := range expected {
+		if b, ok := value.([]byte); ok {
+			if string(values[name].([]byte)) != string(b) {
+				t.Errorf("%s: %+v != %+v", name, values[name], value)
+			}
+		} else if values[name] != value {
+			t.Errorf("%s: %+v != %+v", name, values[name], value)
+		}
+	}
+}
+
+func TestSNMPNotifierFlags(t *testing.T) {
+	defer func(target, version, user, auth, priv string) {
+		*snmpTarget, *snmpVersion, *snmpUser, *snmpAuthPassphrase, *snmpPrivPassphrase = target, version, user, auth, priv
+	}(*snmpTarget, *snmpVersion, *snmpUser, *snmpAuthPassphrase, *snmpPrivPassphrase)
+
+	*snmpTarget, *snmpVersion, *snmpUser = "nms.example.com", "3", "monitor"
+	*snmpAuthPassphrase, *snmpPrivPassphrase = "authpassphrase", "privpassphrase"
+	s, err := newSNMPNotifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.host != "nms.example.com" || s.port != 162 || s.flags != gosnmp.AuthPriv {
+		t.Errorf("Unexpected notifier: %+v", s)
+	}
+
+	*snmpAuthPassphrase = ""
+	if _, err := newSNMPNotifier(); err == nil {
+		t.Errorf("Expected an error for privacy without authentication")
+	}
+	*snmpVersion = "1"
+	if _, err := newSNMPNotifier(); err == nil {
+		t.Errorf("Expected an error for SNMPv1")
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Command-line flags to post alert cards to Microsoft Teams
+var teamsWebhook = flag.String("teams-webhook", "", "Microsoft Teams incoming webhook URL alert cards are posted to")
+var teamsSeverity = severityWarning
+
+func init() {
+	flag.Var(&teamsSeverity, "teams-severity", "Least severity of alerts posted to Microsoft Teams: warning or critical")
+}
+
+// Notifier posting Adaptive Cards to a Microsoft Teams incoming webhook
+type teamsNotifier struct {
+	webhook      string
+	dashboardURL string // Linked from cards, if set
+	client       *http.Client
+}
+
+func newTeamsNotifier(webhook, dashboardURL string) *teamsNotifier {
+	return &teamsNotifier{
+		webhook:      webhook,
+		dashboardURL: dashboardURL,
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (t *teamsNotifier) name() string {
+	return "Microsoft Teams"
+}
+
+// Elements of Adaptive Cards describing an alert change
+func teamsCardBody(n notification) []interface{} {
+	title, color := n.Name+" alert resolved", "good"
+	if n.Firing {
+		title, color = n.Name+" alert is firing", "attention"
+		if n.Severity == "warning" {
+			color = "warning"
+		}
+	}
+
+	facts := []map[string]string{
+		{"title": "Rule", "value": n.Name},
+		{"title": "State", "value": n.state()},
+	}
+	if n.Severity != "" {
+		facts = append(facts, map[string]string{"title": "Severity", "value": n.Severity})
+	}
+	if n.Value != nil {
+		facts = append(facts,
+			map[string]string{"title": "Value", "value": fmt.Sprintf("%.2f", *n.Value)},
+			map[string]string{"title": "Threshold", "value": fmt.Sprintf("%g", *n.Threshold)},
+		)
+	}
+	if n.Detail != "" {
+		facts = append(facts, map[string]string{"title": "Detail", "value": n.Detail})
+	}
+	facts = append(facts, map[string]string{"title": "Time", "value": displayTime(n.Time).Format(time.RFC3339)})
+
+	body := []interface{}{
+		map[string]interface{}{"type": "TextBlock", "text": title, "weight": "bolder", "size": "medium", "color": color, "separator": true},
+	}
+	if n.Message != "" {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": n.Message, "wrap": true})
+	}
+	return append(body, map[string]interface{}{"type": "FactSet", "facts": facts})
+}
+
+// Adaptive Card describing alert changes
+func teamsCard(ns []notification, dashboardURL string) map[string]interface{} {
+	var body []interface{}
+	if len(ns) > 1 {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": fmt.Sprintf("%d alerts changed", len(ns)), "weight": "bolder", "size": "large"})
+	}
+	for _, n := range ns {
+		body = append(body, teamsCardBody(n)...)
+	}
+
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+	if dashboardURL != "" {
+		card["actions"] = []interface{}{
+			map[string]string{"type": "Action.OpenUrl", "title": "Open http_monitor", "url": dashboardURL},
+		}
+	}
+	return card
+}
+
+func (t *teamsNotifier) notify(n notification) error {
+	return t.notifyGroup([]notification{n})
+}
+
+func (t *teamsNotifier) notifyGroup(ns []notification) error {
+	return postJSON(t.client, t.name(), t.webhook, nil, map[string]interface{}{
+		"type": "message",
+		"attachments": []interface{}{
+			map[string]interface{}{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content":     teamsCard(ns, t.dashboardURL),
+			},
+		},
+	})
+}
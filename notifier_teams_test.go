@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTeamsNotifier(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	value, threshold := 12.5, 10.0
+	n := notification{
+		Time:      time.Date(2018, 5, 9, 16, 0, 0, 0, time.UTC),
+		Name:      "High-traffic",
+		Firing:    true,
+		Severity:  "warning",
+		Detail:    "at 12.500000 queries per second on average",
+		Value:     &value,
+		Threshold: &threshold,
+	}
+	if err := newTeamsNotifier(server.URL, "https://monitor.example.com").notify(n); err != nil {
+		t.Fatal(err)
+	}
+
+	var message struct {
+		Attachments []struct {
+			ContentType string          `json:"contentType"`
+			Content     json.RawMessage `json:"content"`
+		} `json:"attachments"`
+	}
+	if err := json.Unmarshal([]byte(body), &message); err != nil {
+		t.Fatal(err)
+	}
+	if len(message.Attachments) != 1 || message.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("Unexpected message: %s", body)
+	}
+	card := string(message.Attachments[0].Content)
+	for _, expected := range []string{
+		`"text":"High-traffic alert is firing"`,
+		`"color":"warning"`,
+		`{"title":"Value","value":"12.50"}`,
+		`{"title":"Threshold","value":"10"}`,
+		`"url":"https://monitor.example.com"`,
+	} {
+		if !strings.Contains(card, expected) {
+			t.Errorf("%q not in %q", expected, card)
+		}
+	}
+}
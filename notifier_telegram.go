@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Chat IDs alerts are sent to, by rule name, e.g. High-traffic=-1001234
+type ruleChats map[string]string
+
+func (c ruleChats) String() string {
+	var pairs []string
+	for name, chat := range c {
+		pairs = append(pairs, name+"="+chat)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+func (c ruleChats) reset() {
+	for name := range c {
+		delete(c, name)
+	}
+}
+
+func (c ruleChats) Set(value string) error {
+	// Rule names may contain equal signs, e.g. in section patterns
+	i := strings.LastIndexByte(value, '=')
+	if i <= 0 || i == len(value)-1 {
+		return fmt.Errorf("Expected rule=chat: %s", value)
+	}
+	c[value[:i]] = value[i+1:]
+	return nil
+}
+
+// Chat alerts of a rule are sent to: the one given for the rule itself,
+// or for every rule of its kind (e.g. Section-traffic), if any
+func (c ruleChats) chat(name, fallback string) string {
+	if chat, ok := c[name]; ok {
+		return chat
+	}
+	if i := strings.Index(name, " ("); i > 0 {
+		if chat, ok := c[name[:i]]; ok {
+			return chat
+		}
+	}
+	return fallback
+}
+
+// Command-line flags to send alerts through a Telegram bot
+var telegramToken = flag.String("telegram-token", "", "Telegram bot token, to send alert messages through the Telegram Bot API")
+var telegramChatID = flag.String("telegram-chat-id", "", "Telegram chat alerts are sent to, unless routed elsewhere by -telegram-rule-chat")
+var telegramAPIURL = flag.String("telegram-api-url", "https://api.telegram.org", "Telegram Bot API endpoint")
+var telegramRuleChats = ruleChats{}
+var telegramSeverity = severityWarning
+
+func init() {
+	flag.Var(telegramRuleChats, "telegram-rule-chat", "Telegram chat the alerts of a rule, or of every rule of a kind, are sent to, e.g. Section-traffic=-1001234 (repeatable)")
+	flag.Var(&telegramSeverity, "telegram-severity", "Least severity of alerts sent to Telegram: warning or critical")
+}
+
+// Notifier sending messages through the Telegram Bot API
+type telegramNotifier struct {
+	url          string // Of the sendMessage method
+	chat         string // Default chat
+	chats        ruleChats
+	dashboardURL string // Linked from messages, if set
+	client       *http.Client
+}
+
+func newTelegramNotifier(apiURL, token, chat string, chats ruleChats, dashboardURL string) (*telegramNotifier, error) {
+	if chat == "" && len(chats) == 0 {
+		return nil, fmt.Errorf("Telegram notifications require -telegram-chat-id or -telegram-rule-chat")
+	}
+	return &telegramNotifier{
+		url:          strings.TrimSuffix(apiURL, "/") + "/bot" + token + "/sendMessage",
+		chat:         chat,
+		chats:        chats,
+		dashboardURL: dashboardURL,
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (t *telegramNotifier) name() string {
+	return "Telegram"
+}
+
+// HTML message describing an alert change
+func telegramMessage(n notification, dashboardURL string) string {
+	var text strings.Builder
+	if n.Firing {
+		fmt.Fprintf(&text, "🔥 <b>%s alert is firing</b>", html.EscapeString(n.Name))
+		if n.Severity != "" {
+			fmt.Fprintf(&text, " (%s)", n.Severity)
+		}
+	} else {
+		fmt.Fprintf(&text, "✅ <b>%s alert resolved</b>", html.EscapeString(n.Name))
+	}
+	if detail := n.text(); detail != "" {
+		fmt.Fprintf(&text, "\n%s", html.EscapeString(detail))
+	}
+	if n.Value != nil {
+		fmt.Fprintf(&text, "\nValue: %.2f (threshold %g)", *n.Value, *n.Threshold)
+	}
+	if dashboardURL != "" {
+		fmt.Fprintf(&text, "\n<a href=\"%s\">Open http_monitor</a>", html.EscapeString(dashboardURL))
+	}
+	return text.String()
+}
+
+func (t *telegramNotifier) notify(n notification) error {
+	return t.notifyGroup([]notification{n})
+}
+
+// Send a single message to each chat alerts are routed to
+func (t *telegramNotifier) notifyGroup(ns []notification) error {
+	var chats []string
+	messages := make(map[string][]string)
+	for _, n := range ns {
+		chat := t.chats.chat(n.Name, t.chat)
+		if chat == "" {
+			// Neither routed nor sent to a default chat
+			continue
+		}
+		if messages[chat] == nil {
+			chats = append(chats, chat)
+		}
+		messages[chat] = append(messages[chat], telegramMessage(n, t.dashboardURL))
+	}
+
+	for _, chat := range chats {
+		if err := postJSON(t.client, t.name(), t.url, nil, map[string]interface{}{
+			"chat_id":                  chat,
+			"text":                     strings.Join(messages[chat], "\n\n"),
+			"parse_mode":               "HTML",
+			"disable_web_page_preview": true,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTelegramNotifier(t *testing.T) {
+	type message struct {
+		ChatID    string `json:"chat_id"`
+		Text      string `json:"text"`
+		ParseMode string `json:"parse_mode"`
+	}
+	var messages []message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/botsecret/sendMessage" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var m message
+		json.NewDecoder(r.Body).Decode(&m)
+		messages = append(messages, m)
+	}))
+	defer server.Close()
+
+	chats := ruleChats{}
+	chats.Set("Section-traffic=-100")
+	chats.Set("Scanning=-200")
+	tg, err := newTelegramNotifier(server.URL, "secret", "42", chats, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []notification{
+		{Time: time.Now(), Name: "High-traffic", Firing: true, Severity: "critical", Detail: "at 30.000000 queries per second on average"},
+		{Time: time.Now(), Name: "Section-traffic (^/checkout)", Firing: true, Detail: "at 6.000000 queries per second on average"},
+		{Time: time.Now(), Name: "Scanning"},
+	} {
+		if err := tg.notify(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []message{
+		{"42", "🔥 <b>High-traffic alert is firing</b> (critical)\nat 30.000000 queries per second on average", "HTML"},
+		{"-100", "🔥 <b>Section-traffic (^/checkout) alert is firing</b>\nat 6.000000 queries per second on average", "HTML"},
+		{"-200", "✅ <b>Scanning alert resolved</b>", "HTML"},
+	}
+	if len(messages) != len(expected) {
+		t.Fatalf("%+v != %+v", messages, expected)
+	}
+	for i := range expected {
+		if messages[i] != expected[i] {
+			t.Errorf("%+v != %+v", messages[i], expected[i])
+		}
+	}
+
+	if _, err := newTelegramNotifier(server.URL, "secret", "", ruleChats{}, ""); err == nil {
+		t.Errorf("Expected an error without any chat")
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Command-line flag to customize the text of notifications
+var notifyTemplate = flag.String("notify-template", "", "File holding a Go template for the text of notifications, given the alert's .Name, .State, .Severity, .Detail, .Value, .Threshold, .TopSections, .TopIPs and .URL")
+
+// Fields available to notification templates
+type messageData struct {
+	Time        time.Time
+	Name        string
+	Firing      bool
+	State       string // "firing" or "resolved"
+	Severity    string
+	Detail      string
+	Measured    bool // Whether Value and Threshold are set
+	Value       float64
+	Threshold   float64
+	TopSections []apiCount // Sections with the most requests when the alert changed
+	TopIPs      []apiCount // Client IPs with the most requests when the alert changed
+	URL         string     // Where http_monitor is reachable, from -external-url
+}
+
+// Parse the notification template in the given file
+func parseMessageTemplate(path string) (*template.Template, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := template.New("notification").Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid notification template %s: %s", path, err)
+	}
+	return t, nil
+}
+
+// Text of a notification, as given by a template
+func renderMessage(t *template.Template, n notification, dashboardURL string) (string, error) {
+	data := messageData{
+		Time:        displayTime(n.Time),
+		Name:        n.Name,
+		Firing:      n.Firing,
+		State:       n.state(),
+		Severity:    n.Severity,
+		Detail:      n.Detail,
+		TopSections: n.TopSections,
+		TopIPs:      n.TopIPs,
+		URL:         dashboardURL,
+	}
+	if n.Value != nil {
+		data.Measured, data.Value, data.Threshold = true, *n.Value, *n.Threshold
+	}
+	var text strings.Builder
+	if err := t.Execute(&text, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(text.String()), nil
+}
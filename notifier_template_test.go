@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRenderMessage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notification.tmpl")
+	text := `{{.Name}} is {{.State}}{{if .Measured}} at {{printf "%.1f" .Value}}/{{.Threshold}} QPS{{end}}
+{{range .TopSections}}{{.Key}}={{.Count}} {{end}}
+{{range .TopIPs}}{{.Key}} {{end}}{{.URL}}`
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := parseMessageTemplate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, threshold := 25.25, 10.0
+	for _, test := range []struct {
+		n        notification
+		expected string
+	}{
+		{
+			notification{
+				Time: time.Now(), Name: "High-traffic", Firing: true, Value: &value, Threshold: &threshold,
+				TopSections: []apiCount{{Key: "/api", Count: 30}, {Key: "/static", Count: 12}},
+				TopIPs:      []apiCount{{Key: "10.0.0.1", Count: 20}},
+			},
+			"High-traffic is firing at 25.2/10 QPS\n/api=30 /static=12 \n10.0.0.1 https://monitor.example.com",
+		},
+		{
+			notification{Time: time.Now(), Name: "Scanning"},
+			"Scanning is resolved\n\nhttps://monitor.example.com",
+		},
+	} {
+		message, err := renderMessage(tmpl, test.n, "https://monitor.example.com")
+		if err != nil || message != test.expected {
+			t.Errorf("%q != %q (%v)", message, test.expected, err)
+		}
+	}
+}
+
+func TestParseMessageTemplateError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notification.tmpl")
+	if err := os.WriteFile(path, []byte("{{.Name"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseMessageTemplate(path); err == nil {
+		t.Errorf("Expected an error for an invalid template")
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+// Command-line flag to link notifications back to http_monitor
+var externalURL = flag.String("external-url", "", "URL http_monitor's HTTP listener is reachable at, linked from notifications, e.g. https://monitor.example.com")
+
+// Command-line flag to group alert changes into fewer notifications
+var notifyGroupInterval = flag.Duration("notify-group-interval", 0, "Time to wait for further alert changes after one, to send them all in a single notification where supported, e.g. 30s (0 disables grouping)")
+
+// Destination of alert changes, e.g. a command or a paging service
+type notifier interface {
+	// Name of the notifier, as shown in error messages
+	name() string
+	notify(n notification) error
+}
+
+// Notifier able to send several alert changes at once, e.g. as a single
+// chat message
+type groupingNotifier interface {
+	notifier
+	notifyGroup(ns []notification) error
+}
+
+// Alert change, as handed to notifiers
+type notification struct {
+	Time     time.Time `json:"time"`
+	Name     string    `json:"name"`
+	Firing   bool      `json:"firing"`
+	Severity string    `json:"severity,omitempty"`
+	Detail   string    `json:"detail,omitempty"`
+
+	// Value compared against the threshold, for rules measuring one
+	Value     *float64 `json:"value,omitempty"`
+	Threshold *float64 `json:"threshold,omitempty"`
+
+	TopSections []apiCount `json:"top_sections,omitempty"`
+	TopIPs      []apiCount `json:"top_ips,omitempty"`
+
+	// Text given by -notify-template, if any
+	Message string `json:"message,omitempty"`
+}
+
+// Notification of an alert change happening now
+func newNotification(change alertChange) notification {
+	n := notification{
+		Time:        time.Now(),
+		Name:        change.Name,
+		Firing:      change.Firing,
+		Severity:    change.Severity.String(),
+		Detail:      change.Detail,
+		TopSections: change.TopSections,
+		TopIPs:      change.TopIPs,
+	}
+	if change.Measured {
+		n.Value, n.Threshold = &change.Value, &change.Threshold
+	}
+	return n
+}
+
+// State of the alert, i.e. "firing" or "resolved"
+func (n notification) state() string {
+	if n.Firing {
+		return "firing"
+	}
+	return "resolved"
+}
+
+// Text describing the alert: the templated message, if any, or else the
+// rule's own detail
+func (n notification) text() string {
+	if n.Message != "" {
+		return n.Message
+	}
+	return n.Detail
+}
+
+func (l *severity) Set(value string) error {
+	switch value {
+	case "warning":
+		*l = severityWarning
+	case "critical":
+		*l = severityCritical
+	default:
+		return fmt.Errorf("Unknown severity: %s", value)
+	}
+	return nil
+}
+
+// Number of alert changes waiting for delivery beyond which new ones are
+// dropped
+const notificationBacklog = 64
+
+// Delivers alert changes of at least some severity to a notifier. Delivery
+// happens in the background, in order, so that slow notifiers do not hold
+// up alerting. Changes may be grouped, waiting for further ones for a while
+// after each
+type notifierQueue struct {
+	notifier      notifier
+	minSeverity   severity
+	groupInterval time.Duration          // 0 if changes are delivered right away
+	template      *template.Template     // Renders the text of notifications, if set
+	notified      map[string]bool        // Alerts whose triggering was delivered
+	held          map[string]alertChange // Alerts which started firing while silenced
+	changes       chan notification
+	done          chan struct{}
+}
+
+func newNotifierQueue(n notifier, minSeverity severity, groupInterval time.Duration) *notifierQueue {
+	q := &notifierQueue{
+		notifier:      n,
+		minSeverity:   minSeverity,
+		groupInterval: groupInterval,
+		notified:      make(map[string]bool),
+		held:          make(map[string]alertChange),
+		changes:       make(chan notification, notificationBacklog),
+		done:          make(chan struct{}),
+	}
+	go func() {
+		defer close(q.done)
+		for n := range q.changes {
+			q.deliver(q.collect(n))
+		}
+	}()
+	return q
+}
+
+// Changes arriving within the group interval after the given one
+func (q *notifierQueue) collect(n notification) []notification {
+	group := []notification{n}
+	if q.groupInterval <= 0 {
+		return group
+	}
+	timer := time.NewTimer(q.groupInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case n, ok := <-q.changes:
+			if !ok {
+				return group
+			}
+			group = append(group, n)
+		case <-timer.C:
+			return group
+		}
+	}
+}
+
+// Hand over a group of changes, as a whole if the notifier supports it.
+// Alerts firing again later in the group, e.g. after escalating, are only
+// notified of once
+func (q *notifierQueue) deliver(group []notification) {
+	var deduplicated []notification
+	for i, n := range group {
+		superseded := false
+		for _, later := range group[i+1:] {
+			superseded = superseded || (n.Firing && later.Firing && later.Name == n.Name)
+		}
+		if !superseded {
+			deduplicated = append(deduplicated, n)
+		}
+	}
+
+	if grouping, ok := q.notifier.(groupingNotifier); ok && len(deduplicated) > 1 {
+		if err := grouping.notifyGroup(deduplicated); err != nil {
+			log.Printf("Cannot notify %s of %d alerts: %s", q.notifier.name(), len(deduplicated), err)
+		}
+		return
+	}
+	for _, n := range deduplicated {
+		if err := q.notifier.notify(n); err != nil {
+			log.Printf("Cannot notify %s of %s alert: %s", q.notifier.name(), n.Name, err)
+		}
+	}
+}
+
+// Queue an alert change, unless it is not severe enough or silenced, in
+// which case it is held until the silence is over. Alerts without a
+// critical threshold are always severe enough, and abandoned alerts are
+// only delivered if their triggering was
+func (q *notifierQueue) alertChanged(change alertChange) {
+	q.queue(change, time.Now())
+}
+
+// Queue an alert change happening, or released, at some time
+func (q *notifierQueue) queue(change alertChange, now time.Time) {
+	_, wasHeld := q.held[change.Name]
+	delete(q.held, change.Name)
+	if change.Firing && change.Severity != 0 && change.Severity < q.minSeverity {
+		return
+	}
+	if change.Firing && silenced(change.Name, now) {
+		if !wasHeld {
+			log.Printf("Not notifying %s of silenced %s alert", q.notifier.name(), change.Name)
+		}
+		q.held[change.Name] = change
+		return
+	}
+	if !change.Firing && !q.notified[change.Name] {
+		return
+	}
+	q.notified[change.Name] = change.Firing
+
+	n := newNotification(change)
+	if q.template != nil {
+		message, err := renderMessage(q.template, n, *externalURL)
+		if err != nil {
+			log.Printf("Cannot render %s alert notification to %s: %s", change.Name, q.notifier.name(), err)
+		}
+		n.Message = message
+	}
+	select {
+	case q.changes <- n:
+	default:
+		log.Printf("Dropping %s alert notification to %s: too many pending", change.Name, q.notifier.name())
+	}
+}
+
+// Notify of alerts which started firing while silenced and still are, once
+// their silence is over
+func (q *notifierQueue) releaseSilenced(now time.Time) {
+	for name, change := range q.held {
+		if !silenced(name, now) {
+			q.queue(change, now)
+		}
+	}
+}
+
+// Carry over the alerts whose triggering previous queues delivered, or
+// held back, to the queues of the same notifiers, so that their abandonment
+// is delivered after a reload
+func inheritNotified(queues, previous []*notifierQueue) {
+	for _, q := range queues {
+		for _, p := range previous {
+			if p.notifier.name() != q.notifier.name() {
+				continue
+			}
+			for name, firing := range p.notified {
+				q.notified[name] = firing
+			}
+			for name, change := range p.held {
+				q.held[name] = change
+			}
+		}
+	}
+}
+
+// Deliver pending changes, then stop
+func (q *notifierQueue) close() {
+	close(q.changes)
+	<-q.done
+}
+
+// Build the notifiers enabled through command-line flags
+func configuredNotifiers() ([]*notifierQueue, error) {
+	var notifiers []notifier
+	var severities []severity
+
+	if *execCommand != "" {
+		notifiers = append(notifiers, &execNotifier{command: *execCommand, timeout: *execTimeout})
+		severities = append(severities, execSeverity)
+	}
+
+	if *snmpTarget != "" {
+		snmp, err := newSNMPNotifier()
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, snmp)
+		severities = append(severities, snmpSeverity)
+	}
+
+	if *opsgenieAPIKey != "" {
+		notifiers = append(notifiers, newOpsGenieNotifier(*opsgenieURL, *opsgenieAPIKey, *opsgenieTags))
+		severities = append(severities, opsgenieSeverity)
+	}
+
+	if *teamsWebhook != "" {
+		notifiers = append(notifiers, newTeamsNotifier(*teamsWebhook, *externalURL))
+		severities = append(severities, teamsSeverity)
+	}
+
+	if *discordWebhook != "" {
+		notifiers = append(notifiers, newDiscordNotifier(*discordWebhook, *externalURL))
+		severities = append(severities, discordSeverity)
+	}
+
+	if *telegramToken != "" {
+		telegram, err := newTelegramNotifier(*telegramAPIURL, *telegramToken, *telegramChatID, telegramRuleChats, *externalURL)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, telegram)
+		severities = append(severities, telegramSeverity)
+	}
+
+	var messageTemplate *template.Template
+	if *notifyTemplate != "" && len(notifiers) > 0 {
+		var err error
+		if messageTemplate, err = parseMessageTemplate(*notifyTemplate); err != nil {
+			return nil, err
+		}
+	}
+
+	// Queues are only started once every notifier is valid
+	var queues []*notifierQueue
+	for i, n := range notifiers {
+		q := newNotifierQueue(n, severities[i], *notifyGroupInterval)
+		q.template = messageTemplate
+		queues = append(queues, q)
+	}
+	return queues, nil
+}
+
+// Stop the given notifiers, once pending changes are delivered
+func closeNotifiers(notifiers []*notifierQueue) {
+	for _, n := range notifiers {
+		n.close()
+	}
+}
+
+// Post a JSON payload to the API of some service
+func postJSON(client *http.Client, service, url string, header http.Header, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s replied with %s", service, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Notifier recording what it was given
+type recordingNotifier struct {
+	notifications []notification
+}
+
+func (r *recordingNotifier) name() string { return "recording" }
+
+func (r *recordingNotifier) notify(n notification) error {
+	r.notifications = append(r.notifications, n)
+	return nil
+}
+
+func TestNotifierQueueSeverity(t *testing.T) {
+	recorder := &recordingNotifier{}
+	q := newNotifierQueue(recorder, severityCritical, 0)
+	for _, change := range []alertChange{
+		{Name: "High-traffic", Firing: true, Severity: severityWarning},
+		{Name: "High-traffic", Firing: false},
+		{Name: "Scanning", Firing: true},
+		{Name: "High-traffic", Firing: true, Severity: severityWarning},
+		{Name: "High-traffic", Firing: true, Severity: severityCritical},
+		{Name: "High-traffic", Firing: true, Severity: severityWarning},
+		{Name: "High-traffic", Firing: false},
+	} {
+		q.alertChanged(change)
+	}
+	q.close()
+
+	var delivered []string
+	for _, n := range recorder.notifications {
+		delivered = append(delivered, n.Name+" "+n.state()+" "+n.Severity)
+	}
+	expected := []string{"Scanning firing ", "High-traffic firing critical", "High-traffic resolved "}
+	if !reflect.DeepEqual(delivered, expected) {
+		t.Errorf("%+v != %+v", delivered, expected)
+	}
+}
+
+func TestSeveritySet(t *testing.T) {
+	var l severity
+	for value, expected := range map[string]severity{"warning": severityWarning, "critical": severityCritical} {
+		if err := l.Set(value); err != nil || l != expected {
+			t.Errorf("%+v != %+v (%v)", l, expected, err)
+		}
+	}
+	if err := l.Set("page"); err == nil {
+		t.Errorf("Expected an error for an unknown severity")
+	}
+}
+
+type groupingRecorder struct {
+	recordingNotifier
+	groups [][]string
+}
+
+func (r *groupingRecorder) notifyGroup(ns []notification) error {
+	var group []string
+	for _, n := range ns {
+		group = append(group, n.Name+" "+n.state()+" "+n.Severity)
+	}
+	r.groups = append(r.groups, group)
+	return nil
+}
+
+func TestNotifierQueueGrouping(t *testing.T) {
+	recorder := &groupingRecorder{}
+	q := newNotifierQueue(recorder, 0, time.Hour)
+	for _, change := range []alertChange{
+		{Name: "High-traffic", Firing: true, Severity: severityWarning},
+		{Name: "Scanning", Firing: true},
+		{Name: "High-traffic", Firing: true, Severity: severityCritical},
+		{Name: "Scanning", Firing: false},
+	} {
+		q.alertChanged(change)
+	}
+	// Closing the queue flushes the pending group
+	q.close()
+
+	expected := [][]string{{"Scanning firing ", "High-traffic firing critical", "Scanning resolved "}}
+	if !reflect.DeepEqual(recorder.groups, expected) {
+		t.Errorf("%+v != %+v", recorder.groups, expected)
+	}
+	if len(recorder.notifications) != 0 {
+		t.Errorf("%+v != []", recorder.notifications)
+	}
+}
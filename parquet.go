@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+)
+
+// Type codes of the Thrift compact protocol Parquet metadata is written in
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// Encoder of Thrift structs using the compact protocol
+type thriftWriter struct {
+	bytes.Buffer
+	fields []int16 // Id of the last field written, for every struct being written
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	t.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (t *thriftWriter) zigzag(v int64) {
+	t.varint(uint64(v<<1 ^ v>>63))
+}
+
+func (t *thriftWriter) fieldHeader(id int16, kind byte) {
+	last := &t.fields[len(t.fields)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.WriteByte(byte(delta)<<4 | kind)
+	} else {
+		t.WriteByte(kind)
+		t.zigzag(int64(id))
+	}
+	*last = id
+}
+
+// Start a struct, written as is when an element of a list
+func (t *thriftWriter) begin() {
+	t.fields = append(t.fields, 0)
+}
+
+// Start a struct written as a field of the current one
+func (t *thriftWriter) beginField(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.begin()
+}
+
+func (t *thriftWriter) end() {
+	t.WriteByte(0)
+	t.fields = t.fields[:len(t.fields)-1]
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.fieldHeader(id, thriftBinary)
+	t.varint(uint64(len(s)))
+	t.WriteString(s)
+}
+
+// Start a list field, whose elements are written next
+func (t *thriftWriter) list(id int16, kind byte, n int) {
+	t.fieldHeader(id, thriftList)
+	if n < 15 {
+		t.WriteByte(byte(n)<<4 | kind)
+	} else {
+		t.WriteByte(0xf0 | kind)
+		t.varint(uint64(n))
+	}
+}
+
+// Parquet physical types, converted types, encodings and codecs used
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+
+	parquetPlain = 0
+	parquetRLE   = 3
+	parquetGzip  = 2
+)
+
+var parquetMagic = []byte("PAR1")
+
+// Physical and converted type of the Parquet columns of each kind of field,
+// the latter being -1 when there is none
+func parquetType(kind fieldKind) (int32, int32) {
+	switch kind {
+	case stringField:
+		return parquetByteArray, parquetUTF8
+	case intField:
+		return parquetInt64, -1
+	case floatField:
+		return parquetDouble, -1
+	case boolField:
+		return parquetBoolean, -1
+	default:
+		return parquetInt64, parquetTimestampMillis
+	}
+}
+
+// Records buffered in memory as PLAIN-encoded columns, all required, until
+// written out as a Parquet file of a single row group
+type parquetBuffer struct {
+	fields  []recordField
+	columns []bytes.Buffer // Booleans are held as a byte each, until bit-packed
+	rows    int
+}
+
+func newParquetBuffer(fields []recordField) *parquetBuffer {
+	return &parquetBuffer{fields: fields, columns: make([]bytes.Buffer, len(fields))}
+}
+
+func (p *parquetBuffer) add(r *logRecord) {
+	var b [8]byte
+	for n, field := range p.fields {
+		column := &p.columns[n]
+		switch v := field.value(r).(type) {
+		case string:
+			binary.LittleEndian.PutUint32(b[:], uint32(len(v)))
+			column.Write(b[:4])
+			column.WriteString(v)
+		case int64:
+			binary.LittleEndian.PutUint64(b[:], uint64(v))
+			column.Write(b[:])
+		case float64:
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+			column.Write(b[:])
+		case bool:
+			if v {
+				column.WriteByte(1)
+			} else {
+				column.WriteByte(0)
+			}
+		case time.Time:
+			binary.LittleEndian.PutUint64(b[:], uint64(v.UnixMilli()))
+			column.Write(b[:])
+		}
+	}
+	p.rows++
+}
+
+// Size of the buffered values, roughly that of the file before compression
+func (p *parquetBuffer) size() int {
+	size := 0
+	for n := range p.columns {
+		size += p.columns[n].Len()
+	}
+	return size
+}
+
+// Values of a column as stored in its page
+func (p *parquetBuffer) pageData(n int) []byte {
+	values := p.columns[n].Bytes()
+	if p.fields[n].kind != boolField {
+		return values
+	}
+	packed := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		packed[i/8] |= v << uint(i%8)
+	}
+	return packed
+}
+
+// Write the buffered records as a Parquet file, with a gzipped data page
+// for every column
+func (p *parquetBuffer) writeTo(w io.Writer) error {
+	var file bytes.Buffer
+	file.Write(parquetMagic)
+
+	offsets := make([]int64, len(p.fields))
+	sizes := make([][2]int64, len(p.fields)) // Uncompressed and compressed
+	for n := range p.fields {
+		data := p.pageData(n)
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		if _, err := gz.Write(data); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+
+		header := &thriftWriter{}
+		header.begin()
+		header.i32(1, 0) // Data page
+		header.i32(2, int32(len(data)))
+		header.i32(3, int32(compressed.Len()))
+		header.beginField(5)
+		header.i32(1, int32(p.rows))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.end()
+		header.end()
+
+		offsets[n] = int64(file.Len())
+		sizes[n] = [2]int64{int64(header.Len() + len(data)), int64(header.Len() + compressed.Len())}
+		file.Write(header.Bytes())
+		file.Write(compressed.Bytes())
+	}
+
+	meta := &thriftWriter{}
+	meta.begin()
+	meta.i32(1, 1) // Version
+	meta.list(2, thriftStruct, len(p.fields)+1)
+	meta.begin()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(p.fields)))
+	meta.end()
+	for _, field := range p.fields {
+		physical, converted := parquetType(field.kind)
+		meta.begin()
+		meta.i32(1, physical)
+		meta.i32(3, 0) // Required
+		meta.binary(4, field.name)
+		if converted >= 0 {
+			meta.i32(6, converted)
+		}
+		meta.end()
+	}
+	meta.i64(3, int64(p.rows))
+	meta.list(4, thriftStruct, 1)
+	meta.begin()
+	meta.list(1, thriftStruct, len(p.fields))
+	var total int64
+	for n, field := range p.fields {
+		physical, _ := parquetType(field.kind)
+		meta.begin()
+		meta.i64(2, offsets[n])
+		meta.beginField(3)
+		meta.i32(1, physical)
+		meta.list(2, thriftI32, 1)
+		meta.zigzag(parquetPlain)
+		meta.list(3, thriftBinary, 1)
+		meta.varint(uint64(len(field.name)))
+		meta.WriteString(field.name)
+		meta.i32(4, parquetGzip)
+		meta.i64(5, int64(p.rows))
+		meta.i64(6, sizes[n][0])
+		meta.i64(7, sizes[n][1])
+		meta.i64(9, offsets[n])
+		meta.end()
+		meta.end()
+		total += sizes[n][0]
+	}
+	meta.i64(2, total)
+	meta.i64(3, int64(p.rows))
+	meta.end()
+	meta.binary(6, "http_monitor")
+	meta.end()
+
+	file.Write(meta.Bytes())
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(meta.Len()))
+	file.Write(length[:])
+	file.Write(parquetMagic)
+	_, err := w.Write(file.Bytes())
+	return err
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestThriftWriter(t *testing.T) {
+	w := &thriftWriter{}
+	w.begin()
+	w.i32(1, 1)
+	w.binary(4, "ab")
+	w.i64(20, -1) // Too far from the previous field for a short header
+	w.list(21, thriftI32, 2)
+	w.zigzag(0)
+	w.zigzag(3)
+	w.beginField(22)
+	w.i32(1, -2)
+	w.end()
+	w.end()
+
+	expected := []byte{
+		0x15, 0x02,
+		0x38, 0x02, 'a', 'b',
+		0x06, 0x28, 0x01,
+		0x19, 0x25, 0x00, 0x06,
+		0x1c, 0x15, 0x03, 0x00,
+		0x00,
+	}
+	if !bytes.Equal(w.Bytes(), expected) {
+		t.Errorf("% x != % x", w.Bytes(), expected)
+	}
+}
+
+func TestParquetBuffer(t *testing.T) {
+	fields := []recordField{recordFields[0], recordFields[1], recordFields[7], recordFields[12]}
+	p := newParquetBuffer(fields)
+	at := time.Date(2018, 5, 9, 16, 0, 39, 0, time.UTC)
+	for n := 0; n < 9; n++ {
+		p.add(&logRecord{Timestamp: at, IP: "10.0.0.1", StatusCode: 200, Bot: n%2 == 0})
+	}
+	if p.rows != 9 || p.size() != 9*8+9*12+9*8+9 {
+		t.Errorf("Unexpected buffer of %d rows, %d bytes", p.rows, p.size())
+	}
+	if packed := p.pageData(3); !bytes.Equal(packed, []byte{0x55, 0x01}) {
+		t.Errorf("% x != 55 01", packed)
+	}
+
+	var out bytes.Buffer
+	if err := p.writeTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	data := out.Bytes()
+	if !bytes.HasPrefix(data, parquetMagic) || !bytes.HasSuffix(data, parquetMagic) {
+		t.Fatalf("Missing magic numbers: % x", data)
+	}
+	footer := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	meta := data[len(data)-8-footer : len(data)-8]
+	for _, name := range []string{"time", "ip", "status", "bot", "http_monitor"} {
+		if !bytes.Contains(meta, []byte(name)) {
+			t.Errorf("Expected %s in file metadata % x", name, meta)
+		}
+	}
+	// Version 1, then a schema of 5 elements, the root included
+	if !bytes.HasPrefix(meta, []byte{0x15, 0x02, 0x19, 0x5c}) {
+		t.Errorf("Unexpected file metadata % x", meta)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+)
+
+// Protocol versions as logged by proxies and servers, mapped to the way
+// request lines name them: ALPN identifiers (h2, h3) and HTTP/2 or HTTP/3
+// with a minor version
+var protocolAliases = map[string]string{
+	"h2":       "HTTP/2",
+	"h2c":      "HTTP/2",
+	"h3":       "HTTP/3",
+	"HTTP/2.0": "HTTP/2",
+	"HTTP/3.0": "HTTP/3",
+	"QUIC":     "HTTP/3",
+}
+
+// Canonical name of a protocol version, e.g. HTTP/2 for h2 or HTTP/2.0
+func canonicalProtocol(protocol string) string {
+	upper := strings.ToUpper(protocol)
+	if alias, ok := protocolAliases[upper]; ok {
+		return alias
+	}
+	if alias, ok := protocolAliases[strings.ToLower(protocol)]; ok {
+		return alias
+	}
+	if strings.HasPrefix(upper, "HTTP/") {
+		return upper
+	}
+	return protocol
+}
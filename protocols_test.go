@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalProtocol(t *testing.T) {
+	tests := []struct {
+		protocol string
+		expected string
+	}{
+		{"", ""},
+		{"HTTP/1.1", "HTTP/1.1"},
+		{"http/1.0", "HTTP/1.0"},
+		{"HTTP/2.0", "HTTP/2"},
+		{"HTTP/2", "HTTP/2"},
+		{"h2", "HTTP/2"},
+		{"H3", "HTTP/3"},
+		{"HTTP/3.0", "HTTP/3"},
+		{"SPDY/3", "SPDY/3"},
+	}
+	for _, test := range tests {
+		if protocol := canonicalProtocol(test.protocol); protocol != test.expected {
+			t.Errorf("%q: %+v != %+v", test.protocol, protocol, test.expected)
+		}
+	}
+}
+
+func TestParseProtocolVersions(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{`127.0.0.1 - - [09/May/2018:16:00:39 +0000] "GET /report HTTP/1.1" 200 123`, "HTTP/1.1"},
+		{`127.0.0.1 - - [09/May/2018:16:00:39 +0000] "GET /report HTTP/2.0" 200 123`, "HTTP/2.0"},
+		{`127.0.0.1 - - [09/May/2018:16:00:39 +0000] "GET /report HTTP/3" 200 123`, "HTTP/3"},
+		{`127.0.0.1 - - [09/May/2018:16:00:39 +0000] "GET /report" 200 123`, ""},
+	}
+	for _, test := range tests {
+		for _, p := range []logParser{w3cParser{}, w3cParser{strict: true}} {
+			record, err := p.parse(test.line)
+			if err != nil {
+				t.Errorf("%q: %s", test.line, err)
+				continue
+			}
+			if record.Protocol != test.expected {
+				t.Errorf("%q: %+v != %+v", test.line, record.Protocol, test.expected)
+			}
+		}
+	}
+}
+
+func TestDumpProtocols(t *testing.T) {
+	s := newStats()
+	var out bytes.Buffer
+	s.updateStats(&logRecord{Section: "/", StatusCode: 200, Protocol: "HTTP/1.1"})
+	s.writeStats(&out)
+	if strings.Contains(out.String(), "Requests per protocol") {
+		t.Errorf("Expected no protocol mix for a single protocol:\n%s", out.String())
+	}
+
+	for _, protocol := range []string{"HTTP/2", "HTTP/2", "HTTP/3"} {
+		s.updateStats(&logRecord{Section: "/", StatusCode: 200, Protocol: protocol})
+	}
+	out.Reset()
+	s.writeStats(&out)
+	for _, expected := range []string{"Requests per protocol:", "1 HTTP/1.1", "2 HTTP/2", "1 HTTP/3"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Windows QPS is averaged over, as load averages are
+var rateWindows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+// Per-second request counters spanning the longest rate window
+type rateCounter struct {
+	buckets map[int64]int
+	first   time.Time // Timestamp of the first request seen
+	latest  time.Time // Timestamp of the latest request seen
+}
+
+func newRateCounter() *rateCounter {
+	return &rateCounter{buckets: make(map[int64]int)}
+}
+
+// Account for a request, forgetting about those out of every window
+func (r *rateCounter) add(t time.Time) {
+	r.buckets[t.Unix()]++
+	if r.first.IsZero() || t.Before(r.first) {
+		r.first = t
+	}
+	if t.Unix() > r.latest.Unix() {
+		oldest := t.Unix() - int64(rateWindows[len(rateWindows)-1].Seconds())
+		for second := range r.buckets {
+			if second <= oldest {
+				delete(r.buckets, second)
+			}
+		}
+	}
+	if t.After(r.latest) {
+		r.latest = t
+	}
+}
+
+// Average QPS over the window ending at the given time, or over the time
+// since the first request when shorter
+func (r *rateCounter) qps(now time.Time, window time.Duration) float64 {
+	if r.first.IsZero() {
+		return 0
+	}
+	span := window
+	if elapsed := now.Sub(r.first) + time.Second; elapsed < span {
+		span = elapsed
+	}
+	requests := 0
+	end := now.Unix()
+	start := end - int64(window.Seconds())
+	for second, count := range r.buckets {
+		if second > start && second <= end {
+			requests += count
+		}
+	}
+	return float64(requests) / span.Seconds()
+}
+
+// Rolling QPS averages over every rate window, as of the latest request
+// or, with a wall clock, as of now
+func (s *stats) qpsAverages() []float64 {
+	if s.rates == nil {
+		return nil
+	}
+	now := s.rates.latest
+	if *windowClock == "wall" {
+		now = time.Now()
+	}
+	var averages []float64
+	for _, window := range rateWindows {
+		averages = append(averages, s.rates.qps(now, window)*s.weight())
+	}
+	return averages
+}
+
+// Dump rolling QPS averages, once some request was seen
+func (s *stats) dumpRates(w io.Writer) {
+	if s.rates == nil || s.rates.first.IsZero() {
+		return
+	}
+	averages := s.qpsAverages()
+	fmt.Fprintf(w, "QPS averages (1m, 5m, 15m): %.2f %.2f %.2f\n", averages[0], averages[1], averages[2])
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRateCounter(t *testing.T) {
+	start := time.Date(2018, 5, 9, 16, 0, 0, 0, time.UTC)
+	r := newRateCounter()
+
+	// 10 QPS for 15 minutes, then 60 QPS for the last minute
+	for second := 0; second < 16*60; second++ {
+		qps := 10
+		if second >= 15*60 {
+			qps = 60
+		}
+		for i := 0; i < qps; i++ {
+			r.add(start.Add(time.Duration(second) * time.Second))
+		}
+	}
+	now := r.latest
+
+	tests := []struct {
+		window   time.Duration
+		expected float64
+	}{
+		{time.Minute, 60},
+		{5 * time.Minute, 20},
+		{15 * time.Minute, (14*60*10 + 60*60) / 900.0},
+	}
+	for _, test := range tests {
+		if qps := r.qps(now, test.window); math.Abs(qps-test.expected) > 0.01 {
+			t.Errorf("%s: %+v != %+v", test.window, qps, test.expected)
+		}
+	}
+	if len(r.buckets) > 15*60 {
+		t.Errorf("Expected buckets older than 15 minutes to be forgotten, got %d", len(r.buckets))
+	}
+
+	// Averaged over the time since the first request when shorter
+	r = newRateCounter()
+	for second := 0; second < 30; second++ {
+		r.add(start.Add(time.Duration(second) * time.Second))
+	}
+	if qps := r.qps(r.latest, 5*time.Minute); qps != 1 {
+		t.Errorf("%+v != %+v", qps, 1)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Command-line flags to resolve top client IPs to hostnames
+var resolveIPs = flag.Bool("resolve-ips", false, "Resolve top client IPs to hostnames in reports")
+var resolveConcurrency = flag.Int("resolve-concurrency", 4, "Maximum number of concurrent reverse DNS lookups")
+
+// How long resolved (or unresolvable) IPs are cached
+const reverseDNSTTL = time.Hour
+
+// Cached hostname of an IP
+type reverseDNSEntry struct {
+	host    string
+	expires time.Time
+}
+
+// Caching reverse DNS resolver. Lookups happen in the background, so
+// reports never block on slow DNS servers: hostnames show up in the first
+// report after they were resolved
+type reverseDNS struct {
+	mutex   sync.Mutex
+	cache   map[string]reverseDNSEntry
+	pending map[string]bool
+	limit   chan struct{} // Bounds the number of concurrent lookups
+
+	lookupAddr func(string) ([]string, error)
+}
+
+func newReverseDNS(concurrency int) *reverseDNS {
+	return &reverseDNS{
+		cache:      make(map[string]reverseDNSEntry),
+		pending:    make(map[string]bool),
+		limit:      make(chan struct{}, concurrency),
+		lookupAddr: net.LookupAddr,
+	}
+}
+
+// Hostname of an IP if already known, otherwise schedule its resolution and
+// return an empty string
+func (r *reverseDNS) name(ip string) string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if entry, ok := r.cache[ip]; ok && time.Now().Before(entry.expires) {
+		return entry.host
+	}
+	if !r.pending[ip] {
+		r.pending[ip] = true
+		go r.resolve(ip)
+	}
+	return ""
+}
+
+// Resolve an IP and cache the result, including failures
+func (r *reverseDNS) resolve(ip string) {
+	r.limit <- struct{}{}
+	names, err := r.lookupAddr(ip)
+	<-r.limit
+
+	var host string
+	if err == nil && len(names) > 0 {
+		host = strings.TrimSuffix(names[0], ".")
+	}
+
+	r.mutex.Lock()
+	r.cache[ip] = reverseDNSEntry{host: host, expires: time.Now().Add(reverseDNSTTL)}
+	delete(r.pending, ip)
+	r.mutex.Unlock()
+}
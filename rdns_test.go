@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Test hostnames are resolved in the background and cached
+func TestReverseDNS(t *testing.T) {
+	lookups := 0
+	r := newReverseDNS(1)
+	r.lookupAddr = func(ip string) ([]string, error) {
+		lookups++
+		if ip == "10.0.0.1" {
+			return []string{"crawler.example.com."}, nil
+		}
+		return nil, fmt.Errorf("no such host")
+	}
+
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		if host := r.name(ip); host != "" {
+			t.Errorf("Unexpected hostname %s before resolution", host)
+		}
+	}
+
+	// Wait for background lookups to complete
+	for i := 0; i < 100; i++ {
+		r.mutex.Lock()
+		pending := len(r.pending)
+		r.mutex.Unlock()
+		if pending == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if host := r.name("10.0.0.1"); host != "crawler.example.com" {
+		t.Errorf("Unexpected hostname %s", host)
+	}
+	if host := r.name("10.0.0.2"); host != "" {
+		t.Errorf("Unexpected hostname %s for unresolvable IP", host)
+	}
+	if lookups != 2 {
+		t.Errorf("Expected 2 lookups, got %d", lookups)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Types of the fields of exported records
+type fieldKind int
+
+const (
+	stringField fieldKind = iota
+	intField
+	floatField
+	boolField
+	timeField
+)
+
+// Field of the records handed over to record sinks, named the same by all
+type recordField struct {
+	name  string
+	kind  fieldKind
+	value func(r *logRecord) interface{} // string, int64, float64, bool or time.Time
+}
+
+// Fields of exported records, enrichments included
+var recordFields = []recordField{
+	{"time", timeField, func(r *logRecord) interface{} { return r.Timestamp }},
+	{"ip", stringField, func(r *logRecord) interface{} { return r.IP }},
+	{"user", stringField, func(r *logRecord) interface{} { return r.User }},
+	{"method", stringField, func(r *logRecord) interface{} { return r.Action }},
+	{"section", stringField, func(r *logRecord) interface{} { return r.Section }},
+	{"resource", stringField, func(r *logRecord) interface{} { return r.Resource }},
+	{"protocol", stringField, func(r *logRecord) interface{} { return r.Protocol }},
+	{"status", intField, func(r *logRecord) interface{} { return int64(r.StatusCode) }},
+	{"size", intField, func(r *logRecord) interface{} { return int64(r.Size) }},
+	{"latency_ms", floatField, func(r *logRecord) interface{} { return float64(r.Latency) / float64(time.Millisecond) }},
+	{"referrer", stringField, func(r *logRecord) interface{} { return r.Referrer }},
+	{"user_agent", stringField, func(r *logRecord) interface{} { return r.UserAgent }},
+	{"bot", boolField, func(r *logRecord) interface{} { return r.Bot }},
+	{"attack", stringField, func(r *logRecord) interface{} { return r.Attack }},
+	{"spam", boolField, func(r *logRecord) interface{} { return r.Spam }},
+	{"country", stringField, func(r *logRecord) interface{} { return r.Country }},
+	{"city", stringField, func(r *logRecord) interface{} { return r.City }},
+	{"group", stringField, func(r *logRecord) interface{} { return r.Group }},
+	{"vhost", stringField, func(r *logRecord) interface{} { return r.VHost }},
+	{"pod", stringField, func(r *logRecord) interface{} { return r.Pod }},
+	{"source", stringField, func(r *logRecord) interface{} { return r.Source }},
+	{"cache_status", stringField, func(r *logRecord) interface{} { return r.CacheStatus }},
+	{"tls_version", stringField, func(r *logRecord) interface{} { return r.TLSVersion }},
+	{"tls_cipher", stringField, func(r *logRecord) interface{} { return r.TLSCipher }},
+	{"received", timeField, func(r *logRecord) interface{} { return r.Received }},
+}
+
+// Record as a JSON document keyed by field name, times being formatted as
+// RFC 3339. Empty strings are left out
+func recordDocument(r *logRecord) map[string]interface{} {
+	doc := make(map[string]interface{}, len(recordFields))
+	for _, field := range recordFields {
+		switch v := field.value(r).(type) {
+		case string:
+			if v != "" {
+				doc[field.name] = v
+			}
+		case time.Time:
+			doc[field.name] = v.UTC().Format(time.RFC3339Nano)
+		default:
+			doc[field.name] = v
+		}
+	}
+	return doc
+}
+
+// Records held by sinks sending them when intervals close, bounded so that
+// an unavailable destination does not exhaust memory
+type recordBuffer struct {
+	limit int
+
+	mutex   sync.Mutex // Records are handed over while intervals are written
+	pending []*logRecord
+	dropped int // Records dropped since they were last taken, as the buffer was full
+}
+
+func (b *recordBuffer) add(r *logRecord) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.pending) >= b.limit {
+		b.dropped++
+		return
+	}
+	b.pending = append(b.pending, r)
+}
+
+// Take the records buffered so far, along with the number of those dropped
+func (b *recordBuffer) take() ([]*logRecord, int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	records, dropped := b.pending, b.dropped
+	b.pending, b.dropped = nil, 0
+	return records, dropped
+}
+
+// Put back records which could not be sent ahead of those received since,
+// dropping the newest ones beyond the limit
+func (b *recordBuffer) requeue(records []*logRecord, dropped int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.dropped += dropped
+	records = append(records, b.pending...)
+	if len(records) > b.limit {
+		b.dropped += len(records) - b.limit
+		records = records[:b.limit]
+	}
+	b.pending = records
+}
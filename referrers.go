@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Command-line flags to detect referrer spam
+var referrerSpamRequests = flag.Int("referrer-spam-requests", 0, "Alert on referrer domains sending at least this many requests in the alerting window, if they look like spam or exceed -referrer-spam-share (0 disables)")
+var referrerSpamShare = flag.Float64("referrer-spam-share", 20, "Percentage of the requests in the alerting window above which a single referrer domain is abnormal")
+var referrerSpamPatterns regexpList
+var referrerIgnore = stringSet{}
+
+func init() {
+	flag.Var(&referrerSpamPatterns, "referrer-spam-patterns", "Additional regular expression, or those in @file, matching spam referrer domains (repeatable)")
+	flag.Var(referrerIgnore, "referrer-ignore", "Comma-separated referrer domains never deemed abnormal, e.g. your own")
+}
+
+// Domains of well-known referrer spammers, and of sites advertising SEO,
+// gambling or pharmacy
+var knownReferrerSpamRegExp = regexp.MustCompile(`(?i)semalt|darodar|ilovevitaly|blackhatworth|hulfingtonpost|` +
+	`priceg\.com|econom\.co|o-o-6-o-o|4webmasters|traffic2money|trafficmonetize|get-free-traffic|` +
+	`buttons-for-(your-)?website|(free|social|simple|floating)-share-buttons|best-seo|success-seo|seo-?offer|` +
+	`\b(casino|viagra|cialis)`)
+
+// Lowercased domain of a referrer URL without any www. prefix, empty when
+// there is no referrer ("-")
+func referrerHost(referrer string) string {
+	if referrer == "" || referrer == "-" {
+		return ""
+	}
+	u, err := url.Parse(referrer)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// Whether a referrer domain matches known or configured spam patterns
+func isSpamReferrer(host string) bool {
+	return host != "" && (knownReferrerSpamRegExp.MatchString(host) || referrerSpamPatterns.match(host))
+}
+
+// Dumps the N referrer domains matching spam patterns with the most requests
+func (s *stats) dumpSpamReferrers(w *tabwriter.Writer, n int) {
+	if len(s.spamReferrers) > 0 {
+		dumpTopCounts(w, "spam referrers", s.scaled(s.spamReferrers), n)
+	}
+}
+
+// Alert firing when referrer domains send many requests while looking like
+// spam, or take an abnormal share of the traffic
+type referrerSpamRule struct {
+	requests int
+	share    float64 // In percent
+}
+
+func (r *referrerSpamRule) name() string {
+	return "Referrer-spam"
+}
+
+func (r *referrerSpamRule) evaluate(s *stats) (bool, string) {
+	spammers := r.spammers(s)
+	return len(spammers) > 0, fmt.Sprintf("from %s", strings.Join(spammers, ", "))
+}
+
+// Referrer domains looking like spam in the alerting window, sorted. Not
+// offenders as abuse rules have, since domains are not client IPs to ban
+func (r *referrerSpamRule) spammers(s *stats) []string {
+	counts := make(map[string]int)
+	for _, record := range s.logsInWindow {
+		host := referrerHost(record.Referrer)
+		if host == "" || referrerIgnore[host] || host == referrerHost("//"+record.VHost) {
+			// Self-referrals are regular navigation
+			continue
+		}
+		counts[host]++
+	}
+
+	var spammers []string
+	for host, count := range counts {
+		if float64(count)*s.weight() < float64(r.requests) {
+			continue
+		}
+		if isSpamReferrer(host) || float64(count)*100 > r.share*float64(len(s.logsInWindow)) {
+			spammers = append(spammers, host)
+		}
+	}
+	sort.Strings(spammers)
+	return spammers
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReferrerHost(t *testing.T) {
+	tests := []struct {
+		referrer string
+		expected string
+	}{
+		{"-", ""},
+		{"", ""},
+		{"not a url", ""},
+		{"https://www.Example.com/page?q=1", "example.com"},
+		{"http://semalt.semalt.com:8080/", "semalt.semalt.com"},
+	}
+	for _, test := range tests {
+		if host := referrerHost(test.referrer); host != test.expected {
+			t.Errorf("%q: %+v != %+v", test.referrer, host, test.expected)
+		}
+	}
+}
+
+func TestIsSpamReferrer(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected bool
+	}{
+		{"", false},
+		{"google.com", false},
+		{"semalt.semalt.com", true},
+		{"best-seo-offer.com", true},
+		{"online-casino.example", true},
+		{"buttons-for-your-website.com", true},
+		{"occasional.example", false},
+	}
+	for _, test := range tests {
+		if spam := isSpamReferrer(test.host); spam != test.expected {
+			t.Errorf("%q: %+v != %+v", test.host, spam, test.expected)
+		}
+	}
+}
+
+func TestReferrerSpamRule(t *testing.T) {
+	var rule alertRule = &referrerSpamRule{}
+	if _, ok := rule.(abuseRule); ok {
+		t.Errorf("Referrer domains would be banned as client IPs")
+	}
+
+	s := newStats()
+	ts := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+
+	// A spammer with few requests, a referrer taking half the traffic,
+	// self-referrals and a regular referrer
+	for i := 0; i < 10; i++ {
+		s.updateAlerting(&logRecord{IP: "10.0.0.1", Timestamp: ts, Referrer: "http://darodar.com/"})
+		for j := 0; j < 5; j++ {
+			s.updateAlerting(&logRecord{IP: "10.0.0.2", Timestamp: ts, Referrer: "http://hot-deals.example/"})
+		}
+		s.updateAlerting(&logRecord{IP: "10.0.0.3", Timestamp: ts, Referrer: "https://www.example.com/", VHost: "example.com"})
+		s.updateAlerting(&logRecord{IP: "10.0.0.3", Timestamp: ts, Referrer: "https://google.com/"})
+		s.updateAlerting(&logRecord{IP: "10.0.0.3", Timestamp: ts, Referrer: "-"})
+	}
+
+	r := &referrerSpamRule{requests: 10, share: 20}
+	firing, detail := r.evaluate(s)
+	if !firing {
+		t.Errorf("Expected referrer spam alert to fire")
+	}
+	if expected := "from darodar.com, hot-deals.example"; detail != expected {
+		t.Errorf("%q != %q", expected, detail)
+	}
+
+	r = &referrerSpamRule{requests: 20, share: 20}
+	if _, detail := r.evaluate(s); detail != "from hot-deals.example" {
+		t.Errorf("%q != %q", "from hot-deals.example", detail)
+	}
+
+	r = &referrerSpamRule{requests: 100, share: 20}
+	if firing, _ := r.evaluate(s); firing {
+		t.Errorf("Expected referrer spam alert not to fire")
+	}
+}
+
+func TestDumpSpamReferrers(t *testing.T) {
+	s := newStats()
+	for _, referrer := range []string{"http://darodar.com/", "http://darodar.com/x", "https://google.com/"} {
+		s.updateStats(&logRecord{Section: "/", StatusCode: 200, Referrer: referrer, Spam: isSpamReferrer(referrerHost(referrer))})
+	}
+	var out bytes.Buffer
+	s.writeStats(&out)
+	if expected := "2 darodar.com"; !strings.Contains(out.String(), expected) {
+		t.Errorf("Expected %q in:\n%s", expected, out.String())
+	}
+	if strings.Contains(out.String(), "google.com") {
+		t.Errorf("Expected only spam referrers in:\n%s", out.String())
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Size of the traffic chart, in pixels
+const (
+	chartWidth  = 800
+	chartHeight = 200
+)
+
+// Colors of response classes in charts
+var statusChartColors = map[string]string{
+	"1XX": "#9e9e9e",
+	"2XX": "#4caf50",
+	"3XX": "#2196f3",
+	"4XX": "#ff9800",
+	"5XX": "#f44336",
+}
+
+// Labeled value drawn as a horizontal bar
+type htmlBar struct {
+	Label   string
+	Count   int
+	Percent string // Share of the total, in percent
+	Width   string // Length of the bar, in percent of the longest one
+	Color   string
+}
+
+// Data rendered by the HTML report template
+type htmlReport struct {
+	Start       string
+	End         string
+	Requests    int
+	Bytes       int
+	QPS         string
+	Traffic     template.HTML // SVG chart of QPS over time
+	Heatmap     template.HTML // Tables of requests and error rate by day and hour
+	Statuses    []htmlBar
+	TopSections []htmlBar
+	TopIPs      []htmlBar
+	Flows       []htmlBar // Section to section transitions within sessions
+	SizeSummary string    // Percentiles of response sizes
+	Sizes       []htmlBar
+	Alerts      []alertEvent
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>HTTP traffic report {{.Start}} - {{.End}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #212121; }
+h1 { font-size: 1.5em; }
+h2 { font-size: 1.2em; margin-top: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: 0.2em 0.8em; text-align: left; }
+td.count { text-align: right; font-family: monospace; }
+td.bar { width: 400px; }
+div.bar { height: 1em; }
+table.heatmap td { width: 1.5em; height: 1.2em; padding: 0; border: 1px solid #fff; }
+table.heatmap th { font-size: 0.8em; font-weight: normal; padding: 0 0.3em; }
+.firing { color: #f44336; font-weight: bold; }
+.resolved { color: #4caf50; }
+</style>
+</head>
+<body>
+<h1>HTTP traffic report</h1>
+<p>{{.Requests}} requests from {{.Start}} to {{.End}}, averaging {{.QPS}} QPS and sending {{.Bytes}} bytes.</p>
+
+<h2>Traffic over time</h2>
+{{.Traffic}}
+
+<h2>Traffic by time of day</h2>
+{{.Heatmap}}
+
+<h2>Status distribution</h2>
+<table>
+{{range .Statuses}}<tr><td>{{.Label}}</td><td class="count">{{.Count}}</td><td class="count">{{.Percent}}%</td><td class="bar"><div class="bar" style="width: {{.Width}}%; background: {{.Color}}"></div></td></tr>
+{{end}}</table>
+
+<h2>Top sections</h2>
+<table>
+{{range .TopSections}}<tr><td>{{.Label}}</td><td class="count">{{.Count}}</td><td class="count">{{.Percent}}%</td><td class="bar"><div class="bar" style="width: {{.Width}}%; background: {{.Color}}"></div></td></tr>
+{{end}}</table>
+
+<h2>Top client IPs</h2>
+<table>
+{{range .TopIPs}}<tr><td>{{.Label}}</td><td class="count">{{.Count}}</td><td class="count">{{.Percent}}%</td><td class="bar"><div class="bar" style="width: {{.Width}}%; background: {{.Color}}"></div></td></tr>
+{{end}}</table>
+
+{{if .Flows}}<h2>Top navigation flows</h2>
+<table>
+{{range .Flows}}<tr><td>{{.Label}}</td><td class="count">{{.Count}}</td><td class="count">{{.Percent}}%</td><td class="bar"><div class="bar" style="width: {{.Width}}%; background: {{.Color}}"></div></td></tr>
+{{end}}</table>
+
+{{end}}{{if .Sizes}}<h2>Response sizes</h2>
+<p>{{.SizeSummary}}</p>
+<table>
+{{range .Sizes}}<tr><td>{{.Label}}</td><td class="count">{{.Count}}</td><td class="count">{{.Percent}}%</td><td class="bar"><div class="bar" style="width: {{.Width}}%; background: {{.Color}}"></div></td></tr>
+{{end}}</table>
+
+{{end}}<h2>Alert timeline</h2>
+{{if .Alerts}}<table>
+{{range .Alerts}}<tr><td>{{.Time.Format "2006-01-02 15:04:05 -0700"}}</td><td>{{.Name}}</td>{{if .Firing}}<td class="firing">firing{{if .Severity}} ({{.Severity}}){{end}} {{.Detail}}</td>{{else}}<td class="resolved">resolved</td>{{end}}</tr>
+{{end}}</table>{{else}}<p>No alerts.</p>{{end}}
+</body>
+</html>
+`))
+
+// Write a standalone HTML report, with charts drawn as inline SVG
+func writeHTMLReport(w io.Writer, a *analysis) error {
+	weight := a.stats.weight()
+	report := htmlReport{
+		Start:    displayTime(a.Start).Format(time.RFC3339),
+		End:      displayTime(a.End).Format(time.RFC3339),
+		Requests: int(math.Round(float64(a.Total.Requests) * weight)),
+		Bytes:    int(math.Round(float64(a.Total.Bytes) * weight)),
+		QPS:      "0.00",
+		Traffic:  trafficChart(a, weight),
+		Heatmap:  newHeatmap(a.Intervals).html(weight),
+	}
+	for _, event := range a.Alerts {
+		event.Time = displayTime(event.Time)
+		report.Alerts = append(report.Alerts, event)
+	}
+	if seconds := a.End.Sub(a.Start).Seconds(); seconds > 0 {
+		report.QPS = fmt.Sprintf("%.2f", float64(report.Requests)/seconds)
+	}
+
+	var classes []string
+	for class := range a.Total.ResponseCodes {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	var statuses []keyCountPair
+	for _, class := range classes {
+		statuses = append(statuses, keyCountPair{key: class, count: int(math.Round(float64(a.Total.ResponseCodes[class]) * weight))})
+	}
+	report.Statuses = htmlBars(statuses, report.Requests)
+	for i := range report.Statuses {
+		report.Statuses[i].Color = statusChartColors[report.Statuses[i].Label]
+	}
+
+	report.TopSections = htmlBars(topCounts(a.stats.scaled(a.stats.sectionCounts), *topN), report.Requests)
+	report.TopIPs = htmlBars(topCounts(a.stats.scaled(a.stats.ipCounts), *topN), report.Requests)
+	report.Flows = htmlBars(topCounts(a.stats.scaled(a.stats.transitions), *topN), report.Requests)
+	if a.stats.sizes != nil && a.stats.sizes.total > 0 {
+		report.SizeSummary = a.stats.sizes.summary()
+		report.Sizes = htmlBars(a.stats.sizes.bucketCounts(weight), report.Requests)
+	}
+	return htmlReportTemplate.Execute(w, report)
+}
+
+// Bars for counters, relative to the largest one
+func htmlBars(counts []keyCountPair, total int) []htmlBar {
+	max := 0
+	for _, v := range counts {
+		if v.count > max {
+			max = v.count
+		}
+	}
+	var bars []htmlBar
+	for _, v := range counts {
+		bar := htmlBar{Label: v.key, Count: v.count, Percent: "0.0", Width: "0", Color: "#607d8b"}
+		if total > 0 {
+			bar.Percent = fmt.Sprintf("%.1f", float64(v.count)*100/float64(total))
+		}
+		if max > 0 {
+			bar.Width = fmt.Sprintf("%.1f", float64(v.count)*100/float64(max))
+		}
+		bars = append(bars, bar)
+	}
+	return bars
+}
+
+// SVG chart of QPS per interval, with periods during which some alert was
+// firing shaded in red
+func trafficChart(a *analysis, weight float64) template.HTML {
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		chartWidth, chartHeight+20, chartWidth, chartHeight+20)
+	fmt.Fprintf(&svg, `<rect width="%d" height="%d" fill="#fafafa"/>`, chartWidth, chartHeight)
+
+	span := a.End.Sub(a.Start).Seconds()
+	if len(a.Intervals) == 0 || span <= 0 {
+		svg.WriteString(`</svg>`)
+		return template.HTML(svg.String())
+	}
+	x := func(t time.Time) float64 {
+		return t.Sub(a.Start).Seconds() / span * chartWidth
+	}
+
+	// Alerting periods, from the first alert firing until the last one
+	// is resolved
+	firing := make(map[string]bool)
+	var since time.Time
+	shade := func(from, to time.Time) {
+		fmt.Fprintf(&svg, `<rect x="%.1f" y="0" width="%.1f" height="%d" fill="#ffcdd2"/>`, x(from), math.Max(x(to)-x(from), 1), chartHeight)
+	}
+	for _, event := range a.Alerts {
+		wasFiring := len(firing) > 0
+		if event.Firing {
+			firing[event.Name] = true
+		} else {
+			delete(firing, event.Name)
+		}
+		if !wasFiring && len(firing) > 0 {
+			since = event.Time
+		}
+		if wasFiring && len(firing) == 0 {
+			shade(since, event.Time)
+		}
+	}
+	if len(firing) > 0 {
+		shade(since, a.End)
+	}
+
+	max := 0.0
+	for _, interval := range a.Intervals {
+		max = math.Max(max, interval.qps()*weight)
+	}
+	var points []string
+	for _, interval := range a.Intervals {
+		y := float64(chartHeight)
+		if max > 0 {
+			y -= interval.qps() * weight / max * (chartHeight - 10)
+		}
+		points = append(points, fmt.Sprintf("%.1f,%.1f %.1f,%.1f", x(interval.Start), y, x(interval.End), y))
+	}
+	fmt.Fprintf(&svg, `<polyline points="%s" fill="none" stroke="#1976d2" stroke-width="1.5"/>`, strings.Join(points, " "))
+	fmt.Fprintf(&svg, `<text x="4" y="12" font-size="11">%.2f QPS</text>`, max)
+	fmt.Fprintf(&svg, `<text x="0" y="%d" font-size="11">%s</text>`, chartHeight+15, displayTime(a.Start).Format("15:04:05"))
+	fmt.Fprintf(&svg, `<text x="%d" y="%d" font-size="11" text-anchor="end">%s</text>`, chartWidth, chartHeight+15, displayTime(a.End).Format("15:04:05"))
+	svg.WriteString(`</svg>`)
+	return template.HTML(svg.String())
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Period during which an alert was firing
+type alertWindow struct {
+	name     string
+	detail   string   // Condition when the alert was triggered
+	severity severity // Highest severity reached
+	start    time.Time
+	end      time.Time // Zero while still firing at the end of the analysis
+}
+
+// Pair alerts being triggered with their resolution. Changes in severity
+// while firing are folded into the window
+func alertWindows(events []alertEvent) []alertWindow {
+	var windows []alertWindow
+	open := make(map[string]int) // Index of the window still open, by alert
+	for _, event := range events {
+		i, ok := open[event.Name]
+		if event.Firing && ok {
+			if event.Severity > windows[i].severity {
+				windows[i].severity = event.Severity
+			}
+		} else if event.Firing {
+			open[event.Name] = len(windows)
+			windows = append(windows, alertWindow{name: event.Name, detail: event.Detail, severity: event.Severity, start: event.Time})
+		} else if ok {
+			windows[i].end = event.Time
+			delete(open, event.Name)
+		}
+	}
+	return windows
+}
+
+// Escape text for a Markdown table cell
+func markdownCell(text string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(text)
+}
+
+// Write a Markdown table of the top counters
+func writeMarkdownCounts(w io.Writer, title string, counts []keyCountPair, total int) {
+	fmt.Fprintf(w, "\n### %s\n\n", title)
+	fmt.Fprintf(w, "| %s | Requests | Share |\n|---|---:|---:|\n", strings.TrimPrefix(title, "Top "))
+	for _, v := range counts {
+		share := 0.0
+		if total > 0 {
+			share = float64(v.count) * 100 / float64(total)
+		}
+		fmt.Fprintf(w, "| `%s` | %d | %.1f%% |\n", markdownCell(v.key), v.count, share)
+	}
+}
+
+// Counters as comma-separated shares of their sum, largest first
+func markdownShares(counters map[string]int) string {
+	total := 0
+	for _, count := range counters {
+		total += count
+	}
+	var shares []string
+	for _, v := range topCounts(counters, len(counters)) {
+		shares = append(shares, fmt.Sprintf("%s %.2f%%", v.key, float64(v.count)*100/float64(total)))
+	}
+	return strings.Join(shares, ", ")
+}
+
+// Write a concise Markdown summary, e.g. for postmortems
+func writeMarkdownReport(w io.Writer, a *analysis) error {
+	weight := a.stats.weight()
+	requests := int(math.Round(float64(a.Total.Requests) * weight))
+	qps := 0.0
+	if seconds := a.End.Sub(a.Start).Seconds(); seconds > 0 {
+		qps = float64(requests) / seconds
+	}
+
+	fmt.Fprintf(w, "## HTTP traffic summary\n\n")
+	fmt.Fprintf(w, "- **Period:** %s to %s (%s)\n", displayTime(a.Start).Format(time.RFC3339), displayTime(a.End).Format(time.RFC3339), a.End.Sub(a.Start))
+	fmt.Fprintf(w, "- **Requests:** %d (%.2f QPS on average)\n", requests, qps)
+	fmt.Fprintf(w, "- **Bytes sent:** %d\n", int(math.Round(float64(a.Total.Bytes)*weight)))
+
+	var classes []string
+	for class := range a.Total.ResponseCodes {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	var rates []string
+	for _, class := range classes {
+		rates = append(rates, fmt.Sprintf("%s %.2f%%", class, float64(a.Total.ResponseCodes[class])*100/float64(a.Total.Requests)))
+	}
+	if len(rates) > 0 {
+		fmt.Fprintf(w, "- **Responses:** %s\n", strings.Join(rates, ", "))
+	}
+
+	if len(a.stats.protocolCounts) > 1 {
+		fmt.Fprintf(w, "- **Protocols:** %s\n", markdownShares(a.stats.protocolCounts))
+	}
+	if len(a.stats.tlsVersions) > 0 {
+		fmt.Fprintf(w, "- **TLS versions:** %s\n", markdownShares(a.stats.tlsVersions))
+	}
+	if a.stats.sizes != nil && a.stats.sizes.total > 0 {
+		fmt.Fprintf(w, "- **Response sizes:** %s\n", a.stats.sizes.summary())
+	}
+
+	writeMarkdownCounts(w, "Top sections", topCounts(a.stats.scaled(a.stats.sectionCounts), *topN), requests)
+	writeMarkdownCounts(w, "Top client IPs", topCounts(a.stats.scaled(a.stats.ipCounts), *topN), requests)
+	if len(a.stats.transitions) > 0 {
+		writeMarkdownCounts(w, "Top navigation flows", topCounts(a.stats.scaled(a.stats.transitions), *topN), requests)
+	}
+
+	fmt.Fprintf(w, "\n### Alerts\n\n")
+	windows := alertWindows(a.Alerts)
+	if len(windows) == 0 {
+		fmt.Fprintf(w, "No alerts.\n")
+		return nil
+	}
+	fmt.Fprintf(w, "| Alert | From | To | Duration | Condition |\n|---|---|---|---:|---|\n")
+	for _, window := range windows {
+		to, duration := "still firing", a.End.Sub(window.start)
+		if !window.end.IsZero() {
+			to, duration = displayTime(window.end).Format(time.RFC3339), window.end.Sub(window.start)
+		}
+		name := window.name
+		if window.severity != 0 {
+			name += fmt.Sprintf(" (%s)", window.severity)
+		}
+		fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n", markdownCell(name), displayTime(window.start).Format(time.RFC3339), to, duration, markdownCell(window.detail))
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Duration given on the command line, which may also be a number of days,
+// weeks or years, e.g. 7d or 1y
+type retentionDuration time.Duration
+
+// Length of the units Go durations lack
+var retentionUnits = map[string]time.Duration{
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+	"y": 365 * 24 * time.Hour,
+}
+
+func (d *retentionDuration) String() string {
+	return time.Duration(*d).String()
+}
+
+func (d *retentionDuration) Set(value string) error {
+	if len(value) > 1 && retentionUnits[value[len(value)-1:]] > 0 {
+		unit := retentionUnits[value[len(value)-1:]]
+		n, err := strconv.ParseFloat(value[:len(value)-1], 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("Invalid retention: %s", value)
+		}
+		*d = retentionDuration(n * float64(unit))
+		return nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return fmt.Errorf("Invalid retention: %s", value)
+	}
+	*d = retentionDuration(duration)
+	return nil
+}
+
+// Command-line flags to prune history from the SQLite database
+var sqliteRetention retentionDuration
+var sqliteDailyRetention retentionDuration
+var sqliteWeeklyRetention retentionDuration
+
+func init() {
+	flag.Var(&sqliteRetention, "sqlite-retention", "How long intervals and alert transitions are kept in the SQLite database, e.g. 7d (0 keeps them forever)")
+	flag.Var(&sqliteDailyRetention, "sqlite-daily-retention", "How long daily rollups are kept in the SQLite database, e.g. 1y (0 keeps them forever)")
+	flag.Var(&sqliteWeeklyRetention, "sqlite-weekly-retention", "How long weekly rollups are kept in the SQLite database, e.g. 5y (0 keeps them forever)")
+}
+
+// How long each kind of record is kept, zero meaning forever
+type retentionPolicy struct {
+	intervals time.Duration // Intervals, along with their counters, and alert transitions
+	days      time.Duration
+	weeks     time.Duration
+}
+
+// Retention policy given on the command line
+func configuredRetention() retentionPolicy {
+	return retentionPolicy{
+		intervals: time.Duration(sqliteRetention),
+		days:      time.Duration(sqliteDailyRetention),
+		weeks:     time.Duration(sqliteWeeklyRetention),
+	}
+}
+
+// Delete the records older than the retention policy allows, at most once a
+// day. Intervals of the current week are kept until it is rolled up
+func (s *sqliteSink) prune(now time.Time) error {
+	today := periodStart("day", now)
+	if s.retention == (retentionPolicy{}) || !s.pruned.Before(today) {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if s.retention.intervals > 0 {
+		cutoff := now.Add(-s.retention.intervals).UTC()
+		if week := periodStart("week", now); cutoff.After(week) {
+			cutoff = week
+		}
+		for _, query := range []string{
+			"DELETE FROM status_classes WHERE interval_id IN (SELECT id FROM intervals WHERE start < ?)",
+			"DELETE FROM sections WHERE interval_id IN (SELECT id FROM intervals WHERE start < ?)",
+			"DELETE FROM intervals WHERE start < ?",
+			"DELETE FROM alerts WHERE time < ?",
+		} {
+			if _, err := tx.Exec(query, cutoff); err != nil {
+				return err
+			}
+		}
+	}
+	for period, retention := range map[string]time.Duration{"day": s.retention.days, "week": s.retention.weeks} {
+		if retention > 0 {
+			if _, err := tx.Exec("DELETE FROM rollups WHERE period = ? AND end < ?", period, now.Add(-retention).UTC()); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.pruned = today
+	return nil
+}
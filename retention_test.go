@@ -0,0 +1,82 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRetentionDuration(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		valid    bool
+	}{
+		{"7d", 7 * 24 * time.Hour, true},
+		{"2w", 14 * 24 * time.Hour, true},
+		{"1y", 365 * 24 * time.Hour, true},
+		{"1.5d", 36 * time.Hour, true},
+		{"90m", 90 * time.Minute, true},
+		{"0", 0, true},
+		{"", 0, false},
+		{"d", 0, false},
+		{"-1d", 0, false},
+		{"forever", 0, false},
+	}
+	for _, test := range tests {
+		var d retentionDuration
+		err := d.Set(test.value)
+		if (err == nil) != test.valid {
+			t.Errorf("%q: unexpected error %v", test.value, err)
+			continue
+		}
+		if time.Duration(d) != test.expected {
+			t.Errorf("%q: %+v != %+v", test.value, time.Duration(d), test.expected)
+		}
+	}
+}
+
+func TestSQLitePrune(t *testing.T) {
+	db, err := openSQLiteSink(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+	db.retention = retentionPolicy{intervals: 7 * 24 * time.Hour, days: 14 * 24 * time.Hour}
+
+	// An interval a day over four weeks, starting on Monday
+	start := time.Date(2019, 1, 7, 10, 0, 0, 0, time.UTC)
+	for day := 0; day < 28; day++ {
+		i := newInterval(start.AddDate(0, 0, day))
+		i.add(&logRecord{StatusCode: 200, Section: "/", Size: 100})
+		i.End = i.Start.Add(10 * time.Second)
+		if err := db.write(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count := func(query string) int {
+		var n int
+		if err := db.db.QueryRow(query).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	// Intervals of the last 7 days, daily rollups of the last 14 days and
+	// every weekly one
+	tests := []struct {
+		query    string
+		expected int
+	}{
+		{"SELECT COUNT(*) FROM intervals", 8},
+		{"SELECT COUNT(*) FROM sections", 8},
+		{"SELECT COUNT(*) FROM status_classes", 8},
+		{"SELECT COUNT(*) FROM rollups WHERE period = 'day'", 14},
+		{"SELECT COUNT(*) FROM rollups WHERE period = 'week'", 3},
+	}
+	for _, test := range tests {
+		if n := count(test.query); n != test.expected {
+			t.Errorf("%s: %+v != %+v", test.query, n, test.expected)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// Periods intervals recorded into SQLite are rolled up into, once over
+var rollupPeriods = []string{"day", "week"}
+
+// Aggregates of the intervals of a day or week
+type rollup struct {
+	Period    string    `json:"period"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Intervals int       `json:"intervals"`
+	Requests  int       `json:"requests"`
+	Bytes     int       `json:"bytes"`
+	Errors    int       `json:"errors"` // 5XX responses
+	PeakQPS   float64   `json:"peak_qps"`
+	P50QPS    float64   `json:"p50_qps"` // Percentiles of the QPS of intervals
+	P95QPS    float64   `json:"p95_qps"`
+	P99QPS    float64   `json:"p99_qps"`
+}
+
+// Start of the day, or of the week starting on Monday, holding a time in UTC
+func periodStart(period string, t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if period == "week" {
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return day
+}
+
+// End of the day or week starting at a time
+func periodEnd(period string, start time.Time) time.Time {
+	if period == "week" {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// Value below which the given fraction of sorted values fall
+func sortedPercentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// Roll up the recorded intervals of the day or week starting at a time
+func computeRollup(db *sql.DB, period string, start time.Time) (*rollup, error) {
+	r := &rollup{Period: period, Start: start, End: periodEnd(period, start)}
+	rows, err := db.Query(`SELECT i.requests, i.bytes, i.qps, COALESCE(s.requests, 0) FROM intervals i
+		LEFT JOIN status_classes s ON s.interval_id = i.id AND s.class = '5XX'
+		WHERE i.start >= ? AND i.start < ?`, r.Start, r.End)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []float64
+	for rows.Next() {
+		var requests, bytes, errors int
+		var qps float64
+		if err := rows.Scan(&requests, &bytes, &qps, &errors); err != nil {
+			return nil, err
+		}
+		r.Intervals++
+		r.Requests += requests
+		r.Bytes += bytes
+		r.Errors += errors
+		rates = append(rates, qps)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Float64s(rates)
+	if len(rates) > 0 {
+		r.PeakQPS = rates[len(rates)-1]
+	}
+	r.P50QPS, r.P95QPS, r.P99QPS = sortedPercentile(rates, 0.5), sortedPercentile(rates, 0.95), sortedPercentile(rates, 0.99)
+	return r, nil
+}
+
+// Roll up every day and week over before the given time which was not yet.
+// Rolled up periods are remembered, so that this is cheap until the next
+// day starts
+func (s *sqliteSink) rollUp(now time.Time) error {
+	for _, period := range rollupPeriods {
+		current := periodStart(period, now)
+		from := s.rolled[period]
+		if !from.Before(current) {
+			continue
+		}
+		if from.IsZero() {
+			// Resume after the last rollup, else from the first interval
+			var last time.Time
+			err := s.db.QueryRow("SELECT end FROM rollups WHERE period = ? ORDER BY start DESC LIMIT 1", period).Scan(&last)
+			if err == sql.ErrNoRows {
+				err = s.db.QueryRow("SELECT start FROM intervals ORDER BY start LIMIT 1").Scan(&last)
+			}
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			if last.IsZero() {
+				s.rolled[period] = current
+				continue
+			}
+			from = periodStart(period, last)
+		}
+		for start := from; start.Before(current); start = periodEnd(period, start) {
+			r, err := computeRollup(s.db, period, start)
+			if err != nil {
+				return err
+			}
+			if r.Intervals == 0 {
+				continue
+			}
+			if _, err := s.db.Exec(`INSERT OR REPLACE INTO rollups (period, start, end, intervals, requests, bytes, errors, peak_qps, p50_qps, p95_qps, p99_qps)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				r.Period, r.Start, r.End, r.Intervals, r.Requests, r.Bytes, r.Errors, r.PeakQPS, r.P50QPS, r.P95QPS, r.P99QPS); err != nil {
+				return err
+			}
+		}
+		s.rolled[period] = current
+	}
+	return nil
+}
+
+// Rollups of a period starting at or after a time, oldest first
+func readRollups(db *sql.DB, period string, since time.Time) ([]rollup, error) {
+	rows, err := db.Query(`SELECT period, start, end, intervals, requests, bytes, errors, peak_qps, p50_qps, p95_qps, p99_qps
+		FROM rollups WHERE period = ? AND start >= ? ORDER BY start`, period, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rollups := []rollup{}
+	for rows.Next() {
+		var r rollup
+		if err := rows.Scan(&r.Period, &r.Start, &r.End, &r.Intervals, &r.Requests, &r.Bytes, &r.Errors, &r.PeakQPS, &r.P50QPS, &r.P95QPS, &r.P99QPS); err != nil {
+			return nil, err
+		}
+		rollups = append(rollups, r)
+	}
+	return rollups, rows.Err()
+}
+
+// Rollups of a period read from a SQLite database file, as the sink
+// recording it may be replaced at any time
+func queryRollups(path, period string, since time.Time) ([]rollup, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return readRollups(db, period, since)
+}
+
+// Whether a period is one intervals are rolled up into
+func checkRollupPeriod(period string) error {
+	for _, p := range rollupPeriods {
+		if period == p {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown rollup period: %s", period)
+}
+
+// Write rollups as a table
+func writeRollups(w io.Writer, rollups []rollup) {
+	t := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(t, "Start (UTC)\tRequests\tBytes\t5XX\tPeak QPS\tp50 QPS\tp95 QPS\tp99 QPS\t\n")
+	for _, r := range rollups {
+		errors := 0.0
+		if r.Requests > 0 {
+			errors = float64(r.Errors) * 100 / float64(r.Requests)
+		}
+		fmt.Fprintf(t, "%s\t%d\t%s\t%.2f%%\t%.2f\t%.2f\t%.2f\t%.2f\t\n", r.Start.Format("2006-01-02"),
+			r.Requests, formatSize(r.Bytes), errors, r.PeakQPS, r.P50QPS, r.P95QPS, r.P99QPS)
+	}
+	t.Flush()
+}
+
+// Run the rollups subcommand, rolling up what the SQLite database recorded
+// so far, then writing daily or weekly aggregates
+func runRollups(args []string) error {
+	flags := flag.NewFlagSet("rollups", flag.ExitOnError)
+	period := flags.String("period", "day", "Period of the aggregates (day, week)")
+	since := flags.Duration("since", 0, "Only write aggregates of periods starting within this long, e.g. 720h (0 for all)")
+	asJSON := flags.Bool("json", false, "Write aggregates as JSON instead of a table")
+	if f := flag.Lookup("sqlite"); f != nil {
+		flags.Var(globalFlag{f}, f.Name, f.Usage)
+	}
+	flags.Parse(args)
+
+	if err := checkRollupPeriod(*period); err != nil {
+		return err
+	}
+	if *sqliteDatabase == "" {
+		return fmt.Errorf("Rollups require a -sqlite database")
+	}
+	db, err := openSQLiteSink(*sqliteDatabase)
+	if err != nil {
+		return err
+	}
+	defer db.close()
+	if err := db.rollUp(time.Now()); err != nil {
+		return err
+	}
+
+	var from time.Time
+	if *since > 0 {
+		from = time.Now().Add(-*since)
+	}
+	rollups, err := readRollups(db.db, *period, from)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return json.NewEncoder(os.Stdout).Encode(rollups)
+	}
+	writeRollups(os.Stdout, rollups)
+	return nil
+}
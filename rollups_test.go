@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPeriodStart(t *testing.T) {
+	// Wednesday evening in Madrid, already Thursday in UTC
+	at := time.Date(2019, 1, 2, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*3600))
+	tests := []struct {
+		period   string
+		expected time.Time
+	}{
+		{"day", time.Date(2019, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"week", time.Date(2018, 12, 31, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		if start := periodStart(test.period, at); !start.Equal(test.expected) {
+			t.Errorf("%s: %+v != %+v", test.period, start, test.expected)
+		}
+	}
+	if start := periodStart("week", time.Date(2019, 1, 6, 12, 0, 0, 0, time.UTC)); !start.Equal(time.Date(2018, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Sundays to belong to the week started on Monday, got %+v", start)
+	}
+}
+
+func TestSQLiteRollups(t *testing.T) {
+	db, err := openSQLiteSink(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+
+	// Four 10-second intervals on January 1st, one of them failing, and one
+	// on January 2nd
+	write := func(start time.Time, requests int, status int) {
+		i := newInterval(start)
+		for n := 0; n < requests; n++ {
+			i.add(&logRecord{StatusCode: status, Section: "/", Size: 100})
+		}
+		i.End = start.Add(10 * time.Second)
+		if err := db.write(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	day := time.Date(2019, 1, 1, 10, 0, 0, 0, time.UTC)
+	write(day, 10, 200)
+	write(day.Add(10*time.Second), 20, 200)
+	write(day.Add(20*time.Second), 30, 503)
+	write(day.Add(30*time.Second), 40, 200)
+
+	rollups, err := readRollups(db.db, "day", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rollups) != 0 {
+		t.Errorf("Expected no rollup of the current day, got %+v", rollups)
+	}
+
+	write(day.Add(24*time.Hour), 10, 200)
+	if rollups, err = readRollups(db.db, "day", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	expected := rollup{
+		Period:    "day",
+		Start:     time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:       time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC),
+		Intervals: 4,
+		Requests:  100,
+		Bytes:     10000,
+		Errors:    30,
+		PeakQPS:   4,
+		P50QPS:    2,
+		P95QPS:    4,
+		P99QPS:    4,
+	}
+	if len(rollups) != 1 || !rollups[0].Start.Equal(expected.Start) || !rollups[0].End.Equal(expected.End) {
+		t.Fatalf("Unexpected rollups: %+v", rollups)
+	}
+	rollups[0].Start, rollups[0].End = expected.Start, expected.End
+	if rollups[0] != expected {
+		t.Errorf("%+v != %+v", rollups[0], expected)
+	}
+
+	// Weeks are rolled up once over, resuming from the last rollup when the
+	// database is opened again
+	db.rolled = make(map[string]time.Time)
+	if err := db.rollUp(time.Date(2019, 1, 8, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	weeks, err := readRollups(db.db, "week", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(weeks) != 1 || weeks[0].Requests != 110 || weeks[0].Intervals != 5 {
+		t.Errorf("Unexpected weekly rollups: %+v", weeks)
+	}
+	days, err := readRollups(db.db, "day", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 2 || days[1].Requests != 10 {
+		t.Errorf("Unexpected daily rollups: %+v", days)
+	}
+
+	var out bytes.Buffer
+	writeRollups(&out, days)
+	if !strings.Contains(out.String(), "2019-01-01       100  9.8KB  30.00%") {
+		t.Errorf("Unexpected rollups table:\n%s", out.String())
+	}
+}
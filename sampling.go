@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Command-line flag to only process a fraction of the log lines
+var sampleRate = flag.Float64("sample", 1.0, "Fraction of log lines to process, e.g. 0.1; counters and rates are scaled accordingly")
+
+// Check that a sample rate is a fraction of the lines, which rejects NaN too
+func checkSampleRate(rate float64) error {
+	if !(rate > 0 && rate <= 1) {
+		return fmt.Errorf("Sample rate must be greater than 0 and at most 1: %g", rate)
+	}
+	return nil
+}
+
+// Whether to process the next log line, as randomly picked by -sample
+func sampled() bool {
+	return *sampleRate >= 1 || rand.Float64() < *sampleRate
+}
+
+// Number of requests each processed log record stands for
+func (s *stats) weight() float64 {
+	if s.sampleRate > 0 && s.sampleRate < 1 {
+		return 1 / s.sampleRate
+	}
+	return 1
+}
+
+// Counters scaled up to estimate the actual number of requests
+func (s *stats) scaled(counters map[string]int) map[string]int {
+	return scaleCounts(counters, s.weight())
+}
+
+// Counters multiplied by the number of requests each record stands for
+func scaleCounts(counters map[string]int, weight float64) map[string]int {
+	if weight == 1 {
+		return counters
+	}
+	result := make(map[string]int, len(counters))
+	for key, count := range counters {
+		result[key] = int(math.Round(float64(count) * weight))
+	}
+	return result
+}
+
+// Interval with its counters scaled up, for outputs to get estimates of the
+// actual traffic as the console does
+func (i *interval) scaled(weight float64) *interval {
+	if weight == 1 {
+		return i
+	}
+	return &interval{
+		Start:         i.Start,
+		End:           i.End,
+		Requests:      int(math.Round(float64(i.Requests) * weight)),
+		Bytes:         int(math.Round(float64(i.Bytes) * weight)),
+		ResponseCodes: scaleCounts(i.ResponseCodes, weight),
+		Sections:      scaleCounts(i.Sections, weight),
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Test counters and rates are scaled up when sampling
+func TestSampledStats(t *testing.T) {
+	s := newStats()
+	s.sampleRate = 0.1
+	ts := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	for i := 0; i <= 10; i++ {
+		s.updateStats(&logRecord{IP: "10.0.0.1", Timestamp: ts.Add(time.Duration(i) * time.Second), StatusCode: 200, Section: "/api"})
+	}
+
+	if scaled := s.scaled(s.sectionCounts)["/api"]; scaled != 110 {
+		t.Errorf("%d != %d", 110, scaled)
+	}
+	if qps, _ := s.getQueryRate(); qps != 11 {
+		t.Errorf("%f != %f", 11.0, qps)
+	}
+	if !s.alerting {
+		t.Errorf("Expected sampled traffic to be alerted on")
+	}
+
+	s.sampleRate = 0
+	if qps, _ := s.getQueryRate(); qps != 1.1 {
+		t.Errorf("%f != %f", 1.1, qps)
+	}
+}
+
+// Test intervals written to outputs are scaled up when sampling
+func TestScaledInterval(t *testing.T) {
+	i := newInterval(time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC))
+	i.add(&logRecord{StatusCode: 200, Section: "/api", Size: 100})
+	i.add(&logRecord{StatusCode: 503, Section: "/api", Size: 10})
+
+	if i.scaled(1) != i {
+		t.Errorf("Interval copied without sampling")
+	}
+	scaled := i.scaled(10)
+	if scaled.Requests != 20 || scaled.Bytes != 1100 || scaled.ResponseCodes["5XX"] != 10 || scaled.Sections["/api"] != 20 {
+		t.Errorf("Unexpected scaled interval %+v", scaled)
+	}
+	if i.Requests != 2 {
+		t.Errorf("Original interval modified: %+v", i)
+	}
+}
+
+func TestCheckSampleRate(t *testing.T) {
+	for _, rate := range []float64{0.01, 0.5, 1} {
+		if err := checkSampleRate(rate); err != nil {
+			t.Errorf("Rejected sample rate %g: %s", rate, err)
+		}
+	}
+	for _, rate := range []float64{0, -0.5, 1.5, math.NaN()} {
+		if err := checkSampleRate(rate); err == nil {
+			t.Errorf("Accepted sample rate %g", rate)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Command-line flags to detect clients scanning for vulnerable paths
+var scanErrors = flag.Int("scan-errors", 0, "Alert on clients getting at least this many 403/404 responses in the alerting window (0 disables)")
+var scanPaths = flag.Int("scan-paths", 10, "Minimum number of distinct paths a client must have requested to be considered a scanner")
+
+// Alert firing when single clients get many 403/404 responses across many
+// distinct paths, which is how vulnerability scanners usually show up
+type scanningRule struct {
+	errors int
+	paths  int
+}
+
+func (r *scanningRule) name() string {
+	return "Scanning"
+}
+
+func (r *scanningRule) evaluate(s *stats) (bool, string) {
+	scanners := r.offenders(s)
+	return len(scanners) > 0, fmt.Sprintf("from %s", strings.Join(scanners, ", "))
+}
+
+// IPs of the clients looking like scanners, sorted
+func (r *scanningRule) offenders(s *stats) []string {
+	errors := make(map[string]int)
+	paths := make(map[string]map[string]bool)
+	for _, record := range s.logsInWindow {
+		if record.StatusCode != 403 && record.StatusCode != 404 {
+			continue
+		}
+		errors[record.IP]++
+		if paths[record.IP] == nil {
+			paths[record.IP] = make(map[string]bool)
+		}
+		paths[record.IP][record.Section+record.Resource] = true
+	}
+
+	var scanners []string
+	for ip, count := range errors {
+		if float64(count)*s.weight() >= float64(r.errors) && len(paths[ip]) >= r.paths {
+			scanners = append(scanners, ip)
+		}
+	}
+	sort.Strings(scanners)
+	return scanners
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestScanningRule(t *testing.T) {
+	s := newStats()
+	ts := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+
+	// A scanner probing many paths, a client hammering a single missing
+	// page and a well-behaved one
+	for i := 0; i < 10; i++ {
+		s.updateAlerting(&logRecord{IP: "10.0.0.1", Timestamp: ts, StatusCode: 404, Section: fmt.Sprintf("/wp-admin%d", i)})
+		s.updateAlerting(&logRecord{IP: "10.0.0.2", Timestamp: ts, StatusCode: 404, Section: "/favicon.ico"})
+		s.updateAlerting(&logRecord{IP: "10.0.0.3", Timestamp: ts, StatusCode: 200, Section: fmt.Sprintf("/page%d", i)})
+	}
+
+	r := &scanningRule{errors: 10, paths: 5}
+	firing, detail := r.evaluate(s)
+	if !firing {
+		t.Errorf("Expected scanning alert to fire")
+	}
+	if expected := "from 10.0.0.1"; detail != expected {
+		t.Errorf("%q != %q", expected, detail)
+	}
+
+	r = &scanningRule{errors: 20, paths: 5}
+	if firing, _ := r.evaluate(s); firing {
+		t.Errorf("Expected scanning alert not to fire")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Threshold applying to requests whose path matches a regular expression
+type sectionThreshold struct {
+	pattern   *regexp.Regexp
+	threshold float64
+}
+
+// Per-path thresholds given on the command line, e.g. ^/checkout=5
+type sectionThresholds []sectionThreshold
+
+func (t *sectionThresholds) String() string {
+	var thresholds []string
+	for _, v := range *t {
+		thresholds = append(thresholds, fmt.Sprintf("%s=%g", v.pattern, v.threshold))
+	}
+	return strings.Join(thresholds, " ")
+}
+
+func (t *sectionThresholds) reset() {
+	*t = nil
+}
+
+func (t *sectionThresholds) Set(value string) error {
+	// Regular expressions may contain equal signs themselves
+	i := strings.LastIndexByte(value, '=')
+	if i <= 0 {
+		return fmt.Errorf("Expected pattern=threshold: %s", value)
+	}
+	pattern, err := regexp.Compile(value[:i])
+	if err != nil {
+		return err
+	}
+	threshold, err := strconv.ParseFloat(value[i+1:], 64)
+	if err != nil {
+		return err
+	}
+	*t = append(*t, sectionThreshold{pattern: pattern, threshold: threshold})
+	return nil
+}
+
+// Command-line flags to alert on traffic to, and errors from, specific paths
+var sectionQPS sectionThresholds
+var sectionErrorPercent sectionThresholds
+
+func init() {
+	flag.Var(&sectionQPS, "section-qps", "Average QPS threshold for requests whose path matches a regular expression, e.g. ^/checkout=5 (repeatable)")
+	flag.Var(&sectionErrorPercent, "section-error-percent", "Percentage of 5XX responses above which requests whose path matches a regular expression are alerted on, e.g. ^/checkout=2 (repeatable)")
+}
+
+// Records in the window whose path matches, and how many of them failed
+// with a server error
+func matchingRecords(s *stats, pattern *regexp.Regexp) (int, int) {
+	var matching, errors int
+	for _, record := range s.logsInWindow {
+		if pattern.MatchString(record.Section + record.Resource) {
+			matching++
+			if record.StatusCode >= 500 {
+				errors++
+			}
+		}
+	}
+	return matching, errors
+}
+
+// Alert firing when requests to matching paths exceed a query rate in the
+// window, however low overall traffic is
+type sectionTrafficRule struct {
+	sectionThreshold
+}
+
+func (r *sectionTrafficRule) name() string {
+	return fmt.Sprintf("Section-traffic (%s)", r.pattern)
+}
+
+func (r *sectionTrafficRule) evaluate(s *stats) (bool, string) {
+	matching, _ := matchingRecords(s, r.pattern)
+	if matching == 0 {
+		return false, ""
+	}
+	qps := s.windowRate(matching)
+	return qps > r.threshold, fmt.Sprintf("at %f queries per second on average", qps)
+}
+
+func (r *sectionTrafficRule) measure(s *stats) (float64, float64) {
+	matching, _ := matchingRecords(s, r.pattern)
+	if matching == 0 {
+		return 0, r.threshold
+	}
+	return s.windowRate(matching), r.threshold
+}
+
+// Alert firing when the share of 5XX responses to matching paths in the
+// window exceeds a percentage
+type sectionErrorRule struct {
+	sectionThreshold
+}
+
+func (r *sectionErrorRule) name() string {
+	return fmt.Sprintf("Section-errors (%s)", r.pattern)
+}
+
+func (r *sectionErrorRule) evaluate(s *stats) (bool, string) {
+	matching, errors := matchingRecords(s, r.pattern)
+	if matching == 0 {
+		return false, ""
+	}
+	percent := float64(errors) * 100 / float64(matching)
+	return percent > r.threshold, fmt.Sprintf("at %.2f%% of %d requests", percent, matching)
+}
+
+func (r *sectionErrorRule) measure(s *stats) (float64, float64) {
+	matching, errors := matchingRecords(s, r.pattern)
+	if matching == 0 {
+		return 0, r.threshold
+	}
+	return float64(errors) * 100 / float64(matching), r.threshold
+}
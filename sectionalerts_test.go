@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSectionThresholdsSet(t *testing.T) {
+	var thresholds sectionThresholds
+	for _, value := range []string{"^/checkout=5", "id=[0-9]+=2.5"} {
+		if err := thresholds.Set(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if thresholds.String() != "^/checkout=5 id=[0-9]+=2.5" {
+		t.Errorf("%+v != %+v", thresholds.String(), "^/checkout=5 id=[0-9]+=2.5")
+	}
+	for _, value := range []string{"/checkout", "=5", "/checkout=many", "(=5"} {
+		if err := thresholds.Set(value); err == nil {
+			t.Errorf("Expected an error for %s", value)
+		}
+	}
+}
+
+func TestSectionRules(t *testing.T) {
+	start := time.Date(2018, 5, 9, 16, 0, 0, 0, time.UTC)
+	s := newStats()
+	add := func(second int, section, resource string, status int) {
+		s.logsInWindow = append(s.logsInWindow, &logRecord{
+			Timestamp:  start.Add(time.Duration(second) * time.Second),
+			Section:    section,
+			Resource:   resource,
+			StatusCode: status,
+		})
+	}
+	for second := 0; second <= 10; second++ {
+		add(second, "/api", "/user", 200)
+		add(second, "/checkout", "/pay", 200)
+		add(second, "/checkout", "/cart", 200)
+	}
+	add(10, "/checkout", "/pay", 503)
+
+	var traffic, errors sectionThresholds
+	traffic.Set("^/checkout=2")
+	traffic.Set("^/checkout/cart=2")
+	errors.Set("^/checkout/pay=5")
+	errors.Set("^/api=5")
+
+	expected := map[alertRule]bool{
+		&sectionTrafficRule{traffic[0]}: true,
+		&sectionTrafficRule{traffic[1]}: false,
+		&sectionErrorRule{errors[0]}:    true,
+		&sectionErrorRule{errors[1]}:    false,
+	}
+	for rule, firing := range expected {
+		if actual, detail := rule.evaluate(s); actual != firing {
+			t.Errorf("%s: %+v != %+v (%s)", rule.name(), actual, firing, detail)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"text/tabwriter"
+	"time"
+)
+
+// Command-line flag to reconstruct visits
+var sessionTimeout = flag.Duration("session-timeout", 30*time.Minute, "Idle time after which a client's session (requests from an IP and user agent) ends (0 disables session tracking)")
+
+// Requests from a client with no gap longer than the session timeout
+type session struct {
+	start    time.Time
+	last     time.Time
+	requests int
+	interval int    // Last interval the session was active in
+	section  string // Section of the last request
+}
+
+// Sessions of clients, open and ended in the current interval. Time is
+// taken from log timestamps, so that analyzing logs reconstructs the same
+// sessions as following them
+type sessionTracker struct {
+	timeout  time.Duration
+	open     map[string]*session
+	latest   time.Time // Latest timestamp seen
+	interval int
+	active   int // Sessions active in the current interval
+	started  int // Sessions started in the current interval
+	ended    int // Sessions ended in the current interval
+	requests int // Requests of the sessions ended in the current interval
+	duration time.Duration
+}
+
+// Session tracker, nil when sessions are not tracked
+func newSessionTracker(timeout time.Duration) *sessionTracker {
+	if timeout <= 0 {
+		return nil
+	}
+	return &sessionTracker{timeout: timeout, open: make(map[string]*session)}
+}
+
+// Client a session belongs to
+func sessionKey(log *logRecord) string {
+	return log.IP + " " + log.UserAgent
+}
+
+// Account for a request in its client's session, returning the section of
+// the previous request of the session, empty if the request starts one
+func (t *sessionTracker) add(log *logRecord) string {
+	if log.Timestamp.After(t.latest) {
+		t.latest = log.Timestamp
+	}
+	key := sessionKey(log)
+	s := t.open[key]
+	if s != nil && log.Timestamp.Sub(s.last) > t.timeout {
+		t.end(key, s)
+		s = nil
+	}
+	if s == nil {
+		s = &session{start: log.Timestamp, last: log.Timestamp, interval: -1}
+		t.open[key] = s
+		t.started++
+	}
+	if s.interval != t.interval {
+		s.interval = t.interval
+		t.active++
+	}
+	if log.Timestamp.After(s.last) {
+		s.last = log.Timestamp
+	}
+	s.requests++
+	previous := s.section
+	s.section = log.Section
+	return previous
+}
+
+// Account for a session ending
+func (t *sessionTracker) end(key string, s *session) {
+	delete(t.open, key)
+	t.ended++
+	t.requests += s.requests
+	t.duration += s.last.Sub(s.start)
+}
+
+// Whether a session is idle for longer than the timeout
+func (t *sessionTracker) idle(s *session) bool {
+	return t.latest.Sub(s.last) > t.timeout
+}
+
+// Sessions ended in the current interval, including idle ones not ended yet,
+// with their average number of requests and duration
+func (t *sessionTracker) endedSessions() (int, float64, time.Duration) {
+	ended, requests, duration := t.ended, t.requests, t.duration
+	for _, s := range t.open {
+		if t.idle(s) {
+			ended++
+			requests += s.requests
+			duration += s.last.Sub(s.start)
+		}
+	}
+	if ended == 0 {
+		return 0, 0, 0
+	}
+	return ended, float64(requests) / float64(ended), duration / time.Duration(ended)
+}
+
+// End idle sessions, then start a new interval
+func (t *sessionTracker) endInterval() {
+	for key, s := range t.open {
+		if t.idle(s) {
+			t.end(key, s)
+		}
+	}
+	t.interval++
+	t.active, t.started, t.ended, t.requests, t.duration = 0, 0, 0, 0, 0
+}
+
+// Account for a client going from a section to another within its session
+func (s *stats) updateTransitions(from, to string) {
+	if from != "" && from != to && s.transitions != nil {
+		s.transitions[from+" -> "+to]++
+	}
+}
+
+// Dumps session counts in the current interval, and the average number of
+// requests and duration of the sessions that ended
+func (s *stats) dumpSessions(w *tabwriter.Writer) {
+	if s.sessions == nil || s.sessions.active == 0 {
+		return
+	}
+	ended, requests, duration := s.sessions.endedSessions()
+	fmt.Fprintf(w, "Sessions: %d active, %d started, %d ended", s.sessions.active, s.sessions.started, ended)
+	if ended > 0 {
+		fmt.Fprintf(w, " averaging %.1f requests over %s", requests, duration.Round(time.Second))
+	}
+	fmt.Fprintln(w)
+}
+
+// Start a new interval of sessions
+func (s *stats) endSessionInterval() {
+	if s.sessions != nil {
+		s.sessions.endInterval()
+	}
+	for _, source := range s.sources {
+		source.endSessionInterval()
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionTracker(t *testing.T) {
+	if newSessionTracker(0) != nil {
+		t.Errorf("Expected no session tracking with a zero timeout")
+	}
+
+	start := time.Date(2018, 5, 9, 16, 0, 0, 0, time.UTC)
+	tracker := newSessionTracker(30 * time.Minute)
+	visit := func(ip, agent string, offset time.Duration) {
+		tracker.add(&logRecord{IP: ip, UserAgent: agent, Timestamp: start.Add(offset)})
+	}
+
+	// Two browsers behind the same IP, one of them coming back after a
+	// long pause, and a client seen once
+	visit("10.0.0.1", "Firefox", 0)
+	visit("10.0.0.1", "Firefox", 5*time.Minute)
+	visit("10.0.0.1", "Chrome", 0)
+	visit("10.0.0.1", "Firefox", 10*time.Minute)
+	visit("10.0.0.2", "curl", 45*time.Minute)
+	visit("10.0.0.1", "Firefox", 50*time.Minute)
+
+	if tracker.active != 4 || tracker.started != 4 || tracker.ended != 1 {
+		t.Errorf("Unexpected counts: %+v", *tracker)
+	}
+	// The Chrome session is idle by now
+	ended, requests, duration := tracker.endedSessions()
+	if ended != 2 || requests != 2 || duration != 5*time.Minute {
+		t.Errorf("%+v %+v %+v != %+v %+v %+v", ended, requests, duration, 2, 2, 5*time.Minute)
+	}
+
+	tracker.endInterval()
+	if len(tracker.open) != 2 || tracker.active != 0 || tracker.ended != 0 {
+		t.Errorf("Unexpected sessions after the interval: %+v", *tracker)
+	}
+	visit("10.0.0.1", "Firefox", 55*time.Minute)
+	if tracker.active != 1 || tracker.started != 0 {
+		t.Errorf("Unexpected counts: %+v", *tracker)
+	}
+}
+
+func TestDumpSessions(t *testing.T) {
+	s := newStats()
+	s.sessions = newSessionTracker(time.Minute)
+	start := time.Date(2018, 5, 9, 16, 0, 0, 0, time.UTC)
+	for _, offset := range []time.Duration{0, 20 * time.Second, 40 * time.Second, 3 * time.Minute} {
+		s.updateStats(&logRecord{IP: "10.0.0.1", Section: "/", StatusCode: 200, Timestamp: start.Add(offset)})
+	}
+	var out bytes.Buffer
+	s.writeStats(&out)
+	if expected := "Sessions: 2 active, 2 started, 1 ended averaging 3.0 requests over 40s"; !strings.Contains(out.String(), expected) {
+		t.Errorf("Expected %q in:\n%s", expected, out.String())
+	}
+
+	s.endSessionInterval()
+	out.Reset()
+	s.writeStats(&out)
+	if strings.Contains(out.String(), "Sessions") {
+		t.Errorf("Expected no sessions in an idle interval:\n%s", out.String())
+	}
+}
+
+func TestTransitions(t *testing.T) {
+	s := newStats()
+	start := time.Date(2018, 5, 9, 16, 0, 0, 0, time.UTC)
+	visits := []struct {
+		ip      string
+		section string
+		offset  time.Duration
+	}{
+		{"10.0.0.1", "/", 0},
+		{"10.0.0.1", "/static", time.Second},
+		{"10.0.0.1", "/static", 2 * time.Second},
+		{"10.0.0.1", "/cart", time.Minute},
+		{"10.0.0.2", "/", 0},
+		{"10.0.0.2", "/static", time.Second},
+		// A new session, starting from the cart
+		{"10.0.0.1", "/cart", 2 * time.Hour},
+	}
+	for _, v := range visits {
+		s.updateStats(&logRecord{IP: v.ip, Section: v.section, StatusCode: 200, Timestamp: start.Add(v.offset)})
+	}
+
+	expected := map[string]int{"/ -> /static": 2, "/static -> /cart": 1}
+	if !reflect.DeepEqual(s.transitions, expected) {
+		t.Errorf("%+v != %+v", s.transitions, expected)
+	}
+	var out bytes.Buffer
+	s.writeStats(&out)
+	if expected := "2 / -> /static"; !strings.Contains(out.String(), expected) {
+		t.Errorf("Expected %q in:\n%s", expected, out.String())
+	}
+}
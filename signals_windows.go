@@ -0,0 +1,9 @@
+package main
+
+import (
+	"os"
+)
+
+// There is no SIGUSR1 on Windows, so stats are only dumped periodically
+func notifyDump(c chan<- os.Signal) {
+}
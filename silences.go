@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schedule in crontab(5) syntax: minute, hour, day of month, month and day
+// of week, each being *, a number, a range or a list, optionally stepped
+type cronSchedule struct {
+	fields [5][]bool // Allowed values of each field
+	dom    bool      // Whether the day of month is restricted
+	dow    bool      // Whether the day of week is restricted
+}
+
+// Ranges of the fields of cron schedules. Sunday is either 0 or 7
+var cronRanges = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Expected 5 fields in cron schedule: %s", expr)
+	}
+	c := &cronSchedule{dom: fields[2] != "*", dow: fields[4] != "*"}
+	for i, field := range fields {
+		min, max := cronRanges[i][0], cronRanges[i][1]
+		c.fields[i] = make([]bool, max+1)
+		for _, part := range strings.Split(field, ",") {
+			step := 1
+			if j := strings.IndexByte(part, '/'); j >= 0 {
+				var err error
+				if step, err = strconv.Atoi(part[j+1:]); err != nil || step <= 0 {
+					return nil, fmt.Errorf("Invalid step in cron schedule: %s", part)
+				}
+				part = part[:j]
+			}
+			from, to := min, max
+			if part != "*" {
+				bounds := strings.SplitN(part, "-", 2)
+				var err error
+				if from, err = strconv.Atoi(bounds[0]); err != nil {
+					return nil, fmt.Errorf("Invalid value in cron schedule: %s", part)
+				}
+				to = from
+				if len(bounds) == 2 {
+					if to, err = strconv.Atoi(bounds[1]); err != nil {
+						return nil, fmt.Errorf("Invalid value in cron schedule: %s", part)
+					}
+				}
+			}
+			if from < min || to > max || from > to {
+				return nil, fmt.Errorf("Value out of range in cron schedule: %s", part)
+			}
+			for v := from; v <= to; v += step {
+				c.fields[i][v] = true
+			}
+		}
+	}
+	if c.fields[4][7] {
+		c.fields[4][0] = true
+	}
+	return c, nil
+}
+
+// Whether the schedule fires at the minute of the given time. As with
+// cron, restricted days of month and of week are alternatives
+func (c *cronSchedule) matches(t time.Time) bool {
+	if !c.fields[0][t.Minute()] || !c.fields[1][t.Hour()] || !c.fields[3][int(t.Month())] {
+		return false
+	}
+	dom, dow := c.fields[2][t.Day()], c.fields[4][int(t.Weekday())]
+	if c.dom && c.dow {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// Recurring silence, e.g. "0 2 * * 6 2h High-traffic" for 2 hours from
+// 02:00 every Saturday. Schedules follow -timezone
+type silenceSchedule struct {
+	text     string
+	cron     *cronSchedule
+	duration time.Duration
+	matcher  *regexp.Regexp // Of silenced alert names, nil for all of them
+}
+
+// Whether the schedule silences an alert at some time
+func (s *silenceSchedule) active(name string, t time.Time) bool {
+	if s.matcher != nil && !s.matcher.MatchString(name) {
+		return false
+	}
+	t = displayTime(t)
+	for m := t.Truncate(time.Minute); t.Sub(m) < s.duration; m = m.Add(-time.Minute) {
+		if s.cron.matches(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Recurring silences given on the command line
+type silenceSchedules []*silenceSchedule
+
+func (l *silenceSchedules) String() string {
+	var texts []string
+	for _, s := range *l {
+		texts = append(texts, s.text)
+	}
+	return strings.Join(texts, "; ")
+}
+
+func (l *silenceSchedules) reset() {
+	*l = nil
+}
+
+func (l *silenceSchedules) Set(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) < 6 {
+		return fmt.Errorf("Expected a cron schedule, a duration and optionally a regular expression: %s", value)
+	}
+	cron, err := parseCron(strings.Join(fields[:5], " "))
+	if err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(fields[5])
+	if err != nil || duration <= 0 {
+		return fmt.Errorf("Invalid silence duration: %s", fields[5])
+	}
+	s := &silenceSchedule{text: value, cron: cron, duration: duration}
+	if len(fields) > 6 {
+		if s.matcher, err = regexp.Compile(strings.Join(fields[6:], " ")); err != nil {
+			return err
+		}
+	}
+	*l = append(*l, s)
+	return nil
+}
+
+// Command-line flag to silence notifications on a schedule
+var silenceFlags silenceSchedules
+
+func init() {
+	flag.Var(&silenceFlags, "silence", "Silence alert notifications on a cron schedule for some time, optionally only for alerts matching a regular expression, e.g. \"0 2 * * 6 2h High-traffic\" (repeatable)")
+}
+
+// One-off silence, e.g. during a load test
+type silence struct {
+	ID      string    `json:"id"`
+	Matcher string    `json:"matcher,omitempty"` // Regular expression of silenced alert names, empty for all of them
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Comment string    `json:"comment,omitempty"`
+
+	matcher *regexp.Regexp
+}
+
+// One-off silences, added and removed through the API
+type silenceList struct {
+	mutex    sync.Mutex
+	lastID   int
+	silences []*silence
+}
+
+var silences = &silenceList{}
+
+// Command-line flag to authenticate changes to silences
+var silenceToken = flag.String("silence-token", "", "Bearer token required to add or remove silences through the API, which only lists them without one")
+
+// Add a silence, validating its matcher, and forget about those over
+func (l *silenceList) add(s *silence) error {
+	if s.Matcher != "" {
+		var err error
+		if s.matcher, err = regexp.Compile(s.Matcher); err != nil {
+			return err
+		}
+	}
+	if !s.End.After(s.Start) {
+		return fmt.Errorf("Silence ends before it starts")
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := time.Now()
+	kept := l.silences[:0]
+	for _, pending := range l.silences {
+		if pending.End.After(now) {
+			kept = append(kept, pending)
+		}
+	}
+	l.silences = kept
+	l.lastID++
+	s.ID = strconv.Itoa(l.lastID)
+	l.silences = append(l.silences, s)
+	return nil
+}
+
+// Remove a silence, returning whether it existed
+func (l *silenceList) remove(id string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for i, s := range l.silences {
+		if s.ID == id {
+			l.silences = append(l.silences[:i], l.silences[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Silences not over yet at some time
+func (l *silenceList) pending(t time.Time) []silence {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	result := []silence{}
+	for _, s := range l.silences {
+		if s.End.After(t) {
+			result = append(result, *s)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Start.Before(result[j].Start) })
+	return result
+}
+
+// Whether notifications about an alert are silenced at some time, either
+// by a one-off silence or by a schedule
+func silenced(name string, t time.Time) bool {
+	for _, s := range silences.pending(t) {
+		if !t.Before(s.Start) && (s.matcher == nil || s.matcher.MatchString(name)) {
+			return true
+		}
+	}
+	for _, s := range silenceFlags {
+		if s.active(name, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether a request may change silences, replying with an error otherwise
+func silenceChangeAuthorized(w http.ResponseWriter, r *http.Request, token string) bool {
+	if token == "" {
+		http.Error(w, "Changing silences requires -silence-token", http.StatusForbidden)
+		return false
+	}
+	if r.Header.Get("Authorization") != "Bearer "+token {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// Serve the silence API on the HTTP listener: GET lists pending silences,
+// POST adds one, given either its end or its duration, and DELETE on
+// /api/silences/<id> removes one. Changes require the token, and are
+// refused without one, not to let anyone reaching the listener mute pages
+func registerSilenceAPI(token string) {
+	authorized := func(w http.ResponseWriter, r *http.Request) bool {
+		return silenceChangeAuthorized(w, r, token)
+	}
+
+	httpMux.HandleFunc("/api/silences", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && !authorized(w, r) {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, silences.pending(time.Now()))
+		case http.MethodPost:
+			var request struct {
+				silence
+				Duration string `json:"duration"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			s := request.silence
+			if s.Start.IsZero() {
+				s.Start = time.Now()
+			}
+			if request.Duration != "" {
+				duration, err := time.ParseDuration(request.Duration)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				s.End = s.Start.Add(duration)
+			}
+			if err := silences.add(&s); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(s)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	httpMux.HandleFunc("/api/silences/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !authorized(w, r) {
+			return
+		}
+		if !silences.remove(strings.TrimPrefix(r.URL.Path, "/api/silences/")) {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCronSchedule(t *testing.T) {
+	base := time.Date(2019, 1, 5, 2, 30, 0, 0, time.UTC) // A Saturday
+	tests := map[string]map[time.Time]bool{
+		"30 2 * * 6": {
+			base:                  true,
+			base.Add(time.Minute): false,
+			base.AddDate(0, 0, 1): false,
+			base.AddDate(0, 0, 7): true,
+		},
+		"*/15 1-3 * * *": {
+			base:                      true,
+			base.Add(5 * time.Minute): false,
+			base.Add(2 * time.Hour):   false,
+		},
+		"30 2 1,5 * 0": {
+			base:                  true,
+			base.AddDate(0, 0, 1): true,
+			base.AddDate(0, 0, 2): false,
+		},
+		"30 2 * 2 7": {
+			base:                   false,
+			base.AddDate(0, 0, 29): true,
+		},
+	}
+	for expr, times := range tests {
+		c, err := parseCron(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for ts, expected := range times {
+			if c.matches(ts) != expected {
+				t.Errorf("%s at %s: %+v != %+v", expr, ts, c.matches(ts), expected)
+			}
+		}
+	}
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("Expected an error for %s", expr)
+		}
+	}
+}
+
+func TestSilenceSchedules(t *testing.T) {
+	var schedules silenceSchedules
+	if err := schedules.Set("0 2 * * 6 2h High-traffic"); err != nil {
+		t.Fatal(err)
+	}
+	saturday := time.Date(2019, 1, 5, 0, 0, 0, 0, time.UTC)
+	for hour, expected := range map[time.Duration]bool{1: false, 2: true, 3: true, 4: false} {
+		if active := schedules[0].active("High-traffic", saturday.Add(hour*time.Hour)); active != expected {
+			t.Errorf("At %d:00: %+v != %+v", hour, active, expected)
+		}
+	}
+	if schedules[0].active("Scanning", saturday.Add(3*time.Hour)) {
+		t.Errorf("Unmatched alert silenced")
+	}
+	for _, value := range []string{"0 2 * * 6", "0 2 * * 6 soon", "0 2 * * 6 2h ("} {
+		if err := schedules.Set(value); err == nil {
+			t.Errorf("Expected an error for %s", value)
+		}
+	}
+}
+
+func TestSilenceAPI(t *testing.T) {
+	defer func() { silences = &silenceList{} }()
+	registerSilenceAPI("secret")
+	send := func(method, url, body, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, url, strings.NewReader(body))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		httpMux.ServeHTTP(w, r)
+		return w
+	}
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		return send(method, url, body, "secret")
+	}
+
+	if w := send("POST", "/api/silences", `{"duration": "1h"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("%+v != %+v", w.Code, http.StatusUnauthorized)
+	}
+	if w := send("POST", "/api/silences", `{"duration": "1h"}`, "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("%+v != %+v", w.Code, http.StatusUnauthorized)
+	}
+	w := do("POST", "/api/silences", `{"matcher": "^Section-", "duration": "1h", "comment": "Load test"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("%+v != %+v: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+	if do("POST", "/api/silences", `{"matcher": "(", "duration": "1h"}`).Code != http.StatusBadRequest {
+		t.Errorf("Invalid matcher accepted")
+	}
+	if do("POST", "/api/silences", `{"duration": "-1h"}`).Code != http.StatusBadRequest {
+		t.Errorf("Negative duration accepted")
+	}
+
+	if !silenced("Section-traffic (^/checkout)", time.Now()) || silenced("High-traffic", time.Now()) {
+		t.Errorf("Silence not applied to the matching alerts only")
+	}
+	if silenced("Section-traffic (^/checkout)", time.Now().Add(2*time.Hour)) {
+		t.Errorf("Silence applied after its end")
+	}
+
+	var pending []silence
+	json.Unmarshal(send("GET", "/api/silences", "", "").Body.Bytes(), &pending)
+	if len(pending) != 1 || pending[0].Comment != "Load test" {
+		t.Fatalf("Unexpected silences: %+v", pending)
+	}
+	if w := send("DELETE", "/api/silences/"+pending[0].ID, "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("%+v != %+v", w.Code, http.StatusUnauthorized)
+	}
+	if w := do("DELETE", "/api/silences/"+pending[0].ID, ""); w.Code != http.StatusNoContent {
+		t.Errorf("%+v != %+v", w.Code, http.StatusNoContent)
+	}
+	if w := do("DELETE", "/api/silences/"+pending[0].ID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("%+v != %+v", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestSilenceChangeAuthorized(t *testing.T) {
+	for _, test := range []struct {
+		token, header string
+		code          int
+	}{
+		{"", "", http.StatusForbidden},
+		{"", "Bearer ", http.StatusForbidden},
+		{"secret", "", http.StatusUnauthorized},
+		{"secret", "Bearer secret", http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/silences", nil)
+		r.Header.Set("Authorization", test.header)
+		if authorized := silenceChangeAuthorized(w, r, test.token); authorized != (test.code == http.StatusOK) || w.Code != test.code {
+			t.Errorf("%+v: %v %d", test, authorized, w.Code)
+		}
+	}
+}
+
+// Test alerts starting to fire while silenced are notified of once the
+// silence is over, if still firing
+func TestNotifierQueueSilenceEnd(t *testing.T) {
+	defer func() { silences = &silenceList{} }()
+	recorder := &recordingNotifier{}
+	q := newNotifierQueue(recorder, severityWarning, 0)
+	now := time.Now()
+	silences.add(&silence{Start: now, End: now.Add(time.Hour)})
+	q.alertChanged(alertChange{Name: "High-traffic", Firing: true})
+	q.alertChanged(alertChange{Name: "Scanning", Firing: true})
+	q.alertChanged(alertChange{Name: "Scanning", Firing: false})
+
+	q.releaseSilenced(now.Add(time.Minute))
+	q.releaseSilenced(now.Add(2 * time.Hour))
+	q.releaseSilenced(now.Add(3 * time.Hour))
+	q.close()
+
+	if len(recorder.notifications) != 1 || recorder.notifications[0].Name != "High-traffic" || !recorder.notifications[0].Firing {
+		t.Errorf("Unexpected notifications: %+v", recorder.notifications)
+	}
+}
+
+func TestNotifierQueueSilence(t *testing.T) {
+	defer func() { silences = &silenceList{} }()
+	recorder := &recordingNotifier{}
+	q := newNotifierQueue(recorder, severityWarning, 0)
+	q.alertChanged(alertChange{Name: "High-traffic", Firing: true})
+	silences.add(&silence{Start: time.Now(), End: time.Now().Add(time.Hour)})
+	q.alertChanged(alertChange{Name: "Scanning", Firing: true})
+	q.alertChanged(alertChange{Name: "Scanning", Firing: false})
+	q.alertChanged(alertChange{Name: "High-traffic", Firing: false})
+	q.close()
+
+	if len(recorder.notifications) != 2 || recorder.notifications[0].Name != "High-traffic" || recorder.notifications[1].Firing {
+		t.Errorf("Unexpected notifications: %+v", recorder.notifications)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClickHouseSink(t *testing.T) {
+	if _, err := newClickHouseSink("tcp://localhost:9000", "logs", "", true, 10, 10); err == nil {
+		t.Errorf("Expected the native protocol to be rejected")
+	}
+	if _, err := newClickHouseSink("http://localhost:8123", "logs", "time,nope", true, 10, 10); err == nil {
+		t.Errorf("Expected unknown columns to be rejected")
+	}
+
+	type request struct {
+		query, database, user string
+		rows                  []map[string]interface{}
+	}
+	var requests []request
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		req := request{query: r.URL.Query().Get("query"), database: r.URL.Query().Get("database"), user: user}
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var row map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+				t.Error(err)
+			}
+			req.rows = append(req.rows, row)
+		}
+		requests = append(requests, req)
+		if failing && strings.HasPrefix(req.query, "INSERT") {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("Code: 241. DB::Exception: Memory limit exceeded\n"))
+		}
+	}))
+	defer server.Close()
+
+	dsn := strings.Replace(server.URL, "http://", "http://web:secret@", 1) + "/logs"
+	s, err := newClickHouseSink(dsn, "records", "time, ip,status,bot", true, 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.write(nil); err != nil || len(requests) != 0 {
+		t.Errorf("Expected nothing to be done without records: %v", err)
+	}
+	at := time.Date(2018, 5, 9, 16, 0, 39, 0, time.UTC)
+	for n := 0; n < 3; n++ {
+		s.record(&logRecord{Timestamp: at, IP: "10.0.0.1", Section: "/api", StatusCode: 200 + n})
+	}
+
+	// Records are kept while ClickHouse fails, but the table created once
+	if err := s.write(nil); err == nil || !strings.Contains(err.Error(), "Memory limit exceeded") {
+		t.Errorf("Unexpected error %v", err)
+	}
+	failing = false
+	if err := s.write(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 4 {
+		t.Fatalf("Unexpected requests %+v", requests)
+	}
+	expected := "CREATE TABLE IF NOT EXISTS records (`time` DateTime64(3, 'UTC'), `ip` String, `status` Int64, `bot` Bool) ENGINE = MergeTree ORDER BY time"
+	if requests[0].query != expected {
+		t.Errorf("%q != %q", requests[0].query, expected)
+	}
+	if requests[0].database != "logs" || requests[0].user != "web" {
+		t.Errorf("Unexpected database %q or user %q", requests[0].database, requests[0].user)
+	}
+	if requests[2].query != "INSERT INTO records FORMAT JSONEachRow" || len(requests[2].rows) != 2 || len(requests[3].rows) != 1 {
+		t.Errorf("Unexpected inserts %+v", requests[2:])
+	}
+	row := requests[2].rows[0]
+	if len(row) != 4 || row["time"] != "2018-05-09T16:00:39Z" || row["status"] != 200.0 || row["bot"] != false {
+		t.Errorf("Unexpected row %+v", row)
+	}
+}
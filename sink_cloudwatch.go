@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// Command-line flags to publish interval aggregates as CloudWatch metrics
+var cloudWatchNamespace = flag.String("cloudwatch-namespace", "", "CloudWatch namespace to publish custom metrics to, e.g. WebServers/Access")
+var cloudWatchDimensions = flag.String("cloudwatch-dimensions", "", "Additional comma-separated dimensions for every metric, e.g. Host=web01,Env=prod")
+
+// Subset of the CloudWatch API used by the sink
+type cloudWatchAPI interface {
+	PutMetricData(ctx context.Context, params *cloudwatch.PutMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricDataOutput, error)
+}
+
+// Sink publishing interval aggregates as CloudWatch custom metrics
+type cloudWatchSink struct {
+	client     cloudWatchAPI
+	namespace  string
+	dimensions []types.Dimension
+	topN       int
+}
+
+// Build a CloudWatch sink using the default AWS credential chain
+func newCloudWatchSink(namespace, dimensions string, topN int) (*cloudWatchSink, error) {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	s := &cloudWatchSink{client: cloudwatch.NewFromConfig(cfg), namespace: namespace, topN: topN}
+	if dimensions != "" {
+		for _, pair := range strings.Split(dimensions, ",") {
+			i := strings.IndexByte(pair, '=')
+			if i <= 0 {
+				return nil, fmt.Errorf("Expected name=value: %s", pair)
+			}
+			s.dimensions = append(s.dimensions, types.Dimension{Name: aws.String(pair[:i]), Value: aws.String(pair[i+1:])})
+		}
+	}
+	return s, nil
+}
+
+func (s *cloudWatchSink) name() string {
+	return "CloudWatch"
+}
+
+// Metric datum for an interval, with the sink's dimensions plus the given
+// name=value pairs
+func (s *cloudWatchSink) datum(i *interval, name string, value float64, unit types.StandardUnit, dimensions ...string) types.MetricDatum {
+	datum := types.MetricDatum{
+		MetricName: aws.String(name),
+		Timestamp:  aws.Time(i.End),
+		Value:      aws.Float64(value),
+		Unit:       unit,
+		Dimensions: append([]types.Dimension{}, s.dimensions...),
+	}
+	for j := 0; j+1 < len(dimensions); j += 2 {
+		datum.Dimensions = append(datum.Dimensions, types.Dimension{Name: aws.String(dimensions[j]), Value: aws.String(dimensions[j+1])})
+	}
+	return datum
+}
+
+// Metric data for an interval
+func (s *cloudWatchSink) metricData(i *interval) []types.MetricDatum {
+	data := []types.MetricDatum{
+		s.datum(i, "Requests", float64(i.Requests), types.StandardUnitCount),
+		s.datum(i, "Bytes", float64(i.Bytes), types.StandardUnitBytes),
+		s.datum(i, "QPS", i.qps(), types.StandardUnitCountSecond),
+	}
+	for class, count := range i.ResponseCodes {
+		data = append(data, s.datum(i, "Requests", float64(count), types.StandardUnitCount, "StatusClass", class))
+	}
+	for _, v := range topCounts(i.Sections, s.topN) {
+		data = append(data, s.datum(i, "Requests", float64(v.count), types.StandardUnitCount, "Section", v.key))
+	}
+	return data
+}
+
+func (s *cloudWatchSink) write(i *interval) error {
+	// PutMetricData accepts up to 1000 metrics per call
+	data := s.metricData(i)
+	for len(data) > 0 {
+		n := len(data)
+		if n > 1000 {
+			n = 1000
+		}
+		_, err := s.client.PutMetricData(context.Background(), &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(s.namespace),
+			MetricData: data[:n],
+		})
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
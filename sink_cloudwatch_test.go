@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// Fake CloudWatch API recording published metric data
+type fakeCloudWatch struct {
+	inputs []*cloudwatch.PutMetricDataInput
+}
+
+func (f *fakeCloudWatch) PutMetricData(ctx context.Context, params *cloudwatch.PutMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricDataOutput, error) {
+	f.inputs = append(f.inputs, params)
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
+
+func TestCloudWatchSink(t *testing.T) {
+	fake := &fakeCloudWatch{}
+	s := &cloudWatchSink{
+		client:     fake,
+		namespace:  "Web/Access",
+		dimensions: []types.Dimension{{Name: aws.String("Host"), Value: aws.String("web01")}},
+		topN:       5,
+	}
+
+	start := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	i := newInterval(start)
+	i.add(&logRecord{StatusCode: 200, Section: "/api", Size: 100})
+	i.add(&logRecord{StatusCode: 404, Section: "/api", Size: 10})
+	i.End = start.Add(10 * time.Second)
+	if err := s.write(i); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.inputs) != 1 || aws.ToString(fake.inputs[0].Namespace) != "Web/Access" {
+		t.Fatalf("Unexpected calls %+v", fake.inputs)
+	}
+	values := make(map[string]float64)
+	for _, datum := range fake.inputs[0].MetricData {
+		key := aws.ToString(datum.MetricName)
+		for _, dimension := range datum.Dimensions {
+			key += " " + aws.ToString(dimension.Name) + "=" + aws.ToString(dimension.Value)
+		}
+		values[key] = aws.ToFloat64(datum.Value)
+	}
+	expected := map[string]float64{
+		"Requests Host=web01":                 2,
+		"Bytes Host=web01":                    110,
+		"QPS Host=web01":                      0.2,
+		"Requests Host=web01 StatusClass=2XX": 1,
+		"Requests Host=web01 StatusClass=4XX": 1,
+		"Requests Host=web01 Section=/api":    2,
+	}
+	for key, value := range expected {
+		if values[key] != value {
+			t.Errorf("%s: %f != %f", key, value, values[key])
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Command-line flags to submit metrics to Datadog, either through a local
+// dogstatsd agent or directly to the API
+var dogstatsdAddr = flag.String("dogstatsd", "", "Address of the dogstatsd agent to submit metrics to, e.g. localhost:8125")
+var datadogAPIKey = flag.String("datadog-api-key", "", "Datadog API key, to submit metrics directly to the Datadog API")
+var datadogSite = flag.String("datadog-site", "datadoghq.com", "Datadog site receiving metrics submitted to the API")
+var datadogPrefix = flag.String("datadog-prefix", "http_monitor", "Prefix of the Datadog metric names")
+var datadogTags = flag.String("datadog-tags", "", "Additional comma-separated tags for every metric, e.g. env:prod,service:web")
+
+// Metric submitted to Datadog
+type datadogMetric struct {
+	name  string
+	kind  string // count or gauge
+	value float64
+	tags  []string
+}
+
+// Sink submitting request rate, error rate and bandwidth metrics to Datadog
+type datadogSink struct {
+	statsdAddr string // Address of the dogstatsd agent, if not using the API
+	seriesURL  string
+	apiKey     string
+	prefix     string
+	tags       []string
+	topN       int
+
+	client *http.Client
+}
+
+func newDatadogSink(statsdAddr, apiKey, site, prefix, tags string, topN int) *datadogSink {
+	s := &datadogSink{
+		statsdAddr: statsdAddr,
+		seriesURL:  "https://api." + site + "/api/v1/series",
+		apiKey:     apiKey,
+		prefix:     prefix,
+		topN:       topN,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+	if tags != "" {
+		s.tags = strings.Split(tags, ",")
+	}
+	return s
+}
+
+func (s *datadogSink) name() string {
+	return "Datadog"
+}
+
+// Metrics for an interval
+func (s *datadogSink) metrics(i *interval) []datadogMetric {
+	tags := func(extra ...string) []string {
+		return append(append([]string{}, s.tags...), extra...)
+	}
+
+	var errorRate float64
+	if i.Requests > 0 {
+		errorRate = float64(i.ResponseCodes["5XX"]) / float64(i.Requests)
+	}
+	metrics := []datadogMetric{
+		{s.prefix + ".requests", "count", float64(i.Requests), tags()},
+		{s.prefix + ".qps", "gauge", i.qps(), tags()},
+		{s.prefix + ".error_rate", "gauge", errorRate, tags()},
+		{s.prefix + ".bytes", "count", float64(i.Bytes), tags()},
+	}
+
+	var classes []string
+	for class := range i.ResponseCodes {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		metrics = append(metrics, datadogMetric{s.prefix + ".responses", "count", float64(i.ResponseCodes[class]), tags("status_class:" + class)})
+	}
+
+	for _, v := range topCounts(i.Sections, s.topN) {
+		metrics = append(metrics, datadogMetric{s.prefix + ".section.requests", "count", float64(v.count), tags("section:" + v.key)})
+	}
+	return metrics
+}
+
+func (s *datadogSink) write(i *interval) error {
+	if s.statsdAddr != "" {
+		return s.writeStatsd(s.metrics(i))
+	}
+	return s.writeSeries(i, s.metrics(i))
+}
+
+// Submit metrics to dogstatsd, all in a single datagram
+func (s *datadogSink) writeStatsd(metrics []datadogMetric) error {
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		kind := "c"
+		if m.kind == "gauge" {
+			kind = "g"
+		}
+		fmt.Fprintf(&buf, "%s:%g|%s", m.name, m.value, kind)
+		if len(m.tags) > 0 {
+			fmt.Fprintf(&buf, "|#%s", strings.Join(m.tags, ","))
+		}
+		buf.WriteByte('\n')
+	}
+
+	conn, err := net.Dial("udp", s.statsdAddr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write(buf.Bytes())
+	return err
+}
+
+// Submit metrics to the series endpoint of the Datadog API
+func (s *datadogSink) writeSeries(i *interval, metrics []datadogMetric) error {
+	type series struct {
+		Metric   string       `json:"metric"`
+		Type     string       `json:"type"`
+		Points   [][2]float64 `json:"points"`
+		Interval int64        `json:"interval,omitempty"`
+		Host     string       `json:"host,omitempty"`
+		Tags     []string     `json:"tags,omitempty"`
+	}
+	host, _ := os.Hostname()
+	var payload struct {
+		Series []series `json:"series"`
+	}
+	for _, m := range metrics {
+		payload.Series = append(payload.Series, series{
+			Metric:   m.name,
+			Type:     m.kind,
+			Points:   [][2]float64{{float64(i.End.Unix()), m.value}},
+			Interval: int64(i.End.Sub(i.Start).Seconds()),
+			Host:     host,
+			Tags:     m.tags,
+		})
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.seriesURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", s.apiKey)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("Datadog replied with %s", resp.Status)
+	}
+	return nil
+}
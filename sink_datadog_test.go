@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func datadogInterval() *interval {
+	start := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	i := newInterval(start)
+	i.add(&logRecord{StatusCode: 200, Section: "/api", Size: 100})
+	i.add(&logRecord{StatusCode: 200, Section: "/api", Size: 100})
+	i.add(&logRecord{StatusCode: 200, Section: "/api", Size: 100})
+	i.add(&logRecord{StatusCode: 503, Section: "/report", Size: 10})
+	i.End = start.Add(10 * time.Second)
+	return i
+}
+
+func TestDatadogSinkStatsd(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	s := newDatadogSink(conn.LocalAddr().String(), "", "datadoghq.com", "web", "env:prod", 1)
+	if err := s.write(datadogInterval()); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "web.requests:4|c|#env:prod\n" +
+		"web.qps:0.4|g|#env:prod\n" +
+		"web.error_rate:0.25|g|#env:prod\n" +
+		"web.bytes:310|c|#env:prod\n" +
+		"web.responses:3|c|#env:prod,status_class:2XX\n" +
+		"web.responses:1|c|#env:prod,status_class:5XX\n" +
+		"web.section.requests:3|c|#env:prod,section:/api\n"
+	if string(buf[:n]) != expected {
+		t.Errorf("%q != %q", expected, string(buf[:n]))
+	}
+}
+
+func TestDatadogSinkAPI(t *testing.T) {
+	var apiKey string
+	var payload struct {
+		Series []struct {
+			Metric string       `json:"metric"`
+			Points [][2]float64 `json:"points"`
+		} `json:"series"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey = r.Header.Get("DD-API-KEY")
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &payload)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	s := newDatadogSink("", "secret", "datadoghq.com", "web", "", 1)
+	s.seriesURL = server.URL
+	if err := s.write(datadogInterval()); err != nil {
+		t.Fatal(err)
+	}
+	if apiKey != "secret" {
+		t.Errorf("%q != %q", "secret", apiKey)
+	}
+	if len(payload.Series) != 7 || payload.Series[0].Metric != "web.requests" || payload.Series[0].Points[0] != [2]float64{1546336810, 4} {
+		t.Errorf("Unexpected series %+v", payload.Series)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Command-line flags to index records into Elasticsearch or OpenSearch
+var elasticsearchURL = flag.String("elasticsearch-url", "", "Elasticsearch or OpenSearch URL every parsed record is indexed into, e.g. http://localhost:9200")
+var elasticsearchIndex = flag.String("elasticsearch-index", "http_monitor", "Prefix of the daily Elasticsearch indices, suffixed with the date of records, e.g. http_monitor-2018.05.09")
+var elasticsearchUser = flag.String("elasticsearch-user", "", "Elasticsearch user, for basic authentication")
+var elasticsearchPassword = flag.String("elasticsearch-password", "", "Elasticsearch password, for basic authentication")
+var elasticsearchAPIKey = flag.String("elasticsearch-api-key", "", "Elasticsearch API key, instead of basic authentication")
+var elasticsearchBatch = flag.Int("elasticsearch-batch", 1000, "Records indexed per bulk request")
+var elasticsearchBuffer = flag.Int("elasticsearch-buffer", 100000, "Records buffered while Elasticsearch is unavailable, newer ones being dropped")
+
+// Sink bulk-indexing every record into Elasticsearch. Records are buffered,
+// then indexed when intervals close. Those failing to be sent are kept for
+// the next interval, up to the buffer size
+type elasticsearchSink struct {
+	bulkURL  string
+	index    string
+	user     string
+	password string
+	apiKey   string
+	batch    int
+
+	client  *http.Client
+	records *recordBuffer
+}
+
+func newElasticsearchSink(baseURL, index, user, password, apiKey string, batch, limit int) *elasticsearchSink {
+	return &elasticsearchSink{
+		bulkURL:  strings.TrimSuffix(baseURL, "/") + "/_bulk",
+		index:    index,
+		user:     user,
+		password: password,
+		apiKey:   apiKey,
+		batch:    batch,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		records:  &recordBuffer{limit: limit},
+	}
+}
+
+func (s *elasticsearchSink) name() string {
+	return "Elasticsearch"
+}
+
+func (s *elasticsearchSink) record(r *logRecord) {
+	s.records.add(r)
+}
+
+// Bulk request indexing records, each into the index of its day
+func (s *elasticsearchSink) body(records []*logRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, r := range records {
+		action := map[string]map[string]string{"index": {"_index": s.index + "-" + r.Timestamp.UTC().Format("2006.01.02")}}
+		doc := recordDocument(r)
+		doc["@timestamp"] = doc["time"]
+		delete(doc, "time")
+		if err := encoder.Encode(action); err != nil {
+			return nil, err
+		}
+		if err := encoder.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Response to a bulk request, telling about records which were rejected
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// Index a batch of records, returning how many were rejected, e.g. due to
+// mapping conflicts, along with the first reason. Those are not retried
+func (s *elasticsearchSink) send(records []*logRecord) (int, string, error) {
+	body, err := s.body(records)
+	if err != nil {
+		return 0, "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.bulkURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+s.apiKey)
+	} else if s.user != "" {
+		req.SetBasicAuth(s.user, s.password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return 0, "", fmt.Errorf("Elasticsearch replied with %s", resp.Status)
+	}
+
+	var result bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, "", err
+	}
+	rejected, reason := 0, ""
+	for _, item := range result.Items {
+		for _, outcome := range item {
+			if outcome.Status/100 != 2 {
+				rejected++
+				if reason == "" {
+					reason = outcome.Error.Type + ": " + outcome.Error.Reason
+				}
+			}
+		}
+	}
+	return rejected, reason, nil
+}
+
+// Index the records received so far, in batches. Those which cannot be
+// sent are kept for the next interval
+func (s *elasticsearchSink) write(i *interval) error {
+	records, dropped := s.records.take()
+
+	total, rejected, reason := len(records), 0, ""
+	for len(records) > 0 {
+		n := s.batch
+		if n > len(records) {
+			n = len(records)
+		}
+		r, why, err := s.send(records[:n])
+		if err != nil {
+			s.records.requeue(records, dropped)
+			return err
+		}
+		if rejected += r; reason == "" {
+			reason = why
+		}
+		records = records[n:]
+	}
+	if rejected > 0 {
+		s.records.requeue(nil, dropped) // Reported next time
+		return fmt.Errorf("Elasticsearch rejected %d of %d records (%s)", rejected, total, reason)
+	}
+	if dropped > 0 {
+		return fmt.Errorf("Dropped %d records while Elasticsearch was unavailable", dropped)
+	}
+	return nil
+}
+
+func (s *elasticsearchSink) close() error {
+	return s.write(nil)
+}
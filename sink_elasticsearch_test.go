@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestElasticsearchSink(t *testing.T) {
+	var requests [][]map[string]interface{}
+	var auth string
+	available := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		var lines []map[string]interface{}
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var line map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				t.Error(err)
+			}
+			lines = append(lines, line)
+		}
+		requests = append(requests, lines)
+		if len(requests) == 1 {
+			w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}},{"index":{"status":201}}]}`))
+		} else {
+			w.Write([]byte(`{"errors":true,"items":[{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}]}`))
+		}
+	}))
+	defer server.Close()
+
+	s := newElasticsearchSink(server.URL+"/", "web", "", "", "secret", 2, 3)
+	at := time.Date(2018, 5, 9, 16, 0, 39, 0, time.UTC)
+	for n := 0; n < 4; n++ {
+		s.record(&logRecord{Timestamp: at, IP: "10.0.0.1", Section: "/api", StatusCode: 200 + n, Country: "ES", Bot: true})
+	}
+
+	// Records are kept while Elasticsearch is unavailable, up to the buffer
+	// size
+	if err := s.write(nil); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Unexpected error %v", err)
+	}
+	available = true
+	if err := s.write(nil); err == nil || !strings.Contains(err.Error(), "rejected 1 of 3 records (mapper_parsing_exception: failed to parse)") {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if len(requests) != 2 || len(requests[0]) != 4 || len(requests[1]) != 2 {
+		t.Fatalf("Unexpected bulk requests %+v", requests)
+	}
+	if auth != "ApiKey secret" {
+		t.Errorf("%q != %q", auth, "ApiKey secret")
+	}
+	action, doc := requests[0][0], requests[0][1]
+	if index := action["index"].(map[string]interface{})["_index"]; index != "web-2018.05.09" {
+		t.Errorf("%+v != %+v", index, "web-2018.05.09")
+	}
+	if doc["@timestamp"] != "2018-05-09T16:00:39Z" || doc["status"] != 200.0 || doc["country"] != "ES" || doc["bot"] != true {
+		t.Errorf("Unexpected document %+v", doc)
+	}
+	if _, ok := doc["user"]; ok {
+		t.Errorf("Expected empty fields to be left out of %+v", doc)
+	}
+	if err := s.write(nil); err == nil || err.Error() != "Dropped 1 records while Elasticsearch was unavailable" || len(requests) != 2 {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
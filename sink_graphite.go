@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Command-line flags to push metrics to Graphite
+var graphiteAddr = flag.String("graphite", "", "Graphite/carbon plaintext endpoint to push metrics to, e.g. localhost:2003")
+var graphitePrefix = flag.String("graphite-prefix", "http_monitor", "Prefix of the Graphite metric paths")
+var graphiteInterval = flag.Duration("graphite-interval", time.Minute, "How often metrics are pushed to Graphite")
+
+// Sink pushing metrics to Graphite using the plaintext protocol. Intervals
+// are accumulated until the push interval elapses
+type graphiteSink struct {
+	addr     string
+	prefix   string
+	interval time.Duration
+	topN     int
+
+	pending *interval
+}
+
+func newGraphiteSink(addr, prefix string, interval time.Duration, topN int) *graphiteSink {
+	return &graphiteSink{addr: addr, prefix: strings.TrimSuffix(prefix, "."), interval: interval, topN: topN}
+}
+
+// Push metrics accumulated since the last push
+func (s *graphiteSink) close() error {
+	if s.pending == nil {
+		return nil
+	}
+	return s.flush()
+}
+
+func (s *graphiteSink) name() string {
+	return "Graphite"
+}
+
+// Characters not allowed in Graphite metric path components
+var graphiteInvalidRegExp = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// Metric path component for a section, e.g. "api" for "/api"
+func graphiteSection(section string) string {
+	name := graphiteInvalidRegExp.ReplaceAllString(strings.TrimPrefix(section, "/"), "_")
+	if name == "" {
+		return "root"
+	}
+	return name
+}
+
+// Plaintext protocol for an interval
+func (s *graphiteSink) lines(i *interval) string {
+	var buf bytes.Buffer
+	ts := i.End.Unix()
+	fmt.Fprintf(&buf, "%s.requests %d %d\n", s.prefix, i.Requests, ts)
+	fmt.Fprintf(&buf, "%s.bytes %d %d\n", s.prefix, i.Bytes, ts)
+	fmt.Fprintf(&buf, "%s.qps %f %d\n", s.prefix, i.qps(), ts)
+
+	var classes []string
+	for class := range i.ResponseCodes {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		fmt.Fprintf(&buf, "%s.status.%s %d %d\n", s.prefix, class, i.ResponseCodes[class], ts)
+	}
+
+	for _, v := range topCounts(i.Sections, s.topN) {
+		fmt.Fprintf(&buf, "%s.sections.%s %d %d\n", s.prefix, graphiteSection(v.key), v.count, ts)
+	}
+	return buf.String()
+}
+
+func (s *graphiteSink) write(i *interval) error {
+	if s.pending == nil {
+		s.pending = newInterval(i.Start)
+	}
+	s.pending.merge(i)
+	s.pending.End = i.End
+	if s.pending.End.Sub(s.pending.Start) < s.interval {
+		return nil
+	}
+
+	return s.flush()
+}
+
+// Push the accumulated intervals
+func (s *graphiteSink) flush() error {
+	pending := s.pending
+	s.pending = nil
+	conn, err := net.DialTimeout("tcp", s.addr, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(s.lines(pending)))
+	return err
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestGraphiteSink(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	received := make(chan string)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		data, _ := io.ReadAll(conn)
+		conn.Close()
+		received <- string(data)
+	}()
+
+	s := newGraphiteSink(l.Addr().String(), "web.", 20*time.Second, 5)
+	start := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+
+	// Two 10s intervals are pushed together
+	for n := 0; n < 2; n++ {
+		i := newInterval(start.Add(time.Duration(n) * 10 * time.Second))
+		i.add(&logRecord{StatusCode: 200, Section: "/api.v1", Size: 100})
+		i.add(&logRecord{StatusCode: 200, Section: "/", Size: 100})
+		i.End = i.Start.Add(10 * time.Second)
+		if err := s.write(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := "web.requests 4 1546336820\n" +
+		"web.bytes 400 1546336820\n" +
+		"web.qps 0.200000 1546336820\n" +
+		"web.status.2XX 4 1546336820\n" +
+		"web.sections.root 2 1546336820\n" +
+		"web.sections.api_v1 2 1546336820\n"
+	select {
+	case data := <-received:
+		if data != expected {
+			t.Errorf("%q != %q", expected, data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for metrics")
+	}
+}
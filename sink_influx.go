@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Command-line flags to export interval aggregates to InfluxDB
+var influxURL = flag.String("influx-url", "", "InfluxDB URL to write interval aggregates to, e.g. http://localhost:8086")
+var influxOrg = flag.String("influx-org", "", "InfluxDB organization")
+var influxBucket = flag.String("influx-bucket", "http_monitor", "InfluxDB bucket")
+var influxToken = flag.String("influx-token", "", "InfluxDB API token")
+var influxMeasurement = flag.String("influx-measurement", "http_monitor", "Prefix of the InfluxDB measurements written")
+
+// Sink writing interval aggregates to the InfluxDB v2 write API using the
+// line protocol
+type influxSink struct {
+	writeURL    string
+	token       string
+	measurement string
+	topN        int
+
+	client *http.Client
+}
+
+func newInfluxSink(baseURL, org, bucket, token, measurement string, topN int) *influxSink {
+	query := url.Values{"org": {org}, "bucket": {bucket}, "precision": {"s"}}
+	return &influxSink{
+		writeURL:    strings.TrimSuffix(baseURL, "/") + "/api/v2/write?" + query.Encode(),
+		token:       token,
+		measurement: measurement,
+		topN:        topN,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *influxSink) name() string {
+	return "InfluxDB"
+}
+
+// Escape commas, spaces and equal signs in tag values
+var influxTagEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+
+// Line protocol for an interval: overall rates, then one series per
+// response class and per top section
+func (s *influxSink) lines(i *interval) string {
+	var buf bytes.Buffer
+	ts := i.End.Unix()
+	fmt.Fprintf(&buf, "%s qps=%f,requests=%di,bytes=%di %d\n", s.measurement, i.qps(), i.Requests, i.Bytes, ts)
+
+	var classes []string
+	for class := range i.ResponseCodes {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		fmt.Fprintf(&buf, "%s_status,class=%s requests=%di %d\n", s.measurement, class, i.ResponseCodes[class], ts)
+	}
+
+	for _, v := range topCounts(i.Sections, s.topN) {
+		fmt.Fprintf(&buf, "%s_section,section=%s requests=%di %d\n", s.measurement, influxTagEscaper.Replace(v.key), v.count, ts)
+	}
+	return buf.String()
+}
+
+func (s *influxSink) write(i *interval) error {
+	req, err := http.NewRequest(http.MethodPost, s.writeURL, strings.NewReader(s.lines(i)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Token "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("InfluxDB replied with %s", resp.Status)
+	}
+	return nil
+}
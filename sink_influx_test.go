@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestInfluxSink(t *testing.T) {
+	var query, auth, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, auth = r.URL.RawQuery, r.Header.Get("Authorization")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	start := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	i := newInterval(start)
+	i.add(&logRecord{StatusCode: 200, Section: "/api", Size: 100})
+	i.add(&logRecord{StatusCode: 404, Section: "/my section", Size: 10})
+	i.End = start.Add(10 * time.Second)
+
+	s := newInfluxSink(server.URL+"/", "acme", "web", "secret", "http", 5)
+	if err := s.write(i); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "bucket=web&org=acme&precision=s"; query != expected {
+		t.Errorf("%q != %q", expected, query)
+	}
+	if expected := "Token secret"; auth != expected {
+		t.Errorf("%q != %q", expected, auth)
+	}
+	expected := "http qps=0.200000,requests=2i,bytes=110i 1546336810\n" +
+		"http_status,class=2XX requests=1i 1546336810\n" +
+		"http_status,class=4XX requests=1i 1546336810\n" +
+		"http_section,section=/api requests=1i 1546336810\n" +
+		"http_section,section=/my\\ section requests=1i 1546336810\n"
+	if body != expected {
+		t.Errorf("%q != %q", expected, body)
+	}
+}
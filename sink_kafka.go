@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Command-line flags to publish records to a Kafka topic
+var kafkaOutputBrokers = flag.String("kafka-output-brokers", "", "Comma-separated list of Kafka brokers every parsed record is published to")
+var kafkaOutputTopic = flag.String("kafka-output-topic", "http_monitor-records", "Kafka topic records are published to, keyed by client IP")
+var kafkaOutputFormat = flag.String("kafka-output-format", "json", "Format of the records published to Kafka (json, avro)")
+var kafkaSchemaRegistry = flag.String("kafka-schema-registry", "", "Schema registry URL the Avro schema of records is registered with, e.g. http://localhost:8081")
+var kafkaOutputBuffer = flag.Int("kafka-output-buffer", 100000, "Records buffered while Kafka is unavailable, newer ones being dropped")
+
+// Producer of Kafka messages, as implemented by kafka.Writer
+type kafkaProducer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Sink publishing every record to a Kafka topic, as JSON or Avro using the
+// schema registry wire format. Records are published when intervals close,
+// those failing to be kept for the next
+type kafkaSink struct {
+	producer kafkaProducer
+	topic    string
+	format   string
+	registry string
+	schemaID int // Id of the Avro schema, once registered
+
+	client  *http.Client
+	records *recordBuffer
+}
+
+func newKafkaSink(brokers, topic, format, registry string, limit int) (*kafkaSink, error) {
+	switch format {
+	case "json":
+	case "avro":
+		if registry == "" {
+			return nil, fmt.Errorf("Avro records require a -kafka-schema-registry")
+		}
+	default:
+		return nil, fmt.Errorf("Unknown Kafka output format: %s", format)
+	}
+	producer := &kafka.Writer{
+		Addr:                   kafka.TCP(strings.Split(brokers, ",")...),
+		Topic:                  topic,
+		Balancer:               &kafka.Hash{},
+		BatchSize:              1000,
+		BatchTimeout:           10 * time.Millisecond,
+		AllowAutoTopicCreation: true,
+	}
+	return &kafkaSink{
+		producer: producer,
+		topic:    topic,
+		format:   format,
+		registry: strings.TrimSuffix(registry, "/"),
+		client:   &http.Client{Timeout: 30 * time.Second},
+		records:  &recordBuffer{limit: limit},
+	}, nil
+}
+
+func (s *kafkaSink) name() string {
+	return "Kafka"
+}
+
+func (s *kafkaSink) record(r *logRecord) {
+	s.records.add(r)
+}
+
+// Register the Avro schema of records under the subject of the topic
+// values, which is a no-op when already registered
+func (s *kafkaSink) registerSchema() error {
+	body, err := json.Marshal(map[string]string{"schema": avroSchema()})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.registry+"/subjects/"+s.topic+"-value/versions", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("Schema registry replied with %s", resp.Status)
+	}
+	var registered struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&registered); err != nil {
+		return err
+	}
+	s.schemaID = registered.ID
+	return nil
+}
+
+// Message value of a record: JSON, or an Avro datum prefixed by a zero
+// byte and the schema id
+func (s *kafkaSink) value(r *logRecord) ([]byte, error) {
+	if s.format == "json" {
+		return json.Marshal(recordDocument(r))
+	}
+	value := make([]byte, 5, 256)
+	binary.BigEndian.PutUint32(value[1:], uint32(s.schemaID))
+	return append(value, avroRecord(r)...), nil
+}
+
+// Publish records, registering the Avro schema first if need be
+func (s *kafkaSink) publish(records []*logRecord) error {
+	if s.format == "avro" && s.schemaID == 0 {
+		if err := s.registerSchema(); err != nil {
+			return err
+		}
+	}
+	messages := make([]kafka.Message, len(records))
+	for n, r := range records {
+		value, err := s.value(r)
+		if err != nil {
+			return err
+		}
+		messages[n] = kafka.Message{Key: []byte(r.IP), Value: value}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return s.producer.WriteMessages(ctx, messages...)
+}
+
+// Publish the records received so far. Those which cannot be are all kept
+// for the next interval, even if some made it, so that none is lost
+func (s *kafkaSink) write(i *interval) error {
+	records, dropped := s.records.take()
+	if len(records) > 0 {
+		if err := s.publish(records); err != nil {
+			s.records.requeue(records, dropped)
+			return err
+		}
+	}
+	if dropped > 0 {
+		return fmt.Errorf("Dropped %d records while Kafka was unavailable", dropped)
+	}
+	return nil
+}
+
+func (s *kafkaSink) close() error {
+	err := s.write(nil)
+	if closeErr := s.producer.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
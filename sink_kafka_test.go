@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Producer keeping messages, failing while told to
+type fakeProducer struct {
+	messages []kafka.Message
+	failing  bool
+}
+
+func (p *fakeProducer) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if p.failing {
+		return fmt.Errorf("Leader not available")
+	}
+	p.messages = append(p.messages, msgs...)
+	return nil
+}
+
+func (p *fakeProducer) Close() error {
+	return nil
+}
+
+func TestKafkaSink(t *testing.T) {
+	if _, err := newKafkaSink("localhost:9092", "records", "avro", "", 10); err == nil {
+		t.Errorf("Expected Avro to require a schema registry")
+	}
+	if _, err := newKafkaSink("localhost:9092", "records", "xml", "", 10); err == nil {
+		t.Errorf("Expected unknown formats to be rejected")
+	}
+
+	s, err := newKafkaSink("localhost:9092", "records", "json", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	producer := &fakeProducer{failing: true}
+	s.producer = producer
+	at := time.Date(2018, 5, 9, 16, 0, 39, 0, time.UTC)
+	s.record(&logRecord{Timestamp: at, IP: "10.0.0.1", Section: "/api", StatusCode: 200})
+
+	// Records are kept while Kafka is unavailable
+	if err := s.write(nil); err == nil {
+		t.Errorf("Expected an error")
+	}
+	producer.failing = false
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(producer.messages) != 1 || string(producer.messages[0].Key) != "10.0.0.1" {
+		t.Fatalf("Unexpected messages %+v", producer.messages)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(producer.messages[0].Value, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["section"] != "/api" || doc["time"] != "2018-05-09T16:00:39Z" {
+		t.Errorf("Unexpected record %+v", doc)
+	}
+}
+
+func TestKafkaSinkAvro(t *testing.T) {
+	var path string
+	var schema map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&schema)
+		w.Write([]byte(`{"id":42}`))
+	}))
+	defer server.Close()
+
+	s, err := newKafkaSink("localhost:9092", "records", "avro", server.URL+"/", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	producer := &fakeProducer{}
+	s.producer = producer
+	r := &logRecord{Timestamp: time.Unix(1, 0), IP: "10.0.0.1", StatusCode: 200}
+	s.record(r)
+	if err := s.write(nil); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/subjects/records-value/versions" || !strings.Contains(schema["schema"], `"name":"Record"`) {
+		t.Errorf("Unexpected registration of %+v at %s", schema, path)
+	}
+	if len(producer.messages) != 1 {
+		t.Fatalf("Unexpected messages %+v", producer.messages)
+	}
+	value := producer.messages[0].Value
+	if value[0] != 0 || binary.BigEndian.Uint32(value[1:5]) != 42 || string(value[5:]) != string(avroRecord(r)) {
+		t.Errorf("Unexpected value % x", value)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Command-line flags to ship records to Grafana Loki
+var lokiURL = flag.String("loki-url", "", "Grafana Loki URL every parsed record is pushed to as a JSON log line, e.g. http://localhost:3100")
+var lokiLabels = flag.String("loki-labels", "job=http_monitor", "Additional comma-separated labels of every Loki stream, e.g. job=http_monitor,env=prod")
+var lokiTenant = flag.String("loki-tenant", "", "Loki tenant, sent as X-Scope-OrgID")
+var lokiBatch = flag.Int("loki-batch", 1000, "Records pushed per Loki request")
+var lokiBuffer = flag.Int("loki-buffer", 100000, "Records buffered while Loki is unavailable, newer ones being dropped")
+
+// Sink pushing every record to Loki, labeled by section, response class and
+// virtual host so that streams match the monitor's own breakdowns. Records
+// are pushed when intervals close, those failing to be kept for the next
+type lokiSink struct {
+	pushURL string
+	labels  map[string]string
+	tenant  string
+	batch   int
+
+	client  *http.Client
+	records *recordBuffer
+}
+
+// Parse comma-separated key=value labels
+func parseLokiLabels(labels string) (map[string]string, error) {
+	parsed := make(map[string]string)
+	if labels == "" {
+		return parsed, nil
+	}
+	for _, pair := range strings.Split(labels, ",") {
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("Expected key=value: %s", pair)
+		}
+		parsed[strings.TrimSpace(pair[:i])] = strings.TrimSpace(pair[i+1:])
+	}
+	return parsed, nil
+}
+
+func newLokiSink(baseURL, labels, tenant string, batch, limit int) (*lokiSink, error) {
+	parsed, err := parseLokiLabels(labels)
+	if err != nil {
+		return nil, err
+	}
+	return &lokiSink{
+		pushURL: strings.TrimSuffix(baseURL, "/") + "/loki/api/v1/push",
+		labels:  parsed,
+		tenant:  tenant,
+		batch:   batch,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		records: &recordBuffer{limit: limit},
+	}, nil
+}
+
+func (s *lokiSink) name() string {
+	return "Loki"
+}
+
+func (s *lokiSink) record(r *logRecord) {
+	s.records.add(r)
+}
+
+// Stream of log lines sharing the same labels
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"` // Timestamp in nanoseconds and line
+}
+
+// Labels of the stream of a record, leaving out unknown virtual hosts
+func (s *lokiSink) streamLabels(r *logRecord) map[string]string {
+	labels := map[string]string{"section": r.Section, "status_class": statusClass(r.StatusCode)}
+	if r.VHost != "" {
+		labels["vhost"] = r.VHost
+	}
+	for k, v := range s.labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// Push request for records, grouped into streams in order of appearance
+func (s *lokiSink) body(records []*logRecord) ([]byte, error) {
+	var streams []*lokiStream
+	byLabels := make(map[string]*lokiStream)
+	for _, r := range records {
+		line, err := json.Marshal(recordDocument(r))
+		if err != nil {
+			return nil, err
+		}
+		labels := s.streamLabels(r)
+		key, _ := json.Marshal(labels) // Keys are sorted
+		stream := byLabels[string(key)]
+		if stream == nil {
+			stream = &lokiStream{Stream: labels}
+			byLabels[string(key)] = stream
+			streams = append(streams, stream)
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(r.Timestamp.UnixNano(), 10), string(line)})
+	}
+	return json.Marshal(map[string][]*lokiStream{"streams": streams})
+}
+
+// Push a batch of records, telling whether to retry them upon failure,
+// which is not the case when Loki rejects them, e.g. for being too old
+func (s *lokiSink) send(records []*logRecord) (bool, error) {
+	body, err := s.body(records)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.pushURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.tenant != "" {
+		req.Header.Set("X-Scope-OrgID", s.tenant)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		retry := resp.StatusCode/100 != 4 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("Loki replied with %s", resp.Status)
+	}
+	return false, nil
+}
+
+// Push the records received so far, in batches. Those which cannot be sent
+// are kept for the next interval
+func (s *lokiSink) write(i *interval) error {
+	records, dropped := s.records.take()
+	var failure error
+	for len(records) > 0 {
+		n := s.batch
+		if n > len(records) {
+			n = len(records)
+		}
+		if retry, err := s.send(records[:n]); err != nil {
+			if retry {
+				s.records.requeue(records, dropped)
+				return err
+			}
+			failure = err
+		}
+		records = records[n:]
+	}
+	if failure != nil {
+		s.records.requeue(nil, dropped) // Reported next time
+		return failure
+	}
+	if dropped > 0 {
+		return fmt.Errorf("Dropped %d records while Loki was unavailable", dropped)
+	}
+	return nil
+}
+
+func (s *lokiSink) close() error {
+	return s.write(nil)
+}
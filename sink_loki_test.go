@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLokiSink(t *testing.T) {
+	var pushes []map[string][]lokiStream
+	var tenant string
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = r.Header.Get("X-Scope-OrgID")
+		var push map[string][]lokiStream
+		if err := json.NewDecoder(r.Body).Decode(&push); err != nil {
+			t.Error(err)
+		}
+		pushes = append(pushes, push)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	if _, err := newLokiSink(server.URL, "job", "", 10, 10); err == nil {
+		t.Errorf("Expected labels without values to be rejected")
+	}
+	s, err := newLokiSink(server.URL+"/", "job=web, env=prod", "acme", 10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2018, 5, 9, 16, 0, 39, 0, time.UTC)
+	s.record(&logRecord{Timestamp: at, IP: "10.0.0.1", Section: "/api", StatusCode: 200, VHost: "www.example.com"})
+	s.record(&logRecord{Timestamp: at.Add(time.Second), IP: "10.0.0.2", Section: "/", StatusCode: 503})
+	s.record(&logRecord{Timestamp: at.Add(2 * time.Second), IP: "10.0.0.3", Section: "/api", StatusCode: 204, VHost: "www.example.com"})
+
+	// Records are kept while Loki is unavailable
+	if err := s.write(nil); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Unexpected error %v", err)
+	}
+	status = http.StatusNoContent
+	if err := s.write(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(pushes) != 2 || tenant != "acme" {
+		t.Fatalf("Unexpected pushes %+v for tenant %q", pushes, tenant)
+	}
+	streams := pushes[1]["streams"]
+	if len(streams) != 2 {
+		t.Fatalf("Unexpected streams %+v", streams)
+	}
+	expected := map[string]string{"job": "web", "env": "prod", "section": "/api", "status_class": "2XX", "vhost": "www.example.com"}
+	for k, v := range expected {
+		if streams[0].Stream[k] != v {
+			t.Errorf("%s: %q != %q", k, streams[0].Stream[k], v)
+		}
+	}
+	if _, ok := streams[1].Stream["vhost"]; ok {
+		t.Errorf("Expected no vhost label in %+v", streams[1].Stream)
+	}
+	values := streams[0].Values
+	if len(values) != 2 || values[0][0] != "1525881639000000000" || !strings.Contains(values[0][1], `"ip":"10.0.0.1"`) {
+		t.Errorf("Unexpected values %+v", values)
+	}
+
+	// Records Loki rejects are not retried
+	status = http.StatusBadRequest
+	s.record(&logRecord{Timestamp: at, Section: "/", StatusCode: 200})
+	if err := s.write(nil); err == nil {
+		t.Errorf("Expected an error")
+	}
+	if records, _ := s.records.take(); len(records) != 0 {
+		t.Errorf("Unexpected records kept %+v", records)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// NATS server accepting a single connection, collecting what is published
+// and replying with the given error to the first PING after CONNECT, if any
+type fakeNATSServer struct {
+	listener  net.Listener
+	connect   chan string
+	published chan [2]string // Subject and payload
+	failure   string
+}
+
+func newFakeNATSServer(t *testing.T, failure string) *fakeNATSServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeNATSServer{listener: l, connect: make(chan string, 1), published: make(chan [2]string, 100), failure: failure}
+	go s.serve()
+	return s
+}
+
+func (s *fakeNATSServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"test\",\"max_payload\":1048576}\r\n")
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "CONNECT":
+			s.connect <- strings.TrimSpace(line[8:])
+		case "PUB":
+			var size int
+			fmt.Sscan(fields[2], &size)
+			payload := make([]byte, size+2)
+			io.ReadFull(reader, payload)
+			s.published <- [2]string{fields[1], string(payload[:size])}
+		case "PING":
+			if s.failure != "" {
+				fmt.Fprintf(conn, "-ERR '%s'\r\n", s.failure)
+				return
+			}
+			fmt.Fprintf(conn, "PONG\r\n")
+		}
+	}
+}
+
+func TestNATSSink(t *testing.T) {
+	if _, err := newNATSSink("http://localhost:4222", "web", 10); err == nil {
+		t.Errorf("Expected non-NATS URLs to be rejected")
+	}
+
+	server := newFakeNATSServer(t, "")
+	defer server.listener.Close()
+	s, err := newNATSSink("nats://web:secret@"+server.listener.Addr().String(), "web", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2018, 5, 9, 16, 0, 39, 0, time.UTC)
+	s.record(&logRecord{Timestamp: at, IP: "10.0.0.1", Section: "/api", StatusCode: 503})
+	s.alertChanged(alertChange{Name: "High-traffic", Firing: true, Severity: severityCritical, Detail: "hits = 20"})
+	if err := s.write(nil); err != nil {
+		t.Fatal(err)
+	}
+	defer s.close()
+
+	var connect map[string]interface{}
+	if err := json.Unmarshal([]byte(<-server.connect), &connect); err != nil {
+		t.Fatal(err)
+	}
+	if connect["user"] != "web" || connect["pass"] != "secret" || connect["verbose"] != false {
+		t.Errorf("Unexpected CONNECT %+v", connect)
+	}
+	alert, record := <-server.published, <-server.published
+	if alert[0] != "web.alerts" || !strings.Contains(alert[1], `"name":"High-traffic","firing":true,"severity":"critical"`) {
+		t.Errorf("Unexpected alert %+v", alert)
+	}
+	if record[0] != "web.records.5XX" || !strings.Contains(record[1], `"section":"/api"`) {
+		t.Errorf("Unexpected record %+v", record)
+	}
+}
+
+func TestNATSSinkFailure(t *testing.T) {
+	server := newFakeNATSServer(t, "Authorization Violation")
+	defer server.listener.Close()
+	s, err := newNATSSink("nats://token@"+server.listener.Addr().String(), "web", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.record(&logRecord{Section: "/api", StatusCode: 200})
+	if err := s.write(nil); err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if connect := <-server.connect; !strings.Contains(connect, `"auth_token":"token"`) {
+		t.Errorf("Unexpected CONNECT %s", connect)
+	}
+	if records, _ := s.records.take(); len(records) != 1 || s.conn != nil {
+		t.Errorf("Expected the record to be kept and the connection dropped, got %+v", records)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// Command-line flags to export metrics to an OpenTelemetry collector
+var otlpEndpoint = flag.String("otlp-endpoint", "", "OpenTelemetry collector endpoint to export metrics to, e.g. localhost:4317")
+var otlpProtocol = flag.String("otlp-protocol", "grpc", "OTLP protocol (grpc, http)")
+var otlpInsecure = flag.Bool("otlp-insecure", false, "Export metrics to the collector without TLS")
+var otlpInterval = flag.Duration("otlp-interval", 10*time.Second, "How often metrics are exported to the collector")
+var otlpResource = flag.String("otlp-resource", "", "Additional comma-separated resource attributes, e.g. vhost=www.example.com,env=prod")
+
+// Sink exporting counters and gauges over OTLP. Intervals are recorded into
+// instruments, which the SDK exports periodically
+type otlpSink struct {
+	provider *sdkmetric.MeterProvider
+	topN     int
+
+	requests metric.Int64Counter
+	bytes    metric.Int64Counter
+	sections metric.Int64Counter
+	qps      metric.Float64Gauge
+}
+
+// Resource attributes identifying this monitor: host, tailed file and any
+// given as key=value pairs
+func otlpResourceAttributes(extra string) ([]attribute.KeyValue, error) {
+	host, _ := os.Hostname()
+	attributes := []attribute.KeyValue{
+		attribute.String("host.name", host),
+		attribute.String("log.file.path", *fileName),
+	}
+	if extra == "" {
+		return attributes, nil
+	}
+	for _, pair := range strings.Split(extra, ",") {
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("Expected key=value: %s", pair)
+		}
+		attributes = append(attributes, attribute.String(strings.TrimSpace(pair[:i]), strings.TrimSpace(pair[i+1:])))
+	}
+	return attributes, nil
+}
+
+func newOTLPSink(endpoint, protocol string, insecure bool, interval time.Duration, extra string, topN int) (*otlpSink, error) {
+	ctx := context.Background()
+
+	var exporter sdkmetric.Exporter
+	var err error
+	switch protocol {
+	case "grpc":
+		options := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(endpoint)}
+		if insecure {
+			options = append(options, otlpmetricgrpc.WithInsecure())
+		}
+		exporter, err = otlpmetricgrpc.New(ctx, options...)
+	case "http":
+		options := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(endpoint)}
+		if insecure {
+			options = append(options, otlpmetrichttp.WithInsecure())
+		}
+		exporter, err = otlpmetrichttp.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf("Unknown OTLP protocol: %s", protocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	attributes, err := otlpResourceAttributes(extra)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(resource.NewSchemaless(append(attributes, attribute.String("service.name", "http_monitor"))...)),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(interval))),
+	)
+
+	s := &otlpSink{provider: provider, topN: topN}
+	meter := provider.Meter("http_monitor")
+	if s.requests, err = meter.Int64Counter("http.server.requests", metric.WithDescription("Requests, by response class")); err != nil {
+		return nil, err
+	}
+	if s.bytes, err = meter.Int64Counter("http.server.response.bytes", metric.WithUnit("By")); err != nil {
+		return nil, err
+	}
+	if s.sections, err = meter.Int64Counter("http.server.section.requests", metric.WithDescription("Requests to the top sections of each interval")); err != nil {
+		return nil, err
+	}
+	if s.qps, err = meter.Float64Gauge("http.server.qps", metric.WithDescription("Average queries per second over the last interval")); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *otlpSink) name() string {
+	return "OTLP"
+}
+
+// Export pending metrics and stop the exporter
+func (s *otlpSink) close() error {
+	return s.provider.Shutdown(context.Background())
+}
+
+func (s *otlpSink) write(i *interval) error {
+	ctx := context.Background()
+	for class, count := range i.ResponseCodes {
+		s.requests.Add(ctx, int64(count), metric.WithAttributes(attribute.String("status_class", class)))
+	}
+	s.bytes.Add(ctx, int64(i.Bytes))
+	for _, v := range topCounts(i.Sections, s.topN) {
+		s.sections.Add(ctx, int64(v.count), metric.WithAttributes(attribute.String("section", v.key)))
+	}
+	s.qps.Record(ctx, i.qps())
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	collector "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOTLPSink(t *testing.T) {
+	requests := make(chan *collector.ExportMetricsServiceRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		req := &collector.ExportMetricsServiceRequest{}
+		if err := proto.Unmarshal(data, req); err == nil && r.URL.Path == "/v1/metrics" {
+			requests <- req
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Write(nil)
+	}))
+	defer server.Close()
+
+	s, err := newOTLPSink(strings.TrimPrefix(server.URL, "http://"), "http", true, time.Hour, "vhost=www.example.com", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	i := newInterval(start)
+	i.add(&logRecord{StatusCode: 200, Section: "/api", Size: 100})
+	i.add(&logRecord{StatusCode: 500, Section: "/api", Size: 10})
+	i.End = start.Add(10 * time.Second)
+	if err := s.write(i); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.provider.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-requests
+	attributes := make(map[string]string)
+	for _, kv := range req.ResourceMetrics[0].Resource.Attributes {
+		attributes[kv.Key] = kv.Value.GetStringValue()
+	}
+	if attributes["vhost"] != "www.example.com" || attributes["service.name"] != "http_monitor" || attributes["host.name"] == "" {
+		t.Errorf("Unexpected resource attributes %v", attributes)
+	}
+
+	metrics := make(map[string]bool)
+	for _, m := range req.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		metrics[m.Name] = true
+	}
+	for _, name := range []string{"http.server.requests", "http.server.response.bytes", "http.server.section.requests", "http.server.qps"} {
+		if !metrics[name] {
+			t.Errorf("Missing metric %s", name)
+		}
+	}
+}
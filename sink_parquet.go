@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Command-line flags to export records to Parquet files
+var parquetDir = flag.String("parquet-dir", "", "Directory every parsed record is written to as Parquet files, partitioned by day (dt=YYYY-MM-DD), to be queried with DuckDB or Athena")
+var parquetMaxSize = flag.Int("parquet-max-size", 64, "Size in MB of the records buffered before a Parquet file is written")
+var parquetRotate = flag.Duration("parquet-rotate", time.Hour, "Longest time records are buffered before a Parquet file is written")
+
+// Sink writing every record to Parquet files. Records are buffered until
+// they reach the maximum size, or the oldest one the maximum age, so that
+// files are only ever written whole
+type parquetSink struct {
+	dir     string
+	maxSize int // Bytes
+	rotate  time.Duration
+
+	mutex   sync.Mutex // Records are handed over while intervals are written
+	buffer  *parquetBuffer
+	started time.Time // When the first buffered record was received
+	day     time.Time // Day of the first buffered record, partitioning files
+	err     error     // Last failure to write a file, reported with the next interval
+}
+
+func newParquetSink(dir string, maxSize int, rotate time.Duration) (*parquetSink, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &parquetSink{dir: dir, maxSize: maxSize, rotate: rotate, buffer: newParquetBuffer(recordFields)}, nil
+}
+
+func (s *parquetSink) name() string {
+	return "Parquet"
+}
+
+func (s *parquetSink) record(r *logRecord) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.buffer.rows == 0 {
+		s.started = time.Now()
+		s.day = r.Timestamp.UTC().Truncate(24 * time.Hour)
+	}
+	s.buffer.add(r)
+	if s.buffer.size() >= s.maxSize {
+		if err := s.flush(); err != nil {
+			s.err = err
+		}
+	}
+}
+
+// Write buffered records once the oldest is old enough, reporting any
+// failure to write a file since the last interval
+func (s *parquetSink) write(i *interval) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.buffer.rows > 0 && time.Since(s.started) >= s.rotate {
+		if err := s.flush(); err != nil {
+			s.err = err
+		}
+	}
+	err := s.err
+	s.err = nil
+	return err
+}
+
+func (s *parquetSink) close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.buffer.rows == 0 {
+		return nil
+	}
+	return s.flush()
+}
+
+// Write buffered records to a new file, under a temporary name until
+// complete so that readers never see partial files. Records are dropped
+// if the file cannot be written, not to buffer without bounds
+func (s *parquetSink) flush() error {
+	buffer := s.buffer
+	s.buffer = newParquetBuffer(recordFields)
+
+	dir := filepath.Join(s.dir, "dt="+s.day.Format("2006-01-02"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("records-%s.parquet", s.started.UTC().Format("20060102T150405.000000Z")))
+	temporary := filepath.Join(dir, "."+filepath.Base(path)+".tmp")
+	f, err := os.Create(temporary)
+	if err != nil {
+		return err
+	}
+	if err := buffer.writeTo(f); err != nil {
+		f.Close()
+		os.Remove(temporary)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(temporary)
+		return err
+	}
+	return os.Rename(temporary, path)
+}
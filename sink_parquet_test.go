@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParquetSink(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newParquetSink(dir, 1<<20, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := func() []string {
+		matches, err := filepath.Glob(filepath.Join(dir, "dt=*", "*.parquet"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return matches
+	}
+
+	at := time.Date(2018, 5, 9, 16, 0, 39, 0, time.UTC)
+	s.record(&logRecord{Timestamp: at, IP: "10.0.0.1", Section: "/api", StatusCode: 200})
+	if err := s.write(newInterval(at)); err != nil {
+		t.Fatal(err)
+	}
+	if matches := files(); len(matches) != 0 {
+		t.Errorf("Expected records to be buffered, got %v", matches)
+	}
+
+	// Records are written once buffered for long enough
+	s.rotate = 0
+	if err := s.write(newInterval(at)); err != nil {
+		t.Fatal(err)
+	}
+	matches := files()
+	if len(matches) != 1 || filepath.Base(filepath.Dir(matches[0])) != "dt=2018-05-09" {
+		t.Fatalf("Unexpected files %v", matches)
+	}
+
+	// Or once large enough, as well as on close
+	s.rotate = time.Hour
+	s.maxSize = 200
+	s.record(&logRecord{Timestamp: at, IP: "10.0.0.1", Section: "/api", StatusCode: 200, UserAgent: string(make([]byte, 200))})
+	s.record(&logRecord{Timestamp: at.Add(24 * time.Hour), IP: "10.0.0.1", Section: "/api", StatusCode: 200})
+	if matches := files(); len(matches) != 2 {
+		t.Errorf("Expected a file written when full, got %v", matches)
+	}
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+	matches = files()
+	if len(matches) != 3 || filepath.Base(filepath.Dir(matches[2])) != "dt=2018-05-10" {
+		t.Errorf("Unexpected files %v", matches)
+	}
+	if temporary, _ := filepath.Glob(filepath.Join(dir, "dt=*", ".*")); len(temporary) != 0 {
+		t.Errorf("Unexpected temporary files %v", temporary)
+	}
+	if info, err := os.Stat(matches[0]); err != nil || info.Size() == 0 {
+		t.Errorf("Unexpected file %v: %v", info, err)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Command-line flags to keep live counters in Redis and publish alerts
+var redisAddr = flag.String("redis-addr", "", "Redis server live counters are kept in, e.g. localhost:6379")
+var redisPassword = flag.String("redis-password", "", "Redis password")
+var redisDB = flag.Int("redis-db", 0, "Redis database number")
+var redisPrefix = flag.String("redis-prefix", "http_monitor", "Prefix of the Redis keys counters are kept in")
+var redisChannel = flag.String("redis-channel", "", "Redis channel alert changes are published to as JSON, e.g. http_monitor:alerts")
+var redisTTL = flag.Duration("redis-ttl", 24*time.Hour, "How long per-minute Redis counters are kept")
+
+// Sink keeping counters in Redis keys, for dashboards to read without
+// scraping:
+//   - <prefix>:last, a hash of the aggregates of the last interval
+//   - <prefix>:minute:<YYYYmmddHHMM>, hashes of requests, bytes and requests
+//     per response class by minute, expiring after the TTL
+//   - <prefix>:sections:<YYYYmmddHHMM>, sorted sets of requests per section
+//     by minute, expiring after the TTL
+//   - <prefix>:firing, the set of the names of firing alerts
+//
+// Alert changes are also published to a channel, if given, when intervals
+// close
+type redisSink struct {
+	client  *redis.Client
+	prefix  string
+	channel string
+	ttl     time.Duration
+
+	mutex  sync.Mutex // Guards alerts, changing while intervals are written
+	alerts []notification
+}
+
+func newRedisSink(addr, password string, db int, prefix, channel string, ttl time.Duration) *redisSink {
+	return &redisSink{
+		client:  redis.NewClient(&redis.Options{Addr: addr, Password: password, DB: db}),
+		prefix:  prefix,
+		channel: channel,
+		ttl:     ttl,
+	}
+}
+
+func (s *redisSink) name() string {
+	return "Redis"
+}
+
+func (s *redisSink) alertChanged(change alertChange) {
+	s.mutex.Lock()
+	s.alerts = append(s.alerts, newNotification(change))
+	s.mutex.Unlock()
+}
+
+// Commands updating the counters with an interval
+func (s *redisSink) count(ctx context.Context, pipe redis.Pipeliner, i *interval) {
+	last := map[string]interface{}{
+		"start":    i.Start.UTC().Format(time.RFC3339),
+		"end":      i.End.UTC().Format(time.RFC3339),
+		"requests": i.Requests,
+		"bytes":    i.Bytes,
+		"qps":      i.qps(),
+	}
+	for class, requests := range i.ResponseCodes {
+		last[class] = requests
+	}
+	pipe.Del(ctx, s.prefix+":last")
+	pipe.HSet(ctx, s.prefix+":last", last)
+
+	minute := i.Start.UTC().Format("200601021504")
+	key := s.prefix + ":minute:" + minute
+	pipe.HIncrBy(ctx, key, "requests", int64(i.Requests))
+	pipe.HIncrBy(ctx, key, "bytes", int64(i.Bytes))
+	for class, requests := range i.ResponseCodes {
+		pipe.HIncrBy(ctx, key, class, int64(requests))
+	}
+	pipe.Expire(ctx, key, s.ttl)
+
+	if len(i.Sections) > 0 {
+		key = s.prefix + ":sections:" + minute
+		for section, requests := range i.Sections {
+			pipe.ZIncrBy(ctx, key, float64(requests), section)
+		}
+		pipe.Expire(ctx, key, s.ttl)
+	}
+}
+
+// Commands tracking firing alerts and publishing alert changes
+func (s *redisSink) notify(ctx context.Context, pipe redis.Pipeliner, alerts []notification) error {
+	for _, n := range alerts {
+		if n.Firing {
+			pipe.SAdd(ctx, s.prefix+":firing", n.Name)
+		} else {
+			pipe.SRem(ctx, s.prefix+":firing", n.Name)
+		}
+		if s.channel != "" {
+			data, err := json.Marshal(n)
+			if err != nil {
+				return err
+			}
+			pipe.Publish(ctx, s.channel, data)
+		}
+	}
+	return nil
+}
+
+// Update counters with an interval, if any, and publish alert changes in
+// a single transaction. Alert changes are kept for the next interval upon
+// failure, counters being best effort
+func (s *redisSink) write(i *interval) error {
+	s.mutex.Lock()
+	alerts := s.alerts
+	s.alerts = nil
+	s.mutex.Unlock()
+	if i == nil && len(alerts) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if i != nil {
+			s.count(ctx, pipe, i)
+		}
+		return s.notify(ctx, pipe, alerts)
+	})
+	if err != nil {
+		s.mutex.Lock()
+		s.alerts = append(alerts, s.alerts...)
+		s.mutex.Unlock()
+	}
+	return err
+}
+
+// Publish pending alert changes, then disconnect
+func (s *redisSink) close() error {
+	err := s.write(nil)
+	if closeErr := s.client.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestRedisSink(t *testing.T) {
+	server := miniredis.RunT(t)
+	s := newRedisSink(server.Addr(), "", 0, "web", "web:alerts", time.Hour)
+	defer s.close()
+	subscription := s.client.Subscribe(context.Background(), "web:alerts")
+	defer subscription.Close()
+	if _, err := subscription.Receive(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two intervals within the same minute
+	start := time.Date(2019, 1, 1, 10, 0, 0, 0, time.UTC)
+	for n := 0; n < 2; n++ {
+		i := newInterval(start.Add(time.Duration(n) * 10 * time.Second))
+		i.add(&logRecord{StatusCode: 200, Section: "/api", Size: 100})
+		i.add(&logRecord{StatusCode: 503, Section: "/", Size: 10})
+		i.End = i.Start.Add(10 * time.Second)
+		if n == 1 {
+			s.alertChanged(alertChange{Name: "High-traffic", Firing: true, Detail: "hits = 20"})
+		}
+		if err := s.write(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		key, field, expected string
+	}{
+		{"web:last", "requests", "2"},
+		{"web:last", "5XX", "1"},
+		{"web:last", "end", "2019-01-01T10:00:20Z"},
+		{"web:minute:201901011000", "requests", "4"},
+		{"web:minute:201901011000", "bytes", "220"},
+		{"web:minute:201901011000", "2XX", "2"},
+	}
+	for _, test := range tests {
+		if value := server.HGet(test.key, test.field); value != test.expected {
+			t.Errorf("%s %s: %q != %q", test.key, test.field, value, test.expected)
+		}
+	}
+	if score, err := server.ZScore("web:sections:201901011000", "/api"); err != nil || score != 2 {
+		t.Errorf("Unexpected /api score %v: %v", score, err)
+	}
+	if ttl := server.TTL("web:minute:201901011000"); ttl != time.Hour {
+		t.Errorf("%+v != %+v", ttl, time.Hour)
+	}
+	if firing, err := server.Members("web:firing"); err != nil || len(firing) != 1 || firing[0] != "High-traffic" {
+		t.Errorf("Unexpected firing alerts %v: %v", firing, err)
+	}
+
+	select {
+	case message := <-subscription.Channel():
+		if !strings.Contains(message.Payload, `"name":"High-traffic","firing":true`) {
+			t.Errorf("Unexpected alert %s", message.Payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the alert")
+	}
+
+	s.alertChanged(alertChange{Name: "High-traffic", Firing: false})
+	if err := s.write(nil); err != nil {
+		t.Fatal(err)
+	}
+	if server.Exists("web:firing") {
+		t.Errorf("Expected no firing alerts")
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"log"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Command-line flag to record history into a SQLite database
+var sqliteDatabase = flag.String("sqlite", "", "SQLite database file recording the aggregates of every interval, rolled up by day and week, and alert transitions")
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS intervals (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	start TIMESTAMP NOT NULL,
+	end TIMESTAMP NOT NULL,
+	requests INTEGER NOT NULL,
+	bytes INTEGER NOT NULL,
+	qps REAL NOT NULL
+);
+CREATE INDEX IF NOT EXISTS intervals_start ON intervals (start);
+CREATE TABLE IF NOT EXISTS status_classes (
+	interval_id INTEGER NOT NULL REFERENCES intervals (id),
+	class TEXT NOT NULL,
+	requests INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS sections (
+	interval_id INTEGER NOT NULL REFERENCES intervals (id),
+	section TEXT NOT NULL,
+	requests INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS sections_section ON sections (section);
+CREATE TABLE IF NOT EXISTS rollups (
+	period TEXT NOT NULL,
+	start TIMESTAMP NOT NULL,
+	end TIMESTAMP NOT NULL,
+	intervals INTEGER NOT NULL,
+	requests INTEGER NOT NULL,
+	bytes INTEGER NOT NULL,
+	errors INTEGER NOT NULL,
+	peak_qps REAL NOT NULL,
+	p50_qps REAL NOT NULL,
+	p95_qps REAL NOT NULL,
+	p99_qps REAL NOT NULL,
+	PRIMARY KEY (period, start)
+);
+CREATE TABLE IF NOT EXISTS alerts (
+	time TIMESTAMP NOT NULL,
+	name TEXT NOT NULL,
+	firing BOOLEAN NOT NULL,
+	detail TEXT NOT NULL
+);
+`
+
+// Sink recording history into a SQLite database, rolled up into daily and
+// weekly aggregates
+type sqliteSink struct {
+	db        *sql.DB
+	rolled    map[string]time.Time // Start of the period up to which intervals were rolled up
+	retention retentionPolicy
+	pruned    time.Time // Day records were last pruned
+}
+
+func openSQLiteSink(path string) (*sqliteSink, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqliteSink{db: db, rolled: make(map[string]time.Time)}, nil
+}
+
+func (s *sqliteSink) name() string {
+	return "SQLite"
+}
+
+// Record an interval along with its per-class and per-section counters, in
+// a single transaction, then roll up the days and weeks over and prune
+// expired records
+func (s *sqliteSink) write(i *interval) error {
+	if err := s.insert(i); err != nil {
+		return err
+	}
+	if err := s.rollUp(i.Start); err != nil {
+		return err
+	}
+	return s.prune(i.Start)
+}
+
+func (s *sqliteSink) insert(i *interval) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO intervals (start, end, requests, bytes, qps) VALUES (?, ?, ?, ?, ?)",
+		i.Start.UTC(), i.End.UTC(), i.Requests, i.Bytes, i.qps())
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for class, count := range i.ResponseCodes {
+		if _, err := tx.Exec("INSERT INTO status_classes (interval_id, class, requests) VALUES (?, ?, ?)", id, class, count); err != nil {
+			return err
+		}
+	}
+	for section, count := range i.Sections {
+		if _, err := tx.Exec("INSERT INTO sections (interval_id, section, requests) VALUES (?, ?, ?)", id, section, count); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteSink) close() error {
+	return s.db.Close()
+}
+
+// Record an alert transition
+func (s *sqliteSink) alertChanged(change alertChange) {
+	if _, err := s.db.Exec("INSERT INTO alerts (time, name, firing, detail) VALUES (?, ?, ?, ?)",
+		time.Now().UTC(), change.Name, change.Firing, change.Detail); err != nil {
+		log.Printf("Cannot write to SQLite: %s", err)
+	}
+}
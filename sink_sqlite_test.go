@@ -0,0 +1,49 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSQLiteSink(t *testing.T) {
+	db, err := openSQLiteSink(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.db.Close()
+
+	start := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	i := newInterval(start)
+	i.add(&logRecord{StatusCode: 200, Section: "/api", Size: 100})
+	i.add(&logRecord{StatusCode: 404, Section: "/api", Size: 10})
+	i.add(&logRecord{StatusCode: 200, Section: "/report", Size: 50})
+	i.End = start.Add(10 * time.Second)
+	if err := db.write(i); err != nil {
+		t.Fatal(err)
+	}
+	db.alertChanged(alertChange{Name: "High-traffic", Firing: true, Detail: "at 20.000000 queries per second on average"})
+
+	var requests, bytes int
+	var qps float64
+	if err := db.db.QueryRow("SELECT requests, bytes, qps FROM intervals").Scan(&requests, &bytes, &qps); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 || bytes != 160 || qps != 0.3 {
+		t.Errorf("Unexpected interval %d %d %f", requests, bytes, qps)
+	}
+
+	var apiRequests, errors, alerts int
+	if err := db.db.QueryRow("SELECT requests FROM sections WHERE section = '/api'").Scan(&apiRequests); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.db.QueryRow("SELECT requests FROM status_classes WHERE class = '4XX'").Scan(&errors); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.db.QueryRow("SELECT COUNT(*) FROM alerts WHERE firing").Scan(&alerts); err != nil {
+		t.Fatal(err)
+	}
+	if apiRequests != 2 || errors != 1 || alerts != 1 {
+		t.Errorf("Unexpected counters %d %d %d", apiRequests, errors, alerts)
+	}
+}
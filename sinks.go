@@ -0,0 +1,145 @@
+package main
+
+import (
+	"log"
+)
+
+// Destination of the aggregates of every closed interval
+type sink interface {
+	// Name of the sink, as shown in error messages
+	name() string
+	write(i *interval) error
+}
+
+// Sink also recording alert transitions
+type alertSink interface {
+	alertChanged(change alertChange)
+}
+
+// Sink also receiving every record accounted for. Records are handed over
+// while stats are locked, so they are to be buffered rather than sent
+type recordSink interface {
+	record(r *logRecord)
+}
+
+// Build the sinks enabled through command-line flags
+func configuredSinks() ([]sink, error) {
+	var sinks []sink
+
+	if *sqliteDatabase != "" {
+		db, err := openSQLiteSink(*sqliteDatabase)
+		if err != nil {
+			return nil, err
+		}
+		db.retention = configuredRetention()
+		sinks = append(sinks, db)
+	}
+
+	if *influxURL != "" {
+		sinks = append(sinks, newInfluxSink(*influxURL, *influxOrg, *influxBucket, *influxToken, *influxMeasurement, *topN))
+	}
+
+	if *graphiteAddr != "" {
+		sinks = append(sinks, newGraphiteSink(*graphiteAddr, *graphitePrefix, *graphiteInterval, *topN))
+	}
+
+	if *otlpEndpoint != "" {
+		otlp, err := newOTLPSink(*otlpEndpoint, *otlpProtocol, *otlpInsecure, *otlpInterval, *otlpResource, *topN)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, otlp)
+	}
+
+	if *dogstatsdAddr != "" || *datadogAPIKey != "" {
+		sinks = append(sinks, newDatadogSink(*dogstatsdAddr, *datadogAPIKey, *datadogSite, *datadogPrefix, *datadogTags, *topN))
+	}
+
+	if *cloudWatchNamespace != "" {
+		cw, err := newCloudWatchSink(*cloudWatchNamespace, *cloudWatchDimensions, *topN)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, cw)
+	}
+
+	if *parquetDir != "" {
+		parquet, err := newParquetSink(*parquetDir, *parquetMaxSize<<20, *parquetRotate)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, parquet)
+	}
+
+	if *elasticsearchURL != "" {
+		sinks = append(sinks, newElasticsearchSink(*elasticsearchURL, *elasticsearchIndex, *elasticsearchUser, *elasticsearchPassword, *elasticsearchAPIKey, *elasticsearchBatch, *elasticsearchBuffer))
+	}
+
+	if *lokiURL != "" {
+		loki, err := newLokiSink(*lokiURL, *lokiLabels, *lokiTenant, *lokiBatch, *lokiBuffer)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, loki)
+	}
+
+	if *clickHouseDSN != "" {
+		clickHouse, err := newClickHouseSink(*clickHouseDSN, *clickHouseTable, *clickHouseColumns, *clickHouseCreate, *clickHouseBatch, *clickHouseBuffer)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, clickHouse)
+	}
+
+	if *kafkaOutputBrokers != "" {
+		kafka, err := newKafkaSink(*kafkaOutputBrokers, *kafkaOutputTopic, *kafkaOutputFormat, *kafkaSchemaRegistry, *kafkaOutputBuffer)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, kafka)
+	}
+
+	if *natsURL != "" {
+		nats, err := newNATSSink(*natsURL, *natsSubject, *natsBuffer)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, nats)
+	}
+
+	if *redisAddr != "" {
+		sinks = append(sinks, newRedisSink(*redisAddr, *redisPassword, *redisDB, *redisPrefix, *redisChannel, *redisTTL))
+	}
+
+	return sinks, nil
+}
+
+// Sink holding resources or buffered data, released or flushed on shutdown
+type closingSink interface {
+	close() error
+}
+
+// Flush and close every sink holding resources
+func closeSinks(sinks []sink) {
+	for _, sink := range sinks {
+		if closing, ok := sink.(closingSink); ok {
+			if err := closing.close(); err != nil {
+				log.Printf("Cannot close %s: %s", sink.name(), err)
+			}
+		}
+	}
+}
+
+// Write an interval to every sink, returning the outcome of each write.
+// Failures are logged, so that an unavailable sink does not stop monitoring
+func writeSinks(sinks []sink, i *interval) map[string]error {
+	errs := make(map[string]error)
+	for _, sink := range sinks {
+		err := sink.write(i)
+		if err != nil {
+			log.Printf("Cannot write to %s: %s", sink.name(), err)
+		}
+		errs[sink.name()] = err
+	}
+	return errs
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"text/tabwriter"
+)
+
+// Percentiles of response sizes shown in dumps and reports
+var sizePercentiles = []float64{50, 95, 99}
+
+// Number of response size buckets, the last one holding sizes above 1GB
+const sizeBuckets = 32
+
+// Histogram of response sizes, in buckets whose upper bounds are powers of
+// two: bucket i holds sizes above 2^(i-1) bytes, up to 2^i
+type sizeHistogram struct {
+	counts [sizeBuckets]int
+	total  int
+	max    int
+}
+
+// Bucket holding a response size
+func sizeBucket(size int) int {
+	if size <= 1 {
+		return 0
+	}
+	bucket := bits.Len(uint(size - 1))
+	if bucket >= sizeBuckets {
+		return sizeBuckets - 1
+	}
+	return bucket
+}
+
+func (h *sizeHistogram) add(size int) {
+	h.counts[sizeBucket(size)]++
+	h.total++
+	if size > h.max {
+		h.max = size
+	}
+}
+
+// Estimate of the size below which p percent of responses are, as the upper
+// bound of the bucket holding it, or 0 without any response
+func (h *sizeHistogram) percentile(p float64) int {
+	rank := int(math.Ceil(p / 100 * float64(h.total)))
+	seen := 0
+	for bucket, count := range h.counts {
+		seen += count
+		if count > 0 && seen >= rank {
+			if bound := 1 << bucket; bound < h.max && bucket < sizeBuckets-1 {
+				return bound
+			}
+			return h.max
+		}
+	}
+	return 0
+}
+
+// Size in bytes, in binary units as alert expressions take them
+func formatSize(size int) string {
+	units := []string{"KB", "MB", "GB"}
+	if size < 1<<10 {
+		return fmt.Sprintf("%dB", size)
+	}
+	value, unit := float64(size)/(1<<10), units[0]
+	for _, next := range units[1:] {
+		if value < 1<<10 {
+			break
+		}
+		value, unit = value/(1<<10), next
+	}
+	if value == math.Trunc(value) {
+		return fmt.Sprintf("%.0f%s", value, unit)
+	}
+	return fmt.Sprintf("%.1f%s", value, unit)
+}
+
+// Percentiles and largest size, e.g. "p50 4KB, p95 16KB, p99 64KB, max 70.3KB"
+func (h *sizeHistogram) summary() string {
+	var text string
+	for _, p := range sizePercentiles {
+		text += fmt.Sprintf("p%g %s, ", p, formatSize(h.percentile(p)))
+	}
+	return text + "max " + formatSize(h.max)
+}
+
+// Label of a bucket, e.g. "<= 4KB"
+func sizeBucketLabel(bucket int) string {
+	if bucket == sizeBuckets-1 {
+		return "> " + formatSize(1<<(bucket-1))
+	}
+	return "<= " + formatSize(1<<bucket)
+}
+
+// Counters of buckets from the smallest to the largest non-empty one,
+// scaled by the given weight
+func (h *sizeHistogram) bucketCounts(weight float64) []keyCountPair {
+	first, last := -1, 0
+	for bucket, count := range h.counts {
+		if count > 0 {
+			if first < 0 {
+				first = bucket
+			}
+			last = bucket
+		}
+	}
+	var counts []keyCountPair
+	for bucket := first; first >= 0 && bucket <= last; bucket++ {
+		counts = append(counts, keyCountPair{key: sizeBucketLabel(bucket), count: int(float64(h.counts[bucket])*weight + 0.5)})
+	}
+	return counts
+}
+
+// Dumps the distribution of response sizes
+func (s *stats) dumpSizes(w *tabwriter.Writer) {
+	if s.sizes == nil || s.sizes.total == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Response sizes: %s\n", s.sizes.summary())
+	for _, v := range s.sizes.bucketCounts(s.weight()) {
+		fmt.Fprintf(w, "%d\t %s\n", v.count, v.key)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSizeBucket(t *testing.T) {
+	tests := []struct {
+		size     int
+		expected int
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 1},
+		{3, 2},
+		{1024, 10},
+		{1025, 11},
+		{1 << 40, sizeBuckets - 1},
+	}
+	for _, test := range tests {
+		if bucket := sizeBucket(test.size); bucket != test.expected {
+			t.Errorf("%d: %+v != %+v", test.size, bucket, test.expected)
+		}
+	}
+}
+
+func TestSizeHistogramPercentile(t *testing.T) {
+	h := &sizeHistogram{}
+	if p := h.percentile(50); p != 0 {
+		t.Errorf("%+v != %+v", p, 0)
+	}
+
+	// 90 small responses, 9 medium ones and a large one
+	for i := 0; i < 90; i++ {
+		h.add(900)
+	}
+	for i := 0; i < 9; i++ {
+		h.add(3000)
+	}
+	h.add(50000)
+
+	tests := []struct {
+		p        float64
+		expected int
+	}{
+		{50, 1024},
+		{90, 1024},
+		{95, 4096},
+		{99, 4096},
+		{100, 50000},
+	}
+	for _, test := range tests {
+		if size := h.percentile(test.p); size != test.expected {
+			t.Errorf("p%g: %+v != %+v", test.p, size, test.expected)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size     int
+		expected string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{1024, "1KB"},
+		{1536, "1.5KB"},
+		{1 << 20, "1MB"},
+		{5 << 30, "5GB"},
+		{3 << 40, "3072GB"},
+	}
+	for _, test := range tests {
+		if text := formatSize(test.size); text != test.expected {
+			t.Errorf("%d: %+v != %+v", test.size, text, test.expected)
+		}
+	}
+}
+
+func TestDumpSizes(t *testing.T) {
+	s := newStats()
+	for _, size := range []int{100, 200, 3000} {
+		s.updateStats(&logRecord{Section: "/", StatusCode: 200, Size: size})
+	}
+	var out bytes.Buffer
+	s.writeStats(&out)
+	for _, expected := range []string{
+		"Response sizes: p50 256B, p95 2.9KB, p99 2.9KB, max 2.9KB",
+		"1 <= 128B",
+		"1 <= 256B",
+		"0 <= 512B",
+		"1 <= 4KB",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, out.String())
+		}
+	}
+
+	s.resetCounters()
+	out.Reset()
+	s.writeStats(&out)
+	if strings.Contains(out.String(), "Response sizes") {
+		t.Errorf("Expected no response sizes after a reset:\n%s", out.String())
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+// Command-line flag to alert on error budget burn rates
+var sloObjective = flag.Float64("slo", 0, "Availability objective, in percent of requests not failing with a 5XX status, e.g. 99.9 (0 disables burn-rate alerting)")
+
+// Pair of windows the error budget must be burning over, at some rate, for
+// the burn-rate alert to fire: the long one tells enough budget is gone,
+// the short one that it is still burning. From the Google SRE workbook
+type burnWindow struct {
+	long  time.Duration
+	short time.Duration
+	rate  float64 // Multiple of the sustainable error rate
+	level severity
+}
+
+// Fast burn (2% of a 30-day budget in an hour), then slow burn (5% in 6
+// hours)
+var burnWindows = []burnWindow{
+	{time.Hour, 5 * time.Minute, 14.4, severityCritical},
+	{6 * time.Hour, 30 * time.Minute, 6, severityWarning},
+}
+
+// Requests, and failed ones, in a minute
+type errorBucket struct {
+	requests int
+	errors   int
+}
+
+// Per-minute request and error counters spanning the longest burn window
+type errorCounter struct {
+	buckets map[int64]*errorBucket // By minute since the epoch
+	latest  time.Time              // Timestamp of the latest request seen
+}
+
+func newErrorCounter() *errorCounter {
+	return &errorCounter{buckets: make(map[int64]*errorBucket)}
+}
+
+// Account for a request, forgetting about those out of every window
+func (c *errorCounter) add(t time.Time, failed bool) {
+	minute := t.Unix() / 60
+	bucket, ok := c.buckets[minute]
+	if !ok {
+		bucket = &errorBucket{}
+		c.buckets[minute] = bucket
+		oldest := minute - int64(burnWindows[len(burnWindows)-1].long.Minutes())
+		for m := range c.buckets {
+			if m <= oldest {
+				delete(c.buckets, m)
+			}
+		}
+	}
+	bucket.requests++
+	if failed {
+		bucket.errors++
+	}
+	if t.After(c.latest) {
+		c.latest = t
+	}
+}
+
+// Ratio of failed requests over the window ending at the given time, if
+// any request was made
+func (c *errorCounter) ratio(now time.Time, window time.Duration) (float64, bool) {
+	end := now.Unix() / 60
+	start := end - int64(window.Minutes())
+	requests, errors := 0, 0
+	for minute, bucket := range c.buckets {
+		if minute > start && minute <= end {
+			requests += bucket.requests
+			errors += bucket.errors
+		}
+	}
+	if requests == 0 {
+		return 0, false
+	}
+	return float64(errors) / float64(requests), true
+}
+
+// Alert firing when the error budget of the availability objective burns
+// too fast over both windows of a pair: critical for a fast burn, warning
+// for a slow one
+type sloBurnRule struct {
+	objective float64 // In percent
+	level     severity
+	burn      float64 // Burn rate over the long window, as of the last check
+	threshold float64
+}
+
+func (r *sloBurnRule) name() string {
+	return "SLO-burn"
+}
+
+// Error budget consumption rate, relative to the sustainable one
+func (r *sloBurnRule) burnRate(ratio float64) float64 {
+	return ratio / (1 - r.objective/100)
+}
+
+func (r *sloBurnRule) evaluate(s *stats) (bool, string) {
+	r.level, r.burn, r.threshold = 0, 0, burnWindows[0].rate
+	if s.errors == nil {
+		return false, ""
+	}
+	now := s.errors.latest
+	if *windowClock == "wall" {
+		now = time.Now()
+	}
+	for i, window := range burnWindows {
+		long, ok := s.errors.ratio(now, window.long)
+		if !ok {
+			continue
+		}
+		short, _ := s.errors.ratio(now, window.short)
+		if i == 0 {
+			r.burn = r.burnRate(long)
+		}
+		if r.burnRate(long) >= window.rate && r.burnRate(short) >= window.rate {
+			r.level, r.burn, r.threshold = window.level, r.burnRate(long), window.rate
+			return true, fmt.Sprintf("burning the %g%% error budget %.1fx as fast as sustainable over %s, %.1fx over %s",
+				r.objective, r.burnRate(long), window.long, r.burnRate(short), window.short)
+		}
+	}
+	return false, ""
+}
+
+func (r *sloBurnRule) severity(s *stats) severity {
+	return r.level
+}
+
+func (r *sloBurnRule) measure(s *stats) (float64, float64) {
+	return r.burn, r.threshold
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSLOBurnRule(t *testing.T) {
+	start := time.Date(2018, 5, 9, 16, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		errors   func(minute int) int // Failed requests out of 100, each minute
+		firing   bool
+		severity severity
+	}{
+		{"healthy", func(int) int { return 0 }, false, 0},
+		// 2% errors burn a 99.9% budget 20 times as fast as sustainable
+		{"fast burn", func(minute int) int {
+			if minute >= 6*60-60 {
+				return 2
+			}
+			return 0
+		}, true, severityCritical},
+		// 1% errors for 6 hours burn it 10 times as fast
+		{"slow burn", func(int) int { return 1 }, true, severityWarning},
+		// Over with for the last half hour
+		{"burnt out", func(minute int) int {
+			if minute < 6*60-30 {
+				return 1
+			}
+			return 0
+		}, false, 0},
+	}
+
+	for _, test := range tests {
+		s := newStats()
+		for minute := 0; minute < 6*60; minute++ {
+			for i := 0; i < 100; i++ {
+				status := 200
+				if i < test.errors(minute) {
+					status = 500
+				}
+				s.updateStats(&logRecord{Timestamp: start.Add(time.Duration(minute) * time.Minute), StatusCode: status})
+			}
+		}
+		rule := &sloBurnRule{objective: 99.9}
+		firing, detail := rule.evaluate(s)
+		if firing != test.firing || rule.severity(s) != test.severity {
+			t.Errorf("%s: %+v != %+v (%s)", test.name, []interface{}{firing, rule.severity(s)}, []interface{}{test.firing, test.severity}, detail)
+		}
+	}
+}
+
+func TestErrorCounterExpiry(t *testing.T) {
+	start := time.Date(2018, 5, 9, 16, 0, 0, 0, time.UTC)
+	c := newErrorCounter()
+	for hour := 0; hour < 12; hour++ {
+		c.add(start.Add(time.Duration(hour)*time.Hour), true)
+	}
+	if len(c.buckets) > 6 {
+		t.Errorf("Expected buckets older than 6 hours to be forgotten, got %d", len(c.buckets))
+	}
+	if ratio, ok := c.ratio(c.latest, 5*time.Minute); !ok || ratio != 1 {
+		t.Errorf("%+v != %+v", ratio, 1)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Access log file tailed under a label, e.g. api=/var/log/nginx/api.log
+type labeledFile struct {
+	label string
+	path  string
+}
+
+// Labeled access log files given on the command line
+type labeledFiles []labeledFile
+
+func (l *labeledFiles) String() string {
+	var files []string
+	for _, file := range *l {
+		files = append(files, file.label+"="+file.path)
+	}
+	return strings.Join(files, " ")
+}
+
+func (l *labeledFiles) reset() {
+	*l = nil
+}
+
+func (l *labeledFiles) Set(value string) error {
+	i := strings.IndexByte(value, '=')
+	if i <= 0 || i == len(value)-1 {
+		return fmt.Errorf("Expected label=path: %s", value)
+	}
+	*l = append(*l, labeledFile{label: value[:i], path: value[i+1:]})
+	return nil
+}
+
+// Per-label thresholds given on the command line, e.g. api=50,web=10
+type labeledThresholds map[string]float64
+
+func (t labeledThresholds) String() string {
+	var thresholds []string
+	for label, threshold := range t {
+		thresholds = append(thresholds, fmt.Sprintf("%s=%g", label, threshold))
+	}
+	sort.Strings(thresholds)
+	return strings.Join(thresholds, ",")
+}
+
+func (t labeledThresholds) reset() {
+	for label := range t {
+		delete(t, label)
+	}
+}
+
+func (t labeledThresholds) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 {
+			return fmt.Errorf("Expected label=threshold: %s", pair)
+		}
+		threshold, err := strconv.ParseFloat(pair[i+1:], 64)
+		if err != nil {
+			return err
+		}
+		t[strings.TrimSpace(pair[:i])] = threshold
+	}
+	return nil
+}
+
+// Command-line flags to tail several labeled access logs
+var sourceFiles labeledFiles
+var sourceQPS = labeledThresholds{}
+
+func init() {
+	flag.Var(&sourceFiles, "source", "Labeled access log file to tail, e.g. api=/var/log/nginx/api.log (repeatable)")
+	flag.Var(sourceQPS, "source-qps", "Comma-separated average QPS thresholds for labeled sources, e.g. api=50,web=10")
+}
+
+// Stats for the records read from a labeled source, created on first use
+func (s *stats) sourceStats(label string) *stats {
+	source, ok := s.sources[label]
+	if !ok {
+		source = newStats()
+		source.sources = nil
+		source.resolver = s.resolver
+		source.sampleRate = s.sampleRate
+		s.sources[label] = source
+	}
+	return source
+}
+
+// Dump the stats of every labeled source
+func (s *stats) dumpSources() {
+	var labels []string
+	for label := range s.sources {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		fmt.Printf("==> %s <==\n", label)
+		s.sources[label].dumpStats()
+	}
+}
+
+// Alert firing when the average QPS of a labeled source exceeds its own
+// threshold
+type sourceTrafficRule struct {
+	label     string
+	threshold float64
+}
+
+func (r *sourceTrafficRule) name() string {
+	return fmt.Sprintf("High-traffic (%s)", r.label)
+}
+
+func (r *sourceTrafficRule) evaluate(s *stats) (bool, string) {
+	source, ok := s.sources[r.label]
+	if !ok {
+		return false, ""
+	}
+	qps, err := source.getQueryRate()
+	return err == nil && qps > r.threshold, fmt.Sprintf("at %f queries per second on average", qps)
+}
+
+func (r *sourceTrafficRule) measure(s *stats) (float64, float64) {
+	if source, ok := s.sources[r.label]; ok {
+		if qps, err := source.getQueryRate(); err == nil {
+			return qps, r.threshold
+		}
+	}
+	return 0, r.threshold
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLabeledFiles(t *testing.T) {
+	var l labeledFiles
+	if err := l.Set("api=/var/log/nginx/api.log"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Set("/var/log/nginx/web.log"); err == nil {
+		t.Errorf("Expected error for file without label")
+	}
+	if expected := "api=/var/log/nginx/api.log"; l.String() != expected {
+		t.Errorf("%q != %q", expected, l.String())
+	}
+
+	thresholds := labeledThresholds{}
+	if err := thresholds.Set("api=50,web=2.5"); err != nil {
+		t.Fatal(err)
+	}
+	if thresholds["api"] != 50 || thresholds["web"] != 2.5 {
+		t.Errorf("Unexpected thresholds %v", thresholds)
+	}
+}
+
+// Test labeled records are counted both in merged and per-source stats,
+// each with its own alert threshold
+func TestUpdateStatsSources(t *testing.T) {
+	s := newStats()
+	ts := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+
+	// Over 10 seconds, api.log gets 21 requests and web.log 3
+	for i := 0; i <= 20; i++ {
+		at := ts.Add(time.Duration(i) * 500 * time.Millisecond)
+		s.updateStats(&logRecord{Source: "api", Timestamp: at, StatusCode: 200, Section: "/users"})
+		if i%10 == 0 {
+			s.updateStats(&logRecord{Source: "web", Timestamp: at, StatusCode: 200, Section: "/"})
+		}
+	}
+
+	if s.sectionCounts["/users"] != 21 || s.sectionCounts["/"] != 3 {
+		t.Errorf("Unexpected merged counters %v", s.sectionCounts)
+	}
+	if s.sources["api"].sectionCounts["/users"] != 21 || s.sources["web"].sectionCounts["/users"] != 0 {
+		t.Errorf("Unexpected per-source counters")
+	}
+
+	if firing, _ := (&sourceTrafficRule{label: "api", threshold: 2}).evaluate(s); !firing {
+		t.Errorf("Expected api traffic alert to fire")
+	}
+	if firing, _ := (&sourceTrafficRule{label: "web", threshold: 2}).evaluate(s); firing {
+		t.Errorf("Expected web traffic alert not to fire")
+	}
+	if firing, _ := (&sourceTrafficRule{label: "other", threshold: 2}).evaluate(s); firing {
+		t.Errorf("Expected alert for unseen source not to fire")
+	}
+}
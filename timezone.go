@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"time"
+)
+
+// Command-line flag to choose the time zone of reports
+var displayZone = flag.String("timezone", "", "Time zone times are reported in: Local, or a name such as Europe/Madrid. Times are reported with their logged offset by default")
+
+// Location times are reported in, nil to keep their logged offset
+var displayLocation *time.Location
+
+// Load the location named by -timezone
+func loadDisplayLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// Time as it should be reported
+func displayTime(t time.Time) time.Time {
+	if displayLocation == nil {
+		return t
+	}
+	return t.In(displayLocation)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoggedOffset(t *testing.T) {
+	r, err := parseLogLine(`127.0.0.1 - jill [09/May/2018:18:00:41 +0200] "GET /api/user HTTP/1.0" 200 234`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := time.Date(2018, 5, 9, 16, 00, 41, 0, time.UTC)
+	if !r.Timestamp.Equal(expected) {
+		t.Errorf("%+v != %+v", expected, r.Timestamp)
+	}
+	if _, offset := r.Timestamp.Zone(); offset != 2*60*60 {
+		t.Errorf("%+v != %+v", 2*60*60, offset)
+	}
+}
+
+func TestDisplayTime(t *testing.T) {
+	defer func() { displayLocation = nil }()
+	ts := time.Date(2018, 5, 9, 16, 00, 41, 0, time.UTC)
+
+	if displayed := displayTime(ts); displayed != ts {
+		t.Errorf("%+v != %+v", ts, displayed)
+	}
+
+	var err error
+	if displayLocation, err = loadDisplayLocation("Asia/Tokyo"); err != nil {
+		t.Skip(err)
+	}
+	expected := "2018-05-10T01:00:41+09:00"
+	if displayed := displayTime(ts).Format(time.RFC3339); displayed != expected {
+		t.Errorf("%+v != %+v", expected, displayed)
+	}
+
+	if _, err := loadDisplayLocation("Nowhere/Special"); err == nil {
+		t.Errorf("Expected an error for an unknown time zone")
+	}
+}
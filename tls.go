@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// TLS versions due for deprecation
+var legacyTLSVersions = map[string]bool{
+	"SSLv3":   true,
+	"TLSv1.0": true,
+	"TLSv1.1": true,
+}
+
+// TLS field as logged, empty when missing or for plain HTTP ("-")
+func tlsField(value string) string {
+	if value == "-" {
+		return ""
+	}
+	return value
+}
+
+// Canonical name of a TLS version, as nginx logs it (e.g. TLSv1.2) except
+// for TLSv1, named TLSv1.0 to tell it apart
+func canonicalTLSVersion(version string) string {
+	version = tlsField(version)
+	switch strings.ToUpper(strings.Replace(version, " ", "", -1)) {
+	case "TLSV1", "TLSV1.0", "TLS1.0":
+		return "TLSv1.0"
+	case "TLSV1.1", "TLS1.1":
+		return "TLSv1.1"
+	case "TLSV1.2", "TLS1.2":
+		return "TLSv1.2"
+	case "TLSV1.3", "TLS1.3":
+		return "TLSv1.3"
+	case "SSLV3", "SSL3.0":
+		return "SSLv3"
+	}
+	return version
+}
+
+// Percentage of requests over TLS made with legacy versions
+func (s *stats) legacyTLSPercent() float64 {
+	total, legacy := 0, 0
+	for version, count := range s.tlsVersions {
+		total += count
+		if legacyTLSVersions[version] {
+			legacy += count
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(legacy) * 100 / float64(total)
+}
+
+// Dumps the distribution of TLS versions and the N most used ciphers
+func (s *stats) dumpTLS(w *tabwriter.Writer, n int) {
+	if len(s.tlsVersions) == 0 {
+		return
+	}
+	dumpCounts(w, "Requests per TLS version", s.scaled(s.tlsVersions))
+	fmt.Fprintf(w, "Legacy TLS (1.1 and older): %.2f%%\n", s.legacyTLSPercent())
+	if len(s.tlsCiphers) > 0 {
+		dumpTopCounts(w, "TLS ciphers", s.scaled(s.tlsCiphers), n)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalTLSVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected string
+	}{
+		{"", ""},
+		{"-", ""},
+		{"TLSv1", "TLSv1.0"},
+		{"TLSv1.1", "TLSv1.1"},
+		{"tls1.2", "TLSv1.2"},
+		{"TLS 1.3", "TLSv1.3"},
+		{"SSLv3", "SSLv3"},
+		{"QUICv1", "QUICv1"},
+	}
+	for _, test := range tests {
+		if version := canonicalTLSVersion(test.version); version != test.expected {
+			t.Errorf("%q: %+v != %+v", test.version, version, test.expected)
+		}
+	}
+}
+
+func TestParseTLSFields(t *testing.T) {
+	p, err := newRegexpParser(`(?P<ip>\S+) \[(?P<time>[^]]+)\] "(?P<request>[^"]*)" (?P<status>\d+) (?P<ssl_protocol>\S+) (?P<ssl_cipher>\S+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line    string
+		version string
+		cipher  string
+	}{
+		{`10.0.0.1 [2018-05-09T16:00:39Z] "GET /api/user HTTP/1.1" 200 TLSv1 ECDHE-RSA-AES128-SHA`, "TLSv1.0", "ECDHE-RSA-AES128-SHA"},
+		{`10.0.0.1 [2018-05-09T16:00:39Z] "GET /api/user HTTP/2.0" 200 TLSv1.3 TLS_AES_128_GCM_SHA256`, "TLSv1.3", "TLS_AES_128_GCM_SHA256"},
+		{`10.0.0.1 [2018-05-09T16:00:39Z] "GET /api/user HTTP/1.1" 200 - -`, "", ""},
+	}
+	for _, test := range tests {
+		record, err := p.parse(test.line)
+		if err != nil {
+			t.Errorf("%q: %s", test.line, err)
+			continue
+		}
+		if record.TLSVersion != test.version || record.TLSCipher != test.cipher {
+			t.Errorf("%q: %+v %+v != %+v %+v", test.line, record.TLSVersion, record.TLSCipher, test.version, test.cipher)
+		}
+	}
+}
+
+func TestDumpTLS(t *testing.T) {
+	s := newStats()
+	var out bytes.Buffer
+	s.updateStats(&logRecord{Section: "/", StatusCode: 200})
+	s.writeStats(&out)
+	if strings.Contains(out.String(), "TLS") {
+		t.Errorf("Expected no TLS stats without TLS fields:\n%s", out.String())
+	}
+
+	for _, version := range []string{"TLSv1.0", "TLSv1.2", "TLSv1.3", "TLSv1.3"} {
+		s.updateStats(&logRecord{Section: "/", StatusCode: 200, TLSVersion: version, TLSCipher: "TLS_AES_128_GCM_SHA256"})
+	}
+	out.Reset()
+	s.writeStats(&out)
+	for _, expected := range []string{"Requests per TLS version:", "1 TLSv1.0", "2 TLSv1.3", "Legacy TLS (1.1 and older): 25.00%", "4 TLS_AES_128_GCM_SHA256"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+// Command-line flag to control the QPS trend display
+var trendIntervals = flag.Int("trend", 30, "Number of past intervals whose QPS is shown as a sparkline, 0 to disable")
+
+// Bars of increasing height used in sparklines
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// Render values as a sparkline, scaled between their minimum and maximum
+func sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	min, max := values[0], values[0]
+	for _, v := range values {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	bars := make([]rune, len(values))
+	for i, v := range values {
+		bar := 0
+		if max > min {
+			bar = int((v - min) / (max - min) * float64(len(sparkBars)-1))
+		}
+		bars[i] = sparkBars[bar]
+	}
+	return string(bars)
+}
+
+// QPS of the last N closed intervals, oldest first
+func (s *stats) qpsTrend(n int) []float64 {
+	if s.history == nil {
+		return nil
+	}
+	intervals := s.history.intervals
+	if len(intervals) > n {
+		intervals = intervals[len(intervals)-n:]
+	}
+	var trend []float64
+	for _, interval := range intervals {
+		trend = append(trend, interval.qps()*s.weight())
+	}
+	return trend
+}
+
+// Dump the QPS trend as a sparkline, along with its minimum, average and
+// maximum. Nothing is dumped until an interval has been closed
+func (s *stats) dumpTrend(w io.Writer, n int) {
+	trend := s.qpsTrend(n)
+	if len(trend) == 0 {
+		return
+	}
+	min, max, sum := trend[0], trend[0], 0.0
+	for _, qps := range trend {
+		if qps < min {
+			min = qps
+		}
+		if qps > max {
+			max = qps
+		}
+		sum += qps
+	}
+	fmt.Fprintf(w, "QPS trend: %s (min %.2f, avg %.2f, max %.2f)\n", sparkline(trend), min, sum/float64(len(trend)), max)
+}
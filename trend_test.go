@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSparkline(t *testing.T) {
+	tests := map[string][]float64{
+		"":         nil,
+		"▁▁▁":      {5, 5, 5},
+		"▁▄█":      {0, 5, 10},
+		"█▆▄▂▁▁▂▄": {8, 6, 4, 2, 0, 1, 2, 4},
+	}
+	for expected, values := range tests {
+		if spark := sparkline(values); spark != expected {
+			t.Errorf("%+v != %+v", expected, spark)
+		}
+	}
+}
+
+func TestDumpTrend(t *testing.T) {
+	s := newStats()
+	start := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+	s.history = newHistory(time.Hour, start)
+	for n := 1; n <= 3; n++ {
+		for r := 0; r < n*10; r++ {
+			s.history.current.add(&logRecord{StatusCode: 200, Section: "/"})
+		}
+		s.history.rotate(start.Add(time.Duration(n) * 10 * time.Second))
+	}
+
+	var buf bytes.Buffer
+	s.dumpTrend(&buf, 2)
+	expected := "QPS trend: ▁█ (min 2.00, avg 2.50, max 3.00)\n"
+	if buf.String() != expected {
+		t.Errorf("%q != %q", expected, buf.String())
+	}
+}
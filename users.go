@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Command-line flags to alert on individual authenticated users sending too
+// much traffic
+var userQPS = flag.Float64("user-qps", 0, "Alert on authenticated users exceeding this average QPS in the alerting window (0 disables)")
+var userRequests = flag.Int("user-requests", 0, "Alert on authenticated users sending more than this many requests in the alerting window (0 disables)")
+
+// Requests of an authenticated user, and how many of them failed
+type userTraffic struct {
+	requests     int
+	clientErrors int
+	serverErrors int
+}
+
+// Authenticated user of a record, empty if anonymous ("-")
+func authenticatedUser(log *logRecord) string {
+	if log.User == "-" {
+		return ""
+	}
+	return log.User
+}
+
+func (s *stats) updateUsers(log *logRecord) {
+	user := authenticatedUser(log)
+	if user == "" || s.userTraffic == nil {
+		return
+	}
+	traffic := s.userTraffic[user]
+	if traffic == nil {
+		traffic = &userTraffic{}
+		s.userTraffic[user] = traffic
+	}
+	traffic.requests++
+	switch {
+	case log.StatusCode >= 500:
+		traffic.serverErrors++
+	case log.StatusCode >= 400:
+		traffic.clientErrors++
+	}
+}
+
+// Dumps the N authenticated users with the most requests, along with the
+// share of their requests getting 4XX and 5XX responses
+func (s *stats) dumpTopUsers(w *tabwriter.Writer, n int) {
+	if len(s.userTraffic) == 0 {
+		return
+	}
+	counts := make(map[string]int)
+	for user, traffic := range s.userTraffic {
+		counts[user] = traffic.requests
+	}
+	fmt.Fprintf(w, "Top %d users:\n", n)
+	for _, v := range topCounts(counts, n) {
+		traffic := s.userTraffic[v.key]
+		fmt.Fprintf(w, "%d\t %s (4XX %.2f%%, 5XX %.2f%%)\n", int(math.Round(float64(v.count)*s.weight())), v.key,
+			float64(traffic.clientErrors)*100/float64(traffic.requests), float64(traffic.serverErrors)*100/float64(traffic.requests))
+	}
+}
+
+// Alert firing when single authenticated users exceed a query rate or a
+// number of requests in the window, e.g. API clients looping on a call
+type userTrafficRule struct {
+	qps      float64
+	requests int
+}
+
+func (r *userTrafficRule) name() string {
+	return "User-traffic"
+}
+
+func (r *userTrafficRule) evaluate(s *stats) (bool, string) {
+	counts := make(map[string]int)
+	for _, record := range s.logsInWindow {
+		if user := authenticatedUser(record); user != "" {
+			counts[user]++
+		}
+	}
+
+	var offenders []string
+	for user, count := range counts {
+		requests := float64(count) * s.weight()
+		if (r.qps > 0 && s.windowRate(count) > r.qps) || (r.requests > 0 && requests > float64(r.requests)) {
+			offenders = append(offenders, user)
+		}
+	}
+	sort.Strings(offenders)
+
+	var details []string
+	for _, user := range offenders {
+		requests := math.Round(float64(counts[user]) * s.weight())
+		details = append(details, fmt.Sprintf("%s (%.0f requests, %f queries per second on average)", user, requests, s.windowRate(counts[user])))
+	}
+	return len(offenders) > 0, fmt.Sprintf("from %s", strings.Join(details, ", "))
+}
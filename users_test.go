@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserTrafficRule(t *testing.T) {
+	s := newStats()
+	ts := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+
+	// Over 10 seconds, one user sends 21 requests, another one 3, and
+	// anonymous clients many more
+	for i := 0; i <= 20; i++ {
+		at := ts.Add(time.Duration(i) * 500 * time.Millisecond)
+		s.updateAlerting(&logRecord{IP: "10.0.0.1", User: "alice", Timestamp: at, StatusCode: 200, Section: "/"})
+		s.updateAlerting(&logRecord{IP: "10.0.0.3", User: "-", Timestamp: at, StatusCode: 200, Section: "/"})
+		s.updateAlerting(&logRecord{IP: "10.0.0.3", User: "-", Timestamp: at, StatusCode: 200, Section: "/"})
+		if i%10 == 0 {
+			s.updateAlerting(&logRecord{IP: "10.0.0.2", User: "bob", Timestamp: at, StatusCode: 200, Section: "/"})
+		}
+	}
+
+	type testData struct {
+		rule     *userTrafficRule
+		firing   bool
+		expected string
+	}
+
+	x := []testData{
+		{&userTrafficRule{qps: 1}, true, "from alice (21 requests, 2.100000 queries per second on average)"},
+		{&userTrafficRule{qps: 5}, false, "from "},
+		{&userTrafficRule{requests: 2}, true, "from alice (21 requests, 2.100000 queries per second on average), bob (3 requests, 0.300000 queries per second on average)"},
+		{&userTrafficRule{requests: 50}, false, "from "},
+	}
+	for _, elem := range x {
+		firing, detail := elem.rule.evaluate(s)
+		if firing != elem.firing || detail != elem.expected {
+			t.Errorf("%+v: %v %q != %v %q", *elem.rule, elem.firing, elem.expected, firing, detail)
+		}
+	}
+}
+
+func TestDumpTopUsers(t *testing.T) {
+	s := newStats()
+	var out bytes.Buffer
+	s.updateStats(&logRecord{IP: "10.0.0.1", User: "-", Section: "/", StatusCode: 200})
+	s.writeStats(&out)
+	if strings.Contains(out.String(), "users") {
+		t.Errorf("Expected no users without authenticated requests:\n%s", out.String())
+	}
+
+	for _, status := range []int{200, 200, 401, 503} {
+		s.updateStats(&logRecord{IP: "10.0.0.1", User: "alice", Section: "/", StatusCode: status})
+	}
+	s.updateStats(&logRecord{IP: "10.0.0.2", User: "bob", Section: "/", StatusCode: 200})
+	out.Reset()
+	s.writeStats(&out)
+	for _, expected := range []string{"Top 5 users:", "4 alice (4XX 25.00%, 5XX 25.00%)", "1 bob (4XX 0.00%, 5XX 0.00%)"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, out.String())
+		}
+	}
+}
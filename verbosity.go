@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+)
+
+// Command-line flags to choose how much is printed
+var quiet = flag.Bool("quiet", false, "Only print alerts being triggered, abandoned or changing severity, e.g. when running under a supervisor")
+var verbose = flag.Bool("v", false, "Also print a summary of every closed interval")
+var veryVerbose = flag.Bool("vv", false, "As -v, also logging lines parsed with a fallback format")
+
+// How much is printed, from only alerts to parse warnings
+type verbosityLevel int
+
+const (
+	verbosityQuiet verbosityLevel = iota - 1
+	verbosityNormal
+	verbosityVerbose
+	verbosityDebug
+)
+
+// Verbosity selected through command-line flags
+func verbosity() verbosityLevel {
+	switch {
+	case *veryVerbose:
+		return verbosityDebug
+	case *verbose:
+		return verbosityVerbose
+	case *quiet:
+		return verbosityQuiet
+	}
+	return verbosityNormal
+}
+
+// Check that the verbosity flags make sense together
+func checkVerbosity() error {
+	if *quiet && (*verbose || *veryVerbose) {
+		return fmt.Errorf("-quiet cannot be combined with -v or -vv")
+	}
+	return nil
+}
+
+// One-line summary of a closed interval
+func intervalSummary(i *interval, weight float64) string {
+	requests := int(math.Round(float64(i.Requests) * weight))
+	summary := fmt.Sprintf("Interval %s-%s: %d requests", displayTime(i.Start).Format("15:04:05"), displayTime(i.End).Format("15:04:05"), requests)
+	if seconds := i.End.Sub(i.Start).Seconds(); seconds > 0 {
+		summary += fmt.Sprintf(" (%.2f QPS)", float64(requests)/seconds)
+	}
+	summary += fmt.Sprintf(", %d bytes", int(math.Round(float64(i.Bytes)*weight)))
+	if i.Requests > 0 {
+		summary += fmt.Sprintf(", %.2f%% 5XX", float64(i.ResponseCodes["5XX"])*100/float64(i.Requests))
+	}
+	if top := topCounts(i.Sections, 1); len(top) > 0 {
+		summary += ", busiest section " + top[0].key
+	}
+	return summary
+}
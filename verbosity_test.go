@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerbosity(t *testing.T) {
+	defer func(q, v, vv bool) { *quiet, *verbose, *veryVerbose = q, v, vv }(*quiet, *verbose, *veryVerbose)
+	tests := []struct {
+		quiet, verbose, veryVerbose bool
+		expected                    verbosityLevel
+	}{
+		{false, false, false, verbosityNormal},
+		{true, false, false, verbosityQuiet},
+		{false, true, false, verbosityVerbose},
+		{false, true, true, verbosityDebug},
+		{false, false, true, verbosityDebug},
+	}
+	for _, test := range tests {
+		*quiet, *verbose, *veryVerbose = test.quiet, test.verbose, test.veryVerbose
+		if level := verbosity(); level != test.expected {
+			t.Errorf("%+v != %+v", level, test.expected)
+		}
+	}
+
+	*quiet, *verbose = true, true
+	if err := checkVerbosity(); err == nil {
+		t.Errorf("Expected an error for -quiet along with -v")
+	}
+}
+
+func TestIntervalSummary(t *testing.T) {
+	start := time.Date(2018, 5, 9, 16, 0, 0, 0, time.UTC)
+	i := &interval{
+		Start:         start,
+		End:           start.Add(10 * time.Second),
+		Requests:      40,
+		Bytes:         1000,
+		ResponseCodes: map[string]int{"2XX": 38, "5XX": 2},
+		Sections:      map[string]int{"/api": 30, "/static": 10},
+	}
+	expected := "Interval 16:00:00-16:00:10: 80 requests (8.00 QPS), 2000 bytes, 5.00% 5XX, busiest section /api"
+	if summary := intervalSummary(i, 2); summary != expected {
+		t.Errorf("%+v != %+v", summary, expected)
+	}
+}
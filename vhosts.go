@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Command-line flag to alert on traffic to individual virtual hosts
+var vhostQPS = flag.Float64("vhost-qps", 0, "Average QPS threshold for per-virtual-host traffic alerts (0 disables)")
+var vhostQPSCritical = flag.Float64("vhost-qps-critical", 0, "Average QPS above which per-virtual-host traffic alerts are critical rather than warnings (0 makes every alert critical)")
+
+// Alert firing when any virtual host exceeds a query rate in the window
+type vhostTrafficRule struct {
+	threshold float64
+	critical  float64
+}
+
+func (r *vhostTrafficRule) name() string {
+	return "Vhost-traffic"
+}
+
+func (r *vhostTrafficRule) evaluate(s *stats) (bool, string) {
+	counts := make(map[string]int)
+	for _, record := range s.logsInWindow {
+		if record.VHost != "" {
+			counts[record.VHost]++
+		}
+	}
+
+	var vhosts []string
+	for vhost := range counts {
+		vhosts = append(vhosts, vhost)
+	}
+	sort.Strings(vhosts)
+
+	var details []string
+	for _, vhost := range vhosts {
+		if qps := s.windowRate(counts[vhost]); qps > r.threshold {
+			details = append(details, fmt.Sprintf("%s at %f queries per second on average", vhost, qps))
+		}
+	}
+	return len(details) > 0, fmt.Sprintf("for %s", strings.Join(details, ", "))
+}
+
+func (r *vhostTrafficRule) severity(s *stats) severity {
+	if r.critical == 0 {
+		return 0
+	}
+	if critical, _ := (&vhostTrafficRule{threshold: r.critical}).evaluate(s); critical {
+		return severityCritical
+	}
+	return severityWarning
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVHostTrafficRule(t *testing.T) {
+	s := newStats()
+	ts := time.Date(2019, 01, 01, 10, 00, 00, 0, time.UTC)
+
+	// Over 10 seconds, one site gets 21 requests and another one 3
+	for i := 0; i <= 20; i++ {
+		at := ts.Add(time.Duration(i) * 500 * time.Millisecond)
+		s.updateStats(&logRecord{VHost: "api.example.com", Timestamp: at, StatusCode: 200, Section: "/users"})
+		if i%10 == 0 {
+			s.updateStats(&logRecord{VHost: "www.example.com", Timestamp: at, StatusCode: 200, Section: "/"})
+		}
+	}
+
+	if s.vhostCounts["api.example.com"] != 21 || s.vhostSections["www.example.com"]["/"] != 3 {
+		t.Errorf("Unexpected virtual host counters %v %v", s.vhostCounts, s.vhostSections)
+	}
+
+	r := &vhostTrafficRule{threshold: 1}
+	firing, detail := r.evaluate(s)
+	if !firing {
+		t.Errorf("Expected virtual host alert to fire")
+	}
+	if expected := "for api.example.com at 2.100000 queries per second on average"; detail != expected {
+		t.Errorf("%q != %q", expected, detail)
+	}
+}